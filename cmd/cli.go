@@ -0,0 +1,312 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Exit codes.
+const (
+	exitOK      = 0
+	exitFailure = 1 // the command failed
+	exitUsage   = 2 // bad command line
+)
+
+// command is a subcommand of the CLI. Commands either run or have
+// subcommands (e.g. "io watch").
+type command struct {
+	name    string
+	args    string // synopsis of the positional arguments
+	summary string
+	// minArgs and maxArgs are the number of positional arguments allowed.
+	// maxArgs is -1 for no limit.
+	minArgs, maxArgs int
+	// flags defines the command's flags on fs and returns the function
+	// running it with the positional arguments.
+	flags func(fs *flag.FlagSet) runFunc
+	sub   []*command
+	// quiet keeps events out of the output of commands that stream to
+	// stdout.
+	quiet  bool
+	raw    bool // the arguments aren't parsed as flags
+	hidden bool // not listed in help
+}
+
+type runFunc func(env *env, args []string) error
+
+// usageError is a bad command line. It exits with exitUsage after
+// printing the command's usage.
+type usageError struct {
+	msg string
+}
+
+func (e usageError) Error() string {
+	return e.msg
+}
+
+func usagef(format string, args ...any) error {
+	return usageError{fmt.Sprintf(format, args...)}
+}
+
+// lookup resolves the command named by the leading words of args returning
+// it, its full name, and the remaining arguments. It returns nil if the
+// first word isn't a command.
+func lookup(cmds []*command, args []string) (*command, string, []string) {
+	var c *command
+	var path []string
+	for len(args) > 0 {
+		var next *command
+		for _, s := range cmds {
+			if s.name == args[0] {
+				next = s
+				break
+			}
+		}
+		if next == nil {
+			break
+		}
+		c, cmds, args = next, next.sub, args[1:]
+		path = append(path, next.name)
+	}
+	return c, strings.Join(path, " "), args
+}
+
+// newFlagSet returns the flag set of a command with its flags defined.
+func (c *command) newFlagSet(name string) (*flag.FlagSet, runFunc) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	var run runFunc
+	if c.flags != nil {
+		run = c.flags(fs)
+	}
+	return fs, run
+}
+
+// execute runs the command named by args returning the exit code.
+func execute(cmds []*command, args []string) int {
+	c, name, args := lookup(cmds, args)
+	if c == nil {
+		if len(args) == 0 {
+			printUsage(os.Stderr, cmds)
+		} else {
+			fmt.Fprintf(os.Stderr, "xbee: unknown command %q (see xbee help)\n", args[0])
+		}
+		return exitUsage
+	}
+	if len(c.sub) > 0 {
+		if len(args) > 0 && (args[0] == "-h" || args[0] == "-help" || args[0] == "--help") {
+			printCommandUsage(os.Stdout, c, name)
+			return exitOK
+		}
+		if len(args) > 0 {
+			fmt.Fprintf(os.Stderr, "xbee %s: unknown command %q\n", name, args[0])
+		}
+		printCommandUsage(os.Stderr, c, name)
+		return exitUsage
+	}
+	fs, run := c.newFlagSet(name)
+	pos, err := args, error(nil)
+	if !c.raw {
+		pos, err = parseInterspersed(fs, args)
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		printCommandUsage(os.Stdout, c, name)
+		return exitOK
+	}
+	if err == nil && (len(pos) < c.minArgs || c.maxArgs >= 0 && len(pos) > c.maxArgs) {
+		err = usagef("wrong number of arguments")
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "xbee %s: %s\n", name, err)
+		printCommandUsage(os.Stderr, c, name)
+		return exitUsage
+	}
+
+	e := &env{cmd: c, json: *flagJSON}
+	defer e.close()
+	if err := run(e, pos); err != nil {
+		fmt.Fprintf(os.Stderr, "xbee %s: %s\n", name, err)
+		var uerr usageError
+		if errors.As(err, &uerr) {
+			printCommandUsage(os.Stderr, c, name)
+			return exitUsage
+		}
+		return exitFailure
+	}
+	return exitOK
+}
+
+// printUsage prints the global usage listing the commands.
+func printUsage(w io.Writer, cmds []*command) {
+	fmt.Fprint(w, "Usage: xbee [flags] COMMAND [arguments]\n\nCommands:\n")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	var list func(prefix string, cmds []*command)
+	list = func(prefix string, cmds []*command) {
+		for _, c := range cmds {
+			if c.hidden {
+				continue
+			}
+			if len(c.sub) > 0 {
+				list(prefix+c.name+" ", c.sub)
+				continue
+			}
+			fmt.Fprintf(tw, "  %s%s %s\t%s\n", prefix, c.name, c.args, c.summary)
+		}
+	}
+	list("", cmds)
+	tw.Flush()
+	fmt.Fprint(w, "\nFlags:\n")
+	flag.CommandLine.SetOutput(w)
+	flag.PrintDefaults()
+	fmt.Fprint(w, "\nRun xbee help COMMAND for the flags of a command.\n")
+}
+
+// printCommandUsage prints the synopsis and flags of a command or lists
+// its subcommands.
+func printCommandUsage(w io.Writer, c *command, name string) {
+	if len(c.sub) > 0 {
+		fmt.Fprintf(w, "Usage: xbee %s COMMAND\n\nCommands:\n", name)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, s := range c.sub {
+			fmt.Fprintf(tw, "  %s %s %s\t%s\n", name, s.name, s.args, s.summary)
+		}
+		tw.Flush()
+		return
+	}
+	fs, _ := c.newFlagSet(name)
+	synopsis := "xbee " + name
+	if hasFlags(fs) {
+		synopsis += " [flags]"
+	}
+	if c.args != "" {
+		synopsis += " " + c.args
+	}
+	fmt.Fprintf(w, "Usage: %s\n\n%s\n", synopsis, c.summary)
+	if hasFlags(fs) {
+		fmt.Fprint(w, "\nFlags:\n")
+		fs.SetOutput(w)
+		fs.PrintDefaults()
+	}
+}
+
+func hasFlags(fs *flag.FlagSet) bool {
+	n := 0
+	fs.VisitAll(func(*flag.Flag) { n++ })
+	return n > 0
+}
+
+// parseInterspersed parses args allowing flags after positional arguments
+// and returns the positional arguments. Arguments after "--" are always
+// positional.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var pos []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if len(rest) == 0 {
+			return pos, nil
+		}
+		if len(args) > len(rest) && args[len(args)-len(rest)-1] == "--" {
+			return append(pos, rest...), nil
+		}
+		pos = append(pos, rest[0])
+		args = rest[1:]
+	}
+}
+
+// helpCommand implements "help [COMMAND]".
+func helpCommand(cmds func() []*command) func(fs *flag.FlagSet) runFunc {
+	return func(fs *flag.FlagSet) runFunc {
+		return func(env *env, args []string) error {
+			if len(args) == 0 {
+				printUsage(os.Stdout, cmds())
+				return nil
+			}
+			c, name, rest := lookup(cmds(), args)
+			if c == nil || len(rest) > 0 {
+				return usagef("unknown command %q", strings.Join(args, " "))
+			}
+			printCommandUsage(os.Stdout, c, name)
+			return nil
+		}
+	}
+}
+
+const bashCompletion = `# bash completion for xbee. Load with: source <(xbee completion bash)
+_xbee() {
+	local IFS=$'\n'
+	COMPREPLY=($(compgen -W "$("${COMP_WORDS[0]}" __complete "${COMP_WORDS[@]:1:COMP_CWORD-1}" 2>/dev/null)" -- "${COMP_WORDS[COMP_CWORD]}"))
+}
+complete -o default -F _xbee xbee
+`
+
+const zshCompletion = `# zsh completion for xbee. Load with: source <(xbee completion zsh)
+autoload -U +X bashcompinit && bashcompinit
+` + bashCompletion
+
+// printText returns the flags function of a command printing s.
+func printText(s string) func(fs *flag.FlagSet) runFunc {
+	return func(fs *flag.FlagSet) runFunc {
+		return func(env *env, args []string) error {
+			_, err := io.WriteString(os.Stdout, s)
+			return err
+		}
+	}
+}
+
+// completeCommand implements the hidden __complete command used by the
+// completion scripts. It prints the candidates for the word following
+// args, one per line.
+func completeCommand(cmds func() []*command) func(fs *flag.FlagSet) runFunc {
+	return func(fs *flag.FlagSet) runFunc {
+		return func(env *env, args []string) error {
+			// Skip the global flags and their values.
+			for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+				name, _, hasValue := strings.Cut(strings.TrimLeft(args[0], "-"), "=")
+				args = args[1:]
+				f := flag.CommandLine.Lookup(name)
+				if f == nil || hasValue {
+					continue
+				}
+				if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+					continue
+				}
+				if len(args) > 0 {
+					args = args[1:]
+				}
+			}
+			var cands []string
+			c, name, rest := lookup(cmds(), args)
+			switch {
+			case c == nil && len(rest) == 0:
+				for _, c := range cmds() {
+					if !c.hidden {
+						cands = append(cands, c.name)
+					}
+				}
+				flag.VisitAll(func(f *flag.Flag) { cands = append(cands, "-"+f.Name) })
+			case c == nil:
+			case len(c.sub) > 0 && len(rest) == 0:
+				for _, s := range c.sub {
+					cands = append(cands, s.name)
+				}
+			case len(c.sub) == 0:
+				fs, _ := c.newFlagSet(name)
+				fs.VisitAll(func(f *flag.Flag) { cands = append(cands, "-"+f.Name) })
+			}
+			sort.Strings(cands)
+			for _, s := range cands {
+				fmt.Println(s)
+			}
+			return nil
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/samuel/go-xbee/xbee"
+	"github.com/samuel/go-xbee/xbee/frames"
+)
+
+// commands returns the commands of the CLI.
+func commands() []*command {
+	return []*command{
+		{name: "info", summary: "Show the settings of the local module", flags: infoCmd},
+		{name: "find", summary: "List the modules connected to serial ports", flags: findCmd},
+		{name: "scan", args: "[DURATION]", maxArgs: 1, summary: "Scan for networks or access points (default 6s)", flags: scanCmd},
+		{name: "discover", args: "[DURATION]", maxArgs: 1, summary: "Discover the nodes on the network (default 6s)", flags: discoverCmd},
+		{name: "energy", summary: "Measure the energy on each channel once", flags: energyCmd},
+		{name: "spectrum", summary: "Repeat energy scans drawing a chart of each channel", flags: spectrumCmd, quiet: true},
+		{name: "rangetest", args: "ADDR", minArgs: 1, maxArgs: 1, summary: "Measure the link to a node with its loopback cluster", flags: rangeTestCmd},
+		{name: "map", summary: "Print the network topology as Graphviz DOT or JSON", flags: mapCmd},
+		{name: "nodes", sub: []*command{
+			{name: "watch", summary: "Show a live table of the nodes heard from", flags: nodesWatchCmd, quiet: true},
+		}},
+		{name: "dump", summary: "Print the diagnostic registers", flags: dumpCmd},
+		{name: "config", sub: []*command{
+			{name: "get", args: "REG...", minArgs: 1, maxArgs: -1, summary: "Read registers", flags: configGetCmd},
+			{name: "set", args: "REG VALUE", minArgs: 2, maxArgs: 2, summary: "Set a register", flags: configSetCmd},
+		}},
+		{name: "shell", summary: "Run AT commands interactively", flags: shellCmd},
+		{name: "provision", summary: "Configure the module to form or join a network", flags: provisionCmd},
+		{name: "reset", summary: "Reset a module, optionally to its factory defaults", flags: resetCmd},
+		{name: "firmware", sub: []*command{
+			{name: "info", summary: "Show the firmware and hardware versions", flags: firmwareInfoCmd},
+			{name: "update", summary: "Update the firmware of the local module or a remote node", flags: firmwareUpdateCmd},
+		}},
+		{name: "io", sub: []*command{
+			{name: "watch", args: "[ADDR]", maxArgs: 1, summary: "Stream IO samples, configuring sampling on a node", flags: ioWatchCmd, quiet: true},
+			{name: "set", args: "ADDR PIN high|low|input|adc|disabled", minArgs: 3, maxArgs: 3, summary: "Set the mode of a pin of a node", flags: ioSetCmd},
+		}},
+		{name: "tx", args: "ADDR", minArgs: 1, maxArgs: 1, summary: "Transmit stdin to a node", flags: txCmd},
+		{name: "rx", summary: "Write the payload of received packets to stdout", flags: rxCmd, quiet: true},
+		{name: "tunnel", args: "ADDR", minArgs: 1, maxArgs: 1, summary: "Carry IP packets of a TUN interface to a node (experimental)", flags: tunnelCmd, quiet: true},
+		{name: "monitor", summary: "Print every frame sent and received", flags: monitorCmd, quiet: true},
+		{name: "gateway", summary: "Run as a service keeping the module open with the configured services", flags: gatewayCmd},
+		{name: "replay", args: "FILE", minArgs: 1, maxArgs: 1, summary: "Print the frames recorded with -r", flags: replayCmd},
+		{name: "help", args: "[COMMAND]", maxArgs: -1, summary: "Show the help of a command", flags: helpCommand(commands)},
+		{name: "completion", sub: []*command{
+			{name: "bash", summary: "Print the bash completion script", flags: printText(bashCompletion)},
+			{name: "zsh", summary: "Print the zsh completion script", flags: printText(zshCompletion)},
+		}},
+		{name: "__complete", maxArgs: -1, raw: true, hidden: true, flags: completeCommand(commands)},
+	}
+}
+
+func replayCmd(fs *flag.FlagSet) runFunc {
+	return func(env *env, args []string) error {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return frames.Replay(f, func(rec *frames.Record, fr frames.Frame, err error) error {
+			if err != nil {
+				fmt.Printf("%s %s % x: %s\n", rec.Time.Format(time.RFC3339Nano), rec.Direction, rec.Data, err)
+			} else {
+				fmt.Printf("%s %s %+v\n", rec.Time.Format(time.RFC3339Nano), rec.Direction, fr)
+			}
+			return nil
+		})
+	}
+}
+
+func findCmd(fs *flag.FlagSet) runFunc {
+	return func(env *env, args []string) error {
+		found, err := xbee.FindXBees(*flagBaud)
+		if err != nil {
+			return err
+		}
+		fmt.Println("Modules:")
+		for _, d := range found {
+			fmt.Printf("\t%s: serial number %s, firmware %04x, hardware %04x\n", d.Port.Path, d.SerialNumber, d.FirmwareVersion, d.HardwareVersion)
+		}
+		return nil
+	}
+}
+
+// waitArg parses the optional duration argument of scan and discover.
+func waitArg(args []string) (time.Duration, error) {
+	if len(args) == 0 {
+		return 6 * time.Second, nil
+	}
+	d, err := time.ParseDuration(args[0])
+	if err != nil {
+		return 0, usagef("bad duration %q", args[0])
+	}
+	return d, nil
+}
+
+func scanCmd(fs *flag.FlagSet) runFunc {
+	return func(env *env, args []string) error {
+		waitTime, err := waitArg(args)
+		if err != nil {
+			return err
+		}
+		xb, err := env.open()
+		if err != nil {
+			return err
+		}
+		devices, err := xb.ActiveScan(waitTime)
+		if err != nil {
+			return err
+		}
+		if env.json {
+			for _, d := range devices {
+				printJSON(d)
+			}
+			return nil
+		}
+		fmt.Println("Devices:")
+		for _, d := range devices {
+			if d.AccessPoint != nil {
+				fmt.Printf("\t%+v\n", d.AccessPoint)
+			} else {
+				fmt.Printf("\t%+v\n", d)
+			}
+		}
+		return nil
+	}
+}
+
+func energyCmd(fs *flag.FlagSet) runFunc {
+	return func(env *env, args []string) error {
+		xb, err := env.open()
+		if err != nil {
+			return err
+		}
+		energies, err := xb.EnergyScan(100 * time.Millisecond)
+		if err != nil {
+			return err
+		}
+		fmt.Println("Channels:")
+		for _, e := range energies {
+			fmt.Printf("\t%d (0x%02x): %d dBm\n", e.Channel, e.Channel, e.Energy)
+		}
+		fmt.Printf("Quietest 4 channels: SC=%04x\n", xbee.QuietestChannels(energies, 4))
+		return nil
+	}
+}
+
+func discoverCmd(fs *flag.FlagSet) runFunc {
+	return func(env *env, args []string) error {
+		waitTime, err := waitArg(args)
+		if err != nil {
+			return err
+		}
+		xb, err := env.open()
+		if err != nil {
+			return err
+		}
+		nodes, err := xb.NodeDiscover(waitTime)
+		if err != nil {
+			return err
+		}
+		if env.json {
+			for _, n := range nodes {
+				printJSON(n)
+			}
+			return nil
+		}
+		fmt.Println("Nodes:")
+		for _, n := range nodes {
+			fmt.Printf("\t%+v\n", n)
+		}
+		return nil
+	}
+}
+
+func dumpCmd(fs *flag.FlagSet) runFunc {
+	return func(env *env, args []string) error {
+		xb, err := env.open()
+		if err != nil {
+			return err
+		}
+		regs, err := xb.DumpRegisters()
+		if err != nil {
+			return err
+		}
+		for _, cmd := range xbee.DiagnosticRegisters {
+			switch v := regs[cmd].(type) {
+			case nil:
+			case uint64:
+				fmt.Printf("%s: %x\n", cmd, v)
+			default:
+				fmt.Printf("%s: %v\n", cmd, v)
+			}
+		}
+		return nil
+	}
+}
+
+func shellCmd(fs *flag.FlagSet) runFunc {
+	return func(env *env, args []string) error {
+		xb, err := env.open()
+		if err != nil {
+			return err
+		}
+		return runShell(xb)
+	}
+}
+
+func infoCmd(fs *flag.FlagSet) runFunc {
+	return func(env *env, args []string) error {
+		xb, err := env.open()
+		if err != nil {
+			return err
+		}
+		fields := []struct {
+			key, label, format string
+			get                func() (any, error)
+		}{
+			{"module", "Module", "%s", func() (any, error) {
+				c, err := xb.Capabilities()
+				if err != nil {
+					return nil, err
+				}
+				return c.String(), nil
+			}},
+			{"escaped", "Escaped", "%t", func() (any, error) { return xb.APIEnabled() }},
+			{"serialNumber", "Serial number", "%s", func() (any, error) { return xb.SerialNumber() }},
+			{"nodeID", "Node identifier", "%s", func() (any, error) { return xb.NodeIdentifier() }},
+			{"firmwareVersion", "Firmware version", "%04x", func() (any, error) { return xb.FirmwareVersion() }},
+			{"hardwareVersion", "Hardware version", "%04x", func() (any, error) { return xb.HardwareVersion() }},
+			{"associationIndication", "Association indication", "%d", func() (any, error) { return xb.AssociationIndication() }},
+			{"extendedPANID", "Extended PAN ID", "%s", func() (any, error) {
+				v, err := xb.ExtendedPANID()
+				return fmt.Sprintf("%016x", v), err
+			}},
+			{"operatingExtendedPANID", "Operating extended PAN ID", "%s", func() (any, error) {
+				v, err := xb.OperatingExtendedPANID()
+				return fmt.Sprintf("%016x", v), err
+			}},
+			{"encryptionEnabled", "Encryption enabled", "%t", func() (any, error) { return xb.EncryptionEnabled() }},
+			{"encryptionOptions", "Encryption options", "%s", func() (any, error) {
+				v, err := xb.EncryptionOptions()
+				return v.String(), err
+			}},
+			{"maximumRFPayloadBytes", "Maximum RF payload bytes", "%d", func() (any, error) { return xb.MaximumRFPayloadBytes() }},
+			{"nodeDiscoveryTimeout", "Node discovery timeout", "%s", func() (any, error) {
+				v, err := xb.NodeDiscoveryTimeout()
+				return v.String(), err
+			}},
+			{"nodeDiscoveryOptions", "Node discovery options", "%s", func() (any, error) {
+				v, err := xb.NodeDiscoveryOptions()
+				return v.String(), err
+			}},
+		}
+		info := make(map[string]any)
+		for _, f := range fields {
+			v, err := f.get()
+			if errors.Is(err, errors.ErrUnsupported) {
+				continue
+			} else if err != nil {
+				return err
+			}
+			if env.json {
+				info[f.key] = v
+			} else {
+				fmt.Printf("%s: "+f.format+"\n", f.label, v)
+			}
+		}
+		if env.json {
+			printJSON(info)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/samuel/go-xbee/xbee"
+)
+
+// configTarget defines the -target flag and returns a function returning
+// the function sending AT commands to the local module or the target.
+func configTarget(fs *flag.FlagSet) func(env *env) (func(cmd xbee.ATCommand, param []byte) ([]byte, error), error) {
+	target := fs.String("target", "", "64-bit address of a remote node to configure instead of the local module")
+	return func(env *env) (func(cmd xbee.ATCommand, param []byte) ([]byte, error), error) {
+		var addr xbee.Addr64
+		if *target != "" {
+			var err error
+			if addr, err = xbee.ParseAddr64(*target); err != nil {
+				return nil, err
+			}
+		}
+		xb, err := env.open()
+		if err != nil {
+			return nil, err
+		}
+		if *target == "" {
+			return xb.ATCommand, nil
+		}
+		return func(cmd xbee.ATCommand, param []byte) ([]byte, error) {
+			return xb.RemoteATCommand(addr, xbee.Address16Unknown, cmd, param, xbee.RATOApplyChanges)
+		}, nil
+	}
+}
+
+// configGetCmd implements "config get REG..." printing the value of each
+// register, prefixed by its name if there are several.
+func configGetCmd(fs *flag.FlagSet) runFunc {
+	open := configTarget(fs)
+	return func(env *env, args []string) error {
+		cmds := make([]xbee.ATCommand, len(args))
+		for i, name := range args {
+			var err error
+			if cmds[i], err = parseCommand(name); err != nil {
+				return usageError{err.Error()}
+			}
+		}
+		command, err := open(env)
+		if err != nil {
+			return err
+		}
+		for _, cmd := range cmds {
+			res, err := command(cmd, nil)
+			if err != nil {
+				return fmt.Errorf("%s: %w", cmd, err)
+			}
+			if len(cmds) == 1 {
+				fmt.Println(formatParam(cmd, res))
+			} else {
+				fmt.Printf("%s %s\n", cmd, formatParam(cmd, res))
+			}
+		}
+		return nil
+	}
+}
+
+// configSetCmd implements "config set REG VALUE".
+func configSetCmd(fs *flag.FlagSet) runFunc {
+	open := configTarget(fs)
+	write := fs.Bool("write", false, "Save the settings (WR) after setting the register")
+	apply := fs.Bool("apply", false, "Apply the changes (AC) after setting the register")
+	return func(env *env, args []string) error {
+		cmd, err := parseCommand(args[0])
+		if err != nil {
+			return usageError{err.Error()}
+		}
+		param, err := parseValue(cmd, args[1])
+		if err != nil {
+			return fmt.Errorf("%s: %w", cmd, err)
+		}
+		command, err := open(env)
+		if err != nil {
+			return err
+		}
+		if _, err := command(cmd, param); err != nil {
+			return fmt.Errorf("%s: %w", cmd, err)
+		}
+		if *apply {
+			if _, err := command(xbee.ATCommand{'A', 'C'}, nil); err != nil {
+				return fmt.Errorf("AC: %w", err)
+			}
+		}
+		if *write {
+			if _, err := command(xbee.ATCommand{'W', 'R'}, nil); err != nil {
+				return fmt.Errorf("WR: %w", err)
+			}
+		}
+		return nil
+	}
+}
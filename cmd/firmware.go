@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samuel/go-xbee/xbee"
+)
+
+const progressBarWidth = 40
+
+// firmwareRemote defines the -remote flag of the firmware commands and
+// returns a function parsing it, returning nil for the local module.
+func firmwareRemote(fs *flag.FlagSet) func() (*xbee.Addr64, error) {
+	remote := fs.String("remote", "", "64-bit address of a remote node to use instead of the local module")
+	return func() (*xbee.Addr64, error) {
+		if *remote == "" {
+			return nil, nil
+		}
+		addr, err := xbee.ParseAddr64(*remote)
+		if err != nil {
+			return nil, usageError{err.Error()}
+		}
+		return &addr, nil
+	}
+}
+
+// firmwareInfoCmd implements "firmware info".
+func firmwareInfoCmd(fs *flag.FlagSet) runFunc {
+	remote := firmwareRemote(fs)
+	return func(env *env, args []string) error {
+		dest, err := remote()
+		if err != nil {
+			return err
+		}
+		xb, err := env.open()
+		if err != nil {
+			return err
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		return firmwareInfo(ctx, xb, dest)
+	}
+}
+
+// firmwareUpdateCmd implements "firmware update". Local updates use the
+// serial bootloader so the port is closed and reopened at the bootloader's
+// baud rate.
+func firmwareUpdateCmd(fs *flag.FlagSet) runFunc {
+	remote := firmwareRemote(fs)
+	file := fs.String("file", "", "Firmware image: .gbl for the local module, .ebl (GPM) or .ota (OTA) for a remote node")
+	restart := fs.Bool("restart", false, "Ignore progress saved by an interrupted GPM update")
+	return func(env *env, args []string) error {
+		dest, err := remote()
+		if err != nil {
+			return err
+		}
+		if *file == "" {
+			return usagef("-file is required")
+		}
+		image, err := os.ReadFile(*file)
+		if err != nil {
+			return err
+		}
+		xb, err := env.open()
+		if err != nil {
+			return err
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		bar := newProgressBar(os.Stderr)
+		defer bar.finish()
+		switch {
+		case dest == nil:
+			if err := xb.EnterBootloader(); err != nil {
+				return err
+			}
+			// Stop the XBee reading the port so the bootloader has it.
+			env.port.Close()
+			time.Sleep(time.Second)
+			bl, err := openPort(xbee.BootloaderBaudRate)
+			if err != nil {
+				return err
+			}
+			defer bl.Close()
+			return xbee.UpdateFirmwareXMODEM(ctx, bl, image, bar.update)
+		case strings.EqualFold(filepath.Ext(*file), ".ota"):
+			img, err := xbee.ParseOTAImage(image)
+			if err != nil {
+				return err
+			}
+			return xb.ServeOTAImage(ctx, *dest, img, bar.update)
+		default:
+			return updateGPM(ctx, xb, *dest, *file, image, *restart, bar)
+		}
+	}
+}
+
+// updateGPM updates a remote module using GPM saving the offset reached to
+// FILE.resume if the update fails so the next attempt continues from it.
+func updateGPM(ctx context.Context, xb *xbee.XBee, dest xbee.Addr64, file string, image []byte, restart bool, bar *progressBar) error {
+	resumeFile := file + ".resume"
+	u := &xbee.GPMUpdate{Image: image}
+	if b, err := os.ReadFile(resumeFile); err == nil && !restart {
+		if u.Offset, err = strconv.Atoi(strings.TrimSpace(string(b))); err != nil {
+			return fmt.Errorf("%s: %w", resumeFile, err)
+		}
+		fmt.Fprintf(os.Stderr, "Resuming at byte %d\n", u.Offset)
+	}
+	var offset int
+	u.Progress = func(p xbee.FirmwareProgress) {
+		if p.State == xbee.FirmwareTransferring {
+			offset = p.Offset
+		}
+		bar.update(p)
+	}
+	err := xb.UpdateFirmwareGPM(ctx, dest, u)
+	if err != nil {
+		if offset > 0 {
+			os.WriteFile(resumeFile, []byte(strconv.Itoa(offset)+"\n"), 0o644)
+		}
+		return err
+	}
+	if err := os.Remove(resumeFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func firmwareInfo(ctx context.Context, xb *xbee.XBee, dest *xbee.Addr64) error {
+	for _, name := range []string{"VR", "HV"} {
+		cmd, _ := parseCommand(name)
+		var res []byte
+		var err error
+		if dest != nil {
+			res, err = xb.RemoteATCommand(*dest, xbee.Address16Unknown, cmd, nil, 0)
+		} else {
+			res, err = xb.ATCommand(cmd, nil)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", cmd, err)
+		}
+		r, _ := lookupRegister(name)
+		fmt.Printf("%s: %s\n", r.desc, formatValue(cmd, res))
+	}
+	if dest != nil {
+		// Only modules updated with GPM respond.
+		if info, err := xb.GPMPlatformInfo(ctx, *dest); err == nil {
+			fmt.Printf("GPM flash: %d blocks of %d bytes\n", info.Blocks, info.BytesPerBlock)
+		}
+	}
+	return nil
+}
+
+// progressBar renders firmware update progress on a single terminal line.
+type progressBar struct {
+	w     io.Writer
+	drawn bool
+}
+
+func newProgressBar(w io.Writer) *progressBar {
+	return &progressBar{w: w}
+}
+
+func (b *progressBar) update(p xbee.FirmwareProgress) {
+	frac := 0.0
+	if p.Total > 0 {
+		frac = float64(p.Offset) / float64(p.Total)
+	}
+	n := int(frac * progressBarWidth)
+	fmt.Fprintf(b.w, "\r[%s%s] %3.0f%% %-12s %d/%d", strings.Repeat("=", n), strings.Repeat(" ", progressBarWidth-n), frac*100, p.State, p.Offset, p.Total)
+	b.drawn = true
+}
+
+// finish ends the progress line.
+func (b *progressBar) finish() {
+	if b.drawn {
+		fmt.Fprintln(b.w)
+	}
+}
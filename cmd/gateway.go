@@ -0,0 +1,376 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/samuel/go-xbee/xbee"
+	"github.com/samuel/go-xbee/xbee/xbeehttp"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	defaultGatewayReconnect = 5 * time.Second
+	gatewayShutdownTimeout  = 5 * time.Second
+)
+
+// gatewayConfig is the configuration file of the gateway command, e.g.
+//
+//	device: /dev/ttyUSB0
+//	nodeStore: /var/lib/xbee/nodes.json
+//	monitor:
+//	  interval: 1m
+//	http:
+//	  listen: :8080
+//	record:
+//	  path: /var/log/xbee/rf.jsonl
+//	  maxSize: 10485760
+//	  gzip: true
+type gatewayConfig struct {
+	// Device is the serial device of the module. The default is the -d
+	// flag or the first module found.
+	Device string `yaml:"device"`
+	// TCP is the address of an RFC 2217 bridge used instead of Device.
+	TCP  string `yaml:"tcp"`
+	Baud int    `yaml:"baud"` // the default is the -b flag
+	// NodeStore is a JSON file the node registry is saved to so it
+	// survives restarts.
+	NodeStore    string `yaml:"nodeStore"`
+	RSSISampling bool   `yaml:"rssiSampling"`
+	// Reconnect is the delay between attempts to reopen the module after
+	// it fails. The default is 5s.
+	Reconnect time.Duration `yaml:"reconnect"`
+	// Monitor enables the node health monitor.
+	Monitor *gatewayMonitorConfig `yaml:"monitor"`
+	// HTTP enables the status server.
+	HTTP *gatewayHTTPConfig `yaml:"http"`
+	// Record enables recording received packets and samples to rotated
+	// files.
+	Record *gatewayRecordConfig `yaml:"record"`
+}
+
+// gatewayRecordConfig configures the recorder (see xbee.RecorderConfig).
+type gatewayRecordConfig struct {
+	Path     string        `yaml:"path"`
+	Format   string        `yaml:"format"` // json (the default) or binary
+	MaxSize  int64         `yaml:"maxSize"`
+	MaxAge   time.Duration `yaml:"maxAge"`
+	Gzip     bool          `yaml:"gzip"`
+	MaxFiles int           `yaml:"maxFiles"`
+}
+
+type gatewayMonitorConfig struct {
+	Nodes     []string      `yaml:"nodes"` // 64-bit addresses, the default is every node in the registry
+	Interval  time.Duration `yaml:"interval"`
+	Timeout   time.Duration `yaml:"timeout"`
+	DownAfter int           `yaml:"downAfter"`
+	RemoteAT  bool          `yaml:"remoteAT"`
+}
+
+// gatewayHTTPConfig configures the HTTP server which serves the gateway
+// status as JSON at /status and the link counters at /debug/vars.
+type gatewayHTTPConfig struct {
+	Listen string `yaml:"listen"`
+	// API serves the REST API (see package xbeehttp) under /api/.
+	API bool `yaml:"api"`
+}
+
+func loadGatewayConfig(path string) (*gatewayConfig, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	cfg := &gatewayConfig{}
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// gatewayCmd implements the gateway command which keeps the module open
+// (reopening it if it fails) and runs the services enabled in the
+// configuration file until interrupted.
+func gatewayCmd(fs *flag.FlagSet) runFunc {
+	path := fs.String("config", "", "Configuration file (YAML)")
+	return func(env *env, args []string) error {
+		if *path == "" {
+			return usagef("-config is required")
+		}
+		cfg, err := loadGatewayConfig(*path)
+		if err != nil {
+			return err
+		}
+		g, err := newGateway(cfg, env.json)
+		if err != nil {
+			return err
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return g.run(ctx)
+	}
+}
+
+// gateway is the state of the gateway command.
+type gateway struct {
+	cfg     *gatewayConfig
+	monitor *xbee.MonitorConfig // nil if disabled
+	rec     *xbee.Recorder      // nil if disabled
+	log     *slog.Logger
+
+	mu  sync.Mutex // protects xb, mon, and api
+	xb  *xbee.XBee // nil while disconnected
+	mon *xbee.Monitor
+	api *xbeehttp.Handler
+}
+
+func newGateway(cfg *gatewayConfig, jsonLog bool) (*gateway, error) {
+	g := &gateway{cfg: cfg}
+	if jsonLog {
+		g.log = slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	} else {
+		g.log = slog.New(slog.NewTextHandler(os.Stderr, nil))
+	}
+	if cfg.Baud == 0 {
+		cfg.Baud = *flagBaud
+	}
+	if cfg.Device == "" && cfg.TCP == "" {
+		cfg.Device, cfg.TCP = *flagDevice, *flagTCP
+	}
+	if cfg.Reconnect <= 0 {
+		cfg.Reconnect = defaultGatewayReconnect
+	}
+	if m := cfg.Monitor; m != nil {
+		g.monitor = &xbee.MonitorConfig{Interval: m.Interval, Timeout: m.Timeout, DownAfter: m.DownAfter, RemoteAT: m.RemoteAT}
+		for _, s := range m.Nodes {
+			addr, err := xbee.ParseAddr64(s)
+			if err != nil {
+				return nil, fmt.Errorf("monitor: %w", err)
+			}
+			g.monitor.Nodes = append(g.monitor.Nodes, addr)
+		}
+	}
+	if r := cfg.Record; r != nil {
+		rc := xbee.RecorderConfig{Path: r.Path, MaxSize: r.MaxSize, MaxAge: r.MaxAge, Gzip: r.Gzip, MaxFiles: r.MaxFiles}
+		switch r.Format {
+		case "", "json":
+		case "binary":
+			rc.Format = xbee.RecordBinary
+		default:
+			return nil, fmt.Errorf("record: unknown format %q", r.Format)
+		}
+		var err error
+		if g.rec, err = xbee.NewRecorder(rc); err != nil {
+			return nil, fmt.Errorf("record: %w", err)
+		}
+	}
+	return g, nil
+}
+
+// run opens the module and serves until ctx is done reopening the module
+// whenever it fails.
+func (g *gateway) run(ctx context.Context) error {
+	if g.rec != nil {
+		defer g.rec.Close()
+	}
+	if g.cfg.HTTP != nil {
+		srv := &http.Server{Addr: g.cfg.HTTP.Listen, Handler: g.handler()}
+		go func() {
+			if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				g.log.Error("HTTP server failed", "err", err)
+			}
+		}()
+		defer func() {
+			sctx, cancel := context.WithTimeout(context.Background(), gatewayShutdownTimeout)
+			defer cancel()
+			srv.Shutdown(sctx)
+		}()
+		g.log.Info("HTTP server listening", "addr", g.cfg.HTTP.Listen)
+	}
+	for {
+		err := g.session(ctx)
+		if ctx.Err() != nil {
+			g.log.Info("shutting down")
+			return nil
+		}
+		g.log.Error("module disconnected", "err", err, "retry", g.cfg.Reconnect)
+		t := time.NewTimer(g.cfg.Reconnect)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			g.log.Info("shutting down")
+			return nil
+		}
+	}
+}
+
+// session opens the module and runs the services until the module fails
+// or ctx is done.
+func (g *gateway) session(ctx context.Context) error {
+	device := g.cfg.Device
+	var port io.ReadWriteCloser
+	var err error
+	switch {
+	case g.cfg.TCP != "":
+		device = g.cfg.TCP
+		port, err = xbee.OpenRFC2217(g.cfg.TCP, g.cfg.Baud)
+	default:
+		if device == "" {
+			found, ferr := xbee.FindXBees(g.cfg.Baud)
+			if ferr != nil {
+				return ferr
+			}
+			if len(found) == 0 {
+				return errors.New("no module found")
+			}
+			device = found[0].Port.Path
+		}
+		port, err = xbee.OpenPort(device, g.cfg.Baud)
+	}
+	if err != nil {
+		return err
+	}
+
+	opts := []xbee.Option{xbee.WithLogger(g.log)}
+	if g.rec != nil {
+		opts = append(opts, xbee.WithRecorder(g.rec))
+	}
+	if g.cfg.NodeStore != "" {
+		opts = append(opts, xbee.WithNodeStore(&xbee.JSONFileStore{Path: g.cfg.NodeStore}))
+	}
+	if g.cfg.RSSISampling {
+		opts = append(opts, xbee.WithRSSISampling())
+	}
+	xb, err := xbee.Open(port, opts...)
+	if err != nil {
+		port.Close()
+		return err
+	}
+	defer func() {
+		// Stop the read loop before closing the XBee so no event is sent
+		// once its channel is closed.
+		port.Close()
+		select {
+		case <-xb.Done():
+		case <-time.After(time.Second):
+		}
+		xb.Close()
+	}()
+	sn, err := xb.SerialNumber()
+	if err != nil {
+		return err
+	}
+	g.log.Info("module connected", "device", device, "serialNumber", sn)
+
+	var api *xbeehttp.Handler
+	if g.cfg.HTTP != nil && g.cfg.HTTP.API {
+		api = xbeehttp.NewHandler(xb)
+	}
+	go func() {
+		for ev := range xb.EventChan() {
+			g.log.Debug("event", "type", fmt.Sprintf("%T", ev), "event", ev)
+			if api != nil {
+				api.Publish(ev)
+			}
+		}
+	}()
+	var mon *xbee.Monitor
+	if g.monitor != nil {
+		mon = xb.NewMonitor(g.monitor)
+		defer mon.Close()
+		go func() {
+			for ev := range mon.Events() {
+				switch ev := ev.(type) {
+				case *xbee.NodeUp:
+					g.log.Info("node up", "address", ev.Address, "latency", ev.Latency)
+				case *xbee.NodeDown:
+					g.log.Warn("node down", "address", ev.Address, "lastSeen", ev.LastSeen)
+				}
+			}
+		}()
+	}
+	g.set(xb, mon, api)
+	defer g.set(nil, nil, nil)
+
+	select {
+	case <-xb.Done():
+		if err := xb.Err(); err != nil {
+			return err
+		}
+		return errors.New("port closed")
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+func (g *gateway) set(xb *xbee.XBee, mon *xbee.Monitor, api *xbeehttp.Handler) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.xb, g.mon, g.api = xb, mon, api
+}
+
+func (g *gateway) current() (*xbee.XBee, *xbee.Monitor) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.xb, g.mon
+}
+
+// gatewayStatus is served at /status.
+type gatewayStatus struct {
+	Connected bool              `json:"connected"`
+	Stats     *xbee.Stats       `json:"stats,omitempty"`
+	Nodes     []xbee.NodeStats  `json:"nodes,omitempty"`
+	Health    []xbee.NodeHealth `json:"health,omitempty"`
+}
+
+func (g *gateway) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", func(w http.ResponseWriter, r *http.Request) {
+		var st gatewayStatus
+		xb, mon := g.current()
+		if xb != nil {
+			stats := xb.Stats()
+			st.Connected, st.Stats, st.Nodes = true, &stats, xb.NodeStats()
+		}
+		if mon != nil {
+			st.Health = mon.Health()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(st)
+	})
+	expvar.Publish("xbee", expvar.Func(func() any {
+		if xb, _ := g.current(); xb != nil {
+			return xb.Stats()
+		}
+		return nil
+	}))
+	mux.Handle("GET /debug/vars", expvar.Handler())
+	if g.cfg.HTTP.API {
+		mux.Handle("/api/", http.StripPrefix("/api", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			g.mu.Lock()
+			api := g.api
+			g.mu.Unlock()
+			if api == nil {
+				http.Error(w, "module disconnected", http.StatusServiceUnavailable)
+				return
+			}
+			api.ServeHTTP(w, r)
+		})))
+	}
+	return mux
+}
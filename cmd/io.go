@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/samuel/go-xbee/xbee"
+)
+
+var pinModes = map[string]xbee.PinMode{
+	"disabled": xbee.PinDisabled,
+	"adc":      xbee.PinADC,
+	"input":    xbee.PinDigitalInput,
+	"low":      xbee.PinDigitalLow,
+	"high":     xbee.PinDigitalHigh,
+}
+
+// ioSetCmd implements "io set ADDR PIN MODE".
+func ioSetCmd(fs *flag.FlagSet) runFunc {
+	return func(env *env, args []string) error {
+		dest, err := xbee.ParseAddr64(args[0])
+		if err != nil {
+			return usageError{err.Error()}
+		}
+		pin, err := parsePin(args[1])
+		if err != nil {
+			return usageError{err.Error()}
+		}
+		mode, ok := pinModes[strings.ToLower(args[2])]
+		if !ok {
+			return usagef("unknown pin mode %q", args[2])
+		}
+		xb, err := env.open()
+		if err != nil {
+			return err
+		}
+		return xb.SetPinMode(dest, pin, mode)
+	}
+}
+
+// parsePin parses a pin name: D0 to D9, P0 to P2, or DIO0 to DIO12.
+func parsePin(s string) (int, error) {
+	s = strings.ToUpper(s)
+	var n int
+	var err error
+	switch {
+	case strings.HasPrefix(s, "DIO"):
+		n, err = strconv.Atoi(s[3:])
+	case strings.HasPrefix(s, "D"):
+		n, err = strconv.Atoi(s[1:])
+	case strings.HasPrefix(s, "P"):
+		n, err = strconv.Atoi(s[1:])
+		n += 10
+	default:
+		err = strconv.ErrSyntax
+	}
+	if err != nil || n < 0 || n > 12 {
+		return 0, fmt.Errorf("unknown pin %q", s)
+	}
+	return n, nil
+}
+
+// ioWatchCmd implements "io watch [ADDR]" which configures sampling on
+// the node if given and prints the samples received until interrupted.
+func ioWatchCmd(fs *flag.FlagSet) runFunc {
+	rate := fs.Duration("rate", time.Second, "Sample rate to configure on the node (IR)")
+	change := fs.Uint("change", 0, "Digital change detection mask to configure on the node (IC)")
+	return func(env *env, args []string) error {
+		var src *xbee.Addr64
+		if len(args) > 0 {
+			addr, err := xbee.ParseAddr64(args[0])
+			if err != nil {
+				return usageError{err.Error()}
+			}
+			src = &addr
+		}
+		xb, err := env.open()
+		if err != nil {
+			return err
+		}
+		if src != nil {
+			if err := xb.ConfigureIOSampling(*src, *rate, uint16(*change)); err != nil {
+				return err
+			}
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		latest := make(map[xbee.Addr64]*xbee.IODataSampleIndicator)
+		for s := range xb.IOSamples(ctx) {
+			if src != nil && s.SourceAddress != *src {
+				continue
+			}
+			if env.json {
+				printJSON(s)
+				continue
+			}
+			latest[s.SourceAddress] = s
+			fmt.Print(clearScreen)
+			writeSampleTable(latest)
+		}
+		return nil
+	}
+}
+
+func writeSampleTable(latest map[xbee.Addr64]*xbee.IODataSampleIndicator) {
+	addrs := make([]xbee.Addr64, 0, len(latest))
+	for a := range latest {
+		addrs = append(addrs, a)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i] < addrs[j] })
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "ADDRESS\tDIGITAL\tANALOG\n")
+	for _, a := range addrs {
+		s := latest[a]
+		var dio, adc []string
+		for n := 0; n < 16; n++ {
+			if high, ok := s.DigitalValue(n); ok {
+				v := 0
+				if high {
+					v = 1
+				}
+				dio = append(dio, fmt.Sprintf("DIO%d=%d", n, v))
+			}
+		}
+		for n := 0; n < 8; n++ {
+			if v, ok := s.AnalogValue(n); ok {
+				name := fmt.Sprintf("AD%d", n)
+				if n == 7 {
+					name = "VCC"
+				}
+				adc = append(adc, fmt.Sprintf("%s=%d", name, v))
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", a, strings.Join(dio, " "), strings.Join(adc, " "))
+	}
+	tw.Flush()
+	fmt.Printf("\nUpdated %s. Press Ctrl-C to quit.\n", time.Now().Format("15:04:05"))
+}
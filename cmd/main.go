@@ -1,140 +1,107 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
-	"log"
-	"time"
+	"io"
+	"os"
 
 	"github.com/samuel/go-xbee/xbee"
+	"github.com/samuel/go-xbee/xbee/frames"
 )
 
 var (
 	flagBaud   = flag.Int("b", 115200, "Baud rate")
-	flagDevice = flag.String("d", "", "Device path (e.g. /dev/ttyUSB0")
+	flagDevice = flag.String("d", "", "Device path (e.g. /dev/ttyUSB0), found automatically if not set")
+	flagTap    = flag.String("r", "", "Record frames sent and received to a file")
+	flagTrace  = flag.Bool("v", false, "Dump every frame sent and received")
+	flagTCP    = flag.String("t", "", "Address of a RFC 2217 serial-over-TCP bridge (e.g. host:2000), used instead of -d")
+	flagJSON   = flag.Bool("json", false, "Print machine-readable JSON (one object per line for lists and streams)")
 )
 
 func main() {
+	flag.Usage = func() { printUsage(os.Stderr, commands()) }
 	flag.Parse()
+	os.Exit(execute(commands(), flag.Args()))
+}
 
-	port, err := xbee.OpenPort(*flagDevice, *flagBaud)
-	if err != nil {
-		log.Fatal(err)
+// env is the environment of a running command. Commands that use a module
+// open it with open and it's closed when the command returns.
+type env struct {
+	cmd    *command
+	json   bool // the global -json flag
+	port   io.ReadWriteCloser
+	closer []func()
+}
+
+// openPort opens the device or the RFC 2217 bridge at baud.
+func openPort(baud int) (io.ReadWriteCloser, error) {
+	if *flagTCP != "" {
+		return xbee.OpenRFC2217(*flagTCP, baud)
 	}
-	defer port.Close()
+	return xbee.OpenPort(*flagDevice, baud)
+}
 
-	xb, err := xbee.Open(port)
+// open opens the module using the first one found if no device was given.
+// Events are printed unless the command is quiet.
+func (e *env) open(opts ...xbee.Option) (*xbee.XBee, error) {
+	if *flagTCP == "" && *flagDevice == "" {
+		found, err := xbee.FindXBees(*flagBaud)
+		if err != nil {
+			return nil, err
+		}
+		if len(found) == 0 {
+			return nil, errors.New("no module found, use -d to specify the device")
+		}
+		*flagDevice = found[0].Port.Path
+	}
+	port, err := openPort(*flagBaud)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	defer xb.Close()
+	e.port = port
+	e.closer = append(e.closer, func() { port.Close() })
 
-	go func() {
-		ch := xb.EventChan()
-		for ev := range ch {
-			fmt.Printf("%+v\n", ev)
+	if *flagTap != "" {
+		f, err := os.Create(*flagTap)
+		if err != nil {
+			return nil, err
 		}
-	}()
+		e.closer = append(e.closer, func() { f.Close() })
+		opts = append(opts, xbee.WithTap(frames.NewTapWriter(f)))
+	}
+	if *flagTrace {
+		opts = append(opts, xbee.WithFrameTracer(xbee.NewDumpTracer(os.Stderr)))
+	}
 
-	cmd := flag.Arg(0)
-	switch cmd {
-	case "scan":
-		waitTime := time.Second * 6
-		if s := flag.Arg(1); s != "" {
-			waitTime, err = time.ParseDuration(s)
-			if err != nil {
-				log.Fatal("Failed to parse duration")
-			}
-		}
-		fmt.Println("Devices:")
-		if devices, err := xb.ActiveScan(waitTime); err != nil {
-			log.Fatal(err)
-		} else {
-			for _, d := range devices {
-				fmt.Printf("\t%+v\n", d)
+	xb, err := xbee.Open(port, opts...)
+	if err != nil {
+		return nil, err
+	}
+	e.closer = append(e.closer, xb.Close)
+
+	go func() {
+		for ev := range xb.EventChan() {
+			if e.cmd.quiet {
+				continue
 			}
-		}
-	case "discover":
-		waitTime := time.Second * 6
-		if s := flag.Arg(1); s != "" {
-			waitTime, err = time.ParseDuration(s)
-			if err != nil {
-				log.Fatal("Failed to parse duration")
+			if e.json {
+				printJSON(struct {
+					Type  string `json:"type"`
+					Event any    `json:"event"`
+				}{fmt.Sprintf("%T", ev), ev})
+			} else {
+				fmt.Printf("%+v\n", ev)
 			}
 		}
-		fmt.Println("Nodes:")
-		if nodes, err := xb.NodeDiscover(waitTime); err != nil {
-			log.Fatal(err)
-		} else {
-			for _, n := range nodes {
-				fmt.Printf("\t%+v\n", n)
-			}
-		}
-	case "info":
-		if escaped, err := xb.APIEnabled(); err != nil {
-			log.Fatal(err)
-		} else {
-			fmt.Printf("Escaped: %t\n", escaped)
-		}
-		if serial, err := xb.SerialNumber(); err != nil {
-			log.Fatal(err)
-		} else {
-			fmt.Printf("Serial number: %016x\n", serial)
-		}
-		if ni, err := xb.NodeIdentifier(); err != nil {
-			log.Fatal(err)
-		} else {
-			fmt.Printf("Node identifier: %s\n", ni)
-		}
-		if v, err := xb.FirmwareVersion(); err != nil {
-			log.Fatal(err)
-		} else {
-			fmt.Printf("Firmware version: %04x\n", v)
-		}
-		if v, err := xb.HardwareVersion(); err != nil {
-			log.Fatal(err)
-		} else {
-			fmt.Printf("Hardware version: %04x\n", v)
-		}
-		if v, err := xb.AssociationIndication(); err != nil {
-			log.Fatal(err)
-		} else {
-			fmt.Printf("Association indication: %d\n", v)
-		}
-		if v, err := xb.ExtendedPANID(); err != nil {
-			log.Fatal(err)
-		} else {
-			fmt.Printf("Extended PAN ID: %016x\n", v)
-		}
-		if v, err := xb.OperatingExtendedPANID(); err != nil {
-			log.Fatal(err)
-		} else {
-			fmt.Printf("Operating extended PAN ID: %016x\n", v)
-		}
-		if v, err := xb.EncryptionEnabled(); err != nil {
-			log.Fatal(err)
-		} else {
-			fmt.Printf("Encryption enabled: %t\n", v)
-		}
-		if v, err := xb.EncryptionOptions(); err != nil {
-			log.Fatal(err)
-		} else {
-			fmt.Printf("Encryption options: %s\n", v)
-		}
-		if v, err := xb.MaximumRFPayloadBytes(); err != nil {
-			log.Fatal(err)
-		} else {
-			fmt.Printf("Maximum RF payload bytes: %d\n", v)
-		}
-		if v, err := xb.NodeDiscoveryTimeout(); err != nil {
-			log.Fatal(err)
-		} else {
-			fmt.Printf("Node discovery timeout: %s\n", v)
-		}
-		if v, err := xb.NodeDiscoveryOptions(); err != nil {
-			log.Fatal(err)
-		} else {
-			fmt.Printf("Node discovery options: %s\n", v)
-		}
+	}()
+	return xb, nil
+}
+
+// close closes what open opened in reverse order.
+func (e *env) close() {
+	for i := len(e.closer) - 1; i >= 0; i-- {
+		e.closer[i]()
 	}
 }
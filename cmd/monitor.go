@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/samuel/go-xbee/xbee"
+	"github.com/samuel/go-xbee/xbee/frames"
+)
+
+const (
+	colorReset = "\x1b[0m"
+	colorSent  = "\x1b[36m" // cyan
+	colorRecv  = "\x1b[32m" // green
+	colorError = "\x1b[31m" // red
+)
+
+// monitorTracer prints every frame sent and received for the monitor
+// command.
+type monitorTracer struct {
+	mu     sync.Mutex
+	w      io.Writer
+	hex    bool
+	json   bool
+	color  bool
+	types  map[byte]bool // nil for all frame types
+	source *xbee.Addr64  // nil for all sources
+}
+
+// monitorCmd implements the monitor command which prints the frames sent
+// and received until interrupted.
+func monitorCmd(fs *flag.FlagSet) runFunc {
+	hex := fs.Bool("hex", false, "Show an annotated hex dump of each frame")
+	jsonOut := fs.Bool("json", *flagJSON, "Print each frame as a JSON object")
+	color := fs.String("color", "auto", "Colorize output: auto, always, or never")
+	types := fs.String("type", "", "Only show these frame types (comma separated, e.g. 0x90,0x8b)")
+	source := fs.String("source", "", "Only show frames received from this 64-bit address")
+	return func(env *env, args []string) error {
+		t, err := newMonitorTracer(*hex, *jsonOut, *color, *types, *source)
+		if err != nil {
+			return err
+		}
+		if _, err := env.open(xbee.WithFrameTracer(t)); err != nil {
+			return err
+		}
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		<-sig
+		return nil
+	}
+}
+
+// newMonitorTracer returns a tracer printing to stdout with the monitor
+// command's flags.
+func newMonitorTracer(hex, jsonOut bool, color, types, source string) (*monitorTracer, error) {
+	t := &monitorTracer{w: os.Stdout, hex: hex, json: jsonOut}
+	switch color {
+	case "always":
+		t.color = true
+	case "auto":
+		fi, err := os.Stdout.Stat()
+		t.color = err == nil && fi.Mode()&os.ModeCharDevice != 0
+	case "never":
+	default:
+		return nil, usagef("unknown color mode %q", color)
+	}
+	t.color = t.color && !t.json
+	if types != "" {
+		t.types = make(map[byte]bool)
+		for _, s := range strings.Split(types, ",") {
+			v, err := strconv.ParseUint(strings.TrimSpace(s), 0, 8)
+			if err != nil {
+				return nil, usagef("bad frame type %q", s)
+			}
+			t.types[byte(v)] = true
+		}
+	}
+	if source != "" {
+		addr, err := xbee.ParseAddr64(source)
+		if err != nil {
+			return nil, usageError{err.Error()}
+		}
+		t.source = &addr
+	}
+	return t, nil
+}
+
+func (t *monitorTracer) OnSend(raw []byte, f xbee.Frame) {
+	t.print(frames.Sent, raw, f, nil)
+}
+
+func (t *monitorTracer) OnReceive(raw []byte, f xbee.Frame, err error) {
+	t.print(frames.Received, raw, f, err)
+}
+
+type monitorRecord struct {
+	Time      time.Time        `json:"time"`
+	Direction frames.Direction `json:"direction"`
+	Type      string           `json:"type,omitempty"`
+	Frame     xbee.Frame       `json:"frame,omitempty"`
+	Raw       string           `json:"raw,omitempty"`
+	Error     string           `json:"error,omitempty"`
+}
+
+func (t *monitorTracer) print(dir frames.Direction, raw []byte, f xbee.Frame, err error) {
+	if f != nil && !t.match(dir, f) {
+		return
+	}
+	now := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.json {
+		rec := monitorRecord{Time: now, Direction: dir, Frame: f}
+		if f != nil {
+			rec.Type = fmt.Sprintf("%T", f)
+		}
+		if t.hex || err != nil {
+			rec.Raw = fmt.Sprintf("%x", raw)
+		}
+		if err != nil {
+			rec.Error = err.Error()
+		}
+		b, err := json.Marshal(rec)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "monitor: %s\n", err)
+			return
+		}
+		fmt.Fprintf(t.w, "%s\n", b)
+		return
+	}
+
+	arrow, color := ">", colorSent
+	if dir == frames.Received {
+		arrow, color = "<", colorRecv
+	}
+	if err != nil {
+		color = colorError
+	}
+	if t.color {
+		fmt.Fprint(t.w, color)
+	}
+	ts := now.Format("15:04:05.000")
+	if err != nil {
+		fmt.Fprintf(t.w, "%s %s error: %s\n  % x\n", ts, arrow, err, raw)
+	} else {
+		fmt.Fprintf(t.w, "%s %s %T %+v\n", ts, arrow, f, f)
+		if t.hex {
+			fmt.Fprint(t.w, frames.FormatFrame(f))
+		}
+	}
+	if t.color {
+		fmt.Fprint(t.w, colorReset)
+	}
+}
+
+// match reports whether a frame passes the type and source filters. Only
+// received frames with a SourceAddress field can match a source filter.
+func (t *monitorTracer) match(dir frames.Direction, f xbee.Frame) bool {
+	if t.types != nil && !t.types[f.FrameType()] {
+		return false
+	}
+	if t.source == nil {
+		return true
+	}
+	if dir != frames.Received {
+		return false
+	}
+	v := reflect.ValueOf(f)
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return false
+	}
+	fv := v.FieldByName("SourceAddress")
+	if !fv.IsValid() {
+		return false
+	}
+	src, ok := fv.Interface().(xbee.Addr64)
+	return ok && src == *t.source
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/samuel/go-xbee/xbee"
+)
+
+// mapCmd implements the map command printing the network topology as
+// Graphviz DOT or JSON.
+func mapCmd(fs *flag.FlagSet) runFunc {
+	jsonOut := fs.Bool("json", *flagJSON, "Print JSON instead of Graphviz DOT")
+	discover := fs.Duration("discover", 0, "How long to wait for node discovery (default 6s)")
+	return func(env *env, args []string) error {
+		xb, err := env.open()
+		if err != nil {
+			return err
+		}
+		topo, err := xb.MapTopology(context.Background(), &xbee.TopologyConfig{Discover: *discover})
+		if err != nil {
+			return err
+		}
+		for _, n := range topo.Nodes {
+			if n.Err != nil {
+				fmt.Fprintf(os.Stderr, "%s: failed to read neighbor table: %s\n", n.Address, n.Err)
+			}
+		}
+		if *jsonOut {
+			printJSON(topologyJSON(topo))
+			return nil
+		}
+		writeDOT(os.Stdout, topo)
+		return nil
+	}
+}
+
+// topologyJSON returns the topology with the nodes sorted by address for
+// stable output.
+func topologyJSON(topo *xbee.Topology) any {
+	return struct {
+		Nodes []*xbee.TopologyNode
+		Links []xbee.TopologyLink
+	}{sortedNodes(topo), topo.Links}
+}
+
+func sortedNodes(topo *xbee.Topology) []*xbee.TopologyNode {
+	nodes := make([]*xbee.TopologyNode, 0, len(topo.Nodes))
+	for _, n := range topo.Nodes {
+		nodes = append(nodes, n)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Address < nodes[j].Address })
+	return nodes
+}
+
+// writeDOT writes the topology as a directed graph with an edge from each
+// node to every entry in its neighbor table labeled and weighted by LQI.
+func writeDOT(w io.Writer, topo *xbee.Topology) {
+	fmt.Fprintln(w, "digraph mesh {")
+	for _, n := range sortedNodes(topo) {
+		shape := "ellipse"
+		switch n.DeviceType {
+		case xbee.Coordinator:
+			shape = "doublecircle"
+		case xbee.Router:
+			shape = "box"
+		}
+		label := n.Address.String()
+		if n.NodeID != "" {
+			label = n.NodeID + "\\n" + label
+		}
+		fmt.Fprintf(w, "\t%q [label=%q shape=%s];\n", n.Address.String(), label, shape)
+	}
+	for _, l := range topo.Links {
+		fmt.Fprintf(w, "\t%q -> %q [label=\"%d\" weight=%d];\n", l.From.String(), l.To.String(), l.LQI, l.LQI)
+	}
+	fmt.Fprintln(w, "}")
+}
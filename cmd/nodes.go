@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"text/tabwriter"
+	"time"
+
+	"github.com/samuel/go-xbee/xbee"
+)
+
+const clearScreen = "\x1b[H\x1b[2J"
+
+// nodesWatchCmd implements "nodes watch" which redraws a table of the
+// nodes in the registry until interrupted.
+func nodesWatchCmd(fs *flag.FlagSet) runFunc {
+	interval := fs.Duration("interval", time.Second, "How often to refresh the table")
+	discover := fs.Duration("discover", 6*time.Second, "How long to run node discovery first to learn node identifiers (0 to skip)")
+	return func(env *env, args []string) error {
+		xb, err := env.open(xbee.WithRSSISampling())
+		if err != nil {
+			return err
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		if *discover > 0 {
+			fmt.Println("Discovering nodes...")
+			if _, err := xb.NodeDiscover(*discover); err != nil {
+				return err
+			}
+		}
+		t := time.NewTicker(*interval)
+		defer t.Stop()
+		for {
+			fmt.Print(clearScreen)
+			writeNodeTable(os.Stdout, xb.NodeStats(), time.Now())
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
+
+func writeNodeTable(w io.Writer, nodes []xbee.NodeStats, now time.Time) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "NI\tADDRESS\tNET\tTYPE\tLAST SEEN\tRSSI\tPACKETS\n")
+	for _, n := range nodes {
+		seen := "never"
+		if !n.LastSeen.IsZero() {
+			seen = now.Sub(n.LastSeen).Truncate(time.Second).String() + " ago"
+		}
+		rssi := "-"
+		if n.RSSI != 0 {
+			rssi = fmt.Sprintf("%d dBm", n.RSSI)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%d\n", n.NodeID, n.Address, n.Address16, n.DeviceType, seen, rssi, n.PacketsReceived)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\n%d nodes, updated %s. Press Ctrl-C to quit.\n", len(nodes), now.Format("15:04:05"))
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+)
+
+// printJSON writes v to stdout as a single line of JSON.
+func printJSON(v any) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		log.Fatal(err)
+	}
+	os.Stdout.Write(append(b, '\n'))
+}
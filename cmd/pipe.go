@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"os"
+	"os/signal"
+
+	"github.com/samuel/go-xbee/xbee"
+)
+
+// txCmd implements "tx ADDR" transmitting stdin to a node split into
+// packets of at most the maximum RF payload (NP). Each packet is sent once
+// the previous one is delivered so the data arrives in order.
+func txCmd(fs *flag.FlagSet) runFunc {
+	return func(env *env, args []string) error {
+		dest, err := xbee.ParseAddr64(args[0])
+		if err != nil {
+			return usageError{err.Error()}
+		}
+		xb, err := env.open()
+		if err != nil {
+			return err
+		}
+		np, err := xb.MaximumRFPayloadBytes()
+		if err != nil {
+			return err
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		buf := make([]byte, np)
+		for {
+			n, err := io.ReadFull(os.Stdin, buf)
+			if n > 0 {
+				if err := xb.Transmit(dest, buf[:n], xbee.WithRetry(nil), xbee.WithContext(ctx)); err != nil {
+					return err
+				}
+			}
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil
+			} else if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// rxCmd implements "rx [-from ADDR]" writing the payload of received
+// packets to stdout until interrupted. Explicit receive must be disabled
+// (AO=0).
+func rxCmd(fs *flag.FlagSet) runFunc {
+	from := fs.String("from", "", "Only write packets from this 64-bit address")
+	return func(env *env, args []string) error {
+		var src *xbee.Addr64
+		if *from != "" {
+			addr, err := xbee.ParseAddr64(*from)
+			if err != nil {
+				return usageError{err.Error()}
+			}
+			src = &addr
+		}
+		xb, err := env.open()
+		if err != nil {
+			return err
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		for rx := range xb.Packets(ctx) {
+			if src != nil && rx.SourceAddress != *src {
+				continue
+			}
+			if _, err := os.Stdout.Write(rx.Data); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samuel/go-xbee/xbee"
+)
+
+const provisionJoinTimeout = 2 * time.Minute
+
+// provisionCmd implements the provision command which asks for the
+// network settings (using the flags as defaults), applies them, waits for
+// the network to be formed or joined, and saves the settings.
+func provisionCmd(fs *flag.FlagSet) runFunc {
+	role := fs.String("role", "router", "coordinator to form a network, or router or end to join one")
+	pan := fs.String("pan", "0", "Extended PAN ID in hex (0 joins any network)")
+	channels := fs.String("channels", "7FFF", "Scan channels mask in hex")
+	ni := fs.String("ni", "", "Node identifier")
+	networkKey := fs.String("network-key", "", "Network key in hex (coordinator only, empty for random)")
+	linkKey := fs.String("link-key", "", "Trust center link key in hex (empty sends the network key unencrypted)")
+	installCode := fs.String("install-code", "", "Install code in hex with CRC used instead of the link key when joining")
+	yes := fs.Bool("y", false, "Don't prompt, use the flags")
+	return func(env *env, args []string) error {
+		xb, err := env.open()
+		if err != nil {
+			return err
+		}
+
+		in := bufio.NewReader(os.Stdin)
+		ask := func(question string, value *string) error {
+			if *yes {
+				return nil
+			}
+			fmt.Printf("%s [%s]: ", question, *value)
+			line, err := in.ReadString('\n')
+			if err != nil {
+				return err
+			}
+			if line = strings.TrimSpace(line); line != "" {
+				*value = line
+			}
+			return nil
+		}
+
+		if err := ask("Role (coordinator, router, end)", role); err != nil {
+			return err
+		}
+		coordinator := *role == "coordinator"
+		if !coordinator && *role != "router" && *role != "end" {
+			return fmt.Errorf("unknown role %q", *role)
+		}
+		if err := ask("Extended PAN ID (hex)", pan); err != nil {
+			return err
+		}
+		panID, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(*pan), "0x"), 16, 64)
+		if err != nil {
+			return fmt.Errorf("bad PAN ID: %w", err)
+		}
+		if err := ask("Scan channels mask (hex)", channels); err != nil {
+			return err
+		}
+		mask, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(*channels), "0x"), 16, 16)
+		if err != nil {
+			return fmt.Errorf("bad channel mask: %w", err)
+		}
+		if err := ask("Node identifier", ni); err != nil {
+			return err
+		}
+		cfg := xbee.ProvisionConfig{TrustCenter: coordinator}
+		if coordinator {
+			if err := ask("Network key (hex, empty for random)", networkKey); err != nil {
+				return err
+			}
+			if cfg.NetworkKey, err = parseKey(*networkKey); err != nil {
+				return fmt.Errorf("bad network key: %w", err)
+			}
+		}
+		if err := ask("Link key (hex, empty for none)", linkKey); err != nil {
+			return err
+		}
+		if cfg.LinkKey, err = parseKey(*linkKey); err != nil {
+			return fmt.Errorf("bad link key: %w", err)
+		}
+		var code []byte
+		if !coordinator {
+			if err := ask("Install code (hex with CRC, empty for none)", installCode); err != nil {
+				return err
+			}
+			if code, err = parseKey(*installCode); err != nil {
+				return fmt.Errorf("bad install code: %w", err)
+			}
+		}
+		p, err := xbee.NewProvisioner(xb, cfg)
+		if err != nil {
+			return err
+		}
+
+		step := func(name string, fn func() error) error {
+			fmt.Printf("%s... ", name)
+			if err := fn(); err != nil {
+				fmt.Println("failed")
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			fmt.Println("ok")
+			return nil
+		}
+		ce, sm := []byte{0}, []byte{0}
+		if coordinator {
+			ce[0] = 1
+		} else if *role == "end" {
+			sm[0] = 4 // cyclic sleep makes a ZB module join as an end device
+		}
+		steps := []provisionStep{
+			{"Setting role (CE)", func() error { _, err := xb.ATCommand(xbee.ATCommand{'C', 'E'}, ce); return err }},
+			{"Setting sleep mode (SM)", func() error { _, err := xb.ATCommand(xbee.ATCommand{'S', 'M'}, sm); return err }},
+			{"Setting PAN ID (ID)", func() error { return xb.SetExtendedPANID(panID) }},
+			{"Setting scan channels (SC)", func() error { return xb.SetScanChannels(uint16(mask)) }},
+			{"Setting node identifier (NI)", func() error { return xb.SetNodeIdentifier(*ni) }},
+		}
+		if coordinator {
+			steps = append(steps, provisionStep{"Configuring security and saving (EE, EO, NK, KY, WR)", p.SetupCoordinator})
+		} else {
+			steps = append(steps, provisionStep{"Configuring security and saving (EE, EO, KY, WR)", func() error { return p.SetupJoiner(xb, code) }})
+		}
+		steps = append(steps, provisionStep{"Applying changes (AC)", func() error { _, err := xb.ATCommand(xbee.ATCommand{'A', 'C'}, nil); return err }})
+		for _, s := range steps {
+			if err := step(s.name, s.fn); err != nil {
+				return err
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), provisionJoinTimeout)
+		defer cancel()
+		wait := "Waiting to join the network"
+		if coordinator {
+			wait = "Waiting for the network to form"
+		}
+		if err := step(wait, func() error { return p.WaitJoined(ctx, xb) }); err != nil {
+			return err
+		}
+		if coordinator {
+			if err := step("Opening join window (NJ)", p.OpenJoinWindow); err != nil {
+				return err
+			}
+		}
+
+		fmt.Println("Network:")
+		for _, name := range []string{"OP", "OI", "CH", "MY", "EE"} {
+			cmd, _ := parseCommand(name)
+			res, err := xb.ATCommand(cmd, nil)
+			if err != nil {
+				return fmt.Errorf("%s: %w", cmd, err)
+			}
+			r, _ := lookupRegister(name)
+			fmt.Printf("\t%s (%s): %s\n", cmd, r.desc, formatValue(cmd, res))
+		}
+		return step("Saving settings (WR)", xb.Write)
+	}
+}
+
+type provisionStep struct {
+	name string
+	fn   func() error
+}
+
+// parseKey decodes a hex key returning nil if s is empty.
+func parseKey(s string) ([]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+	return hex.DecodeString(strings.TrimPrefix(strings.ToLower(s), "0x"))
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/samuel/go-xbee/xbee"
+)
+
+// rangeTestCmd implements "rangetest ADDR" which sends numbered packets to
+// the loopback cluster of a node printing the result of each and a
+// summary. Interrupting the test prints the summary of the packets sent.
+func rangeTestCmd(fs *flag.FlagSet) runFunc {
+	count := fs.Int("count", 100, "Number of packets to send")
+	size := fs.Int("size", 32, "Payload size of each packet in bytes (at least 4)")
+	interval := fs.Duration("interval", 500*time.Millisecond, "Delay between packets")
+	timeout := fs.Duration("timeout", 2*time.Second, "How long to wait for each packet to be echoed")
+	remoteRSSI := fs.Bool("remote-rssi", true, "Read the signal strength at the remote node (DB) after each packet")
+	return func(env *env, args []string) error {
+		dest, err := xbee.ParseAddr64(args[0])
+		if err != nil {
+			return usageError{err.Error()}
+		}
+		xb, err := env.open()
+		if err != nil {
+			return err
+		}
+		return runRangeTest(xb, dest, &xbee.RangeTestConfig{
+			Count:       *count,
+			PayloadSize: *size,
+			Interval:    *interval,
+			Timeout:     *timeout,
+			RemoteRSSI:  *remoteRSSI,
+		}, env.json)
+	}
+}
+
+func runRangeTest(xb *xbee.XBee, dest xbee.Addr64, cfg *xbee.RangeTestConfig, jsonOut bool) error {
+	cfg.Progress = func(p xbee.RangeTestPacket) {
+		if jsonOut {
+			rec := struct {
+				Seq        int    `json:"seq"`
+				OK         bool   `json:"ok"`
+				Error      string `json:"error,omitempty"`
+				RTT        int64  `json:"rttMicros,omitempty"`
+				LocalRSSI  int    `json:"localRSSI,omitempty"`
+				RemoteRSSI int    `json:"remoteRSSI,omitempty"`
+			}{Seq: p.Seq, OK: p.Err == nil, RTT: p.RTT.Microseconds(), LocalRSSI: p.LocalRSSI, RemoteRSSI: p.RemoteRSSI}
+			if p.Err != nil {
+				rec.Error = p.Err.Error()
+			}
+			printJSON(rec)
+		} else if p.Err != nil {
+			fmt.Printf("%4d: %s\n", p.Seq, p.Err)
+		} else {
+			fmt.Printf("%4d: rtt %s, local %d dBm, remote %d dBm\n", p.Seq, p.RTT, p.LocalRSSI, p.RemoteRSSI)
+		}
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	res, err := xb.RangeTest(ctx, dest, cfg)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	if jsonOut {
+		printJSON(struct {
+			Sent          int     `json:"sent"`
+			Received      int     `json:"received"`
+			SuccessRate   float64 `json:"successRate"`
+			MinRTT        int64   `json:"minRTTMicros"`
+			AvgRTT        int64   `json:"avgRTTMicros"`
+			MaxRTT        int64   `json:"maxRTTMicros"`
+			AvgLocalRSSI  float64 `json:"avgLocalRSSI"`
+			AvgRemoteRSSI float64 `json:"avgRemoteRSSI"`
+		}{res.Sent, res.Received, res.SuccessRate(), res.MinRTT.Microseconds(), res.AvgRTT.Microseconds(), res.MaxRTT.Microseconds(), res.AvgLocalRSSI, res.AvgRemoteRSSI})
+		return nil
+	}
+	fmt.Printf("Received %d/%d (%.1f%%)\n", res.Received, res.Sent, res.SuccessRate()*100)
+	fmt.Printf("RTT min/avg/max: %s/%s/%s\n", res.MinRTT, res.AvgRTT, res.MaxRTT)
+	fmt.Printf("RSSI local %.1f dBm, remote %.1f dBm\n", res.AvgLocalRSSI, res.AvgRemoteRSSI)
+	return nil
+}
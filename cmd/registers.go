@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/samuel/go-xbee/xbee"
+)
+
+type register struct {
+	name string
+	desc string
+	text bool // value is a string rather than a number
+}
+
+// registers are the AT commands known to the shell and config commands.
+var registers = []register{
+	// Addressing
+	{name: "DH", desc: "Destination address high"},
+	{name: "DL", desc: "Destination address low"},
+	{name: "MY", desc: "16-bit network address"},
+	{name: "MP", desc: "16-bit parent address"},
+	{name: "NC", desc: "Number of remaining children"},
+	{name: "SH", desc: "Serial number high"},
+	{name: "SL", desc: "Serial number low"},
+	{name: "NI", desc: "Node identifier", text: true},
+	{name: "SE", desc: "Source endpoint"},
+	{name: "DE", desc: "Destination endpoint"},
+	{name: "CI", desc: "Cluster ID"},
+	{name: "TO", desc: "Transmit options"},
+	{name: "NP", desc: "Maximum RF payload bytes"},
+	{name: "DD", desc: "Device type identifier"},
+	// Networking
+	{name: "CH", desc: "Operating channel"},
+	{name: "ID", desc: "Extended PAN ID"},
+	{name: "OP", desc: "Operating extended PAN ID"},
+	{name: "OI", desc: "Operating 16-bit PAN ID"},
+	{name: "NH", desc: "Maximum hops"},
+	{name: "BH", desc: "Broadcast radius"},
+	{name: "NT", desc: "Node discovery timeout"},
+	{name: "NO", desc: "Node discovery options"},
+	{name: "SC", desc: "Scan channels"},
+	{name: "SD", desc: "Scan duration"},
+	{name: "ZS", desc: "Zigbee stack profile"},
+	{name: "NJ", desc: "Node join time"},
+	{name: "JV", desc: "Coordinator join verification"},
+	{name: "NW", desc: "Network watchdog timeout"},
+	{name: "JN", desc: "Join notification"},
+	{name: "AR", desc: "Many-to-one route broadcast time"},
+	{name: "CE", desc: "Coordinator enable"},
+	{name: "AI", desc: "Association indication"},
+	{name: "DA", desc: "Force disassociation"},
+	// Security
+	{name: "EE", desc: "Encryption enable"},
+	{name: "EO", desc: "Encryption options"},
+	{name: "NK", desc: "Network encryption key (write only)"},
+	{name: "KY", desc: "Link key (write only)"},
+	// RF
+	{name: "PL", desc: "Power level"},
+	{name: "PM", desc: "Power mode"},
+	{name: "PP", desc: "Peak power"},
+	{name: "DB", desc: "RSSI of last packet"},
+	// Serial interfacing
+	{name: "AP", desc: "API enable"},
+	{name: "AO", desc: "API options"},
+	{name: "BD", desc: "Baud rate"},
+	{name: "NB", desc: "Parity"},
+	{name: "SB", desc: "Stop bits"},
+	{name: "RO", desc: "Packetization timeout"},
+	// I/O
+	{name: "IR", desc: "I/O sample rate"},
+	{name: "IC", desc: "Digital change detection"},
+	{name: "V+", desc: "Supply voltage threshold"},
+	{name: "%V", desc: "Supply voltage"},
+	{name: "TP", desc: "Temperature"},
+	// Versions
+	{name: "VR", desc: "Firmware version"},
+	{name: "HV", desc: "Hardware version"},
+	// Sleep
+	{name: "SM", desc: "Sleep mode"},
+	{name: "SN", desc: "Number of sleep periods"},
+	{name: "SP", desc: "Sleep period"},
+	{name: "ST", desc: "Time before sleep"},
+	{name: "SO", desc: "Sleep options"},
+	// Execution
+	{name: "AC", desc: "Apply changes"},
+	{name: "WR", desc: "Write settings"},
+	{name: "RE", desc: "Restore defaults"},
+	{name: "FR", desc: "Software reset"},
+	{name: "NR", desc: "Network reset"},
+	{name: "CB", desc: "Commissioning button"},
+	{name: "DN", desc: "Destination node", text: true},
+	{name: "IS", desc: "Force sample"},
+}
+
+func lookupRegister(name string) (register, bool) {
+	for _, r := range registers {
+		if r.name == name {
+			return r, true
+		}
+	}
+	return register{}, false
+}
+
+// parseCommand parses a two character AT command optionally prefixed by
+// "AT" (e.g. "ni" or "ATNI").
+func parseCommand(s string) (xbee.ATCommand, error) {
+	s = strings.ToUpper(s)
+	if len(s) == 4 && strings.HasPrefix(s, "AT") {
+		s = s[2:]
+	}
+	var cmd xbee.ATCommand
+	if err := cmd.UnmarshalText([]byte(s)); err != nil {
+		return cmd, err
+	}
+	return cmd, nil
+}
+
+// parseValue converts a register value typed by the user to its parameter
+// bytes. Values of text registers and quoted values are used as is, values
+// starting with 0x are hex, and other numbers decimal. Anything else is
+// treated as a string.
+func parseValue(cmd xbee.ATCommand, s string) ([]byte, error) {
+	if r, ok := lookupRegister(cmd.String()); ok && r.text {
+		return []byte(s), nil
+	}
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		v, err := strconv.Unquote(s)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(v), nil
+	}
+	if h, ok := strings.CutPrefix(strings.ToLower(s), "0x"); ok {
+		if len(h)%2 == 1 {
+			h = "0" + h
+		}
+		return hex.DecodeString(h)
+	}
+	if n, err := strconv.ParseUint(s, 10, 64); err == nil {
+		b := binary.BigEndian.AppendUint64(nil, n)
+		for len(b) > 1 && b[0] == 0 {
+			b = b[1:]
+		}
+		return b, nil
+	}
+	return []byte(s), nil
+}
+
+// formatValue formats a register value read from a module.
+func formatValue(cmd xbee.ATCommand, b []byte) string {
+	if len(b) == 0 {
+		return "OK"
+	}
+	if r, ok := lookupRegister(cmd.String()); ok && r.text {
+		return fmt.Sprintf("%q", b)
+	}
+	if len(b) > 8 {
+		return fmt.Sprintf("% X", b)
+	}
+	var n uint64
+	for _, c := range b {
+		n = n<<8 | uint64(c)
+	}
+	return fmt.Sprintf("0x%0*X (%d)", len(b)*2, n, n)
+}
+
+// formatParam formats a register value so it can be passed back to
+// parseValue: text as is and anything else as 0x hex.
+func formatParam(cmd xbee.ATCommand, b []byte) string {
+	if r, ok := lookupRegister(cmd.String()); ok && r.text {
+		return string(b)
+	}
+	if len(b) == 0 {
+		return ""
+	}
+	return "0x" + strings.ToUpper(hex.EncodeToString(b))
+}
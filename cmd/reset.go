@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/samuel/go-xbee/xbee"
+)
+
+// resetCmd implements the reset command which resets the local module or
+// a remote node (FR), after restoring and saving the factory defaults
+// (RE, WR) with -factory.
+func resetCmd(fs *flag.FlagSet) runFunc {
+	factory := fs.Bool("factory", false, "Restore and save the factory default settings before resetting")
+	remote := fs.String("remote", "", "64-bit address of a remote node to reset instead of the local module")
+	yes := fs.Bool("y", false, "Don't ask for confirmation")
+	return func(env *env, args []string) error {
+		var dest xbee.Addr64
+		if *remote != "" {
+			var err error
+			if dest, err = xbee.ParseAddr64(*remote); err != nil {
+				return usageError{err.Error()}
+			}
+		}
+		xb, err := env.open()
+		if err != nil {
+			return err
+		}
+		target := "the local module"
+		restore, write, reset := xb.RestoreDefaults, xb.Write, xb.SoftwareReset
+		if *remote != "" {
+			target = dest.String()
+			at := func(cmd xbee.ATCommand) func() error {
+				return func() error {
+					_, err := xb.RemoteATCommand(dest, xbee.Address16Unknown, cmd, nil, 0)
+					return err
+				}
+			}
+			restore, write, reset = at(xbee.ATCommand{'R', 'E'}), at(xbee.ATCommand{'W', 'R'}), at(xbee.ATCommand{'F', 'R'})
+		}
+
+		if !*yes {
+			q := "Reset " + target
+			if *factory {
+				q = "Erase all settings of " + target + " and reset it"
+			}
+			fmt.Printf("%s? [y/N]: ", q)
+			line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+			if err != nil {
+				return err
+			}
+			if a := strings.ToLower(strings.TrimSpace(line)); a != "y" && a != "yes" {
+				return fmt.Errorf("cancelled")
+			}
+		}
+		var steps []provisionStep
+		if *factory {
+			steps = append(steps, provisionStep{"Restoring factory defaults (RE)", restore}, provisionStep{"Saving settings (WR)", write})
+		}
+		steps = append(steps, provisionStep{"Resetting (FR)", reset})
+		for _, s := range steps {
+			fmt.Printf("%s... ", s.name)
+			if err := s.fn(); err != nil {
+				fmt.Println("failed")
+				return fmt.Errorf("%s: %w", s.name, err)
+			}
+			fmt.Println("ok")
+		}
+		return nil
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/samuel/go-xbee/xbee"
+)
+
+const shellHelp = `Commands:
+  NI              read a register (AT prefix optional)
+  NI value        set a register (0x hex, decimal, "quoted", or text)
+  target ADDR     send commands to a remote node by 64-bit address
+  target local    send commands to the local module
+  registers       list known registers
+  help            show this help
+  exit            leave the shell
+Press Tab to complete register names and targets.
+`
+
+// runShell reads AT commands from stdin and prints the decoded responses.
+func runShell(xb *xbee.XBee) error {
+	ed := &lineEditor{in: bufio.NewReader(os.Stdin), out: os.Stdout}
+	if restore, err := makeRaw(int(os.Stdin.Fd())); err == nil {
+		defer restore()
+		ed.raw = true
+	}
+	ed.complete = func(prev, word string) []string {
+		var cands []string
+		fields := strings.Fields(prev)
+		switch {
+		case len(fields) == 0:
+			for _, r := range registers {
+				cands = append(cands, r.name)
+			}
+			cands = append(cands, "target", "registers", "help", "exit")
+		case len(fields) == 1 && fields[0] == "target":
+			cands = append(cands, "local")
+			for _, n := range xb.NodeStats() {
+				cands = append(cands, n.Address.String())
+			}
+		}
+		var matches []string
+		for _, c := range cands {
+			if strings.HasPrefix(strings.ToUpper(c), strings.ToUpper(word)) {
+				matches = append(matches, c)
+			}
+		}
+		sort.Strings(matches)
+		return matches
+	}
+
+	var target xbee.Addr64
+	remote := false
+	fmt.Fprint(ed.out, "Type help for a list of commands.\n")
+	for {
+		prompt := "local> "
+		if remote {
+			prompt = target.String() + "> "
+		}
+		line, err := ed.readLine(prompt)
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		name, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+		arg = strings.TrimSpace(arg)
+		switch strings.ToLower(name) {
+		case "":
+		case "exit", "quit":
+			return nil
+		case "help":
+			fmt.Fprint(ed.out, shellHelp)
+		case "registers":
+			for _, r := range registers {
+				fmt.Fprintf(ed.out, "  %s  %s\n", r.name, r.desc)
+			}
+		case "target":
+			if arg == "local" || arg == "" {
+				remote = false
+				continue
+			}
+			addr, err := xbee.ParseAddr64(arg)
+			if err != nil {
+				fmt.Fprintf(ed.out, "error: %s\n", err)
+				continue
+			}
+			target, remote = addr, true
+		default:
+			cmd, err := parseCommand(name)
+			if err != nil {
+				fmt.Fprintf(ed.out, "error: %s\n", err)
+				continue
+			}
+			var param []byte
+			if arg != "" {
+				if param, err = parseValue(cmd, arg); err != nil {
+					fmt.Fprintf(ed.out, "error: %s\n", err)
+					continue
+				}
+			}
+			var res []byte
+			if remote {
+				res, err = xb.RemoteATCommand(target, xbee.Address16Unknown, cmd, param, xbee.RATOApplyChanges)
+			} else {
+				res, err = xb.ATCommand(cmd, param)
+			}
+			if err != nil {
+				fmt.Fprintf(ed.out, "error: %s\n", err)
+				continue
+			}
+			fmt.Fprintf(ed.out, "%s: %s\n", cmd, formatValue(cmd, res))
+		}
+	}
+}
+
+// lineEditor reads lines with basic editing and tab completion when the
+// terminal is in raw mode, or plain lines otherwise.
+type lineEditor struct {
+	in  *bufio.Reader
+	out io.Writer
+	raw bool
+	// complete returns the candidates for the word being typed given the
+	// text of the line before it.
+	complete func(prev, word string) []string
+}
+
+func (e *lineEditor) readLine(prompt string) (string, error) {
+	fmt.Fprint(e.out, prompt)
+	if !e.raw {
+		line, err := e.in.ReadString('\n')
+		if err != nil && (line == "" || !errors.Is(err, io.EOF)) {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+	var line []byte
+	for {
+		c, err := e.in.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		switch c {
+		case '\r', '\n':
+			fmt.Fprint(e.out, "\n")
+			return string(line), nil
+		case 3: // Ctrl-C discards the line
+			fmt.Fprint(e.out, "^C\n", prompt)
+			line = line[:0]
+		case 4: // Ctrl-D on an empty line exits
+			if len(line) == 0 {
+				fmt.Fprint(e.out, "\n")
+				return "", io.EOF
+			}
+		case 8, 127:
+			if len(line) > 0 {
+				line = line[:len(line)-1]
+				fmt.Fprint(e.out, "\b \b")
+			}
+		case '\t':
+			line = e.completeLine(prompt, line)
+		case 27:
+			// Skip escape sequences such as arrow keys.
+			if b, err := e.in.ReadByte(); err == nil && b == '[' {
+				e.in.ReadByte()
+			}
+		default:
+			if c >= 0x20 {
+				line = append(line, c)
+				e.out.Write([]byte{c})
+			}
+		}
+	}
+}
+
+// completeLine extends the last word of line to the longest common prefix
+// of the completion candidates listing them if that doesn't extend it.
+func (e *lineEditor) completeLine(prompt string, line []byte) []byte {
+	if e.complete == nil {
+		return line
+	}
+	i := strings.LastIndexByte(string(line), ' ') + 1
+	prev, word := string(line[:i]), string(line[i:])
+	cands := e.complete(prev, word)
+	if len(cands) == 0 {
+		fmt.Fprint(e.out, "\a")
+		return line
+	}
+	prefix := cands[0]
+	for _, c := range cands[1:] {
+		for !strings.HasPrefix(strings.ToUpper(c), strings.ToUpper(prefix)) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	if len(cands) == 1 {
+		prefix += " "
+	}
+	if len(prefix) > len(word) {
+		rest := prefix[len(word):]
+		line = append(line[:i], prefix...)
+		fmt.Fprint(e.out, strings.Repeat("\b", len(word))+prefix[:len(word)]+rest)
+		return line
+	}
+	fmt.Fprint(e.out, "\n"+strings.Join(cands, "  ")+"\n"+prompt+string(line))
+	return line
+}
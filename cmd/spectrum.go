@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samuel/go-xbee/xbee"
+)
+
+const (
+	spectrumBarWidth = 50
+	spectrumFloor    = -100 // dBm drawn as an empty bar
+	spectrumCeiling  = -20  // dBm drawn as a full bar
+)
+
+// spectrumChannel accumulates the energy readings of one channel.
+type spectrumChannel struct {
+	Channel byte    `json:"channel"`
+	Last    int     `json:"last"`
+	Avg     float64 `json:"avg"`
+	Max     int     `json:"max"`
+	sum     int
+	n       int
+}
+
+func (c *spectrumChannel) add(energy int) {
+	if c.n == 0 || energy > c.Max {
+		c.Max = energy
+	}
+	c.Last = energy
+	c.sum += energy
+	c.n++
+	c.Avg = float64(c.sum) / float64(c.n)
+}
+
+// spectrumCmd implements the spectrum command which runs energy scans (ED)
+// until interrupted or -count scans are done, drawing a bar chart of each
+// channel or writing every reading as CSV or JSON.
+func spectrumCmd(fs *flag.FlagSet) runFunc {
+	count := fs.Int("count", 0, "Number of scans (0 to scan until interrupted)")
+	dwell := fs.Duration("dwell", 100*time.Millisecond, "Time to scan each channel (at most 255ms)")
+	defFormat := "chart"
+	if *flagJSON {
+		defFormat = "json"
+	}
+	format := fs.String("format", defFormat, "Output format: chart, csv, or json")
+	return func(env *env, args []string) error {
+		return runSpectrum(env, *count, *dwell, *format)
+	}
+}
+
+func runSpectrum(env *env, count int, dwell time.Duration, format string) error {
+	var cw *csv.Writer
+	switch format {
+	case "chart", "csv", "json":
+	default:
+		return usagef("unknown format %q", format)
+	}
+	xb, err := env.open()
+	if err != nil {
+		return err
+	}
+	if format == "csv" {
+		cw = csv.NewWriter(os.Stdout)
+		cw.Write([]string{"time", "scan", "channel", "energy"})
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	var channels []*spectrumChannel
+	for scan := 1; count <= 0 || scan <= count; scan++ {
+		energies, err := xb.EnergyScan(dwell)
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		now := time.Now()
+		if channels == nil {
+			channels = make([]*spectrumChannel, len(energies))
+			for i, e := range energies {
+				channels[i] = &spectrumChannel{Channel: e.Channel}
+			}
+		}
+		for i, e := range energies[:min(len(energies), len(channels))] {
+			channels[i].add(e.Energy)
+		}
+		switch format {
+		case "chart":
+			fmt.Print(clearScreen)
+			writeSpectrum(os.Stdout, channels, scan)
+			fmt.Printf("Updated %s. Press Ctrl-C to quit.\n", now.Format("15:04:05"))
+		case "csv":
+			ts := now.Format(time.RFC3339Nano)
+			for _, e := range energies {
+				cw.Write([]string{ts, strconv.Itoa(scan), strconv.Itoa(int(e.Channel)), strconv.Itoa(e.Energy)})
+			}
+			cw.Flush()
+			if err := cw.Error(); err != nil {
+				return err
+			}
+		case "json":
+			printJSON(struct {
+				Time     time.Time            `json:"time"`
+				Scan     int                  `json:"scan"`
+				Channels []xbee.ChannelEnergy `json:"channels"`
+			}{now, scan, energies})
+		}
+	}
+	if format == "chart" && len(channels) > 0 {
+		fmt.Printf("Quietest 4 channels by average: SC=%04x\n", xbee.QuietestChannels(averageEnergies(channels), 4))
+	}
+	return nil
+}
+
+// averageEnergies returns the average energy of each channel rounded to
+// dBm.
+func averageEnergies(channels []*spectrumChannel) []xbee.ChannelEnergy {
+	out := make([]xbee.ChannelEnergy, len(channels))
+	for i, c := range channels {
+		out[i] = xbee.ChannelEnergy{Channel: c.Channel, Energy: int(math.Round(c.Avg))}
+	}
+	return out
+}
+
+// writeSpectrum draws a bar per channel for its last reading with its
+// average (|) and maximum (^) marked.
+func writeSpectrum(w io.Writer, channels []*spectrumChannel, scans int) {
+	pos := func(dbm float64) int {
+		p := int((dbm - spectrumFloor) * spectrumBarWidth / (spectrumCeiling - spectrumFloor))
+		return min(max(p, 0), spectrumBarWidth-1)
+	}
+	fmt.Fprintf(w, "Energy after %d scans (%d to %d dBm, # last, | average, ^ maximum)\n\n", scans, spectrumFloor, spectrumCeiling)
+	for _, c := range channels {
+		bar := []byte(strings.Repeat(" ", spectrumBarWidth))
+		for i := 0; i <= pos(float64(c.Last)) && c.Last > spectrumFloor; i++ {
+			bar[i] = '#'
+		}
+		bar[pos(c.Avg)] = '|'
+		bar[pos(float64(c.Max))] = '^'
+		fmt.Fprintf(w, "%2d (0x%02x) [%s] %4d dBm  avg %6.1f  max %4d\n", c.Channel, c.Channel, bar, c.Last, c.Avg, c.Max)
+	}
+	fmt.Fprintln(w)
+}
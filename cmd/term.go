@@ -0,0 +1,30 @@
+package main
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// makeRaw puts the terminal into raw mode (no echo or line editing) and
+// returns a function that restores it. It fails if fd isn't a terminal.
+func makeRaw(fd int) (func(), error) {
+	var old syscall.Termios
+	if err := termios(fd, ioctlGetTermios, &old); err != nil {
+		return nil, err
+	}
+	t := old
+	t.Lflag &^= syscall.ECHO | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	t.Cc[syscall.VMIN] = 1
+	t.Cc[syscall.VTIME] = 0
+	if err := termios(fd, ioctlSetTermios, &t); err != nil {
+		return nil, err
+	}
+	return func() { termios(fd, ioctlSetTermios, &old) }, nil
+}
+
+func termios(fd int, req uintptr, t *syscall.Termios) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), req, uintptr(unsafe.Pointer(t))); errno != 0 {
+		return errno
+	}
+	return nil
+}
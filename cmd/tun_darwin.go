@@ -0,0 +1,11 @@
+package main
+
+import (
+	"errors"
+	"io"
+)
+
+// openTUN isn't supported on macOS which has utun interfaces instead.
+func openTUN(name string, mtu int) (io.ReadWriteCloser, string, error) {
+	return nil, "", errors.New("TUN interfaces are only supported on Linux")
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"io"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	tunDevice = "/dev/net/tun"
+	iffTUN    = 0x0001
+	iffNoPI   = 0x1000
+	tunSetIff = 0x400454ca // TUNSETIFF
+)
+
+// ifreq is the interface request of the TUNSETIFF and SIOCSIFMTU ioctls.
+type ifreq struct {
+	name  [syscall.IFNAMSIZ]byte
+	flags uint16 // or the MTU as an int32
+	_     [22]byte
+}
+
+// openTUN creates a TUN interface without packet information returning
+// it and its name.
+func openTUN(name string, mtu int) (io.ReadWriteCloser, string, error) {
+	f, err := os.OpenFile(tunDevice, os.O_RDWR, 0)
+	if err != nil {
+		return nil, "", err
+	}
+	var req ifreq
+	copy(req.name[:syscall.IFNAMSIZ-1], name)
+	req.flags = iffTUN | iffNoPI
+	if err := ioctl(f.Fd(), tunSetIff, unsafe.Pointer(&req)); err != nil {
+		f.Close()
+		return nil, "", err
+	}
+	ifname := string(req.name[:clen(req.name[:])])
+	if err := setMTU(ifname, mtu); err != nil {
+		f.Close()
+		return nil, "", err
+	}
+	return f, ifname, nil
+}
+
+func setMTU(ifname string, mtu int) error {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM, 0)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(fd)
+	var req ifreq
+	copy(req.name[:syscall.IFNAMSIZ-1], ifname)
+	*(*int32)(unsafe.Pointer(&req.flags)) = int32(mtu)
+	return ioctl(uintptr(fd), syscall.SIOCSIFMTU, unsafe.Pointer(&req))
+}
+
+func ioctl(fd, req uintptr, arg unsafe.Pointer) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, req, uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// clen returns the length of a NUL terminated string.
+func clen(b []byte) int {
+	for i, c := range b {
+		if c == 0 {
+			return i
+		}
+	}
+	return len(b)
+}
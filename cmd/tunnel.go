@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/samuel/go-xbee/xbee"
+)
+
+// tunnelCmd implements "tunnel ADDR" which carries the IP packets of a TUN
+// interface to a node running the same command until interrupted. The
+// interface still needs an address, e.g.
+//
+//	ip addr add 10.0.0.1 peer 10.0.0.2 dev xbee0 && ip link set xbee0 up
+func tunnelCmd(fs *flag.FlagSet) runFunc {
+	name := fs.String("name", "xbee0", "Name of the TUN interface")
+	mtu := fs.Int("mtu", xbee.DefaultIPTunnelMTU, "MTU of the interface")
+	return func(env *env, args []string) error {
+		remote, err := xbee.ParseAddr64(args[0])
+		if err != nil {
+			return usageError{err.Error()}
+		}
+		xb, err := env.open()
+		if err != nil {
+			return err
+		}
+		t, err := xb.NewIPTunnel(remote, &xbee.IPTunnelConfig{MTU: *mtu})
+		if err != nil {
+			return err
+		}
+		defer t.Close()
+		tun, ifname, err := openTUN(*name, *mtu)
+		if err != nil {
+			return fmt.Errorf("opening TUN interface: %w", err)
+		}
+		defer tun.Close()
+		fmt.Fprintf(os.Stderr, "Tunneling %s to %s, configure the interface with ip or ifconfig\n", ifname, remote)
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		errc := make(chan error, 2)
+		go func() {
+			buf := make([]byte, *mtu)
+			for {
+				n, err := tun.Read(buf)
+				if err != nil {
+					errc <- err
+					return
+				}
+				// Packets lost on the link are left to the protocols above.
+				if err := t.WritePacket(buf[:n]); errors.Is(err, xbee.ErrClosed) {
+					errc <- err
+					return
+				}
+			}
+		}()
+		go func() {
+			for {
+				p, err := t.ReadPacket()
+				if err != nil {
+					errc <- err
+					return
+				}
+				if _, err := tun.Write(p); err != nil {
+					errc <- err
+					return
+				}
+			}
+		}()
+		select {
+		case err := <-errc:
+			return err
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
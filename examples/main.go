@@ -49,7 +49,7 @@ func main() {
 		time.Sleep(time.Hour)
 	case "client":
 		for {
-			if err := xb.Transmit(xbee.AddressCoordinator, xbee.Address16Unknown, 0, 0, []byte("ping")); err != nil {
+			if err := xb.Transmit(xbee.AddressCoordinator, []byte("ping")); err != nil {
 				log.Println(err)
 			}
 			time.Sleep(time.Second * 5)
@@ -0,0 +1,106 @@
+package xbee
+
+import (
+	"sync"
+
+	"github.com/samuel/go-xbee/xbee/frames"
+)
+
+// addrCache maps 64-bit addresses to the 16-bit network addresses learned
+// from received frames and transmit statuses. Filling in the 16-bit
+// address of a transmit saves the module from doing address discovery
+// which otherwise makes the first transmit to a node slow.
+type addrCache struct {
+	mu   sync.Mutex
+	net  map[Addr64]Addr16
+	sent map[byte]Addr64 // destination of unicasts awaiting a transmit status by frame ID
+}
+
+// Address16 returns the cached 16-bit network address of dest. Transmits
+// and remote AT commands given Address16Unknown for dest use it.
+func (xb *XBee) Address16(dest Addr64) (Addr16, bool) {
+	xb.addrs.mu.Lock()
+	defer xb.addrs.mu.Unlock()
+	net, ok := xb.addrs.net[dest]
+	return net, ok
+}
+
+// fill sets the 16-bit destination address of f from the cache if the
+// caller left it unknown, and remembers the destination of transmits so
+// the transmit status can update the cache.
+func (c *addrCache) fill(f frames.Frame) {
+	var frameID byte
+	var dest Addr64
+	var net *Addr16
+	switch f := f.(type) {
+	case *frames.TransmitRequest:
+		frameID, dest, net = f.FrameID, f.DestinationAddress, &f.DestinationAddress16
+	case *frames.ExplicitTransmitRequest:
+		frameID, dest, net = f.FrameID, f.DestinationAddress, &f.DestinationAddress16
+	case *frames.RemoteATCommandRequest:
+		dest, net = f.DestinationAddress, &f.DestinationAddress16
+	default:
+		return
+	}
+	if dest == AddressUnknown || dest.IsBroadcast() || *net != Address16Unknown {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if n, ok := c.net[dest]; ok {
+		*net = n
+	}
+	if frameID != 0 {
+		if c.sent == nil {
+			c.sent = make(map[byte]Addr64)
+		}
+		c.sent[frameID] = dest
+	}
+}
+
+// frameReceived updates the cache from the source addresses of received
+// frames and the addresses reported by transmit statuses. A failed
+// delivery forgets the address so the next transmit rediscovers it.
+func (c *addrCache) frameReceived(f frames.Frame) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch f := f.(type) {
+	case *frames.ReceivePacket:
+		c.setLocked(f.SourceAddress, f.SourceAddress16)
+	case *frames.ExplicitReceivePacket:
+		c.setLocked(f.SourceAddress, f.SourceAddress16)
+	case *frames.RemoteATCommandResponse:
+		c.setLocked(f.SourceAddress, f.SourceAddress16)
+	case *frames.TransmitStatus:
+		dest, ok := c.sent[f.FrameID]
+		if !ok {
+			return
+		}
+		delete(c.sent, f.FrameID)
+		if f.DeliveryStatus == DSSuccess {
+			c.setLocked(dest, f.DestinationAddress)
+		} else {
+			delete(c.net, dest)
+		}
+	}
+}
+
+func (c *addrCache) setLocked(addr Addr64, net Addr16) {
+	// DigiMesh and 802.15.4 don't use 16-bit addresses and report them
+	// as unknown.
+	if addr == AddressUnknown || net == Address16Unknown {
+		return
+	}
+	if c.net == nil {
+		c.net = make(map[Addr64]Addr16)
+	}
+	c.net[addr] = net
+}
+
+// sentBy reports whether a frame from src with the network address src16
+// was sent by dest. Frames from the coordinator carry its serial number
+// rather than AddressCoordinator so they're recognized by its network
+// address (0x0000).
+func sentBy(dest, src Addr64, src16 Addr16) bool {
+	return src == dest || (dest == AddressCoordinator && src16 == 0)
+}
@@ -0,0 +1,131 @@
+package xbee
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/samuel/go-xbee/xbee/frames"
+)
+
+var (
+	ErrTransparentMode = errors.New("xbee: module is in transparent mode (AP=0)")
+	ErrNoResponse      = errors.New("xbee: no response from module")
+	ErrNoReadDeadline  = errors.New("xbee: port does not support read deadlines")
+)
+
+// APIMode is the value of the AP register.
+type APIMode byte
+
+const (
+	APIModeTransparent APIMode = 0
+	APIModeUnescaped   APIMode = 1
+	APIModeEscaped     APIMode = 2
+)
+
+func (m APIMode) String() string {
+	switch m {
+	case APIModeTransparent:
+		return "Transparent"
+	case APIModeUnescaped:
+		return "Unescaped"
+	case APIModeEscaped:
+		return "Escaped"
+	}
+	return fmt.Sprintf("APIMode(%d)", byte(m))
+}
+
+const (
+	// How long to wait for a response to the API probe.
+	detectTimeout = 500 * time.Millisecond
+	detectFrameID = 0x01
+)
+
+// DetectAPIMode determines the mode of a module by entering command mode
+// with the guard sequence (+++) and reading the AP register or, if the
+// module doesn't respond, by sending an API frame to query AP. Command mode
+// is tried first because a module in transparent mode would transmit the
+// API frame over the air to its destination (DH/DL). Firmware that doesn't
+// offer command mode in API mode discards the sequence. The query frame
+// contains no bytes that need escaping so it's understood in both API
+// modes. The port must implement SetReadDeadline (e.g. net.Conn or
+// *os.File) otherwise ErrNoReadDeadline is returned.
+func DetectAPIMode(port io.ReadWriter) (APIMode, error) {
+	ds, ok := port.(deadlineSetter)
+	if !ok {
+		return 0, ErrNoReadDeadline
+	}
+	defer ds.SetReadDeadline(time.Time{})
+
+	c := NewATModeClient(port)
+	err := c.EnterCommandMode()
+	if err == nil {
+		mode, err := c.CommandUint(atAPIEnable)
+		if err != nil {
+			return 0, err
+		}
+		if err := c.ExitCommandMode(); err != nil {
+			return 0, err
+		}
+		return APIMode(mode), nil
+	} else if !errors.Is(err, ErrNoCommandMode) {
+		return 0, err
+	}
+
+	wr := frames.NewWriter(port)
+	if err := wr.WriteFrame(&frames.ATCommandRequest{FrameID: detectFrameID, ATCommand: atAPIEnable}); err != nil {
+		return 0, err
+	}
+	if err := ds.SetReadDeadline(time.Now().Add(detectTimeout)); err != nil {
+		return 0, err
+	}
+	rd := frames.NewReader(port)
+	for {
+		data, err := rd.ReadData()
+		if isTimeout(err) {
+			return 0, ErrNoResponse
+		} else if err == frames.ErrChecksum || err == frames.ErrEmpty {
+			continue
+		} else if err != nil {
+			return 0, err
+		}
+		f, err := frames.Decode(data)
+		if err != nil {
+			continue
+		}
+		res, ok := f.(*frames.ATCommandResponse)
+		if !ok || res.FrameID != detectFrameID || res.ATCommand != atAPIEnable {
+			continue
+		}
+		if res.CommandStatus != frames.CSOK || len(res.Data) != 1 {
+			return 0, fmt.Errorf("xbee.DetectAPIMode: %w: unexpected AP response %+v", ErrUnexpectedFrame, res)
+		}
+		return APIMode(res.Data[0]), nil
+	}
+}
+
+// resolveAPIMode returns the API mode to use for the port.
+func resolveAPIMode(port io.ReadWriter, o *options) (APIMode, error) {
+	if !o.detect {
+		return o.mode, nil
+	}
+	mode, err := DetectAPIMode(port)
+	if err != nil {
+		return 0, err
+	}
+	if mode != APIModeTransparent {
+		return mode, nil
+	}
+	if !o.configure {
+		return 0, ErrTransparentMode
+	}
+	c := NewATModeClient(port)
+	if err := c.EnterCommandMode(); err != nil {
+		return 0, err
+	}
+	if err := c.SetAPIMode(false); err != nil {
+		return 0, err
+	}
+	return APIModeUnescaped, nil
+}
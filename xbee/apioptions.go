@@ -0,0 +1,155 @@
+package xbee
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StackProfile is the Zigbee stack profile of a network (ZS).
+type StackProfile byte
+
+const (
+	StackProfileNetworkSpecific StackProfile = 0
+	StackProfileZigBee          StackProfile = 1 // ZigBee-2006
+	StackProfileZigBeePro       StackProfile = 2 // ZigBee-PRO
+)
+
+func (p StackProfile) String() string {
+	switch p {
+	case StackProfileNetworkSpecific:
+		return "NetworkSpecific"
+	case StackProfileZigBee:
+		return "ZigBee"
+	case StackProfileZigBeePro:
+		return "ZigBeePro"
+	}
+	return fmt.Sprintf("StackProfile(%d)", p)
+}
+
+func (p StackProfile) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// ZigBeeStackProfile returns the stack profile (ZS).
+func (xb *XBee) ZigBeeStackProfile() (StackProfile, error) {
+	b, err := xb.atCommand(atZigBeeStackProfile, nil)
+	if err != nil {
+		return 0, err
+	}
+	return StackProfile(decodeUint(b)), nil
+}
+
+// SetZigBeeStackProfile sets the stack profile (ZS). Every node of a
+// network must use the same profile.
+func (xb *XBee) SetZigBeeStackProfile(p StackProfile) error {
+	if p > StackProfileZigBeePro {
+		return fmt.Errorf("xbee.SetZigBeeStackProfile: %w: unknown stack profile %s", ErrInvalidParameter, p)
+	}
+	if _, err := xb.checkATParam(atZigBeeStackProfile, uint64(p)); err != nil {
+		return err
+	}
+	_, err := xb.atCommand(atZigBeeStackProfile, []byte{byte(p)})
+	return err
+}
+
+// APIOption selects how received data is delivered in API mode (AO). The
+// bits other than AOExplicitReceive depend on the firmware.
+type APIOption byte
+
+const (
+	// AOExplicitReceive delivers data as ExplicitReceivePacket with the
+	// application addressing rather than ReceivePacket.
+	AOExplicitReceive APIOption = 0x01
+	// AOZDOPassthrough passes ZDO requests the stack doesn't handle to
+	// the host (Zigbee). It requires AOExplicitReceive.
+	AOZDOPassthrough APIOption = 0x02
+	// AOSupportedZDOPassthrough passes ZDO requests the stack handles to
+	// the host as well (XBee3 Zigbee).
+	AOSupportedZDOPassthrough APIOption = 0x04
+	// AOBindingPassthrough passes binding requests to the host (XBee3
+	// Zigbee).
+	AOBindingPassthrough APIOption = 0x08
+	// AOLegacyReceive delivers data with the legacy 802.15.4 receive
+	// frames (802.15.4 firmware). It shares its bit with
+	// AOZDOPassthrough.
+	AOLegacyReceive APIOption = 0x02
+)
+
+func (o APIOption) Has(opt APIOption) bool {
+	return (o & opt) != 0
+}
+
+func (o APIOption) String() string {
+	if o == 0 {
+		return "None"
+	}
+	var opts []string
+	if o.Has(AOExplicitReceive) {
+		opts = append(opts, "ExplicitReceive")
+		o &^= AOExplicitReceive
+	}
+	if o.Has(AOZDOPassthrough) {
+		opts = append(opts, "ZDOPassthrough")
+		o &^= AOZDOPassthrough
+	}
+	if o.Has(AOSupportedZDOPassthrough) {
+		opts = append(opts, "SupportedZDOPassthrough")
+		o &^= AOSupportedZDOPassthrough
+	}
+	if o.Has(AOBindingPassthrough) {
+		opts = append(opts, "BindingPassthrough")
+		o &^= AOBindingPassthrough
+	}
+	if o != 0 {
+		opts = append(opts, fmt.Sprintf("APIOption(%d)", o))
+	}
+	return strings.Join(opts, "|")
+}
+
+func (o APIOption) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
+}
+
+// SupportedAPIOptions returns the API option bits the firmware supports.
+// Every bit is assumed supported for unknown firmware.
+func (c *Capabilities) SupportedAPIOptions() APIOption {
+	switch c.Protocol {
+	case ProtocolZigbee:
+		if c.Family == FamilyXBee3 {
+			return AOExplicitReceive | AOZDOPassthrough | AOSupportedZDOPassthrough | AOBindingPassthrough
+		}
+		return AOExplicitReceive | AOZDOPassthrough
+	case Protocol802154:
+		return AOExplicitReceive | AOLegacyReceive
+	case ProtocolDigiMesh:
+		return AOExplicitReceive
+	case ProtocolWiFi:
+		return 0
+	}
+	return 0xff
+}
+
+// APIOptions returns the API options (AO).
+func (xb *XBee) APIOptions() (APIOption, error) {
+	b, err := xb.atCommand(atAPIOptions, nil)
+	if err != nil {
+		return 0, err
+	}
+	return APIOption(decodeUint(b)), nil
+}
+
+// SetAPIOptions sets the API options (AO). Once Capabilities has
+// identified the module, options the firmware doesn't support are
+// rejected.
+func (xb *XBee) SetAPIOptions(o APIOption) error {
+	if c := xb.caps.Load(); c != nil {
+		if unsupported := o &^ c.SupportedAPIOptions(); unsupported != 0 {
+			return fmt.Errorf("xbee.SetAPIOptions: %w: %s not supported by %s firmware", ErrInvalidParameter, unsupported, c.Protocol)
+		}
+		if c.Protocol == ProtocolZigbee && o.Has(AOZDOPassthrough) && !o.Has(AOExplicitReceive) {
+			return fmt.Errorf("xbee.SetAPIOptions: %w: ZDOPassthrough requires ExplicitReceive", ErrInvalidParameter)
+		}
+	}
+	_, err := xb.atCommand(atAPIOptions, []byte{byte(o)})
+	return err
+}
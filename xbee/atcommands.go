@@ -1,7 +1,5 @@
 package xbee
 
-type ATCommand [2]byte
-
 // Addressing commands
 var (
 	// Destination Address High.Set/Get the upper 32
@@ -107,7 +105,11 @@ var (
 // Networking Commands
 var (
 	// CH - Operating Channel
-	// DA - Force Disassociation
+
+	// Force Disassociation. End device will immediately disassociate from
+	// a Coordinator (if associated) and reattempt to associate.
+	// Node Type: RE
+	atForceDisassociation = ATCommand([2]byte{'D', 'A'})
 
 	// Extended PAN ID. Set/read the 64-bit extended PAN ID. If set to 0,
 	// the coordinator will select a random extended PAN ID, and the
@@ -128,7 +130,32 @@ var (
 
 	// NH - Maximum Unicast Hops
 	// BH - Broadcast Hops
-	// OI - Operating 16-bit PAN ID
+	// Operating 16-bit PAN ID. Read the 16-bit PAN ID the module is
+	// operating on. It changes if the network manager resolves a PAN ID
+	// conflict (see CR).
+	// Node Type: CRE
+	// Parameter Range: 0 - 0xFFFF [read-only]
+	atOperatingPANID = ATCommand([2]byte{'O', 'I'})
+	// Initial ID. The 16-bit PAN ID a coordinator attempts to use when
+	// forming a network. If it's in use another is selected.
+	// Node Type: C
+	// Parameter Range: 0 - 0xFFFF
+	// Default: 0xFFFF (random)
+	atInitialID = ATCommand([2]byte{'I', 'I'})
+	// ZigBee Stack Profile. Set the stack profile: 0 network specific,
+	// 1 ZigBee-2006, or 2 ZigBee-PRO. It must be the same on every node
+	// of the network.
+	// Node Type: CRE
+	// Parameter Range: 0 - 2
+	// Default: 0
+	atZigBeeStackProfile = ATCommand([2]byte{'Z', 'S'})
+	// Network Watchdog Timeout. If a router doesn't receive a response
+	// from the coordinator within three times the timeout it leaves the
+	// network and attempts to join a new one.
+	// Node Type: R
+	// Parameter Range: 0 - 0x64FF [x 1 minute]
+	// Default: 0 (disabled)
+	atNetworkWatchdogTimeout = ATCommand([2]byte{'N', 'W'})
 
 	// Node Discovery Timeout. Set/Read the node discovery timeout. When the
 	// network discovery (ND) command is issued, the NT value is included in
@@ -150,11 +177,50 @@ var (
 	// Parameter Range: 0 - 0x03 [bitfield]
 	// Default: 0
 	atNodeDiscoveryOptions = ATCommand([2]byte{'N', 'O'})
+	// Scan Channels. Set/Read the list of channels to scan. Coordinators
+	// pick a channel from the list to form a network and routers and end
+	// devices scan them to join one. Bit 0 is channel 0x0B (11) and bit 15
+	// is channel 0x1A (26).
+	// Node Type: CRE
+	// Parameter Range: 1 - 0xFFFF [bitfield]
+	// Default: 0x7FFF
+	atScanChannels = ATCommand([2]byte{'S', 'C'})
+	// Node Join Time. Set/Read the time in seconds that the coordinator or
+	// router allows nodes to join. 0xFF allows joining always. Setting NJ
+	// opens a new joining window.
+	// Node Type: CR
+	// Parameter Range: 0 - 0xFF [x 1 sec]
+	// Default: 0xFF
+	atNodeJoinTime = ATCommand([2]byte{'N', 'J'})
+
+	// Coordinator Enable. Set or read whether the module runs as a
+	// coordinator (1) or a router or end device (0).
+	// Node Type: CRE
+	// Parameter Range: 0 - 1
+	// Default: 0
+	atCoordinatorEnable = ATCommand([2]byte{'C', 'E'})
+
+	// Invoke Bootloader. Resets the module into the serial bootloader
+	// at 115200 baud to update its firmware over XMODEM (XBee3 only).
+	// Node Type: CRE
+	atInvokeBootloader = ATCommand([2]byte{'%', 'P'})
+
+	// IO Sample Rate. Set the interval between periodic I/O samples sent
+	// to the destination address. 0 disables periodic sampling.
+	// Node Type: CRE
+	// Parameter Range: 0, 0x32 - 0xFFFF [ms]
+	atIOSampleRate = ATCommand([2]byte{'I', 'R'})
+	// IO Digital Change Detection. Bitfield of the DIO pins that send a
+	// sample when they change state.
+	// Node Type: CRE
+	// Parameter Range: 0 - 0xFFFF
+	atIOChangeDetection = ATCommand([2]byte{'I', 'C'})
+	// Force Sample. Read the enabled I/O pins immediately.
+	// Node Type: CRE
+	atForceSample = ATCommand([2]byte{'I', 'S'})
 
-// SC - Scan Channels
 // SD - Scan Duration
 // ZS - ZigBee Stack Profile
-// NJ - Node Join Time
 // JV - Channel Verification
 // NW - Network Watchdog Timeout
 // JN - Join Notification
@@ -191,10 +257,15 @@ var (
 
 // RF Interface Commands
 var (
-// PL - Power Level
-// PM - Power Mode
-// DB - Received Signal Strength
-// PP - Peak Power
+	// PL - Power Level
+	// PM - Power Mode
+	// Received Signal Strength. This command reports the received signal
+	// strength of the last received RF data packet as -dBm. DB only
+	// indicates the signal strength of the last hop.
+	// Node Type: RE
+	// Parameter Range: observed range 0x1A - 0x5C [read-only]
+	atReceivedSignalStrength = ATCommand([2]byte{'D', 'B'})
+	// PP - Peak Power
 )
 
 // Serial Interfacing (I/O) Commands
@@ -203,7 +274,13 @@ var (
 	// using API firmware: 21xx (API coordinator), 23xx (API router), 29xx
 	// (API end device).
 	atAPIEnable = ATCommand([2]byte{'A', 'P'})
-	// AO - API Options
+	// API Options. Configure the API output of received data: 0 for
+	// ReceivePacket, bit 0 for ExplicitReceivePacket, and on Zigbee bit
+	// 1 to pass unsupported ZDO requests to the host.
+	// Node Type: CRE
+	// Parameter Range: 0 - 3 (more bits on XBee3)
+	// Default: 0
+	atAPIOptions = ATCommand([2]byte{'A', 'O'})
 
 	// Interface Data Rate. Set/Read the serial interface data rate
 	// for communication between the module serial port and host.
@@ -250,6 +327,10 @@ var (
 	// Parameter Range: 0 - 0xFFFF [read-only] 0x1Exx
 	// Default: factory-set
 	atHardwareVersion = ATCommand([2]byte{'H', 'V'})
+	// Version Long. Read detailed version information including the
+	// application build date and the stack and bootloader versions.
+	// Not supported by older firmware.
+	atVersionLong = ATCommand([2]byte{'V', 'L'})
 	// Association Indication. Read information regarding last node join request:
 	// 0x00 - Successfully formed or joined a network. (Coordinators form a network, routers and end devices join a network).
 	// 0x21 - Scan found no PANs
@@ -268,9 +349,57 @@ var (
 	// 0xFF - Scanning for a ZigBee network (routers and end devices)
 	// NOTE: New non-zero AI values may be added in later firmware versions. Applications should read AI until it returns 0x00, indicating a successful startup (coordinator) or join (routers and end devices)
 	atAssociationIndication = ATCommand([2]byte{'A', 'I'})
+	// Energy Detect. Start an energy detect scan. The parameter is the
+	// time in milliseconds to scan each channel. The response is the
+	// maximum energy detected on each channel in -dBm starting with
+	// channel 0x0B (11).
+	// Node Type: CR
+	// Parameter Range: 0 - 0xFF
+	atEnergyDetect = ATCommand([2]byte{'E', 'D'})
 )
 
 // Sleep Commands
+var (
+	// Sleep Mode. Sets the sleep mode of the module. 0 disables sleep
+	// (routers), other values select pin or cyclic sleep.
+	// Node Type: RE
+	atSleepMode = ATCommand([2]byte{'S', 'M'})
+	// Sleep Period. The time a cyclic sleeping end device sleeps. On a
+	// parent it sets how long data is buffered for its end devices.
+	// Node Type: CRE
+	// Parameter Range: 0x20 - 0xAF0 [x 10 ms]
+	// Default: 0x20
+	atSleepPeriod = ATCommand([2]byte{'S', 'P'})
+	// Number of Sleep Periods. The number of sleep periods between
+	// asserting the On/Sleep pin when extended sleep is enabled.
+	// Node Type: CRE
+	// Parameter Range: 1 - 0xFFFF
+	// Default: 1
+	atNumberOfSleepPeriods = ATCommand([2]byte{'S', 'N'})
+	// Time Before Sleep. The time of inactivity before a cyclic sleeping
+	// end device goes back to sleep.
+	// Node Type: E
+	// Parameter Range: 1 - 0xFFFE [x 1 ms]
+	// Default: 0x1388 (5 seconds)
+	atTimeBeforeSleep = ATCommand([2]byte{'S', 'T'})
+	// Sleep Options. Bit 1 wakes for ST every SN periods, bit 2 sleeps
+	// for the entire SN * SP time.
+	// Node Type: E
+	atSleepOptions = ATCommand([2]byte{'S', 'O'})
+	// Polling Rate. How often an awake end device polls its parent for
+	// data. 0 is the default of 100 ms.
+	// Node Type: E
+	// Parameter Range: 0 - 0x3E8 [x 10 ms]
+	// Default: 0
+	atPollRate = ATCommand([2]byte{'P', 'O'})
+	// End Device Timeout. How long the parent keeps an end device in its
+	// child table without hearing from it: 0 is 10 seconds and n is 2^n
+	// minutes. Zigbee 3.0 firmware only.
+	// Node Type: E
+	// Parameter Range: 0 - 0x0E
+	// Default: 0x08 (256 minutes)
+	atEndDeviceTimeout = ATCommand([2]byte{'E', 'T'})
+)
 
 // Execution Commands
 var (
@@ -293,6 +422,9 @@ var (
 	// Software Reset. Reset module. Responds immediately with an OK status,
 	// and then performs a software reset about two seconds later.
 	atSoftwareReset = ATCommand([2]byte{'F', 'R'})
+	// Exit Command Mode. Explicitly exit the module from AT command mode
+	// applying any changes.
+	atExitCommandMode = ATCommand([2]byte{'C', 'N'})
 	// Node Discover. Discovers and reports all RF modules found. The following
 	// information is reported for each
 	// module discovered. SH<CR>
@@ -0,0 +1,167 @@
+package xbee
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoCommandMode = errors.New("xbee: module did not enter command mode")
+	ErrATModeError   = errors.New("xbee: AT command returned ERROR")
+)
+
+// DefaultGuardTime is the default guard time (GT) required before and
+// after the command sequence.
+const DefaultGuardTime = time.Second
+
+// deadlineSetter is implemented by ports that support read timeouts such
+// as net.Conn and *os.File.
+type deadlineSetter interface {
+	SetReadDeadline(t time.Time) error
+}
+
+// ATModeClient talks to a module running transparent firmware (AP=0)
+// using the ASCII command mode. It can be used to provision factory fresh
+// modules or to switch them to API mode.
+//
+// Responses are only read with a timeout if the port implements
+// SetReadDeadline (e.g. net.Conn or *os.File). Otherwise reads block until
+// the module responds.
+type ATModeClient struct {
+	port io.ReadWriter
+	buf  []byte
+
+	// GuardTime is the silence required around the command sequence. It
+	// must match the module's GT setting.
+	GuardTime time.Duration
+	// CommandChar is the character repeated three times to enter command
+	// mode. It must match the module's CC setting.
+	CommandChar byte
+	// Timeout is how long to wait for a response.
+	Timeout time.Duration
+}
+
+func NewATModeClient(port io.ReadWriter) *ATModeClient {
+	return &ATModeClient{
+		port:        port,
+		GuardTime:   DefaultGuardTime,
+		CommandChar: '+',
+		Timeout:     DefaultGuardTime * 2,
+	}
+}
+
+// EnterCommandMode sends the command sequence surrounded by the guard time
+// and waits for the module to respond with OK.
+func (c *ATModeClient) EnterCommandMode() error {
+	time.Sleep(c.GuardTime)
+	seq := []byte{c.CommandChar, c.CommandChar, c.CommandChar}
+	if _, err := c.port.Write(seq); err != nil {
+		return err
+	}
+	res, err := c.readLine(c.GuardTime + c.Timeout)
+	if err != nil {
+		if errors.Is(err, ErrTimeout) {
+			return ErrNoCommandMode
+		}
+		return err
+	}
+	if res != "OK" {
+		return ErrNoCommandMode
+	}
+	return nil
+}
+
+// ExitCommandMode leaves command mode (ATCN) returning to transparent
+// mode, or API mode if AP was changed.
+func (c *ATModeClient) ExitCommandMode() error {
+	_, err := c.Command(atExitCommandMode, "")
+	return err
+}
+
+// Command sends an AT command with an optional parameter (as ASCII, e.g.
+// hexadecimal for numeric values) and returns the response. Set commands
+// respond with "OK". An "ERROR" response returns ErrATModeError.
+func (c *ATModeClient) Command(cmd ATCommand, param string) (string, error) {
+	req := make([]byte, 0, 5+len(param))
+	req = append(req, 'A', 'T', cmd[0], cmd[1])
+	if param != "" {
+		req = append(req, ' ')
+		req = append(req, param...)
+	}
+	req = append(req, '\r')
+	if _, err := c.port.Write(req); err != nil {
+		return "", err
+	}
+	res, err := c.readLine(c.Timeout)
+	if err != nil {
+		return "", err
+	}
+	if res == "ERROR" {
+		return "", ErrATModeError
+	}
+	return res, nil
+}
+
+// CommandUint sends an AT command and parses the hexadecimal response.
+func (c *ATModeClient) CommandUint(cmd ATCommand) (uint64, error) {
+	res, err := c.Command(cmd, "")
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	if _, err := fmt.Sscanf(res, "%x", &v); err != nil {
+		return 0, fmt.Errorf("xbee.ATModeClient: %w: invalid response %q to %s", ErrMalformedFrame, res, cmd)
+	}
+	return v, nil
+}
+
+// SetAPIMode configures the module for API mode (AP=1, or AP=2 if
+// escaped), saves the setting, and leaves command mode. The client must
+// be in command mode. After it returns the port can be passed to Open.
+func (c *ATModeClient) SetAPIMode(escaped bool) error {
+	mode := "1"
+	if escaped {
+		mode = "2"
+	}
+	if _, err := c.Command(atAPIEnable, mode); err != nil {
+		return err
+	}
+	if _, err := c.Command(atWrite, ""); err != nil {
+		return err
+	}
+	return c.ExitCommandMode()
+}
+
+// readLine reads a carriage return terminated response.
+func (c *ATModeClient) readLine(timeout time.Duration) (string, error) {
+	if ds, ok := c.port.(deadlineSetter); ok {
+		if err := ds.SetReadDeadline(time.Now().Add(timeout)); err == nil {
+			defer ds.SetReadDeadline(time.Time{})
+		}
+	}
+	var b [64]byte
+	for {
+		if ix := bytes.IndexByte(c.buf, '\r'); ix >= 0 {
+			line := string(c.buf[:ix])
+			c.buf = c.buf[ix+1:]
+			return strings.TrimSpace(line), nil
+		}
+		n, err := c.port.Read(b[:])
+		c.buf = append(c.buf, b[:n]...)
+		if err != nil {
+			if isTimeout(err) {
+				return "", ErrTimeout
+			}
+			return "", err
+		}
+	}
+}
+
+func isTimeout(err error) bool {
+	te, ok := err.(interface{ Timeout() bool })
+	return ok && te.Timeout()
+}
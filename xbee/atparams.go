@@ -0,0 +1,247 @@
+package xbee
+
+import (
+	"fmt"
+	"math/bits"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ATParam describes the valid values of a numeric AT register.
+type ATParam struct {
+	Command  ATCommand
+	Min, Max uint64
+	// Values if not nil are the only valid values between Min and Max.
+	Values []uint64
+	Size   int // bytes written when set
+}
+
+// Valid returns whether v is a valid value of the register.
+func (p ATParam) Valid(v uint64) bool {
+	if v < p.Min || v > p.Max {
+		return false
+	}
+	return p.Values == nil || slices.Contains(p.Values, v)
+}
+
+func (p ATParam) rangeString() string {
+	if p.Values != nil {
+		vals := make([]string, len(p.Values))
+		for i, v := range p.Values {
+			vals[i] = "0x" + strconv.FormatUint(v, 16)
+		}
+		return "one of " + strings.Join(vals, ", ")
+	}
+	return fmt.Sprintf("0x%x to 0x%x", p.Min, p.Max)
+}
+
+// atParams builds a table of registers.
+func atParams(params ...ATParam) map[ATCommand]ATParam {
+	m := make(map[ATCommand]ATParam, len(params))
+	for _, p := range params {
+		m[p.Command] = p
+	}
+	return m
+}
+
+// extend returns a copy of base with params added or replaced.
+func extend(base map[ATCommand]ATParam, params ...ATParam) map[ATCommand]ATParam {
+	m := make(map[ATCommand]ATParam, len(base)+len(params))
+	for cmd, p := range base {
+		m[cmd] = p
+	}
+	for _, p := range params {
+		m[p.Command] = p
+	}
+	return m
+}
+
+// Registers common to every RF firmware.
+var commonATParams = atParams(
+	ATParam{Command: ATCommand{'P', 'L'}, Max: 4, Size: 1},
+	ATParam{Command: ATCommand{'A', 'P'}, Max: 2, Size: 1},
+	ATParam{Command: ATCommand{'R', 'O'}, Max: 0xff, Size: 1},
+	ATParam{Command: ATCommand{'N', 'B'}, Max: 2, Size: 1},
+	ATParam{Command: ATCommand{'D', 'H'}, Max: 0xffffffff, Size: 4},
+	ATParam{Command: ATCommand{'D', 'L'}, Max: 0xffffffff, Size: 4},
+	ATParam{Command: ATCommand{'E', 'E'}, Max: 1, Size: 1},
+)
+
+var zigbeeATParams = extend(commonATParams,
+	ATParam{Command: ATCommand{'Z', 'S'}, Max: 2, Size: 1},
+	ATParam{Command: ATCommand{'S', 'C'}, Min: 1, Max: 0xffff, Size: 2},
+	ATParam{Command: ATCommand{'S', 'D'}, Max: 7, Size: 1},
+	ATParam{Command: ATCommand{'N', 'J'}, Max: 0xff, Size: 1},
+	ATParam{Command: ATCommand{'N', 'H'}, Max: 0xff, Size: 1},
+	ATParam{Command: ATCommand{'B', 'H'}, Max: 0x1e, Size: 1},
+	ATParam{Command: ATCommand{'N', 'T'}, Min: 0x20, Max: 0xff, Size: 1},
+	ATParam{Command: ATCommand{'N', 'O'}, Max: 7, Size: 1},
+	ATParam{Command: ATCommand{'A', 'O'}, Max: 0x0f, Size: 1},
+	ATParam{Command: ATCommand{'C', 'E'}, Max: 1, Size: 1},
+	ATParam{Command: ATCommand{'J', 'V'}, Max: 1, Size: 1},
+	ATParam{Command: ATCommand{'C', 'R'}, Min: 1, Max: 0x3f, Size: 1},
+	ATParam{Command: ATCommand{'I', 'I'}, Max: 0xffff, Size: 2},
+	ATParam{Command: ATCommand{'N', 'W'}, Max: 0x64ff, Size: 2},
+	ATParam{Command: ATCommand{'E', 'O'}, Max: 0xff, Size: 1},
+	ATParam{Command: ATCommand{'S', 'M'}, Max: 5, Values: []uint64{0, 1, 4, 5}, Size: 1},
+	ATParam{Command: ATCommand{'S', 'P'}, Min: 0x20, Max: 0xaf0, Size: 2},
+	ATParam{Command: ATCommand{'S', 'N'}, Min: 1, Max: 0xffff, Size: 2},
+	ATParam{Command: ATCommand{'S', 'T'}, Min: 1, Max: 0xfffe, Size: 2},
+	ATParam{Command: ATCommand{'S', 'O'}, Max: 0xff, Size: 1},
+	ATParam{Command: ATCommand{'P', 'O'}, Max: 0x3e8, Size: 2},
+	ATParam{Command: ATCommand{'E', 'T'}, Max: 0x0e, Size: 1},
+)
+
+// 802.15.4 firmware including the MAC mode (MM) of the S1.
+var ieee802154ATParams = extend(commonATParams,
+	ATParam{Command: ATCommand{'C', 'H'}, Min: 0x0b, Max: 0x1a, Size: 1},
+	ATParam{Command: ATCommand{'I', 'D'}, Max: 0xffff, Size: 2},
+	ATParam{Command: ATCommand{'M', 'Y'}, Max: 0xffff, Size: 2},
+	ATParam{Command: ATCommand{'M', 'M'}, Max: 3, Size: 1},
+	ATParam{Command: ATCommand{'R', 'R'}, Max: 6, Size: 1},
+	ATParam{Command: ATCommand{'R', 'N'}, Max: 3, Size: 1},
+	ATParam{Command: ATCommand{'C', 'E'}, Max: 1, Size: 1},
+	ATParam{Command: ATCommand{'A', '1'}, Max: 0x0f, Size: 1},
+	ATParam{Command: ATCommand{'A', '2'}, Max: 0x0f, Size: 1},
+	ATParam{Command: ATCommand{'S', 'C'}, Max: 0xffff, Size: 2},
+	ATParam{Command: ATCommand{'S', 'D'}, Max: 0x0f, Size: 1},
+	ATParam{Command: ATCommand{'N', 'O'}, Max: 1, Size: 1},
+	ATParam{Command: ATCommand{'A', 'O'}, Max: 2, Size: 1},
+	ATParam{Command: ATCommand{'S', 'M'}, Max: 6, Values: []uint64{0, 1, 2, 4, 5, 6}, Size: 1},
+	ATParam{Command: ATCommand{'S', 'P'}, Max: 0x68b0, Size: 2},
+	ATParam{Command: ATCommand{'S', 'T'}, Min: 1, Max: 0xffff, Size: 2},
+	ATParam{Command: ATCommand{'S', 'O'}, Max: 6, Size: 1},
+)
+
+// Registers common to DigiMesh firmware of every band.
+var meshATParams = extend(commonATParams,
+	ATParam{Command: ATCommand{'I', 'D'}, Max: 0xffff, Size: 2},
+	ATParam{Command: ATCommand{'M', 'T'}, Max: 0x0f, Size: 1},
+	ATParam{Command: ATCommand{'R', 'R'}, Max: 0x0f, Size: 1},
+	ATParam{Command: ATCommand{'N', 'H'}, Min: 1, Max: 0x20, Size: 1},
+	ATParam{Command: ATCommand{'B', 'H'}, Max: 0x20, Size: 1},
+	ATParam{Command: ATCommand{'N', 'N'}, Min: 1, Max: 0x20, Size: 1},
+	ATParam{Command: ATCommand{'N', 'O'}, Max: 7, Size: 1},
+	ATParam{Command: ATCommand{'A', 'O'}, Max: 1, Size: 1},
+	ATParam{Command: ATCommand{'C', 'E'}, Max: 0, Size: 1},
+	ATParam{Command: ATCommand{'S', 'M'}, Max: 8, Values: []uint64{0, 1, 4, 5, 7, 8}, Size: 1},
+	ATParam{Command: ATCommand{'S', 'P'}, Min: 1, Max: 0x15f900, Size: 4},
+	ATParam{Command: ATCommand{'S', 'N'}, Min: 1, Max: 0xffff, Size: 2},
+	ATParam{Command: ATCommand{'S', 'T'}, Min: 0x45, Max: 0x36ee80, Size: 4},
+	ATParam{Command: ATCommand{'S', 'O'}, Max: 0x13e, Size: 2},
+)
+
+// DigiMesh 2.4 GHz firmware.
+var digiMeshATParams = extend(meshATParams,
+	ATParam{Command: ATCommand{'C', 'H'}, Min: 0x0b, Max: 0x1a, Size: 1},
+)
+
+// SX and 900HP sub-GHz DigiMesh firmware with the preamble ID (HP) and
+// channel mask (CM) instead of a channel.
+var sxATParams = extend(meshATParams,
+	ATParam{Command: ATCommand{'H', 'P'}, Max: 7, Size: 1},
+	ATParam{Command: ATCommand{'C', 'M'}, Min: 1, Max: 0xffffffffffffffff, Size: 8},
+	ATParam{Command: ATCommand{'M', 'F'}, Max: 0x3f, Size: 1},
+	ATParam{Command: ATCommand{'I', 'D'}, Max: 0x7fff, Size: 2},
+	ATParam{Command: ATCommand{'B', 'R'}, Max: 2, Size: 1},
+)
+
+var wifiATParams = atParams(
+	ATParam{Command: ATCommand{'P', 'L'}, Max: 4, Size: 1},
+	ATParam{Command: ATCommand{'A', 'P'}, Max: 2, Size: 1},
+	ATParam{Command: ATCommand{'R', 'O'}, Max: 0xff, Size: 1},
+	ATParam{Command: ATCommand{'N', 'B'}, Max: 2, Size: 1},
+	ATParam{Command: ATCommand{'A', 'H'}, Max: 2, Size: 1},
+	ATParam{Command: ATCommand{'I', 'P'}, Max: 1, Size: 1},
+	ATParam{Command: ATCommand{'M', 'A'}, Max: 1, Size: 1},
+	ATParam{Command: ATCommand{'E', 'E'}, Max: 3, Size: 1},
+	ATParam{Command: ATCommand{'C', '0'}, Max: 0xffff, Size: 2},
+	ATParam{Command: ATCommand{'D', 'E'}, Max: 0xffff, Size: 2},
+	ATParam{Command: ATCommand{'T', 'M'}, Max: 0xffff, Size: 2},
+)
+
+// knownATParam returns whether a table describes cmd.
+func knownATParam(cmd ATCommand) bool {
+	for _, params := range []map[ATCommand]ATParam{zigbeeATParams, ieee802154ATParams, digiMeshATParams, sxATParams, wifiATParams} {
+		if _, ok := params[cmd]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ATParams returns the numeric registers of the module's firmware or nil
+// if the firmware isn't known.
+func (c *Capabilities) ATParams() map[ATCommand]ATParam {
+	switch c.Protocol {
+	case ProtocolZigbee:
+		return zigbeeATParams
+	case Protocol802154:
+		return ieee802154ATParams
+	case ProtocolDigiMesh:
+		if c.Family == FamilySX {
+			return sxATParams
+		}
+		return digiMeshATParams
+	case ProtocolWiFi:
+		return wifiATParams
+	}
+	return nil
+}
+
+// checkATParam validates the value of a register against the registers of
+// the module's firmware once identified by Capabilities returning the
+// parameter or nil if the firmware or register isn't known. A register of
+// another firmware (e.g. MM on Zigbee) is rejected.
+func (xb *XBee) checkATParam(cmd ATCommand, v uint64) (*ATParam, error) {
+	c := xb.caps.Load()
+	if c == nil {
+		return nil, nil
+	}
+	params := c.ATParams()
+	if params == nil {
+		return nil, nil
+	}
+	p, ok := params[cmd]
+	if !ok {
+		if !knownATParam(cmd) {
+			// Not described by any table.
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%w: not a register of %s firmware", ErrInvalidCommand(cmd.String()), c.Protocol)
+	}
+	if !p.Valid(v) {
+		return nil, fmt.Errorf("%w: %s must be %s for %s firmware", ErrInvalidParameter, cmd, p.rangeString(), c.Protocol)
+	}
+	return &p, nil
+}
+
+// SetATParam sets a numeric register. Once Capabilities has identified the
+// module the value is checked against the registers of its firmware
+// (ATParams) and written with the register's size. Otherwise, or for a
+// register without a known size, it's written with as few bytes as
+// possible.
+func (xb *XBee) SetATParam(cmd ATCommand, v uint64) error {
+	p, err := xb.checkATParam(cmd, v)
+	if err != nil {
+		return err
+	}
+	size := 0
+	if p != nil {
+		size = p.Size
+	}
+	_, err = xb.atCommand(cmd, encodeUint(v, size))
+	return err
+}
+
+// encodeUint encodes v big-endian in at least size bytes and as few as
+// possible otherwise.
+func encodeUint(v uint64, size int) []byte {
+	b := make([]byte, max((bits.Len64(v)+7)/8, size, 1))
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i] = byte(v)
+		v >>= 8
+	}
+	return b
+}
@@ -0,0 +1,100 @@
+package xbee
+
+import (
+	"log/slog"
+	"sync"
+
+	"github.com/samuel/go-xbee/xbee/frames"
+)
+
+const (
+	// pooledBufferSize fits the frame data of the largest RF payload
+	// supported by most modules.
+	pooledBufferSize = 256
+	// maxPooledBufferSize limits the buffers returned to the pool so an
+	// occasional large frame isn't retained.
+	maxPooledBufferSize = 1024
+	// maxLeased limits the frames leased at a time so a consumer that
+	// doesn't call Release can't pin buffers without bound.
+	maxLeased = 256
+)
+
+// bufferPool recycles read buffers for the frames leased to the user by
+// WithPooledBuffers.
+type bufferPool struct {
+	pool   sync.Pool
+	log    *slog.Logger
+	mu     sync.Mutex
+	leased map[Event]*[]byte
+	warned bool // the lease limit was reached
+}
+
+func newBufferPool(log *slog.Logger) *bufferPool {
+	return &bufferPool{
+		pool: sync.Pool{New: func() any {
+			b := make([]byte, 0, pooledBufferSize)
+			return &b
+		}},
+		log:    log,
+		leased: make(map[Event]*[]byte),
+	}
+}
+
+func (p *bufferPool) get() *[]byte {
+	return p.pool.Get().(*[]byte)
+}
+
+func (p *bufferPool) put(b *[]byte) {
+	if cap(*b) <= maxPooledBufferSize {
+		*b = (*b)[:0]
+		p.pool.Put(b)
+	}
+}
+
+// lease records that f references b until released returning false if f
+// isn't a frame type that is leased or too many frames are leased. A frame
+// that isn't leased keeps its buffer, which is then never reused, as if it
+// had been read without pooling.
+func (p *bufferPool) lease(f frames.Frame, b *[]byte) bool {
+	switch f.(type) {
+	case *frames.ReceivePacket, *frames.ExplicitReceivePacket, *frames.IPv4ReceivePacket:
+	default:
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.leased) >= maxLeased {
+		if !p.warned {
+			p.warned = true
+			p.log.Warn("xbee: pooled buffers aren't being released, see WithPooledBuffers", "leased", len(p.leased))
+		}
+		return false
+	}
+	p.leased[f] = b
+	return true
+}
+
+func (p *bufferPool) release(ev Event) {
+	p.mu.Lock()
+	b := p.leased[ev]
+	delete(p.leased, ev)
+	p.mu.Unlock()
+	if b != nil {
+		p.put(b)
+	}
+}
+
+// Release returns the buffer referenced by an event received from
+// EventChan, Events, or Manager.Events to the pool when using
+// WithPooledBuffers. The event including
+// its Data must not be used after. It's a no-op for events that don't
+// reference a pooled buffer and when pooling isn't enabled.
+func (xb *XBee) Release(ev Event) {
+	if xb.pool == nil {
+		return
+	}
+	switch ev.(type) {
+	case *frames.ReceivePacket, *frames.ExplicitReceivePacket, *frames.IPv4ReceivePacket:
+		xb.pool.release(ev)
+	}
+}
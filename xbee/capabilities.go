@@ -0,0 +1,301 @@
+package xbee
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ModuleFamily is the hardware platform of a module identified by the
+// upper byte of its hardware version (HV).
+type ModuleFamily int
+
+const (
+	FamilyUnknown ModuleFamily = iota
+	FamilyS1                   // XBee 802.15.4 (Series 1)
+	FamilyS2                   // XBee ZB (Series 2, S2B)
+	FamilyS2C                  // XBee S2C
+	FamilyXBee3                // XBee3
+	FamilySX                   // XBee SX and XBee-PRO 900HP
+	FamilyWiFi                 // XBee Wi-Fi (S6B)
+)
+
+func (f ModuleFamily) String() string {
+	switch f {
+	case FamilyUnknown:
+		return "Unknown"
+	case FamilyS1:
+		return "S1"
+	case FamilyS2:
+		return "S2"
+	case FamilyS2C:
+		return "S2C"
+	case FamilyXBee3:
+		return "XBee3"
+	case FamilySX:
+		return "SX"
+	case FamilyWiFi:
+		return "Wi-Fi"
+	}
+	return fmt.Sprintf("ModuleFamily(%d)", f)
+}
+
+func (f ModuleFamily) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+// hardwareFamilies maps the upper byte of the hardware version to the
+// module family.
+var hardwareFamilies = map[byte]ModuleFamily{
+	0x17: FamilyS1,
+	0x18: FamilyS1, // PRO
+	0x19: FamilyS2,
+	0x1A: FamilyS2, // PRO
+	0x1E: FamilyS2, // PRO S2B
+	0x1F: FamilyWiFi,
+	0x21: FamilyS2C,
+	0x22: FamilyS2C, // PRO
+	0x23: FamilySX,  // PRO 900HP
+	0x27: FamilySX,
+	0x41: FamilyXBee3,
+	0x42: FamilyXBee3,
+}
+
+// Protocol is the network protocol of a module's firmware.
+type Protocol int
+
+const (
+	ProtocolUnknown Protocol = iota
+	ProtocolZigbee
+	Protocol802154
+	ProtocolDigiMesh
+	ProtocolWiFi
+)
+
+func (p Protocol) String() string {
+	switch p {
+	case ProtocolUnknown:
+		return "Unknown"
+	case ProtocolZigbee:
+		return "Zigbee"
+	case Protocol802154:
+		return "802.15.4"
+	case ProtocolDigiMesh:
+		return "DigiMesh"
+	case ProtocolWiFi:
+		return "Wi-Fi"
+	}
+	return fmt.Sprintf("Protocol(%d)", p)
+}
+
+func (p Protocol) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// Feature is an optional feature of a module.
+type Feature int
+
+const (
+	FeatureFileSystem    Feature = iota // file system and MicroPython
+	FeatureBootloader                   // serial bootloader (EnterBootloader)
+	FeatureUserDataRelay                // user data relay (SendUserDataRelay)
+	FeatureSecureSession                // secure sessions (SecureSessionLogin)
+	FeatureIP                           // IP sockets (TransmitIPv4)
+	FeatureWiFiScan                     // access point scan (ActiveScanWiFi)
+	FeatureExtendedPANID                // 64-bit extended PAN ID (ID, OP)
+)
+
+func (f Feature) String() string {
+	switch f {
+	case FeatureFileSystem:
+		return "file system"
+	case FeatureBootloader:
+		return "serial bootloader"
+	case FeatureUserDataRelay:
+		return "user data relay"
+	case FeatureSecureSession:
+		return "secure session"
+	case FeatureIP:
+		return "IP"
+	case FeatureWiFiScan:
+		return "Wi-Fi scan"
+	case FeatureExtendedPANID:
+		return "extended PAN ID"
+	}
+	return fmt.Sprintf("Feature(%d)", f)
+}
+
+// Capabilities describes a module as classified by its hardware (HV) and
+// firmware (VR) versions.
+type Capabilities struct {
+	HardwareVersion uint16
+	FirmwareVersion uint16
+	// VersionLong is the detailed version information (VL) if the
+	// firmware supports it.
+	VersionLong string
+	Family      ModuleFamily
+	Protocol    Protocol
+	// Role is the role of the module in the network from the firmware
+	// variant or the coordinator enable (CE) and sleep mode (SM)
+	// registers. It's DeviceTypeUnknown if it couldn't be determined.
+	Role    DeviceType
+	APIMode APIMode
+}
+
+// Supports returns whether the module supports a feature. Unknown modules
+// are assumed to support every feature.
+func (c *Capabilities) Supports(f Feature) bool {
+	if c.Family == FamilyUnknown {
+		return true
+	}
+	switch f {
+	case FeatureFileSystem, FeatureBootloader, FeatureUserDataRelay, FeatureSecureSession:
+		return c.Family == FamilyXBee3
+	case FeatureIP, FeatureWiFiScan:
+		return c.Protocol == ProtocolWiFi
+	case FeatureExtendedPANID:
+		return c.Protocol == ProtocolZigbee
+	}
+	return true
+}
+
+func (c *Capabilities) String() string {
+	s := fmt.Sprintf("%s %s", c.Family, c.Protocol)
+	if c.Role != DeviceTypeUnknown {
+		s += " " + c.Role.String()
+	}
+	return fmt.Sprintf("%s (HV %04X, VR %04X)", s, c.HardwareVersion, c.FirmwareVersion)
+}
+
+// UnsupportedError is returned by methods for a feature that the module
+// doesn't support once Capabilities has identified it. It unwraps to
+// errors.ErrUnsupported.
+type UnsupportedError struct {
+	Feature      Feature
+	Capabilities *Capabilities
+}
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("xbee: %s isn't supported by %s", e.Feature, e.Capabilities)
+}
+
+func (e *UnsupportedError) Unwrap() error {
+	return errors.ErrUnsupported
+}
+
+// Capabilities identifies the module. Once identified, methods for
+// features the module doesn't support return an *UnsupportedError rather
+// than sending a command the module would reject.
+func (xb *XBee) Capabilities() (*Capabilities, error) {
+	hv, err := xb.HardwareVersion()
+	if err != nil {
+		return nil, err
+	}
+	vr, err := xb.FirmwareVersion()
+	if err != nil {
+		return nil, err
+	}
+	c := &Capabilities{
+		HardwareVersion: hv,
+		FirmwareVersion: vr,
+		Family:          hardwareFamilies[byte(hv>>8)],
+		Role:            DeviceTypeUnknown,
+		APIMode:         APIModeUnescaped,
+	}
+	if xb.escaped {
+		c.APIMode = APIModeEscaped
+	}
+	c.Protocol = firmwareProtocol(c.Family, vr)
+	if b, err := xb.atCommand(atVersionLong, nil); err == nil {
+		c.VersionLong = strings.TrimSpace(string(b))
+	}
+	if c.Family == FamilyS2 && vr>>12 == 2 {
+		// ZB firmware on S2 is built per role with the variant in
+		// the second digit.
+		switch (vr >> 8) & 0xf {
+		case 0, 1:
+			c.Role = Coordinator
+		case 2, 3:
+			c.Role = Router
+		case 8, 9:
+			c.Role = EndDevice
+		}
+	} else {
+		c.Role = xb.detectRole(c.Protocol)
+	}
+	xb.caps.Store(c)
+	return c, nil
+}
+
+// firmwareProtocol returns the protocol of the firmware with version vr
+// for the family. The first digit identifies the firmware in a family.
+func firmwareProtocol(family ModuleFamily, vr uint16) Protocol {
+	d := vr >> 12
+	switch family {
+	case FamilyS1:
+		if d == 8 {
+			return ProtocolDigiMesh
+		}
+		return Protocol802154
+	case FamilyS2:
+		if d == 8 {
+			return ProtocolDigiMesh
+		}
+		return ProtocolZigbee
+	case FamilyS2C:
+		switch d {
+		case 2:
+			return Protocol802154
+		case 4:
+			return ProtocolZigbee
+		case 9:
+			return ProtocolDigiMesh
+		}
+	case FamilyXBee3:
+		switch d {
+		case 1:
+			return ProtocolZigbee
+		case 2:
+			return Protocol802154
+		case 3:
+			return ProtocolDigiMesh
+		}
+	case FamilySX:
+		return ProtocolDigiMesh
+	case FamilyWiFi:
+		return ProtocolWiFi
+	}
+	return ProtocolUnknown
+}
+
+// detectRole determines the role of the module from the coordinator enable
+// (CE) and sleep mode (SM) registers.
+func (xb *XBee) detectRole(p Protocol) DeviceType {
+	switch p {
+	case ProtocolZigbee, Protocol802154, ProtocolDigiMesh:
+	default:
+		return DeviceTypeUnknown
+	}
+	if p != ProtocolDigiMesh {
+		if b, err := xb.atCommand(atCoordinatorEnable, nil); err == nil && decodeUint(b) != 0 {
+			return Coordinator
+		}
+	}
+	b, err := xb.atCommand(atSleepMode, nil)
+	if err != nil {
+		return DeviceTypeUnknown
+	}
+	if decodeUint(b) != 0 || p == Protocol802154 {
+		return EndDevice
+	}
+	return Router
+}
+
+// require returns an *UnsupportedError if Capabilities found that the
+// module doesn't support f.
+func (xb *XBee) require(f Feature) error {
+	if c := xb.caps.Load(); c != nil && !c.Supports(f) {
+		return &UnsupportedError{Feature: f, Capabilities: c}
+	}
+	return nil
+}
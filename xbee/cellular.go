@@ -0,0 +1,16 @@
+package xbee
+
+import "github.com/samuel/go-xbee/xbee/frames"
+
+// Support for XBee Cellular modules. IP data is sent with TransmitIPv4
+// and received as IPv4ReceivePacket events the same as XBee Wi-Fi.
+
+// TransmitSMS sends an SMS message. The phone number may include a
+// leading '+' and is limited to 20 characters.
+func (xb *XBee) TransmitSMS(phoneNumber string, data []byte) error {
+	return xb.writeFrame(&frames.SMSTransmitRequest{
+		FrameID:     xb.nextFrameID(),
+		PhoneNumber: phoneNumber,
+		Data:        data,
+	})
+}
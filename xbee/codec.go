@@ -0,0 +1,208 @@
+package xbee
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Codec encodes and decodes packet payloads. Other formats such as CBOR
+// or protocol buffers are supported by wrapping the library's marshal and
+// unmarshal functions with CodecFuncs.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// JSONCodec encodes payloads as JSON.
+type JSONCodec struct{}
+
+func (JSONCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// BinaryCodec encodes payloads of fixed size types (see encoding/binary),
+// typically the packed structs sent by sensor firmware. Order defaults to
+// little-endian, the byte order of most microcontrollers.
+type BinaryCodec struct {
+	Order binary.ByteOrder
+}
+
+func (c BinaryCodec) order() binary.ByteOrder {
+	if c.Order == nil {
+		return binary.LittleEndian
+	}
+	return c.Order
+}
+
+func (c BinaryCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, c.order(), v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c BinaryCodec) Unmarshal(data []byte, v any) error {
+	_, err := binary.Decode(data, c.order(), v)
+	return err
+}
+
+// CodecFuncs returns a Codec calling marshal and unmarshal, e.g. the
+// functions of a CBOR or protobuf library.
+func CodecFuncs(marshal func(any) ([]byte, error), unmarshal func([]byte, any) error) Codec {
+	return codecFuncs{marshal, unmarshal}
+}
+
+type codecFuncs struct {
+	marshal   func(any) ([]byte, error)
+	unmarshal func([]byte, any) error
+}
+
+func (c codecFuncs) Marshal(v any) ([]byte, error)      { return c.marshal(v) }
+func (c codecFuncs) Unmarshal(data []byte, v any) error { return c.unmarshal(data, v) }
+
+// PayloadType selects the packets carrying a type of value by their
+// application addressing and optionally a type byte at the start of the
+// payload. Packets received as ReceivePacket (AO=0) are treated as sent to
+// DefaultExplicitAddress.
+type PayloadType struct {
+	Address ExplicitAddress // the source endpoint is only used when encoding
+	// TypeByte is the first byte of the payload if HasTypeByte is set.
+	// It's removed before decoding and added after encoding which allows
+	// several types on the same cluster.
+	TypeByte    byte
+	HasTypeByte bool
+}
+
+type payloadKey struct {
+	endpoint byte
+	cluster  uint16
+	profile  uint16
+	typeByte byte
+	hasType  bool
+}
+
+func (t PayloadType) key() payloadKey {
+	return payloadKey{t.Address.DestinationEndpoint, t.Address.ClusterID, t.Address.ProfileID, t.TypeByte, t.HasTypeByte}
+}
+
+type codecEntry struct {
+	pt    PayloadType
+	codec Codec
+	typ   reflect.Type // the registered type T, values are *T
+}
+
+// CodecRegistry maps payload types to codecs and Go types so received
+// packets can be decoded to structs. It's safe for concurrent use.
+type CodecRegistry struct {
+	mu     sync.RWMutex
+	byKey  map[payloadKey]*codecEntry
+	byType map[reflect.Type]*codecEntry
+}
+
+// NewCodecRegistry returns an empty registry.
+func NewCodecRegistry() *CodecRegistry {
+	return &CodecRegistry{
+		byKey:  make(map[payloadKey]*codecEntry),
+		byType: make(map[reflect.Type]*codecEntry),
+	}
+}
+
+// RegisterPayload registers a payload type decoded to *T with codec c
+// replacing an earlier registration of the payload type or T.
+func RegisterPayload[T any](r *CodecRegistry, pt PayloadType, c Codec) {
+	e := &codecEntry{pt: pt, codec: c, typ: reflect.TypeFor[T]()}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.byKey[pt.key()] = e
+	r.byType[e.typ] = e
+}
+
+// DecodedPacket is a received packet with its payload decoded.
+type DecodedPacket struct {
+	Source  Addr64
+	Address ExplicitAddress
+	Value   any // a pointer to the registered type
+}
+
+// Decode decodes the payload of a ReceivePacket or ExplicitReceivePacket
+// event. It returns false if the event isn't a packet of a registered
+// payload type and an error if the payload can't be decoded.
+func (r *CodecRegistry) Decode(ev Event) (*DecodedPacket, bool, error) {
+	var p DecodedPacket
+	var data []byte
+	switch rx := ev.(type) {
+	case *ReceivePacket:
+		p.Source, p.Address, data = rx.SourceAddress, DefaultExplicitAddress, rx.Data
+	case *ExplicitReceivePacket:
+		p.Source, data = rx.SourceAddress, rx.Data
+		p.Address = ExplicitAddress{
+			SourceEndpoint:      rx.SourceEndpoint,
+			DestinationEndpoint: rx.DestinationEndpoint,
+			ClusterID:           rx.ClusterID,
+			ProfileID:           rx.ProfileID,
+		}
+	default:
+		return nil, false, nil
+	}
+	key := PayloadType{Address: p.Address}.key()
+	r.mu.RLock()
+	e := r.byKey[key]
+	if e == nil && len(data) > 0 {
+		key.typeByte, key.hasType = data[0], true
+		if e = r.byKey[key]; e != nil {
+			data = data[1:]
+		}
+	}
+	r.mu.RUnlock()
+	if e == nil {
+		return nil, false, nil
+	}
+	v := reflect.New(e.typ)
+	if err := e.codec.Unmarshal(data, v.Interface()); err != nil {
+		return nil, true, fmt.Errorf("xbee: decoding %s: %w", e.typ, err)
+	}
+	p.Value = v.Interface()
+	return &p, true, nil
+}
+
+// Encode encodes a value of a registered type (T or *T) returning the
+// payload and the addressing to send it with.
+func (r *CodecRegistry) Encode(v any) (ExplicitAddress, []byte, error) {
+	t := reflect.TypeOf(v)
+	r.mu.RLock()
+	e := r.byType[t]
+	if e == nil && t != nil && t.Kind() == reflect.Pointer {
+		e = r.byType[t.Elem()]
+	}
+	r.mu.RUnlock()
+	if e == nil {
+		return ExplicitAddress{}, nil, fmt.Errorf("xbee: %w: no codec registered for %T", ErrInvalidParameter, v)
+	}
+	data, err := e.codec.Marshal(v)
+	if err != nil {
+		return ExplicitAddress{}, nil, fmt.Errorf("xbee: encoding %T: %w", v, err)
+	}
+	if e.pt.HasTypeByte {
+		data = append([]byte{e.pt.TypeByte}, data...)
+	}
+	return e.pt.Address, data, nil
+}
+
+// TransmitValue encodes v with the registry and sends it to dest with the
+// payload type's addressing.
+func (xb *XBee) TransmitValue(dest Addr64, r *CodecRegistry, v any) error {
+	addr, data, err := r.Encode(v)
+	if err != nil {
+		return err
+	}
+	return xb.TransmitExplicit(dest, Address16Unknown, addr, 0, 0, data)
+}
@@ -0,0 +1,90 @@
+package xbee
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"time"
+
+	"github.com/samuel/go-xbee/xbee/frames"
+)
+
+// DedupMode is what WithBroadcastDedup does with a repeated broadcast.
+type DedupMode int
+
+const (
+	// DedupDrop drops repeated broadcasts so each is delivered once.
+	DedupDrop DedupMode = iota
+	// DedupMark delivers repeated broadcasts with Duplicate set.
+	DedupMark
+)
+
+// WithBroadcastDedup detects broadcasts that the mesh delivers more than
+// once: a broadcast packet from the same node with the same payload (and
+// cluster for explicit packets) as one received less than window ago.
+// Duplicates are counted in Stats.Duplicates.
+func WithBroadcastDedup(window time.Duration, mode DedupMode) Option {
+	return func(o *options) {
+		o.dedupWindow = window
+		o.dedupMode = mode
+	}
+}
+
+// broadcastDedup remembers recent broadcasts. It's only used by the read
+// loop.
+type broadcastDedup struct {
+	window    time.Duration
+	mode      DedupMode
+	seen      map[uint64]time.Time // by hash of source and payload
+	lastSweep time.Time
+}
+
+func newBroadcastDedup(window time.Duration, mode DedupMode) *broadcastDedup {
+	return &broadcastDedup{window: window, mode: mode, seen: make(map[uint64]time.Time)}
+}
+
+// check returns false if f is a duplicate that should be dropped and
+// marks it if it's delivered.
+func (d *broadcastDedup) check(f frames.Frame, now time.Time) bool {
+	h := fnv.New64a()
+	var b [10]byte
+	var dup *bool
+	switch f := f.(type) {
+	case *frames.ReceivePacket:
+		if !f.ReceiveOptions.Has(frames.ROBroadcast) {
+			return true
+		}
+		binary.BigEndian.PutUint64(b[:], uint64(f.SourceAddress))
+		h.Write(b[:8])
+		h.Write(f.Data)
+		dup = &f.Duplicate
+	case *frames.ExplicitReceivePacket:
+		if !f.ReceiveOptions.Has(frames.ROBroadcast) {
+			return true
+		}
+		binary.BigEndian.PutUint64(b[:], uint64(f.SourceAddress))
+		binary.BigEndian.PutUint16(b[8:], f.ClusterID)
+		h.Write(b[:])
+		h.Write(f.Data)
+		dup = &f.Duplicate
+	default:
+		return true
+	}
+	if now.Sub(d.lastSweep) >= d.window {
+		for k, t := range d.seen {
+			if now.Sub(t) >= d.window {
+				delete(d.seen, k)
+			}
+		}
+		d.lastSweep = now
+	}
+	key := h.Sum64()
+	if t, ok := d.seen[key]; ok && now.Sub(t) < d.window {
+		if d.mode == DedupDrop {
+			return false
+		}
+		*dup = true
+		return true
+	}
+	d.seen[key] = now
+	return true
+}
@@ -0,0 +1,84 @@
+package xbee
+
+import (
+	"errors"
+	"sync"
+)
+
+// dumpWorkers is the number of registers read at once by DumpRegisters
+// when the number of frames in flight isn't limited.
+const dumpWorkers = 8
+
+// DiagnosticRegisters are the registers read by DumpRegisters: addressing,
+// network state, versions, RF, serial, and sleep settings.
+var DiagnosticRegisters = []ATCommand{
+	// Addressing
+	{'S', 'H'}, {'S', 'L'}, {'M', 'Y'}, {'M', 'P'}, {'D', 'H'}, {'D', 'L'},
+	{'N', 'I'}, {'N', 'C'}, {'N', 'P'}, {'D', 'D'},
+	// Network
+	{'C', 'H'}, {'I', 'D'}, {'O', 'P'}, {'O', 'I'}, {'S', 'C'}, {'S', 'D'},
+	{'Z', 'S'}, {'N', 'J'}, {'N', 'H'}, {'B', 'H'}, {'N', 'T'}, {'N', 'O'},
+	{'C', 'E'}, {'A', 'I'},
+	// Security
+	{'E', 'E'}, {'E', 'O'},
+	// Versions
+	{'V', 'R'}, {'H', 'V'},
+	// RF and diagnostics
+	{'P', 'L'}, {'P', 'M'}, {'P', 'P'}, {'D', 'B'}, {'%', 'V'}, {'T', 'P'},
+	// Serial interfacing
+	{'A', 'P'}, {'A', 'O'}, {'B', 'D'}, {'N', 'B'}, {'S', 'B'},
+	// Sleep
+	{'S', 'M'}, {'S', 'P'}, {'S', 'T'},
+}
+
+// DumpRegisters reads DiagnosticRegisters concurrently (within the limit
+// set by WithMaxInFlight) to capture the state of the module. Values are
+// decoded as a string for NI, a uint64 for values of up to 8 bytes, or
+// left as []byte. Registers the firmware doesn't support are left out.
+func (xb *XBee) DumpRegisters() (map[ATCommand]any, error) {
+	workers := xb.maxInFlight
+	if workers <= 0 {
+		workers = dumpWorkers
+	}
+	sem := make(chan struct{}, workers)
+	var mu sync.Mutex
+	var firstErr error
+	regs := make(map[ATCommand]any, len(DiagnosticRegisters))
+	var wg sync.WaitGroup
+	for _, cmd := range DiagnosticRegisters {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(cmd ATCommand) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			b, err := xb.atCommand(cmd, nil)
+			mu.Lock()
+			defer mu.Unlock()
+			var invalid ErrInvalidCommand
+			if errors.As(err, &invalid) {
+				return
+			} else if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			regs[cmd] = decodeRegister(cmd, b)
+		}(cmd)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return regs, nil
+}
+
+func decodeRegister(cmd ATCommand, b []byte) any {
+	switch {
+	case stringRegisters[cmd]:
+		return string(b)
+	case len(b) <= 8:
+		return decodeUint(b)
+	}
+	return b
+}
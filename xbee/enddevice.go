@@ -0,0 +1,233 @@
+package xbee
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	defaultPollRate = 100 * time.Millisecond
+	maxPollRate     = 10 * time.Second // PO 0x3E8
+	sleepPeriodUnit = 10 * time.Millisecond
+)
+
+// SleepMode is the sleep mode of a module (SM).
+type SleepMode byte
+
+const (
+	SleepDisabled      SleepMode = 0 // routers and coordinators
+	SleepPin           SleepMode = 1 // sleep while the Sleep_RQ pin is asserted
+	SleepCyclic        SleepMode = 4
+	SleepCyclicPinWake SleepMode = 5 // cyclic sleep that also wakes on Sleep_RQ
+	SleepAsync         SleepMode = 7 // DigiMesh asynchronous cyclic sleep
+	SleepSync          SleepMode = 8 // DigiMesh synchronous cyclic sleep
+)
+
+func (m SleepMode) String() string {
+	switch m {
+	case SleepDisabled:
+		return "Disabled"
+	case SleepPin:
+		return "Pin"
+	case SleepCyclic:
+		return "Cyclic"
+	case SleepCyclicPinWake:
+		return "CyclicPinWake"
+	case SleepAsync:
+		return "Async"
+	case SleepSync:
+		return "Sync"
+	}
+	return fmt.Sprintf("SleepMode(%d)", m)
+}
+
+func (m SleepMode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// SleepSettings are the sleep and polling parameters of an end device.
+// Registers the device's firmware doesn't have (e.g. PO on DigiMesh) are
+// left zero.
+type SleepSettings struct {
+	Mode            SleepMode
+	Period          time.Duration // SP, in units of 10 ms
+	Periods         int           // number of sleep periods (SN)
+	TimeBeforeSleep time.Duration // ST, in units of 1 ms
+	Options         uint16        // firmware specific sleep options (SO)
+	// PollRate is how often the end device polls its parent for data
+	// while awake (PO).
+	PollRate time.Duration
+	// EndDeviceTimeout is how long the parent keeps the end device as
+	// its child without hearing from it (ET, Zigbee 3.0 only). It's set
+	// rounded up to 10 seconds or 2^n minutes up to 16384 minutes. 0
+	// leaves it unchanged.
+	EndDeviceTimeout time.Duration
+}
+
+// PollRate returns how often the remote end device polls its parent for
+// data while awake (PO). A sleeping end device only answers once it wakes
+// and polls for the request.
+func (xb *XBee) PollRate(dest Addr64) (time.Duration, error) {
+	b, err := xb.RemoteATCommand(dest, Address16Unknown, atPollRate, nil, 0)
+	if err != nil {
+		return 0, err
+	}
+	return decodePollRate(b), nil
+}
+
+// SetPollRate sets how often the remote end device polls its parent for
+// data while awake (PO) and applies the change. The rate is rounded to 10
+// ms up to 10 s. 0 selects the default of 100 ms. Polling less often
+// saves power at the cost of latency.
+func (xb *XBee) SetPollRate(dest Addr64, rate time.Duration) error {
+	po, err := encodePollRate(rate)
+	if err != nil {
+		return fmt.Errorf("xbee.SetPollRate: %w", err)
+	}
+	_, err = xb.RemoteATCommand(dest, Address16Unknown, atPollRate, po, RATOApplyChanges)
+	return err
+}
+
+func decodePollRate(b []byte) time.Duration {
+	if po := decodeUint(b); po != 0 {
+		return time.Duration(po) * sleepPeriodUnit
+	}
+	return defaultPollRate
+}
+
+func encodePollRate(rate time.Duration) ([]byte, error) {
+	if rate < 0 || rate > maxPollRate {
+		return nil, fmt.Errorf("%w: poll rate %s out of range", ErrInvalidParameter, rate)
+	}
+	return encodeUint(uint64(rate/sleepPeriodUnit), 2), nil
+}
+
+// sleepParams returns the registers of the firmware of a remote end
+// device, which uses the module's protocol, once Capabilities has
+// identified the module and those of Zigbee otherwise.
+func (xb *XBee) sleepParams() (map[ATCommand]ATParam, Protocol) {
+	if c := xb.caps.Load(); c != nil {
+		if params := c.ATParams(); params != nil {
+			return params, c.Protocol
+		}
+	}
+	return zigbeeATParams, ProtocolZigbee
+}
+
+// SleepSettings reads the sleep and polling parameters of a remote end
+// device.
+func (xb *XBee) SleepSettings(dest Addr64) (*SleepSettings, error) {
+	params, _ := xb.sleepParams()
+	vals := make(map[ATCommand][]byte)
+	for _, cmd := range []ATCommand{atSleepMode, atSleepPeriod, atNumberOfSleepPeriods, atTimeBeforeSleep, atSleepOptions, atPollRate, atEndDeviceTimeout} {
+		if _, ok := params[cmd]; !ok {
+			continue
+		}
+		b, err := xb.RemoteATCommand(dest, Address16Unknown, cmd, nil, 0)
+		var ae *ATError
+		if cmd == atEndDeviceTimeout && errors.As(err, &ae) && ae.Status == CSInvalidCommand {
+			// Zigbee firmware before 3.0
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		vals[cmd] = b
+	}
+	s := &SleepSettings{
+		Mode:            SleepMode(decodeUint(vals[atSleepMode])),
+		Period:          time.Duration(decodeUint(vals[atSleepPeriod])) * sleepPeriodUnit,
+		Periods:         int(decodeUint(vals[atNumberOfSleepPeriods])),
+		TimeBeforeSleep: time.Duration(decodeUint(vals[atTimeBeforeSleep])) * time.Millisecond,
+		Options:         uint16(decodeUint(vals[atSleepOptions])),
+	}
+	if b, ok := vals[atPollRate]; ok {
+		s.PollRate = decodePollRate(b)
+	}
+	if b, ok := vals[atEndDeviceTimeout]; ok {
+		s.EndDeviceTimeout = endDeviceTimeout(decodeUint(b))
+	}
+	return s, nil
+}
+
+// SetSleepSettings writes the sleep and polling parameters of a remote end
+// device applying them together once all are set. Every value is checked
+// against the registers of the firmware of the module's protocol (see
+// Capabilities, Zigbee until the module is identified) before anything
+// is sent. A setting the firmware has no register for must be zero. The
+// device may go to sleep with the new settings before the response is
+// received.
+func (xb *XBee) SetSleepSettings(dest Addr64, s *SleepSettings) error {
+	if s.Period < 0 || s.TimeBeforeSleep < 0 || s.Periods < 0 || s.PollRate < 0 || s.EndDeviceTimeout < 0 {
+		return fmt.Errorf("xbee.SetSleepSettings: %w: negative sleep setting", ErrInvalidParameter)
+	}
+	type register struct {
+		cmd ATCommand
+		v   uint64
+	}
+	var regs []register
+	if s.EndDeviceTimeout != 0 {
+		regs = append(regs, register{atEndDeviceTimeout, encodeEndDeviceTimeout(s.EndDeviceTimeout)})
+	}
+	regs = append(regs,
+		register{atPollRate, uint64(s.PollRate / sleepPeriodUnit)},
+		register{atSleepPeriod, uint64(s.Period / sleepPeriodUnit)},
+		register{atNumberOfSleepPeriods, uint64(s.Periods)},
+		register{atTimeBeforeSleep, uint64(s.TimeBeforeSleep / time.Millisecond)},
+		register{atSleepOptions, uint64(s.Options)},
+		// The mode last so the device doesn't start sleeping with
+		// settings half applied.
+		register{atSleepMode, uint64(s.Mode)},
+	)
+	all, proto := xb.sleepParams()
+	var cmds []ATCommand
+	var params [][]byte
+	for _, r := range regs {
+		p, ok := all[r.cmd]
+		if !ok {
+			if r.v == 0 {
+				continue
+			}
+			return fmt.Errorf("xbee.SetSleepSettings: %w: not a register of %s firmware", ErrInvalidCommand(r.cmd.String()), proto)
+		}
+		if !p.Valid(r.v) {
+			return fmt.Errorf("xbee.SetSleepSettings: %w: %s must be %s for %s firmware", ErrInvalidParameter, r.cmd, p.rangeString(), proto)
+		}
+		cmds = append(cmds, r.cmd)
+		params = append(params, encodeUint(r.v, p.Size))
+	}
+	for i, cmd := range cmds {
+		var opts RemoteATCommandOption
+		if i == len(cmds)-1 {
+			opts = RATOApplyChanges
+		}
+		if _, err := xb.RemoteATCommand(dest, Address16Unknown, cmd, params[i], opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// End device timeouts (ET) are 10 seconds for 0 and 2^n minutes for n up
+// to 14.
+const (
+	minEndDeviceTimeout = 10 * time.Second
+	maxEndDeviceTimeout = 14
+)
+
+func endDeviceTimeout(n uint64) time.Duration {
+	if n == 0 {
+		return minEndDeviceTimeout
+	}
+	return time.Minute << min(n, maxEndDeviceTimeout)
+}
+
+// encodeEndDeviceTimeout returns the smallest ET at least d, which is past
+// the largest valid value if d is longer so it's rejected.
+func encodeEndDeviceTimeout(d time.Duration) uint64 {
+	var n uint64
+	for n <= maxEndDeviceTimeout && endDeviceTimeout(n) < d {
+		n++
+	}
+	return n
+}
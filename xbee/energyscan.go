@@ -0,0 +1,82 @@
+package xbee
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// firstChannel is the channel of bit 0 of SC and the first energy reading
+// of ED.
+const firstChannel = 0x0B
+
+// ChannelEnergy is the energy detected on a channel by EnergyScan.
+type ChannelEnergy struct {
+	Channel byte
+	Energy  int // maximum energy in dBm, lower values are quieter
+}
+
+// EnergyScan measures the energy on each channel (ED) scanning each for
+// perChannel (at most 255ms, rounded down to milliseconds). The module
+// isn't able to receive during the scan.
+func (xb *XBee) EnergyScan(perChannel time.Duration) ([]ChannelEnergy, error) {
+	ms := min(perChannel.Milliseconds(), 0xff)
+	if ms < 0 {
+		return nil, ErrInvalidParameter
+	}
+	b, err := xb.atCommand(atEnergyDetect, []byte{byte(ms)})
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, fmt.Errorf("xbee.EnergyScan: %w: empty response", ErrMalformedFrame)
+	}
+	energies := make([]ChannelEnergy, len(b))
+	for i, e := range b {
+		energies[i] = ChannelEnergy{Channel: byte(firstChannel + i), Energy: -int(e)}
+	}
+	return energies, nil
+}
+
+// QuietestChannels returns the SC scan channels bitfield selecting the n
+// channels with the least energy from an EnergyScan. Ties are broken by
+// the lower channel.
+func QuietestChannels(energies []ChannelEnergy, n int) uint16 {
+	sorted := append([]ChannelEnergy(nil), energies...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Energy != sorted[j].Energy {
+			return sorted[i].Energy < sorted[j].Energy
+		}
+		return sorted[i].Channel < sorted[j].Channel
+	})
+	var mask uint16
+	for _, e := range sorted[:min(max(n, 0), len(sorted))] {
+		if e.Channel >= firstChannel && e.Channel < firstChannel+16 {
+			mask |= 1 << (e.Channel - firstChannel)
+		}
+	}
+	return mask
+}
+
+// ScanChannels returns the bitfield of channels scanned to form or join a
+// network (SC). Bit 0 is channel 11 (0x0B).
+func (xb *XBee) ScanChannels() (uint16, error) {
+	b, err := xb.atCommand(atScanChannels, nil)
+	if err != nil {
+		return 0, err
+	}
+	return uint16(decodeUint(b)), nil
+}
+
+// SetScanChannels sets the bitfield of channels scanned to form or join a
+// network (SC), e.g. to the result of QuietestChannels.
+func (xb *XBee) SetScanChannels(mask uint16) error {
+	if mask == 0 {
+		return ErrInvalidParameter
+	}
+	if _, err := xb.checkATParam(atScanChannels, uint64(mask)); err != nil {
+		return err
+	}
+	_, err := xb.atCommand(atScanChannels, []byte{byte(mask >> 8), byte(mask)})
+	return err
+}
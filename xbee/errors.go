@@ -0,0 +1,54 @@
+package xbee
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrMalformedFrame is wrapped by errors for responses that are too
+	// short or otherwise not in the expected format.
+	ErrMalformedFrame = errors.New("xbee: malformed frame")
+	// ErrUnexpectedFrame is wrapped by errors for responses of the wrong
+	// frame type or for a different command than the request.
+	ErrUnexpectedFrame = errors.New("xbee: unexpected frame")
+)
+
+// ATError is returned when the response to a local or remote AT command
+// reports a status other than OK. It unwraps to ErrResponse,
+// ErrInvalidCommand, ErrInvalidParameter, or ErrTXFailure so those can be
+// checked with errors.Is and errors.As.
+type ATError struct {
+	Command ATCommand
+	Status  CommandStatus
+}
+
+func (e *ATError) Error() string {
+	return fmt.Sprintf("xbee: AT command %s failed: %s", e.Command, e.Status)
+}
+
+func (e *ATError) Unwrap() error {
+	switch e.Status {
+	case CSError:
+		return ErrResponse
+	case CSInvalidCommand:
+		return ErrInvalidCommand(e.Command.String())
+	case CSInvalidParameter:
+		return ErrInvalidParameter
+	case CSTxFailure:
+		return ErrTXFailure
+	}
+	return nil
+}
+
+// wrongFrame returns the error for a response that isn't the expected
+// frame type.
+func wrongFrame(want string, ev Event) error {
+	return fmt.Errorf("%w: expected %s got %T", ErrUnexpectedFrame, want, ev)
+}
+
+// wrongCommand returns the error for an AT command response to a
+// different command.
+func wrongCommand(want, got ATCommand) error {
+	return fmt.Errorf("%w: expected AT command response cmd %s got %s", ErrUnexpectedFrame, want, got)
+}
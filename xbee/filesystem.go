@@ -0,0 +1,344 @@
+package xbee
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/fs"
+
+	"github.com/samuel/go-xbee/xbee/frames"
+)
+
+// FileOpenFlag controls how a file is opened by FileSystem.Open.
+type FileOpenFlag byte
+
+const (
+	FOCreate    FileOpenFlag = 0x01 // create the file if it doesn't exist
+	FOExclusive FileOpenFlag = 0x02 // used with FOCreate, file must not exist
+	FORead      FileOpenFlag = 0x04
+	FOWrite     FileOpenFlag = 0x08
+	FOTruncate  FileOpenFlag = 0x10
+	FOAppend    FileOpenFlag = 0x20
+	FOSecure    FileOpenFlag = 0x80 // contents can't be read back, only hashed
+)
+
+const (
+	// Maximum amount of data to transfer in a single read or write request.
+	fsLocalChunkSize  = 256
+	fsRemoteChunkSize = 64
+
+	// Offset used to read or write at the current position in a file.
+	fsCurrentOffset = 0xffffffff
+
+	// Directory entry metadata flags
+	fsEntryDir    = 0x80000000
+	fsEntrySecure = 0x40000000
+	fsEntrySize   = 0x00ffffff
+)
+
+// FileSystemError is returned when a file system command fails.
+type FileSystemError struct {
+	Command FileSystemCommand
+	Path    string
+	Status  FileSystemStatus
+}
+
+func (e *FileSystemError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("xbee: file system %s: %s", e.Command, e.Status)
+	}
+	return fmt.Sprintf("xbee: file system %s %s: %s", e.Command, e.Path, e.Status)
+}
+
+// Is allows matching the error against fs.ErrNotExist, fs.ErrExist, and
+// fs.ErrPermission.
+func (e *FileSystemError) Is(target error) bool {
+	switch target {
+	case fs.ErrNotExist:
+		return e.Status == frames.FSDoesNotExist
+	case fs.ErrExist:
+		return e.Status == frames.FSAlreadyExists
+	case fs.ErrPermission:
+		return e.Status == frames.FSAccessDenied
+	}
+	return false
+}
+
+// FileInfo describes an entry in a directory.
+type FileInfo struct {
+	Name   string
+	Size   int64
+	IsDir  bool
+	Secure bool
+}
+
+// FileSystem accesses the file system of a local or remote XBee3 module.
+type FileSystem struct {
+	xb     *XBee
+	remote bool
+	addr   Addr64
+}
+
+// FileSystem returns a client for the file system of the local module.
+func (xb *XBee) FileSystem() *FileSystem {
+	return &FileSystem{xb: xb}
+}
+
+// RemoteFileSystem returns a client for the file system of a remote module.
+func (xb *XBee) RemoteFileSystem(addr Addr64) *FileSystem {
+	return &FileSystem{xb: xb, remote: true, addr: addr}
+}
+
+func (fsys *FileSystem) chunkSize() int {
+	if fsys.remote {
+		return fsRemoteChunkSize
+	}
+	return fsLocalChunkSize
+}
+
+func (fsys *FileSystem) command(cmd FileSystemCommand, path string, data []byte) ([]byte, error) {
+	ev, err := fsys.xb.request(remoteATTimeout, func(frameID byte) frames.Frame {
+		if fsys.remote {
+			return &frames.RemoteFileSystemRequest{
+				FrameID:            frameID,
+				DestinationAddress: fsys.addr,
+				Command:            cmd,
+				Data:               data,
+			}
+		}
+		return &frames.FileSystemRequest{FrameID: frameID, Command: cmd, Data: data}
+	})
+	if err != nil {
+		return nil, err
+	}
+	var status FileSystemStatus
+	var res []byte
+	switch r := ev.(type) {
+	case *frames.FileSystemResponse:
+		status, res = r.Status, r.Data
+	case *frames.RemoteFileSystemResponse:
+		status, res = r.Status, r.Data
+	default:
+		return nil, wrongFrame("file system response", ev)
+	}
+	if status != frames.FSSuccess {
+		return nil, &FileSystemError{Command: cmd, Path: path, Status: status}
+	}
+	return res, nil
+}
+
+// pathCommand issues a command whose parameter is a path relative to the
+// root directory (path ID 0).
+func (fsys *FileSystem) pathCommand(cmd FileSystemCommand, path string) ([]byte, error) {
+	return fsys.command(cmd, path, append([]byte{0, 0}, path...))
+}
+
+// Open opens a file. At least one of FORead or FOWrite must be set.
+func (fsys *FileSystem) Open(path string, flag FileOpenFlag) (*File, error) {
+	data := append([]byte{0, 0, byte(flag)}, path...)
+	res, err := fsys.command(frames.FSFileOpen, path, data)
+	if err != nil {
+		return nil, err
+	}
+	if len(res) < 6 {
+		return nil, fmt.Errorf("xbee.FileSystem.Open: %w: expected at least 6 byte response got %d", ErrMalformedFrame, len(res))
+	}
+	return &File{
+		fsys:   fsys,
+		path:   path,
+		handle: binary.BigEndian.Uint16(res),
+		size:   int64(binary.BigEndian.Uint32(res[2:])),
+	}, nil
+}
+
+// ReadFile returns the contents of a file.
+func (fsys *FileSystem) ReadFile(path string) ([]byte, error) {
+	f, err := fsys.Open(path, FORead)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// WriteFile creates or truncates a file and writes data to it.
+func (fsys *FileSystem) WriteFile(path string, data []byte) error {
+	f, err := fsys.Open(path, FOCreate|FOWrite|FOTruncate)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadDir returns the entries in a directory.
+func (fsys *FileSystem) ReadDir(path string) ([]FileInfo, error) {
+	res, err := fsys.pathCommand(frames.FSDirOpen, path)
+	if err != nil {
+		return nil, err
+	}
+	if len(res) < 2 {
+		return nil, fmt.Errorf("xbee.FileSystem.ReadDir: %w: expected at least 2 byte response got %d", ErrMalformedFrame, len(res))
+	}
+	handle := res[:2]
+	entries := decodeDirEntries(res[2:])
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	// The directory is closed by the module once all entries are read.
+	for {
+		res, err := fsys.command(frames.FSDirRead, path, handle)
+		if err != nil {
+			fsys.command(frames.FSDirClose, path, handle)
+			return entries, err
+		}
+		if len(res) <= 2 {
+			return entries, nil
+		}
+		entries = append(entries, decodeDirEntries(res[2:])...)
+	}
+}
+
+func decodeDirEntries(b []byte) []FileInfo {
+	var entries []FileInfo
+	for len(b) >= 4 {
+		meta := binary.BigEndian.Uint32(b)
+		b = b[4:]
+		name := b
+		if ix := bytes.IndexByte(b, 0); ix >= 0 {
+			name, b = b[:ix], b[ix+1:]
+		} else {
+			b = nil
+		}
+		entries = append(entries, FileInfo{
+			Name:   string(name),
+			Size:   int64(meta & fsEntrySize),
+			IsDir:  meta&fsEntryDir != 0,
+			Secure: meta&fsEntrySecure != 0,
+		})
+	}
+	return entries
+}
+
+func (fsys *FileSystem) Mkdir(path string) error {
+	_, err := fsys.pathCommand(frames.FSDirCreate, path)
+	return err
+}
+
+// Remove deletes a file or empty directory.
+func (fsys *FileSystem) Remove(path string) error {
+	_, err := fsys.pathCommand(frames.FSDelete, path)
+	return err
+}
+
+func (fsys *FileSystem) Rename(oldPath, newPath string) error {
+	data := append([]byte{0, 0}, oldPath...)
+	data = append(data, ',')
+	_, err := fsys.command(frames.FSRename, oldPath, append(data, newPath...))
+	return err
+}
+
+// Hash returns the SHA-256 hash of a file's contents. This is the only
+// way to verify the contents of secure files.
+func (fsys *FileSystem) Hash(path string) ([32]byte, error) {
+	var hash [32]byte
+	res, err := fsys.pathCommand(frames.FSFileHash, path)
+	if err != nil {
+		return hash, err
+	}
+	if len(res) != len(hash) {
+		return hash, fmt.Errorf("xbee.FileSystem.Hash: %w: expected 32 byte response got %d", ErrMalformedFrame, len(res))
+	}
+	copy(hash[:], res)
+	return hash, nil
+}
+
+// VolumeInfo returns usage of a volume (e.g. "/flash") in bytes.
+func (fsys *FileSystem) VolumeInfo(volume string) (used, free, bad int64, err error) {
+	res, err := fsys.pathCommand(frames.FSVolumeStat, volume)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if len(res) < 12 {
+		return 0, 0, 0, fmt.Errorf("xbee.FileSystem.VolumeInfo: %w: expected 12 byte response got %d", ErrMalformedFrame, len(res))
+	}
+	used = int64(binary.BigEndian.Uint32(res))
+	free = int64(binary.BigEndian.Uint32(res[4:]))
+	bad = int64(binary.BigEndian.Uint32(res[8:]))
+	return used, free, bad, nil
+}
+
+// Format erases all files on a volume.
+func (fsys *FileSystem) Format(volume string) error {
+	_, err := fsys.pathCommand(frames.FSVolumeFormat, volume)
+	return err
+}
+
+// File is an open file on a module's file system. Reads and writes are
+// sequential starting from the beginning of the file (or end if opened
+// with FOAppend).
+type File struct {
+	fsys   *FileSystem
+	path   string
+	handle uint16
+	size   int64
+}
+
+// Size returns the size of the file when it was opened.
+func (f *File) Size() int64 {
+	return f.size
+}
+
+func (f *File) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	n := len(p)
+	if cs := f.fsys.chunkSize(); n > cs {
+		n = cs
+	}
+	req := binary.BigEndian.AppendUint16(nil, f.handle)
+	req = binary.BigEndian.AppendUint32(req, fsCurrentOffset)
+	req = binary.BigEndian.AppendUint16(req, uint16(n))
+	res, err := f.fsys.command(frames.FSFileRead, f.path, req)
+	if e, ok := err.(*FileSystemError); ok && e.Status == frames.FSEOF {
+		return 0, io.EOF
+	} else if err != nil {
+		return 0, err
+	}
+	if len(res) < 6 {
+		return 0, fmt.Errorf("xbee.File.Read: %w: expected at least 6 byte response got %d", ErrMalformedFrame, len(res))
+	}
+	if len(res) == 6 {
+		return 0, io.EOF
+	}
+	return copy(p, res[6:]), nil
+}
+
+func (f *File) Write(p []byte) (int, error) {
+	var written int
+	cs := f.fsys.chunkSize()
+	for len(p) > 0 {
+		n := len(p)
+		if n > cs {
+			n = cs
+		}
+		req := binary.BigEndian.AppendUint16(nil, f.handle)
+		req = binary.BigEndian.AppendUint32(req, fsCurrentOffset)
+		if _, err := f.fsys.command(frames.FSFileWrite, f.path, append(req, p[:n]...)); err != nil {
+			return written, err
+		}
+		written += n
+		p = p[n:]
+	}
+	return written, nil
+}
+
+func (f *File) Close() error {
+	_, err := f.fsys.command(frames.FSFileClose, f.path, binary.BigEndian.AppendUint16(nil, f.handle))
+	return err
+}
@@ -0,0 +1,96 @@
+package xbee
+
+import "github.com/samuel/go-xbee/xbee/frames"
+
+// ReceiveFilter selects the unsolicited frames that are processed. A
+// rejected frame is dropped by the read loop before it updates the node
+// registry or reaches a matcher, middleware, or EventChan. Frames with a
+// frame ID, i.e. responses to requests, always pass.
+type ReceiveFilter struct {
+	// Allow if not empty passes only frames from these nodes. Frames
+	// without a source address such as modem status pass.
+	Allow []Addr64
+	Deny  []Addr64 // nodes whose frames are dropped
+	// AllowTypes if not empty passes only frames of these frame types
+	// (e.g. frames.TypeReceivePacket).
+	AllowTypes []byte
+	DenyTypes  []byte // frame types that are dropped
+}
+
+type receiveFilter struct {
+	allow      map[Addr64]bool
+	deny       map[Addr64]bool
+	allowTypes bool // whether types lists the allowed types
+	types      [256]bool
+}
+
+// SetReceiveFilter replaces the receive filter. A nil filter passes every
+// frame.
+func (xb *XBee) SetReceiveFilter(f *ReceiveFilter) {
+	if f == nil {
+		xb.filter.Store(nil)
+		return
+	}
+	rf := &receiveFilter{allowTypes: len(f.AllowTypes) > 0}
+	if len(f.Allow) > 0 {
+		rf.allow = make(map[Addr64]bool, len(f.Allow))
+		for _, a := range f.Allow {
+			rf.allow[a] = true
+		}
+	}
+	if len(f.Deny) > 0 {
+		rf.deny = make(map[Addr64]bool, len(f.Deny))
+		for _, a := range f.Deny {
+			rf.deny[a] = true
+		}
+	}
+	if rf.allowTypes {
+		for _, t := range f.AllowTypes {
+			rf.types[t] = true
+		}
+	}
+	for _, t := range f.DenyTypes {
+		// types holds the denied types when there's no allow list.
+		rf.types[t] = !rf.allowTypes
+	}
+	xb.filter.Store(rf)
+}
+
+// pass returns whether f is processed.
+func (rf *receiveFilter) pass(f frames.Frame) bool {
+	if idf, ok := f.(frames.Identified); ok && idf.ID() != 0 {
+		return true
+	}
+	t := f.FrameType()
+	if rf.allowTypes != rf.types[t] {
+		return false
+	}
+	if rf.allow == nil && rf.deny == nil {
+		return true
+	}
+	addr, ok := frameSource(f)
+	if !ok {
+		return true
+	}
+	if rf.deny[addr] {
+		return false
+	}
+	return rf.allow == nil || rf.allow[addr]
+}
+
+// frameSource returns the address of the node that sent f.
+func frameSource(f frames.Frame) (Addr64, bool) {
+	switch f := f.(type) {
+	case *frames.ReceivePacket:
+		return f.SourceAddress, true
+	case *frames.ExplicitReceivePacket:
+		return f.SourceAddress, true
+	case *frames.IODataSampleIndicator:
+		return f.SourceAddress, true
+	case *frames.NodeIdentificationIndicator:
+		return f.SourceAddress, true
+	case *frames.RemoteATCommandResponse:
+		return f.SourceAddress, true
+	}
+	return 0, false
+}
@@ -0,0 +1,89 @@
+package xbee
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Remote firmware updates are supported using two methods depending on
+// the module:
+//
+// - GPM (general purpose memory) where the host writes the .ebl image to
+//   the module's flash and tells it to install it (XBee ZB S2C and
+//   earlier). See UpdateFirmwareGPM.
+// - The Zigbee OTA upgrade cluster where the module requests blocks of an
+//   .ota image from the host acting as the OTA server (XBee3). See
+//   ServeOTAImage.
+//
+// Both require explicit receive to be enabled (AO=1) on the local module
+// as responses are received as ExplicitReceivePacket events.
+
+type FirmwareUpdateState int
+
+const (
+	FirmwareErasing FirmwareUpdateState = iota
+	FirmwareTransferring
+	FirmwareVerifying
+	FirmwareInstalling
+	FirmwareDone
+)
+
+func (s FirmwareUpdateState) String() string {
+	switch s {
+	case FirmwareErasing:
+		return "Erasing"
+	case FirmwareTransferring:
+		return "Transferring"
+	case FirmwareVerifying:
+		return "Verifying"
+	case FirmwareInstalling:
+		return "Installing"
+	case FirmwareDone:
+		return "Done"
+	}
+	return fmt.Sprintf("FirmwareUpdateState(%d)", int(s))
+}
+
+func (s FirmwareUpdateState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// FirmwareProgress reports the progress of a firmware update. Offset is
+// the number of bytes of the image transferred so far and can be used to
+// resume an interrupted GPM update.
+type FirmwareProgress struct {
+	State  FirmwareUpdateState
+	Offset int
+	Total  int
+}
+
+const (
+	// How many times to retry a firmware command that timed out.
+	firmwareRetries = 3
+	// How long to wait for a response to a firmware command.
+	firmwareTimeout = 5 * time.Second
+)
+
+// waitExplicit waits for the next event received by m.
+func waitExplicit(ctx context.Context, m *matcher, timeout time.Duration) (*ExplicitReceivePacket, error) {
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case ev := <-m.ch:
+		return ev.(*ExplicitReceivePacket), nil
+	case <-t.C:
+		return nil, ErrTimeout
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// explicitMatcher registers a matcher for explicit packets from addr for
+// a cluster and profile.
+func (xb *XBee) explicitMatcher(addr Addr64, clusterID, profileID uint16) *matcher {
+	return xb.registerMatcher(matchSession, func(ev Event) bool {
+		rx, ok := ev.(*ExplicitReceivePacket)
+		return ok && rx.SourceAddress == addr && rx.ClusterID == clusterID && rx.ProfileID == profileID
+	})
+}
@@ -0,0 +1,119 @@
+package frames
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Addr64 is the unique 64-bit (IEEE extended) address of a module.
+// It's formatted as 16 hex digits (e.g. "0013A20040A1B2C3").
+type Addr64 uint64
+
+// Addr16 is the 16-bit network address a module is given when it
+// joins a network. It's formatted as 4 hex digits (e.g. "FFFE").
+type Addr16 uint16
+
+const (
+	AddressCoordinator Addr64 = 0x0000000000000000
+	AddressBroadcast   Addr64 = 0x000000000000FFFF
+	// AddressUnknown is used when the destination is only known by its
+	// 16-bit address (e.g. multicast groups).
+	AddressUnknown Addr64 = 0xFFFFFFFFFFFFFFFF
+
+	// Address16Unknown is used when the 16-bit address of the destination
+	// is not known or when broadcasting.
+	Address16Unknown Addr16 = 0xFFFE
+	// Address16Broadcast is the ZigBee broadcast address for all devices.
+	Address16Broadcast Addr16 = 0xFFFF
+	// Address16BroadcastRxOnIdle is the ZigBee broadcast address for all
+	// non-sleeping devices.
+	Address16BroadcastRxOnIdle Addr16 = 0xFFFD
+	// Address16BroadcastRouters is the ZigBee broadcast address for all
+	// routers and the coordinator.
+	Address16BroadcastRouters Addr16 = 0xFFFC
+)
+
+// ParseAddr64 parses a 64-bit address given as up to 16 hex digits. An
+// optional "0x" prefix is allowed as are ':', '-', and ' ' separators
+// (e.g. "0013A20040A1B2C3", "00:13:a2:00:40:a1:b2:c3").
+func ParseAddr64(s string) (Addr64, error) {
+	v, err := parseHexAddr(s, 16)
+	if err != nil {
+		return 0, fmt.Errorf("frames: invalid 64-bit address %q", s)
+	}
+	return Addr64(v), nil
+}
+
+// ParseAddr16 parses a 16-bit network address given as up to 4 hex digits
+// using the same notation accepted by ParseAddr64.
+func ParseAddr16(s string) (Addr16, error) {
+	v, err := parseHexAddr(s, 4)
+	if err != nil {
+		return 0, fmt.Errorf("frames: invalid 16-bit address %q", s)
+	}
+	return Addr16(v), nil
+}
+
+func (a Addr64) String() string {
+	return fmt.Sprintf("%016X", uint64(a))
+}
+
+func (a Addr64) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+func (a *Addr64) UnmarshalText(text []byte) error {
+	v, err := ParseAddr64(string(text))
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+// IsBroadcast returns true if the address is the broadcast address.
+func (a Addr64) IsBroadcast() bool {
+	return a == AddressBroadcast
+}
+
+func (a Addr16) String() string {
+	return fmt.Sprintf("%04X", uint16(a))
+}
+
+func (a Addr16) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+func (a *Addr16) UnmarshalText(text []byte) error {
+	v, err := ParseAddr16(string(text))
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+// IsBroadcast returns true if the address is one of the ZigBee broadcast
+// addresses (0xFFFC, 0xFFFD, or 0xFFFF).
+func (a Addr16) IsBroadcast() bool {
+	return a == Address16Broadcast || a == Address16BroadcastRxOnIdle || a == Address16BroadcastRouters
+}
+
+func parseHexAddr(s string, maxDigits int) (uint64, error) {
+	s = strings.TrimSpace(s)
+	if len(s) > 2 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X') {
+		s = s[2:]
+	}
+	s = strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '-', ' ':
+			return -1
+		}
+		return r
+	}, s)
+	if len(s) == 0 || len(s) > maxDigits {
+		return 0, strconv.ErrSyntax
+	}
+	return strconv.ParseUint(s, 16, 64)
+}
@@ -0,0 +1,123 @@
+package frames
+
+import "fmt"
+
+// ATCommand is the two character name of an AT command (e.g. "NI").
+type ATCommand [2]byte
+
+func (c ATCommand) String() string {
+	return string(c[:])
+}
+
+func (c ATCommand) MarshalText() ([]byte, error) {
+	return c[:], nil
+}
+
+func (c *ATCommand) UnmarshalText(b []byte) error {
+	if len(b) != 2 {
+		return fmt.Errorf("frames: AT command should be 2 characters, got %q", b)
+	}
+	copy(c[:], b)
+	return nil
+}
+
+type CommandStatus byte
+
+const (
+	CSOK               CommandStatus = 0
+	CSError            CommandStatus = 1
+	CSInvalidCommand   CommandStatus = 2
+	CSInvalidParameter CommandStatus = 3
+	CSTxFailure        CommandStatus = 4
+)
+
+func (cs CommandStatus) String() string {
+	switch cs {
+	case CSOK:
+		return "OK"
+	case CSError:
+		return "Error"
+	case CSInvalidCommand:
+		return "InvalidCommand"
+	case CSInvalidParameter:
+		return "InvalidParameter"
+	case CSTxFailure:
+		return "TxFailure"
+	}
+	return fmt.Sprintf("CommandStatus(%d)", cs)
+}
+
+func (cs CommandStatus) MarshalText() ([]byte, error) {
+	return []byte(cs.String()), nil
+}
+
+// ATCommandRequest queries or sets a register on the local module. If
+// Queue is true the new value is not applied until changes are applied
+// (AC command or a non-queued AT command).
+type ATCommandRequest struct {
+	FrameID   byte
+	ATCommand ATCommand
+	Parameter []byte
+	Queue     bool
+}
+
+func (f *ATCommandRequest) FrameType() byte {
+	if f.Queue {
+		return TypeATCommandQueue
+	}
+	return TypeATCommand
+}
+
+func (f *ATCommandRequest) ID() byte {
+	return f.FrameID
+}
+
+func (f *ATCommandRequest) AppendData(b []byte) ([]byte, error) {
+	b = append(b, f.FrameID, f.ATCommand[0], f.ATCommand[1])
+	return append(b, f.Parameter...), nil
+}
+
+func decodeATCommandRequest(b []byte) (Frame, error) {
+	if err := checkLen(b, 4); err != nil {
+		return nil, err
+	}
+	return &ATCommandRequest{
+		FrameID:   b[1],
+		ATCommand: ATCommand{b[2], b[3]},
+		Parameter: b[4:],
+		Queue:     b[0] == TypeATCommandQueue,
+	}, nil
+}
+
+// ATCommandResponse is the response to an ATCommandRequest.
+type ATCommandResponse struct {
+	FrameID       byte
+	ATCommand     ATCommand
+	CommandStatus CommandStatus
+	Data          []byte
+}
+
+func (f *ATCommandResponse) FrameType() byte {
+	return TypeATCommandResponse
+}
+
+func (f *ATCommandResponse) ID() byte {
+	return f.FrameID
+}
+
+func (f *ATCommandResponse) AppendData(b []byte) ([]byte, error) {
+	b = append(b, f.FrameID, f.ATCommand[0], f.ATCommand[1], byte(f.CommandStatus))
+	return append(b, f.Data...), nil
+}
+
+func decodeATCommandResponse(b []byte) (Frame, error) {
+	if err := checkLen(b, 5); err != nil {
+		return nil, err
+	}
+	return &ATCommandResponse{
+		FrameID:       b[1],
+		ATCommand:     ATCommand{b[2], b[3]},
+		CommandStatus: CommandStatus(b[4]),
+		Data:          b[5:],
+	}, nil
+}
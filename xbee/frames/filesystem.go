@@ -0,0 +1,271 @@
+package frames
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// File System frames give access to the file system on XBee3 modules. The
+// command specific fields of requests and responses are left undecoded in
+// Data.
+
+type FileSystemCommand byte
+
+const (
+	FSFileOpen     FileSystemCommand = 0x01
+	FSFileClose    FileSystemCommand = 0x02
+	FSFileRead     FileSystemCommand = 0x03
+	FSFileWrite    FileSystemCommand = 0x04
+	FSFileHash     FileSystemCommand = 0x08
+	FSDirCreate    FileSystemCommand = 0x10
+	FSDirOpen      FileSystemCommand = 0x11
+	FSDirClose     FileSystemCommand = 0x12
+	FSDirRead      FileSystemCommand = 0x13
+	FSGetPathID    FileSystemCommand = 0x1c
+	FSRename       FileSystemCommand = 0x21
+	FSDelete       FileSystemCommand = 0x2f
+	FSVolumeStat   FileSystemCommand = 0x40
+	FSVolumeFormat FileSystemCommand = 0x4f
+)
+
+func (c FileSystemCommand) String() string {
+	switch c {
+	case FSFileOpen:
+		return "FileOpen"
+	case FSFileClose:
+		return "FileClose"
+	case FSFileRead:
+		return "FileRead"
+	case FSFileWrite:
+		return "FileWrite"
+	case FSFileHash:
+		return "FileHash"
+	case FSDirCreate:
+		return "DirCreate"
+	case FSDirOpen:
+		return "DirOpen"
+	case FSDirClose:
+		return "DirClose"
+	case FSDirRead:
+		return "DirRead"
+	case FSGetPathID:
+		return "GetPathID"
+	case FSRename:
+		return "Rename"
+	case FSDelete:
+		return "Delete"
+	case FSVolumeStat:
+		return "VolumeStat"
+	case FSVolumeFormat:
+		return "VolumeFormat"
+	}
+	return fmt.Sprintf("FileSystemCommand(%d)", c)
+}
+
+func (c FileSystemCommand) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+type FileSystemStatus byte
+
+const (
+	FSSuccess          FileSystemStatus = 0x00
+	FSError            FileSystemStatus = 0x01
+	FSInvalidCommand   FileSystemStatus = 0x02
+	FSInvalidParameter FileSystemStatus = 0x03
+	FSAccessDenied     FileSystemStatus = 0x50
+	FSAlreadyExists    FileSystemStatus = 0x51
+	FSDoesNotExist     FileSystemStatus = 0x52
+	FSInvalidName      FileSystemStatus = 0x53
+	FSIsDirectory      FileSystemStatus = 0x54
+	FSDirNotEmpty      FileSystemStatus = 0x55
+	FSEOF              FileSystemStatus = 0x56
+	FSHardwareFailure  FileSystemStatus = 0x57
+	FSNoDevice         FileSystemStatus = 0x58
+	FSVolumeFull       FileSystemStatus = 0x59
+	FSTimedOut         FileSystemStatus = 0x5a
+	FSBusy             FileSystemStatus = 0x5b
+	FSResourceFailure  FileSystemStatus = 0x5c
+)
+
+func (s FileSystemStatus) String() string {
+	switch s {
+	case FSSuccess:
+		return "Success"
+	case FSError:
+		return "Error"
+	case FSInvalidCommand:
+		return "InvalidCommand"
+	case FSInvalidParameter:
+		return "InvalidParameter"
+	case FSAccessDenied:
+		return "AccessDenied"
+	case FSAlreadyExists:
+		return "AlreadyExists"
+	case FSDoesNotExist:
+		return "DoesNotExist"
+	case FSInvalidName:
+		return "InvalidName"
+	case FSIsDirectory:
+		return "IsDirectory"
+	case FSDirNotEmpty:
+		return "DirNotEmpty"
+	case FSEOF:
+		return "EOF"
+	case FSHardwareFailure:
+		return "HardwareFailure"
+	case FSNoDevice:
+		return "NoDevice"
+	case FSVolumeFull:
+		return "VolumeFull"
+	case FSTimedOut:
+		return "TimedOut"
+	case FSBusy:
+		return "Busy"
+	case FSResourceFailure:
+		return "ResourceFailure"
+	}
+	return fmt.Sprintf("FileSystemStatus(%d)", s)
+}
+
+func (s FileSystemStatus) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// FileSystemRequest issues a file system command on the local module.
+type FileSystemRequest struct {
+	FrameID byte
+	Command FileSystemCommand
+	Data    []byte
+}
+
+func (f *FileSystemRequest) FrameType() byte {
+	return TypeFileSystemRequest
+}
+
+func (f *FileSystemRequest) ID() byte {
+	return f.FrameID
+}
+
+func (f *FileSystemRequest) AppendData(b []byte) ([]byte, error) {
+	b = append(b, f.FrameID, byte(f.Command))
+	return append(b, f.Data...), nil
+}
+
+func decodeFileSystemRequest(b []byte) (Frame, error) {
+	if err := checkLen(b, 3); err != nil {
+		return nil, err
+	}
+	return &FileSystemRequest{
+		FrameID: b[1],
+		Command: FileSystemCommand(b[2]),
+		Data:    b[3:],
+	}, nil
+}
+
+// FileSystemResponse is the response to a FileSystemRequest.
+type FileSystemResponse struct {
+	FrameID byte
+	Command FileSystemCommand
+	Status  FileSystemStatus
+	Data    []byte
+}
+
+func (f *FileSystemResponse) FrameType() byte {
+	return TypeFileSystemResponse
+}
+
+func (f *FileSystemResponse) ID() byte {
+	return f.FrameID
+}
+
+func (f *FileSystemResponse) AppendData(b []byte) ([]byte, error) {
+	b = append(b, f.FrameID, byte(f.Command), byte(f.Status))
+	return append(b, f.Data...), nil
+}
+
+func decodeFileSystemResponse(b []byte) (Frame, error) {
+	if err := checkLen(b, 4); err != nil {
+		return nil, err
+	}
+	return &FileSystemResponse{
+		FrameID: b[1],
+		Command: FileSystemCommand(b[2]),
+		Status:  FileSystemStatus(b[3]),
+		Data:    b[4:],
+	}, nil
+}
+
+// RemoteFileSystemRequest issues a file system command on a remote module.
+type RemoteFileSystemRequest struct {
+	FrameID            byte
+	DestinationAddress Addr64
+	Options            TransmitOption
+	Command            FileSystemCommand
+	Data               []byte
+}
+
+func (f *RemoteFileSystemRequest) FrameType() byte {
+	return TypeRemoteFileSystemRequest
+}
+
+func (f *RemoteFileSystemRequest) ID() byte {
+	return f.FrameID
+}
+
+func (f *RemoteFileSystemRequest) AppendData(b []byte) ([]byte, error) {
+	b = binary.BigEndian.AppendUint64(append(b, f.FrameID), uint64(f.DestinationAddress))
+	b = append(b, byte(f.Options), byte(f.Command))
+	return append(b, f.Data...), nil
+}
+
+func decodeRemoteFileSystemRequest(b []byte) (Frame, error) {
+	if err := checkLen(b, 12); err != nil {
+		return nil, err
+	}
+	return &RemoteFileSystemRequest{
+		FrameID:            b[1],
+		DestinationAddress: Addr64(binary.BigEndian.Uint64(b[2:])),
+		Options:            TransmitOption(b[10]),
+		Command:            FileSystemCommand(b[11]),
+		Data:               b[12:],
+	}, nil
+}
+
+// RemoteFileSystemResponse is the response to a RemoteFileSystemRequest.
+type RemoteFileSystemResponse struct {
+	FrameID        byte
+	SourceAddress  Addr64
+	ReceiveOptions ReceiveOption
+	Command        FileSystemCommand
+	Status         FileSystemStatus
+	Data           []byte
+}
+
+func (f *RemoteFileSystemResponse) FrameType() byte {
+	return TypeRemoteFileSystemResponse
+}
+
+func (f *RemoteFileSystemResponse) ID() byte {
+	return f.FrameID
+}
+
+func (f *RemoteFileSystemResponse) AppendData(b []byte) ([]byte, error) {
+	b = binary.BigEndian.AppendUint64(append(b, f.FrameID), uint64(f.SourceAddress))
+	b = append(b, byte(f.ReceiveOptions), byte(f.Command), byte(f.Status))
+	return append(b, f.Data...), nil
+}
+
+func decodeRemoteFileSystemResponse(b []byte) (Frame, error) {
+	if err := checkLen(b, 13); err != nil {
+		return nil, err
+	}
+	return &RemoteFileSystemResponse{
+		FrameID:        b[1],
+		SourceAddress:  Addr64(binary.BigEndian.Uint64(b[2:])),
+		ReceiveOptions: ReceiveOption(b[10]),
+		Command:        FileSystemCommand(b[11]),
+		Status:         FileSystemStatus(b[12]),
+		Data:           b[13:],
+	}, nil
+}
@@ -0,0 +1,228 @@
+package frames
+
+import (
+	"fmt"
+	"net/netip"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// formatBytesPerLine is the number of bytes shown on each line of
+// FormatFrame output.
+const formatBytesPerLine = 8
+
+// FormatFrame returns an annotated dump of the frame data of f for
+// debugging. Each line shows the offset in the frame data, the bytes, and
+// the field they encode with its decoded value. The offsets of a field are
+// found by changing it and encoding the frame again so frames registered
+// with RegisterFrameType are annotated as well. Bytes that can't be
+// attributed to a field (e.g. all of an UnknownFrame) are dumped unnamed.
+func FormatFrame(f Frame) string {
+	data, err := Encode(f)
+	if err != nil {
+		return fmt.Sprintf("%T: %v\n", f, err)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s (0x%02X), %d bytes\n", frameName(f), f.FrameType(), len(data))
+
+	spans := []fieldSpan{{name: "FrameType", value: fmt.Sprintf("0x%02X", data[0]), start: 0, end: 1}}
+	var unplaced []fieldSpan
+	for _, s := range frameFields(f, data) {
+		if s.end > s.start {
+			spans = append(spans, s)
+		} else {
+			unplaced = append(unplaced, s)
+		}
+	}
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	off := 0
+	for _, s := range spans {
+		if s.start < off {
+			// Overlaps a field already shown (e.g. a flag encoded in
+			// the frame type).
+			unplaced = append(unplaced, s)
+			continue
+		}
+		if s.start > off {
+			formatSpan(&sb, data, off, s.start, "", "")
+		}
+		formatSpan(&sb, data, s.start, s.end, s.name, s.value)
+		off = s.end
+	}
+	if off < len(data) {
+		formatSpan(&sb, data, off, len(data), "", "")
+	}
+	for _, s := range unplaced {
+		formatLine(&sb, "----", "", s.name+" "+s.value)
+	}
+	return sb.String()
+}
+
+type fieldSpan struct {
+	name  string
+	value string
+	start int
+	end   int
+}
+
+func frameName(f Frame) string {
+	t := reflect.TypeOf(f)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Name()
+}
+
+// formatSpan writes the bytes data[start:end] annotated with the field name
+// and value on the first line.
+func formatSpan(sb *strings.Builder, data []byte, start, end int, name, value string) {
+	for i := start; i < end; i += formatBytesPerLine {
+		j := min(i+formatBytesPerLine, end)
+		hex := fmt.Sprintf("% X", data[i:j])
+		note := printable(data[i:j])
+		if i == start && name != "" {
+			note = name + " " + value
+		}
+		formatLine(sb, fmt.Sprintf("%04X", i), hex, note)
+	}
+}
+
+func formatLine(sb *strings.Builder, off, hex, note string) {
+	line := fmt.Sprintf("  %-4s  %-*s  %s", off, formatBytesPerLine*3-1, hex, note)
+	sb.WriteString(strings.TrimRight(line, " "))
+	sb.WriteByte('\n')
+}
+
+// frameFields returns the exported fields of a struct frame with the range
+// of data each encodes. The range is empty if it can't be determined.
+func frameFields(f Frame, data []byte) []fieldSpan {
+	v := reflect.ValueOf(f)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		if v.Kind() == reflect.Slice {
+			// UnknownFrame: nothing to annotate.
+			return nil
+		}
+		// Single value frames such as ModemStatus.
+		return []fieldSpan{{name: "Value", value: formatValue(v), start: 1, end: len(data)}}
+	}
+	v = v.Elem()
+	t := v.Type()
+	var spans []fieldSpan
+	for i := 0; i < t.NumField(); i++ {
+		if !t.Field(i).IsExported() {
+			continue
+		}
+		s := fieldSpan{name: t.Field(i).Name, value: formatValue(v.Field(i))}
+		s.start, s.end = locateField(v, i, data)
+		spans = append(spans, s)
+	}
+	return spans
+}
+
+// locateField encodes a copy of the frame with field i changed and returns
+// the range of bytes that differ from data.
+func locateField(v reflect.Value, i int, data []byte) (int, int) {
+	cp := reflect.New(v.Type())
+	cp.Elem().Set(v)
+	if !perturb(cp.Elem().Field(i)) {
+		return 0, 0
+	}
+	b, err := Encode(cp.Interface().(Frame))
+	if err != nil || len(b) != len(data) {
+		return 0, 0
+	}
+	start, end := -1, 0
+	for j := range b {
+		if b[j] != data[j] {
+			if start < 0 {
+				start = j
+			}
+			end = j + 1
+		}
+	}
+	if start < 0 {
+		return 0, 0
+	}
+	return start, end
+}
+
+// perturb changes every byte of the value without changing its encoded
+// length returning false if the kind of value isn't supported.
+func perturb(v reflect.Value) bool {
+	if a, ok := v.Interface().(netip.Addr); ok {
+		if !a.Is4() {
+			return false
+		}
+		b := a.As4()
+		for i := range b {
+			b[i] ^= 0xff
+		}
+		v.Set(reflect.ValueOf(netip.AddrFrom4(b)))
+		return true
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(!v.Bool())
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(^v.Uint())
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(^v.Int())
+	case reflect.String:
+		b := []byte(v.String())
+		for i := range b {
+			b[i] ^= 0x01
+		}
+		v.SetString(string(b))
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			return false
+		}
+		b := make([]byte, v.Len())
+		for i := range b {
+			b[i] = byte(v.Index(i).Uint()) ^ 0xff
+		}
+		v.SetBytes(b)
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if !perturb(v.Index(i)) {
+				return false
+			}
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+func formatValue(v reflect.Value) string {
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String()
+	}
+	switch v.Kind() {
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return fmt.Sprintf("0x%0*X (%d)", v.Type().Size()*2, v.Uint(), v.Uint())
+	case reflect.String:
+		return fmt.Sprintf("%q", v.String())
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return fmt.Sprintf("(%d bytes) %s", v.Len(), printable(v.Bytes()))
+		}
+	}
+	return fmt.Sprintf("%v", v.Interface())
+}
+
+// printable returns b quoted if it's printable text or an empty string.
+func printable(b []byte) string {
+	if len(b) == 0 || !utf8.Valid(b) {
+		return ""
+	}
+	for _, r := range string(b) {
+		if r < 0x20 || r == 0x7f {
+			return ""
+		}
+	}
+	return fmt.Sprintf("%q", b)
+}
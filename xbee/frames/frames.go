@@ -0,0 +1,244 @@
+// Package frames implements encoding and decoding of XBee API frames
+// independent of any transport.
+//
+// An API frame on the wire is made up of a start delimiter (0x7E), a
+// big-endian 16-bit length, the frame data, and a checksum. The frame
+// data starts with the frame type followed by the frame specific fields.
+package frames
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Delimiter is the byte that starts every API frame.
+const Delimiter = 0x7e
+
+// MaxDataLength is the maximum length of frame data (frame type and
+// frame specific fields).
+const MaxDataLength = 65535
+
+// API frame types
+const (
+	TypeIPRemoteATCommand             byte = 0x07
+	TypeATCommand                     byte = 0x08
+	TypeATCommandQueue                byte = 0x09
+	TypeTransmitRequest               byte = 0x10
+	TypeExplicitTransmit              byte = 0x11
+	TypeRemoteATCommand               byte = 0x17
+	TypeSMSTransmitRequest            byte = 0x1f
+	TypeIPv4TransmitRequest           byte = 0x20
+	TypeRegisterJoiningDevice         byte = 0x24
+	TypeUserDataRelay                 byte = 0x2d
+	TypeSecureSessionControl          byte = 0x2e
+	TypeFileSystemRequest             byte = 0x3b
+	TypeRemoteFileSystemRequest       byte = 0x3c
+	TypeIPRemoteATCommandResponse     byte = 0x87
+	TypeATCommandResponse             byte = 0x88
+	TypeIPTransmitStatus              byte = 0x89
+	TypeModemStatus                   byte = 0x8a
+	TypeTransmitStatus                byte = 0x8b
+	TypeReceivePacket                 byte = 0x90
+	TypeExplicitReceivePacket         byte = 0x91
+	TypeIODataSampleIndicator         byte = 0x92
+	TypeNodeIdentificationIndicator   byte = 0x95
+	TypeRemoteATCommandResponse       byte = 0x97
+	TypeSMSReceivePacket              byte = 0x9f
+	TypeRegisterJoiningDeviceResponse byte = 0xa4
+	TypeUserDataRelayOutput           byte = 0xad
+	TypeSecureSessionResponse         byte = 0xae
+	TypeIPv4ReceivePacket             byte = 0xb0
+	TypeFileSystemResponse            byte = 0xbb
+	TypeRemoteFileSystemResponse      byte = 0xbc
+)
+
+var (
+	ErrChecksum  = errors.New("frames: bad checksum")
+	ErrTooLong   = errors.New("frames: frame data too long")
+	ErrEmpty     = errors.New("frames: empty frame")
+	ErrDelimiter = errors.New("frames: missing frame delimiter")
+	ErrTruncated = errors.New("frames: frame interrupted by delimiter")
+)
+
+// Frame is an XBee API frame.
+type Frame interface {
+	// FrameType returns the API frame type identifier.
+	FrameType() byte
+	// AppendData appends the frame specific fields (everything
+	// following the frame type) to b.
+	AppendData(b []byte) ([]byte, error)
+}
+
+// Identified is implemented by frames that carry a frame ID used to
+// correlate a request with its response. A frame ID of 0 means no
+// response is requested.
+type Identified interface {
+	Frame
+	ID() byte
+}
+
+// ShortFrameError is returned when decoding frame data that is too short
+// for its frame type.
+type ShortFrameError struct {
+	Type byte
+	Len  int
+	Min  int
+}
+
+func (e *ShortFrameError) Error() string {
+	return fmt.Sprintf("frames: frame type 0x%02x too short (%d bytes, need at least %d)", e.Type, e.Len, e.Min)
+}
+
+func checkLen(b []byte, min int) error {
+	if len(b) < min {
+		return &ShortFrameError{Type: b[0], Len: len(b), Min: min}
+	}
+	return nil
+}
+
+// UnknownFrame holds the frame data (including the frame type) of frames
+// that have no decoder.
+type UnknownFrame []byte
+
+func (f UnknownFrame) FrameType() byte {
+	if len(f) == 0 {
+		return 0
+	}
+	return f[0]
+}
+
+func (f UnknownFrame) AppendData(b []byte) ([]byte, error) {
+	if len(f) == 0 {
+		return nil, ErrEmpty
+	}
+	return append(b, f[1:]...), nil
+}
+
+// DecodeFunc decodes frame data (frame type followed by frame specific
+// fields). The data is owned by the returned frame.
+type DecodeFunc func(data []byte) (Frame, error)
+
+var (
+	decodersMu sync.RWMutex
+	decoders   = map[byte]DecodeFunc{
+		TypeIPRemoteATCommand:             decodeIPRemoteATCommandRequest,
+		TypeATCommand:                     decodeATCommandRequest,
+		TypeATCommandQueue:                decodeATCommandRequest,
+		TypeTransmitRequest:               decodeTransmitRequest,
+		TypeExplicitTransmit:              decodeExplicitTransmitRequest,
+		TypeRemoteATCommand:               decodeRemoteATCommandRequest,
+		TypeSMSTransmitRequest:            decodeSMSTransmitRequest,
+		TypeIPv4TransmitRequest:           decodeIPv4TransmitRequest,
+		TypeRegisterJoiningDevice:         decodeRegisterJoiningDevice,
+		TypeUserDataRelay:                 decodeUserDataRelay,
+		TypeSecureSessionControl:          decodeSecureSessionControl,
+		TypeFileSystemRequest:             decodeFileSystemRequest,
+		TypeRemoteFileSystemRequest:       decodeRemoteFileSystemRequest,
+		TypeIPRemoteATCommandResponse:     decodeIPRemoteATCommandResponse,
+		TypeATCommandResponse:             decodeATCommandResponse,
+		TypeIPTransmitStatus:              decodeIPTransmitStatus,
+		TypeModemStatus:                   decodeModemStatus,
+		TypeTransmitStatus:                decodeTransmitStatus,
+		TypeReceivePacket:                 decodeReceivePacket,
+		TypeExplicitReceivePacket:         decodeExplicitReceivePacket,
+		TypeIODataSampleIndicator:         decodeIODataSampleIndicator,
+		TypeNodeIdentificationIndicator:   decodeNodeIdentificationIndicator,
+		TypeRemoteATCommandResponse:       decodeRemoteATCommandResponse,
+		TypeSMSReceivePacket:              decodeSMSReceivePacket,
+		TypeRegisterJoiningDeviceResponse: decodeRegisterJoiningDeviceResponse,
+		TypeUserDataRelayOutput:           decodeUserDataRelayOutput,
+		TypeSecureSessionResponse:         decodeSecureSessionResponse,
+		TypeIPv4ReceivePacket:             decodeIPv4ReceivePacket,
+		TypeFileSystemResponse:            decodeFileSystemResponse,
+		TypeRemoteFileSystemResponse:      decodeRemoteFileSystemResponse,
+	}
+)
+
+// RegisterFrameType registers the decoder for a frame type replacing any
+// existing decoder. This allows handling frame types that aren't supported
+// by this package. If the decoded frame implements Identified then it's
+// delivered as the response to the request with the same frame ID.
+// Passing a nil fn removes the decoder causing the frame type to be
+// decoded as UnknownFrame.
+func RegisterFrameType(typ byte, fn DecodeFunc) {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	if fn == nil {
+		delete(decoders, typ)
+	} else {
+		decoders[typ] = fn
+	}
+}
+
+// Encode returns the frame data (frame type followed by frame specific
+// fields) for f.
+func Encode(f Frame) ([]byte, error) {
+	b, err := f.AppendData([]byte{f.FrameType()})
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > MaxDataLength {
+		return nil, ErrTooLong
+	}
+	return b, nil
+}
+
+// Decode decodes frame data (frame type followed by frame specific
+// fields). Frame types without a decoder are returned as UnknownFrame.
+// The returned frame may reference data.
+func Decode(data []byte) (Frame, error) {
+	if len(data) == 0 {
+		return nil, ErrEmpty
+	}
+	decodersMu.RLock()
+	dec := decoders[data[0]]
+	decodersMu.RUnlock()
+	if dec != nil {
+		return dec(data)
+	}
+	return UnknownFrame(data), nil
+}
+
+// Marshal returns the unescaped wire encoding of f including the
+// delimiter, length, and checksum.
+func Marshal(f Frame) ([]byte, error) {
+	b, err := f.AppendData([]byte{Delimiter, 0, 0, f.FrameType()})
+	if err != nil {
+		return nil, err
+	}
+	n := len(b) - 3
+	if n > MaxDataLength {
+		return nil, ErrTooLong
+	}
+	b[1] = byte(n >> 8)
+	b[2] = byte(n)
+	return append(b, Checksum(b[3:])), nil
+}
+
+// Unmarshal decodes a single unescaped frame as returned by Marshal.
+func Unmarshal(b []byte) (Frame, error) {
+	if len(b) < 5 {
+		return nil, ErrEmpty
+	}
+	if b[0] != Delimiter {
+		return nil, ErrDelimiter
+	}
+	n := int(b[1])<<8 | int(b[2])
+	if len(b) != n+4 {
+		return nil, fmt.Errorf("frames: frame length %d does not match buffer length %d", n, len(b)-4)
+	}
+	if Checksum(b[3:3+n]) != b[3+n] {
+		return nil, ErrChecksum
+	}
+	return Decode(b[3 : 3+n])
+}
+
+// Checksum returns the checksum for frame data.
+func Checksum(data []byte) byte {
+	var sum byte
+	for _, v := range data {
+		sum += v
+	}
+	return 0xff - sum
+}
@@ -0,0 +1,153 @@
+package frames
+
+import (
+	"bytes"
+	"io"
+	"net/netip"
+	"testing"
+)
+
+// seedFrames returns a valid frame of every frame type with a decoder.
+func seedFrames() []Frame {
+	ip := netip.AddrFrom4([4]byte{192, 168, 1, 10})
+	return []Frame{
+		&ATCommandRequest{FrameID: 1, ATCommand: ATCommand{'N', 'I'}},
+		&ATCommandRequest{FrameID: 2, ATCommand: ATCommand{'I', 'D'}, Parameter: []byte{0x12, 0x34}, Queue: true},
+		&ATCommandResponse{FrameID: 1, ATCommand: ATCommand{'N', 'I'}, Data: []byte("node")},
+		&RemoteATCommandRequest{FrameID: 3, DestinationAddress: 0x0013a20040a1b2c3, DestinationAddress16: 0xfffe, ATCommand: ATCommand{'D', '0'}, Parameter: []byte{4}},
+		&RemoteATCommandResponse{FrameID: 3, SourceAddress: 0x0013a20040a1b2c3, SourceAddress16: 0x1234, ATCommand: ATCommand{'D', '0'}},
+		&TransmitRequest{FrameID: 4, DestinationAddress: 0x0013a20040a1b2c3, DestinationAddress16: 0xfffe, Data: []byte("hello")},
+		&ExplicitTransmitRequest{FrameID: 5, DestinationAddress: 0x0013a20040a1b2c3, DestinationAddress16: 0xfffe, SourceEndpoint: 0xe8, DestinationEndpoint: 0xe8, ClusterID: 0x11, ProfileID: 0xc105, Data: []byte("hello")},
+		&TransmitStatus{FrameID: 4, DestinationAddress: 0x1234, RetryCount: 1},
+		&ReceivePacket{SourceAddress: 0x0013a20040a1b2c3, SourceAddress16: 0x1234, ReceiveOptions: 1, Data: []byte("hello")},
+		&ExplicitReceivePacket{SourceAddress: 0x0013a20040a1b2c3, SourceAddress16: 0x1234, SourceEndpoint: 0xe8, DestinationEndpoint: 0xe8, ClusterID: 0x11, ProfileID: 0xc105, Data: []byte("hello")},
+		ModemStatus(2),
+		&IODataSampleIndicator{},
+		&NodeIdentificationIndicator{},
+		&RegisterJoiningDevice{},
+		&RegisterJoiningDeviceResponse{},
+		&UserDataRelay{},
+		&UserDataRelayOutput{},
+		&SecureSessionControl{},
+		&SecureSessionResponse{},
+		&SMSTransmitRequest{},
+		&SMSReceivePacket{},
+		&FileSystemRequest{},
+		&FileSystemResponse{},
+		&RemoteFileSystemRequest{},
+		&RemoteFileSystemResponse{},
+		&IPv4TransmitRequest{FrameID: 6, DestinationAddress: ip, DestinationPort: 9750, Data: []byte("hello")},
+		&IPv4ReceivePacket{SourceAddress: ip, DestinationPort: 9750, SourcePort: 9750, Data: []byte("hello")},
+		&IPTransmitStatus{FrameID: 6},
+		&IPRemoteATCommandRequest{FrameID: 7, DestinationAddress: ip, ATCommand: ATCommand{'N', 'I'}},
+		&IPRemoteATCommandResponse{FrameID: 7, SourceAddress: ip, ATCommand: ATCommand{'N', 'I'}, Data: []byte("node")},
+	}
+}
+
+// encodeSeeds returns the frame data of every seed frame checking that
+// they cover every registered frame type.
+func encodeSeeds(f *testing.F) [][]byte {
+	seen := make(map[byte]bool)
+	var seeds [][]byte
+	for _, fr := range seedFrames() {
+		data, err := Encode(fr)
+		if err != nil {
+			f.Fatalf("Encode(%T): %v", fr, err)
+		}
+		seen[data[0]] = true
+		seeds = append(seeds, data)
+	}
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+	for typ := range decoders {
+		if !seen[typ] {
+			f.Fatalf("no seed for frame type 0x%02x", typ)
+		}
+	}
+	return seeds
+}
+
+// checkRoundTrip checks that a decoded frame encodes to frame data that
+// decodes to a frame encoding to the same data.
+func checkRoundTrip(t *testing.T, data []byte) {
+	fr, err := Decode(data)
+	if err != nil {
+		return
+	}
+	enc, err := Encode(fr)
+	if err != nil {
+		if len(data) > MaxDataLength && err == ErrTooLong {
+			return
+		}
+		t.Fatalf("Encode(%T) of decoded %x: %v", fr, data, err)
+	}
+	fr2, err := Decode(enc)
+	if err != nil {
+		t.Fatalf("Decode(%x) of encoded %T: %v", enc, fr, err)
+	}
+	enc2, err := Encode(fr2)
+	if err != nil {
+		t.Fatalf("Encode(%T) of %x: %v", fr2, enc, err)
+	}
+	if !bytes.Equal(enc, enc2) {
+		t.Fatalf("%T doesn't round trip: %x encodes to %x then %x", fr, data, enc, enc2)
+	}
+}
+
+func FuzzDecode(f *testing.F) {
+	for _, data := range encodeSeeds(f) {
+		f.Add(data)
+	}
+	f.Fuzz(checkRoundTrip)
+}
+
+func FuzzReader(f *testing.F) {
+	seeds := encodeSeeds(f)
+	for _, escaped := range []bool{false, true} {
+		var stream bytes.Buffer
+		w := NewWriter(&stream)
+		w.Escaped = escaped
+		for _, data := range seeds {
+			b, err := w.AppendData(nil, data)
+			if err != nil {
+				f.Fatal(err)
+			}
+			f.Add(b, escaped)
+			stream.Write(b)
+		}
+		f.Add(stream.Bytes(), escaped)
+	}
+	f.Fuzz(func(t *testing.T, b []byte, escaped bool) {
+		rd := NewReader(bytes.NewReader(b))
+		rd.Escaped = escaped
+		for {
+			data, err := rd.ReadData()
+			switch err {
+			case nil:
+			case ErrChecksum, ErrTruncated, ErrEmpty:
+				continue
+			case io.EOF, io.ErrUnexpectedEOF:
+				return
+			default:
+				t.Fatalf("ReadData: %v", err)
+			}
+			checkRoundTrip(t, data)
+			if len(data) > MaxDataLength {
+				continue
+			}
+			// Frame data written and read again is unchanged.
+			var buf bytes.Buffer
+			w := NewWriter(&buf)
+			w.Escaped = escaped
+			if err := w.WriteData(data); err != nil {
+				t.Fatalf("WriteData(%x): %v", data, err)
+			}
+			r2 := NewReader(&buf)
+			r2.Escaped = escaped
+			got, err := r2.ReadData()
+			if err != nil || !bytes.Equal(got, data) {
+				t.Fatalf("ReadData of written %x = %x, %v", data, got, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,217 @@
+package frames
+
+import (
+	"bufio"
+	"io"
+)
+
+const (
+	escape = 0x7d
+	xon    = 0x11
+	xoff   = 0x13
+)
+
+func needsEscape(b byte) bool {
+	return b == Delimiter || b == escape || b == xon || b == xoff
+}
+
+// Reader reads API frames from a byte stream.
+type Reader struct {
+	// Escaped enables decoding of escaped frames (API mode 2).
+	Escaped bool
+
+	rd      *bufio.Reader
+	raw     []byte
+	pending []byte // bytes to scan again before reading from rd
+}
+
+func NewReader(r io.Reader) *Reader {
+	rd, ok := r.(*bufio.Reader)
+	if !ok {
+		rd = bufio.NewReader(r)
+	}
+	return &Reader{rd: rd}
+}
+
+// ReadData reads the next frame and returns its data (frame type and frame
+// specific fields). Any bytes before the frame delimiter are discarded.
+// If the checksum does not match then the frame data is returned along
+// with ErrChecksum. In escaped mode a delimiter in the middle of a frame
+// returns ErrTruncated. After either error the reader resynchronizes by
+// scanning for the next delimiter from the byte following the corrupt
+// frame's delimiter rather than after the frame, so a corrupt length
+// doesn't cause the frames that follow it to be lost.
+func (r *Reader) ReadData() ([]byte, error) {
+	return r.ReadDataInto(nil)
+}
+
+// ReadDataInto is like ReadData but reads the frame into buf if it has
+// enough capacity for the frame data and checksum, allocating a new buffer
+// otherwise. The returned data references buf in that case.
+func (r *Reader) ReadDataInto(buf []byte) ([]byte, error) {
+	for {
+		b, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+		if b == Delimiter {
+			break
+		}
+	}
+	r.raw = append(r.raw[:0], Delimiter)
+	var hdr [2]byte
+	if err := r.read(hdr[:]); err != nil {
+		return nil, r.truncated(err)
+	}
+	n := int(hdr[0])<<8 | int(hdr[1])
+	// +1 for checksum
+	if cap(buf) >= n+1 {
+		buf = buf[:n+1]
+	} else {
+		buf = make([]byte, n+1)
+	}
+	if err := r.read(buf); err != nil {
+		return nil, r.truncated(err)
+	}
+	data := buf[:n]
+	if Checksum(data) != buf[n] {
+		r.resync(1)
+		return data, ErrChecksum
+	}
+	if n == 0 {
+		return data, ErrEmpty
+	}
+	return data, nil
+}
+
+// truncated resynchronizes at the delimiter that interrupted a frame.
+func (r *Reader) truncated(err error) error {
+	if err == ErrTruncated {
+		r.resync(len(r.raw) - 1)
+		r.raw = r.raw[:len(r.raw)-1]
+	}
+	return err
+}
+
+// resync causes the raw bytes of the last frame from offset to be scanned
+// again for a delimiter.
+func (r *Reader) resync(offset int) {
+	p := make([]byte, 0, len(r.raw)-offset+len(r.pending))
+	p = append(p, r.raw[offset:]...)
+	r.pending = append(p, r.pending...)
+}
+
+// ReadFrame reads and decodes the next frame.
+func (r *Reader) ReadFrame() (Frame, error) {
+	data, err := r.ReadData()
+	if err != nil {
+		return nil, err
+	}
+	return Decode(data)
+}
+
+// Raw returns the bytes of the last frame read as they were received
+// (including the delimiter, length, escapes, and checksum). It's only
+// valid until the next read.
+func (r *Reader) Raw() []byte {
+	return r.raw
+}
+
+func (r *Reader) readByte() (byte, error) {
+	if len(r.pending) != 0 {
+		c := r.pending[0]
+		r.pending = r.pending[1:]
+		return c, nil
+	}
+	return r.rd.ReadByte()
+}
+
+func (r *Reader) read(b []byte) error {
+	if !r.Escaped {
+		n := copy(b, r.pending)
+		r.pending = r.pending[n:]
+		m, err := io.ReadFull(r.rd, b[n:])
+		r.raw = append(r.raw, b[:n+m]...)
+		return err
+	}
+	for i := range b {
+		c, err := r.readByte()
+		if err != nil {
+			return err
+		}
+		r.raw = append(r.raw, c)
+		if c == Delimiter {
+			return ErrTruncated
+		}
+		if c == escape {
+			if c, err = r.readByte(); err != nil {
+				return err
+			}
+			r.raw = append(r.raw, c)
+			if c == Delimiter {
+				return ErrTruncated
+			}
+			c ^= 0x20
+		}
+		b[i] = c
+	}
+	return nil
+}
+
+// Writer writes API frames to a byte stream.
+type Writer struct {
+	// Escaped enables encoding of escaped frames (API mode 2).
+	Escaped bool
+
+	w   io.Writer
+	buf []byte
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// WriteFrame encodes and writes a single frame.
+func (w *Writer) WriteFrame(f Frame) error {
+	data, err := Encode(f)
+	if err != nil {
+		return err
+	}
+	return w.WriteData(data)
+}
+
+// WriteData writes frame data (frame type and frame specific fields)
+// adding the delimiter, length, and checksum.
+func (w *Writer) WriteData(data []byte) error {
+	b, err := w.AppendData(w.buf[:0], data)
+	if err != nil {
+		return err
+	}
+	w.buf = b
+	_, err = w.w.Write(b)
+	return err
+}
+
+// AppendData appends the bytes written by WriteData for frame data to b.
+func (w *Writer) AppendData(b, data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, ErrEmpty
+	}
+	if len(data) > MaxDataLength {
+		return nil, ErrTooLong
+	}
+	b = append(b, Delimiter)
+	b = w.appendByte(b, byte(len(data)>>8))
+	b = w.appendByte(b, byte(len(data)))
+	for _, c := range data {
+		b = w.appendByte(b, c)
+	}
+	return w.appendByte(b, Checksum(data)), nil
+}
+
+func (w *Writer) appendByte(b []byte, c byte) []byte {
+	if w.Escaped && needsEscape(c) {
+		return append(b, escape, c^0x20)
+	}
+	return append(b, c)
+}
@@ -0,0 +1,92 @@
+package frames
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// IODataSampleIndicator is an I/O sample received from a remote device
+// configured to sample its pins periodically (IR) or on change (IC).
+type IODataSampleIndicator struct {
+	SourceAddress   Addr64
+	SourceAddress16 Addr16
+	ReceiveOptions  ReceiveOption
+	NumSamples      byte     // always 1
+	DigitalMask     uint16   // bit n set if DIOn is a digital input or output
+	AnalogMask      byte     // bit n set if ADn is an analog input, bit 7 for the supply voltage
+	Digital         uint16   // levels of the pins in DigitalMask, only present if DigitalMask isn't 0
+	Analog          []uint16 // one 10-bit value for each bit set in AnalogMask from the lowest
+}
+
+// DigitalValue returns the level of DIO pin n (0 to 15) and whether it's
+// included in the sample.
+func (f *IODataSampleIndicator) DigitalValue(n int) (high, ok bool) {
+	if n < 0 || n > 15 || f.DigitalMask&(1<<n) == 0 {
+		return false, false
+	}
+	return f.Digital&(1<<n) != 0, true
+}
+
+// AnalogValue returns the value of analog input n (0 to 7, 7 being the
+// supply voltage) and whether it's included in the sample.
+func (f *IODataSampleIndicator) AnalogValue(n int) (uint16, bool) {
+	if n < 0 || n > 7 || f.AnalogMask&(1<<n) == 0 {
+		return 0, false
+	}
+	i := bits.OnesCount8(f.AnalogMask & (1<<n - 1))
+	if i >= len(f.Analog) {
+		return 0, false
+	}
+	return f.Analog[i], true
+}
+
+func (f *IODataSampleIndicator) FrameType() byte {
+	return TypeIODataSampleIndicator
+}
+
+func (f *IODataSampleIndicator) AppendData(b []byte) ([]byte, error) {
+	b = binary.BigEndian.AppendUint64(b, uint64(f.SourceAddress))
+	b = binary.BigEndian.AppendUint16(b, uint16(f.SourceAddress16))
+	b = append(b, byte(f.ReceiveOptions), f.NumSamples)
+	b = binary.BigEndian.AppendUint16(b, f.DigitalMask)
+	b = append(b, f.AnalogMask)
+	if f.DigitalMask != 0 {
+		b = binary.BigEndian.AppendUint16(b, f.Digital)
+	}
+	for _, v := range f.Analog {
+		b = binary.BigEndian.AppendUint16(b, v)
+	}
+	return b, nil
+}
+
+func decodeIODataSampleIndicator(b []byte) (Frame, error) {
+	if err := checkLen(b, 16); err != nil {
+		return nil, err
+	}
+	f := &IODataSampleIndicator{
+		SourceAddress:   Addr64(binary.BigEndian.Uint64(b[1:])),
+		SourceAddress16: Addr16(binary.BigEndian.Uint16(b[9:])),
+		ReceiveOptions:  ReceiveOption(b[11]),
+		NumSamples:      b[12],
+		DigitalMask:     binary.BigEndian.Uint16(b[13:]),
+		AnalogMask:      b[15],
+	}
+	n := 16
+	if f.DigitalMask != 0 {
+		n += 2
+	}
+	n += 2 * bits.OnesCount8(f.AnalogMask)
+	if err := checkLen(b, n); err != nil {
+		return nil, err
+	}
+	rest := b[16:]
+	if f.DigitalMask != 0 {
+		f.Digital = binary.BigEndian.Uint16(rest)
+		rest = rest[2:]
+	}
+	for len(f.Analog) < bits.OnesCount8(f.AnalogMask) {
+		f.Analog = append(f.Analog, binary.BigEndian.Uint16(rest))
+		rest = rest[2:]
+	}
+	return f, nil
+}
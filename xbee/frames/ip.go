@@ -0,0 +1,256 @@
+package frames
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/netip"
+)
+
+// Frames used by IP based modules (XBee Wi-Fi and XBee Cellular).
+// The IPv4 transmit, receive, and transmit status frames are shared
+// by both module types.
+
+var errNotIPv4 = errors.New("frames: address is not IPv4")
+
+type IPProtocol byte
+
+const (
+	IPProtocolUDP IPProtocol = 0
+	IPProtocolTCP IPProtocol = 1
+	IPProtocolSSL IPProtocol = 4 // TLS, XBee Cellular only
+)
+
+func (p IPProtocol) String() string {
+	switch p {
+	case IPProtocolUDP:
+		return "UDP"
+	case IPProtocolTCP:
+		return "TCP"
+	case IPProtocolSSL:
+		return "SSL"
+	}
+	return fmt.Sprintf("IPProtocol(%d)", p)
+}
+
+func (p IPProtocol) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+type IPTransmitOption byte
+
+const (
+	// IPTOCloseSocket closes the TCP socket after the transmission.
+	IPTOCloseSocket IPTransmitOption = 0x02
+)
+
+func appendIPv4(b []byte, addr netip.Addr) ([]byte, error) {
+	if !addr.Is4() {
+		return nil, errNotIPv4
+	}
+	a := addr.As4()
+	return append(b, a[:]...), nil
+}
+
+func ipv4(b []byte) netip.Addr {
+	return netip.AddrFrom4([4]byte{b[0], b[1], b[2], b[3]})
+}
+
+// IPv4TransmitRequest sends data to a host over IPv4.
+type IPv4TransmitRequest struct {
+	FrameID            byte
+	DestinationAddress netip.Addr
+	DestinationPort    uint16
+	SourcePort         uint16 // 0 to use a random port
+	Protocol           IPProtocol
+	Options            IPTransmitOption
+	Data               []byte
+}
+
+func (f *IPv4TransmitRequest) FrameType() byte {
+	return TypeIPv4TransmitRequest
+}
+
+func (f *IPv4TransmitRequest) ID() byte {
+	return f.FrameID
+}
+
+func (f *IPv4TransmitRequest) AppendData(b []byte) ([]byte, error) {
+	b, err := appendIPv4(append(b, f.FrameID), f.DestinationAddress)
+	if err != nil {
+		return nil, err
+	}
+	b = binary.BigEndian.AppendUint16(b, f.DestinationPort)
+	b = binary.BigEndian.AppendUint16(b, f.SourcePort)
+	b = append(b, byte(f.Protocol), byte(f.Options))
+	return append(b, f.Data...), nil
+}
+
+// Destination returns the destination address and port.
+func (f *IPv4TransmitRequest) Destination() netip.AddrPort {
+	return netip.AddrPortFrom(f.DestinationAddress, f.DestinationPort)
+}
+
+func decodeIPv4TransmitRequest(b []byte) (Frame, error) {
+	if err := checkLen(b, 12); err != nil {
+		return nil, err
+	}
+	return &IPv4TransmitRequest{
+		FrameID:            b[1],
+		DestinationAddress: ipv4(b[2:]),
+		DestinationPort:    binary.BigEndian.Uint16(b[6:]),
+		SourcePort:         binary.BigEndian.Uint16(b[8:]),
+		Protocol:           IPProtocol(b[10]),
+		Options:            IPTransmitOption(b[11]),
+		Data:               b[12:],
+	}, nil
+}
+
+// IPv4ReceivePacket is data received from a host over IPv4.
+type IPv4ReceivePacket struct {
+	SourceAddress   netip.Addr
+	DestinationPort uint16
+	SourcePort      uint16
+	Protocol        IPProtocol
+	Status          byte // reserved
+	Data            []byte
+}
+
+func (f *IPv4ReceivePacket) FrameType() byte {
+	return TypeIPv4ReceivePacket
+}
+
+func (f *IPv4ReceivePacket) AppendData(b []byte) ([]byte, error) {
+	b, err := appendIPv4(b, f.SourceAddress)
+	if err != nil {
+		return nil, err
+	}
+	b = binary.BigEndian.AppendUint16(b, f.DestinationPort)
+	b = binary.BigEndian.AppendUint16(b, f.SourcePort)
+	b = append(b, byte(f.Protocol), f.Status)
+	return append(b, f.Data...), nil
+}
+
+// Source returns the source address and port.
+func (f *IPv4ReceivePacket) Source() netip.AddrPort {
+	return netip.AddrPortFrom(f.SourceAddress, f.SourcePort)
+}
+
+func decodeIPv4ReceivePacket(b []byte) (Frame, error) {
+	if err := checkLen(b, 11); err != nil {
+		return nil, err
+	}
+	return &IPv4ReceivePacket{
+		SourceAddress:   ipv4(b[1:]),
+		DestinationPort: binary.BigEndian.Uint16(b[5:]),
+		SourcePort:      binary.BigEndian.Uint16(b[7:]),
+		Protocol:        IPProtocol(b[9]),
+		Status:          b[10],
+		Data:            b[11:],
+	}, nil
+}
+
+// IPTransmitStatus reports the outcome of an IPv4TransmitRequest.
+type IPTransmitStatus struct {
+	FrameID byte
+	Status  DeliveryStatus
+}
+
+func (f *IPTransmitStatus) FrameType() byte {
+	return TypeIPTransmitStatus
+}
+
+func (f *IPTransmitStatus) ID() byte {
+	return f.FrameID
+}
+
+func (f *IPTransmitStatus) AppendData(b []byte) ([]byte, error) {
+	return append(b, f.FrameID, byte(f.Status)), nil
+}
+
+func decodeIPTransmitStatus(b []byte) (Frame, error) {
+	if err := checkLen(b, 3); err != nil {
+		return nil, err
+	}
+	return &IPTransmitStatus{FrameID: b[1], Status: DeliveryStatus(b[2])}, nil
+}
+
+// IPRemoteATCommandRequest queries or sets a register on a remote XBee
+// Wi-Fi module.
+type IPRemoteATCommandRequest struct {
+	FrameID            byte
+	DestinationAddress netip.Addr
+	Options            RemoteATCommandOption
+	ATCommand          ATCommand
+	Parameter          []byte
+}
+
+func (f *IPRemoteATCommandRequest) FrameType() byte {
+	return TypeIPRemoteATCommand
+}
+
+func (f *IPRemoteATCommandRequest) ID() byte {
+	return f.FrameID
+}
+
+func (f *IPRemoteATCommandRequest) AppendData(b []byte) ([]byte, error) {
+	// The address field is 64-bits with the IPv4 address in the low 32-bits
+	b, err := appendIPv4(append(b, f.FrameID, 0, 0, 0, 0), f.DestinationAddress)
+	if err != nil {
+		return nil, err
+	}
+	b = append(b, byte(f.Options), f.ATCommand[0], f.ATCommand[1])
+	return append(b, f.Parameter...), nil
+}
+
+func decodeIPRemoteATCommandRequest(b []byte) (Frame, error) {
+	if err := checkLen(b, 13); err != nil {
+		return nil, err
+	}
+	return &IPRemoteATCommandRequest{
+		FrameID:            b[1],
+		DestinationAddress: ipv4(b[6:]),
+		Options:            RemoteATCommandOption(b[10]),
+		ATCommand:          ATCommand{b[11], b[12]},
+		Parameter:          b[13:],
+	}, nil
+}
+
+// IPRemoteATCommandResponse is the response to an IPRemoteATCommandRequest.
+type IPRemoteATCommandResponse struct {
+	FrameID       byte
+	SourceAddress netip.Addr
+	ATCommand     ATCommand
+	CommandStatus CommandStatus
+	Data          []byte
+}
+
+func (f *IPRemoteATCommandResponse) FrameType() byte {
+	return TypeIPRemoteATCommandResponse
+}
+
+func (f *IPRemoteATCommandResponse) ID() byte {
+	return f.FrameID
+}
+
+func (f *IPRemoteATCommandResponse) AppendData(b []byte) ([]byte, error) {
+	b, err := appendIPv4(append(b, f.FrameID, 0, 0, 0, 0), f.SourceAddress)
+	if err != nil {
+		return nil, err
+	}
+	b = append(b, f.ATCommand[0], f.ATCommand[1], byte(f.CommandStatus))
+	return append(b, f.Data...), nil
+}
+
+func decodeIPRemoteATCommandResponse(b []byte) (Frame, error) {
+	if err := checkLen(b, 13); err != nil {
+		return nil, err
+	}
+	return &IPRemoteATCommandResponse{
+		FrameID:       b[1],
+		SourceAddress: ipv4(b[6:]),
+		ATCommand:     ATCommand{b[10], b[11]},
+		CommandStatus: CommandStatus(b[12]),
+		Data:          b[13:],
+	}, nil
+}
@@ -0,0 +1,60 @@
+package frames
+
+import "fmt"
+
+type ModemStatus byte
+
+const (
+	MSHardwareReset              ModemStatus = 0
+	MSWatchdogTimerReset         ModemStatus = 1
+	MSJoinedNetwork              ModemStatus = 2 // routers and end devices
+	MSDisassociated              ModemStatus = 3
+	MSCoordinatorStarted         ModemStatus = 6
+	MSNetworkKeyUpdated          ModemStatus = 7
+	MSVoltageSupplyLimitExceeded ModemStatus = 0x0d // PRO S2B only
+	MSConfigChangeDuringJoin     ModemStatus = 0x11
+)
+
+func (ms ModemStatus) String() string {
+	switch ms {
+	case MSHardwareReset:
+		return "HardwareReset"
+	case MSWatchdogTimerReset:
+		return "WatchdogTimerReset"
+	case MSJoinedNetwork:
+		return "JoinedNetwork"
+	case MSDisassociated:
+		return "Disassociated"
+	case MSCoordinatorStarted:
+		return "CoordinatorStarted"
+	case MSNetworkKeyUpdated:
+		return "NetworkKeyUpdated"
+	case MSVoltageSupplyLimitExceeded:
+		return "VoltageSupplyLimitExceeded"
+	case MSConfigChangeDuringJoin:
+		return "ConfigChangeDuringJoin"
+	}
+	if ms >= 0x80 {
+		return "StackError"
+	}
+	return fmt.Sprintf("ModemStatus(%d)", ms)
+}
+
+func (ms ModemStatus) MarshalText() ([]byte, error) {
+	return []byte(ms.String()), nil
+}
+
+func (ms ModemStatus) FrameType() byte {
+	return TypeModemStatus
+}
+
+func (ms ModemStatus) AppendData(b []byte) ([]byte, error) {
+	return append(b, byte(ms)), nil
+}
+
+func decodeModemStatus(b []byte) (Frame, error) {
+	if err := checkLen(b, 2); err != nil {
+		return nil, err
+	}
+	return ModemStatus(b[1]), nil
+}
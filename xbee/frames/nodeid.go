@@ -0,0 +1,97 @@
+package frames
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// NodeIDEvent is what caused a node identification indicator to be sent.
+type NodeIDEvent byte
+
+const (
+	NIEPushbutton NodeIDEvent = 1
+	NIEJoined     NodeIDEvent = 2 // sent by routers and end devices that join if JN=1
+	NIEPowerCycle NodeIDEvent = 3
+)
+
+func (e NodeIDEvent) String() string {
+	switch e {
+	case NIEPushbutton:
+		return "Pushbutton"
+	case NIEJoined:
+		return "Joined"
+	case NIEPowerCycle:
+		return "PowerCycle"
+	}
+	return fmt.Sprintf("NodeIDEvent(%d)", e)
+}
+
+func (e NodeIDEvent) MarshalText() ([]byte, error) {
+	return []byte(e.String()), nil
+}
+
+// NodeIdentificationIndicator is received when a node identifies itself
+// (e.g. after joining the network). The source is the node that sent the
+// frame and the remote address is the node being identified (normally
+// the same).
+type NodeIdentificationIndicator struct {
+	SourceAddress   Addr64
+	SourceAddress16 Addr16
+	ReceiveOptions  ReceiveOption
+	RemoteAddress16 Addr16
+	RemoteAddress   Addr64
+	NodeID          string
+	ParentAddress16 Addr16
+	DeviceType      byte // 0 coordinator, 1 router, 2 end device
+	SourceEvent     NodeIDEvent
+	ProfileID       uint16
+	ManufacturerID  uint16
+	Extra           []byte // device type identifier (DD) and RSSI if enabled by NO
+}
+
+func (f *NodeIdentificationIndicator) FrameType() byte {
+	return TypeNodeIdentificationIndicator
+}
+
+func (f *NodeIdentificationIndicator) AppendData(b []byte) ([]byte, error) {
+	b = binary.BigEndian.AppendUint64(b, uint64(f.SourceAddress))
+	b = binary.BigEndian.AppendUint16(b, uint16(f.SourceAddress16))
+	b = append(b, byte(f.ReceiveOptions))
+	b = binary.BigEndian.AppendUint16(b, uint16(f.RemoteAddress16))
+	b = binary.BigEndian.AppendUint64(b, uint64(f.RemoteAddress))
+	b = append(b, f.NodeID...)
+	b = append(b, 0)
+	b = binary.BigEndian.AppendUint16(b, uint16(f.ParentAddress16))
+	b = append(b, f.DeviceType, byte(f.SourceEvent))
+	b = binary.BigEndian.AppendUint16(b, f.ProfileID)
+	b = binary.BigEndian.AppendUint16(b, f.ManufacturerID)
+	return append(b, f.Extra...), nil
+}
+
+func decodeNodeIdentificationIndicator(b []byte) (Frame, error) {
+	if err := checkLen(b, 22); err != nil {
+		return nil, err
+	}
+	f := &NodeIdentificationIndicator{
+		SourceAddress:   Addr64(binary.BigEndian.Uint64(b[1:])),
+		SourceAddress16: Addr16(binary.BigEndian.Uint16(b[9:])),
+		ReceiveOptions:  ReceiveOption(b[11]),
+		RemoteAddress16: Addr16(binary.BigEndian.Uint16(b[12:])),
+		RemoteAddress:   Addr64(binary.BigEndian.Uint64(b[14:])),
+	}
+	rest := b[22:]
+	ix := bytes.IndexByte(rest, 0)
+	if ix < 0 || len(rest) < ix+9 {
+		return nil, &ShortFrameError{Type: b[0], Len: len(b), Min: 22 + max(ix, 0) + 9}
+	}
+	f.NodeID = string(rest[:ix])
+	rest = rest[ix+1:]
+	f.ParentAddress16 = Addr16(binary.BigEndian.Uint16(rest))
+	f.DeviceType = rest[2]
+	f.SourceEvent = NodeIDEvent(rest[3])
+	f.ProfileID = binary.BigEndian.Uint16(rest[4:])
+	f.ManufacturerID = binary.BigEndian.Uint16(rest[6:])
+	f.Extra = rest[8:]
+	return f, nil
+}
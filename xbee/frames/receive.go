@@ -0,0 +1,132 @@
+package frames
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+type ReceiveOption byte
+
+const (
+	ROAcknowledged  ReceiveOption = 0x01
+	ROBroadcast     ReceiveOption = 0x02
+	ROEncrypted     ReceiveOption = 0x20
+	ROFromEndDevice ReceiveOption = 0x40
+)
+
+func (o ReceiveOption) Has(opt ReceiveOption) bool {
+	return (o & opt) != 0
+}
+
+func (o ReceiveOption) String() string {
+	if o == 0 {
+		return "None"
+	}
+	var opts []string
+	if o.Has(ROAcknowledged) {
+		opts = append(opts, "Acknowledged")
+		o &^= ROAcknowledged
+	}
+	if o.Has(ROBroadcast) {
+		opts = append(opts, "Broadcast")
+		o &^= ROBroadcast
+	}
+	if o.Has(ROEncrypted) {
+		opts = append(opts, "Encrypted")
+		o &^= ROEncrypted
+	}
+	if o.Has(ROFromEndDevice) {
+		opts = append(opts, "FromEndDevice")
+		o &^= ROFromEndDevice
+	}
+	if o != 0 {
+		opts = append(opts, fmt.Sprintf("ReceiveOption(%d)", o))
+	}
+	return strings.Join(opts, "|")
+}
+
+func (o ReceiveOption) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
+}
+
+// ReceivePacket is data received from a remote device.
+type ReceivePacket struct {
+	SourceAddress   Addr64
+	SourceAddress16 Addr16
+	ReceiveOptions  ReceiveOption
+	Data            []byte
+	// Duplicate is set on a repeated broadcast when the XBee detects
+	// duplicates. It isn't part of the frame.
+	Duplicate bool
+}
+
+func (f *ReceivePacket) FrameType() byte {
+	return TypeReceivePacket
+}
+
+func (f *ReceivePacket) AppendData(b []byte) ([]byte, error) {
+	b = binary.BigEndian.AppendUint64(b, uint64(f.SourceAddress))
+	b = binary.BigEndian.AppendUint16(b, uint16(f.SourceAddress16))
+	b = append(b, byte(f.ReceiveOptions))
+	return append(b, f.Data...), nil
+}
+
+func decodeReceivePacket(b []byte) (Frame, error) {
+	if err := checkLen(b, 12); err != nil {
+		return nil, err
+	}
+	return &ReceivePacket{
+		SourceAddress:   Addr64(binary.BigEndian.Uint64(b[1:])),
+		SourceAddress16: Addr16(binary.BigEndian.Uint16(b[9:])),
+		ReceiveOptions:  ReceiveOption(b[11]),
+		Data:            b[12:],
+	}, nil
+}
+
+// ExplicitReceivePacket is data received from a remote device including
+// the application layer addressing. It's sent instead of ReceivePacket
+// when explicit receive is enabled (AO=1).
+type ExplicitReceivePacket struct {
+	SourceAddress       Addr64
+	SourceAddress16     Addr16
+	SourceEndpoint      byte
+	DestinationEndpoint byte
+	ClusterID           uint16
+	ProfileID           uint16
+	ReceiveOptions      ReceiveOption
+	Data                []byte
+	// Duplicate is set on a repeated broadcast when the XBee detects
+	// duplicates. It isn't part of the frame.
+	Duplicate bool
+}
+
+func (f *ExplicitReceivePacket) FrameType() byte {
+	return TypeExplicitReceivePacket
+}
+
+func (f *ExplicitReceivePacket) AppendData(b []byte) ([]byte, error) {
+	b = binary.BigEndian.AppendUint64(b, uint64(f.SourceAddress))
+	b = binary.BigEndian.AppendUint16(b, uint16(f.SourceAddress16))
+	b = append(b, f.SourceEndpoint, f.DestinationEndpoint)
+	b = binary.BigEndian.AppendUint16(b, f.ClusterID)
+	b = binary.BigEndian.AppendUint16(b, f.ProfileID)
+	b = append(b, byte(f.ReceiveOptions))
+	return append(b, f.Data...), nil
+}
+
+func decodeExplicitReceivePacket(b []byte) (Frame, error) {
+	if err := checkLen(b, 18); err != nil {
+		return nil, err
+	}
+	return &ExplicitReceivePacket{
+		SourceAddress:       Addr64(binary.BigEndian.Uint64(b[1:])),
+		SourceAddress16:     Addr16(binary.BigEndian.Uint16(b[9:])),
+		SourceEndpoint:      b[11],
+		DestinationEndpoint: b[12],
+		ClusterID:           binary.BigEndian.Uint16(b[13:]),
+		ProfileID:           binary.BigEndian.Uint16(b[15:]),
+		ReceiveOptions:      ReceiveOption(b[17]),
+		Data:                b[18:],
+	}, nil
+}
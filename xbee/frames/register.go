@@ -0,0 +1,123 @@
+package frames
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// RegisterJoiningDeviceOption selects how the key of a RegisterJoiningDevice
+// request is interpreted.
+type RegisterJoiningDeviceOption byte
+
+const (
+	RJOLinkKey     RegisterJoiningDeviceOption = 0x00
+	RJOInstallCode RegisterJoiningDeviceOption = 0x01 // key is an install code followed by its CRC
+)
+
+type RegisterJoiningDeviceStatus byte
+
+const (
+	RJSuccess            RegisterJoiningDeviceStatus = 0x00
+	RJKeyTooLong         RegisterJoiningDeviceStatus = 0x01
+	RJAddressNotFound    RegisterJoiningDeviceStatus = 0xb1
+	RJInvalidKey         RegisterJoiningDeviceStatus = 0xb2
+	RJInvalidAddress     RegisterJoiningDeviceStatus = 0xb3
+	RJKeyTableFull       RegisterJoiningDeviceStatus = 0xb4
+	RJInvalidInstallCode RegisterJoiningDeviceStatus = 0xbd
+	RJKeyNotFound        RegisterJoiningDeviceStatus = 0xbe
+)
+
+func (s RegisterJoiningDeviceStatus) String() string {
+	switch s {
+	case RJSuccess:
+		return "Success"
+	case RJKeyTooLong:
+		return "KeyTooLong"
+	case RJAddressNotFound:
+		return "AddressNotFound"
+	case RJInvalidKey:
+		return "InvalidKey"
+	case RJInvalidAddress:
+		return "InvalidAddress"
+	case RJKeyTableFull:
+		return "KeyTableFull"
+	case RJInvalidInstallCode:
+		return "InvalidInstallCode"
+	case RJKeyNotFound:
+		return "KeyNotFound"
+	}
+	return fmt.Sprintf("RegisterJoiningDeviceStatus(%d)", s)
+}
+
+func (s RegisterJoiningDeviceStatus) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// RegisterJoiningDevice adds a device to the trust center's key table so
+// it's able to join a network using a link key or install code. An empty
+// key removes the device from the table.
+type RegisterJoiningDevice struct {
+	FrameID              byte
+	DestinationAddress   Addr64
+	DestinationAddress16 Addr16 // reserved, should be Address16Unknown
+	Options              RegisterJoiningDeviceOption
+	Key                  []byte
+}
+
+func (f *RegisterJoiningDevice) FrameType() byte {
+	return TypeRegisterJoiningDevice
+}
+
+func (f *RegisterJoiningDevice) ID() byte {
+	return f.FrameID
+}
+
+func (f *RegisterJoiningDevice) AppendData(b []byte) ([]byte, error) {
+	b = append(b, f.FrameID)
+	b = binary.BigEndian.AppendUint64(b, uint64(f.DestinationAddress))
+	b = binary.BigEndian.AppendUint16(b, uint16(f.DestinationAddress16))
+	b = append(b, byte(f.Options))
+	return append(b, f.Key...), nil
+}
+
+func decodeRegisterJoiningDevice(b []byte) (Frame, error) {
+	if err := checkLen(b, 13); err != nil {
+		return nil, err
+	}
+	return &RegisterJoiningDevice{
+		FrameID:              b[1],
+		DestinationAddress:   Addr64(binary.BigEndian.Uint64(b[2:])),
+		DestinationAddress16: Addr16(binary.BigEndian.Uint16(b[10:])),
+		Options:              RegisterJoiningDeviceOption(b[12]),
+		Key:                  b[13:],
+	}, nil
+}
+
+// RegisterJoiningDeviceResponse is the response to a RegisterJoiningDevice
+// request.
+type RegisterJoiningDeviceResponse struct {
+	FrameID byte
+	Status  RegisterJoiningDeviceStatus
+}
+
+func (f *RegisterJoiningDeviceResponse) FrameType() byte {
+	return TypeRegisterJoiningDeviceResponse
+}
+
+func (f *RegisterJoiningDeviceResponse) ID() byte {
+	return f.FrameID
+}
+
+func (f *RegisterJoiningDeviceResponse) AppendData(b []byte) ([]byte, error) {
+	return append(b, f.FrameID, byte(f.Status)), nil
+}
+
+func decodeRegisterJoiningDeviceResponse(b []byte) (Frame, error) {
+	if err := checkLen(b, 3); err != nil {
+		return nil, err
+	}
+	return &RegisterJoiningDeviceResponse{
+		FrameID: b[1],
+		Status:  RegisterJoiningDeviceStatus(b[2]),
+	}, nil
+}
@@ -0,0 +1,86 @@
+package frames
+
+import "fmt"
+
+// RelayInterface identifies a local interface on XBee3 modules that
+// User Data Relay frames are exchanged with.
+type RelayInterface byte
+
+const (
+	RelaySerial      RelayInterface = 0
+	RelayBLE         RelayInterface = 1
+	RelayMicroPython RelayInterface = 2
+)
+
+func (i RelayInterface) String() string {
+	switch i {
+	case RelaySerial:
+		return "Serial"
+	case RelayBLE:
+		return "BLE"
+	case RelayMicroPython:
+		return "MicroPython"
+	}
+	return fmt.Sprintf("RelayInterface(%d)", i)
+}
+
+func (i RelayInterface) MarshalText() ([]byte, error) {
+	return []byte(i.String()), nil
+}
+
+// UserDataRelay sends data to another interface on the local module.
+type UserDataRelay struct {
+	FrameID     byte
+	Destination RelayInterface
+	Data        []byte
+}
+
+func (f *UserDataRelay) FrameType() byte {
+	return TypeUserDataRelay
+}
+
+func (f *UserDataRelay) ID() byte {
+	return f.FrameID
+}
+
+func (f *UserDataRelay) AppendData(b []byte) ([]byte, error) {
+	b = append(b, f.FrameID, byte(f.Destination))
+	return append(b, f.Data...), nil
+}
+
+func decodeUserDataRelay(b []byte) (Frame, error) {
+	if err := checkLen(b, 3); err != nil {
+		return nil, err
+	}
+	return &UserDataRelay{
+		FrameID:     b[1],
+		Destination: RelayInterface(b[2]),
+		Data:        b[3:],
+	}, nil
+}
+
+// UserDataRelayOutput is data relayed from another interface on the
+// local module.
+type UserDataRelayOutput struct {
+	Source RelayInterface
+	Data   []byte
+}
+
+func (f *UserDataRelayOutput) FrameType() byte {
+	return TypeUserDataRelayOutput
+}
+
+func (f *UserDataRelayOutput) AppendData(b []byte) ([]byte, error) {
+	b = append(b, byte(f.Source))
+	return append(b, f.Data...), nil
+}
+
+func decodeUserDataRelayOutput(b []byte) (Frame, error) {
+	if err := checkLen(b, 2); err != nil {
+		return nil, err
+	}
+	return &UserDataRelayOutput{
+		Source: RelayInterface(b[1]),
+		Data:   b[2:],
+	}, nil
+}
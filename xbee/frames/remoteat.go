@@ -0,0 +1,136 @@
+package frames
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// RemoteATCommandOption is the options field of remote AT command requests.
+type RemoteATCommandOption byte
+
+const (
+	RATODisableACK RemoteATCommandOption = 0x01
+	// RATOApplyChanges applies changes on the remote device. If not set
+	// then the AC command must be sent to apply the changes.
+	RATOApplyChanges RemoteATCommandOption = 0x02
+	// RATOSecureSession sends the command over an established secure
+	// session (XBee3 only).
+	RATOSecureSession   RemoteATCommandOption = 0x10
+	RATOExtendedTimeout RemoteATCommandOption = 0x40
+)
+
+func (o RemoteATCommandOption) Has(opt RemoteATCommandOption) bool {
+	return (o & opt) != 0
+}
+
+func (o RemoteATCommandOption) String() string {
+	if o == 0 {
+		return "None"
+	}
+	var opts []string
+	if o.Has(RATODisableACK) {
+		opts = append(opts, "DisableACK")
+		o &^= RATODisableACK
+	}
+	if o.Has(RATOApplyChanges) {
+		opts = append(opts, "ApplyChanges")
+		o &^= RATOApplyChanges
+	}
+	if o.Has(RATOSecureSession) {
+		opts = append(opts, "SecureSession")
+		o &^= RATOSecureSession
+	}
+	if o.Has(RATOExtendedTimeout) {
+		opts = append(opts, "ExtendedTimeout")
+		o &^= RATOExtendedTimeout
+	}
+	if o != 0 {
+		opts = append(opts, fmt.Sprintf("RemoteATCommandOption(%d)", o))
+	}
+	return strings.Join(opts, "|")
+}
+
+func (o RemoteATCommandOption) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
+}
+
+// RemoteATCommandRequest queries or sets a register on a remote device.
+type RemoteATCommandRequest struct {
+	FrameID              byte
+	DestinationAddress   Addr64
+	DestinationAddress16 Addr16
+	Options              RemoteATCommandOption
+	ATCommand            ATCommand
+	Parameter            []byte
+}
+
+func (f *RemoteATCommandRequest) FrameType() byte {
+	return TypeRemoteATCommand
+}
+
+func (f *RemoteATCommandRequest) ID() byte {
+	return f.FrameID
+}
+
+func (f *RemoteATCommandRequest) AppendData(b []byte) ([]byte, error) {
+	b = append(b, f.FrameID)
+	b = binary.BigEndian.AppendUint64(b, uint64(f.DestinationAddress))
+	b = binary.BigEndian.AppendUint16(b, uint16(f.DestinationAddress16))
+	b = append(b, byte(f.Options), f.ATCommand[0], f.ATCommand[1])
+	return append(b, f.Parameter...), nil
+}
+
+func decodeRemoteATCommandRequest(b []byte) (Frame, error) {
+	if err := checkLen(b, 15); err != nil {
+		return nil, err
+	}
+	return &RemoteATCommandRequest{
+		FrameID:              b[1],
+		DestinationAddress:   Addr64(binary.BigEndian.Uint64(b[2:])),
+		DestinationAddress16: Addr16(binary.BigEndian.Uint16(b[10:])),
+		Options:              RemoteATCommandOption(b[12]),
+		ATCommand:            ATCommand{b[13], b[14]},
+		Parameter:            b[15:],
+	}, nil
+}
+
+// RemoteATCommandResponse is the response to a RemoteATCommandRequest.
+type RemoteATCommandResponse struct {
+	FrameID         byte
+	SourceAddress   Addr64
+	SourceAddress16 Addr16
+	ATCommand       ATCommand
+	CommandStatus   CommandStatus
+	Data            []byte
+}
+
+func (f *RemoteATCommandResponse) FrameType() byte {
+	return TypeRemoteATCommandResponse
+}
+
+func (f *RemoteATCommandResponse) ID() byte {
+	return f.FrameID
+}
+
+func (f *RemoteATCommandResponse) AppendData(b []byte) ([]byte, error) {
+	b = append(b, f.FrameID)
+	b = binary.BigEndian.AppendUint64(b, uint64(f.SourceAddress))
+	b = binary.BigEndian.AppendUint16(b, uint16(f.SourceAddress16))
+	b = append(b, f.ATCommand[0], f.ATCommand[1], byte(f.CommandStatus))
+	return append(b, f.Data...), nil
+}
+
+func decodeRemoteATCommandResponse(b []byte) (Frame, error) {
+	if err := checkLen(b, 15); err != nil {
+		return nil, err
+	}
+	return &RemoteATCommandResponse{
+		FrameID:         b[1],
+		SourceAddress:   Addr64(binary.BigEndian.Uint64(b[2:])),
+		SourceAddress16: Addr16(binary.BigEndian.Uint16(b[10:])),
+		ATCommand:       ATCommand{b[12], b[13]},
+		CommandStatus:   CommandStatus(b[14]),
+		Data:            b[15:],
+	}, nil
+}
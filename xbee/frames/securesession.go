@@ -0,0 +1,116 @@
+package frames
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Secure Session frames are used to log in to a password protected remote
+// XBee3 module (using SRP) before sending remote AT commands with the
+// RATOSecureSession option.
+
+type SecureSessionOption byte
+
+const (
+	// SSOLogout ends the session rather than starting one.
+	SSOLogout SecureSessionOption = 0x01
+	// SSOServerTermination has the remote module end the session when
+	// the timeout elapses instead of the local module.
+	SSOServerTermination SecureSessionOption = 0x02
+	// SSOFixedTimeout ends the session after the timeout instead of
+	// resetting the timeout with each secured command.
+	SSOFixedTimeout SecureSessionOption = 0x04
+)
+
+type SecureSessionStatus byte
+
+const (
+	SSSuccess         SecureSessionStatus = 0x00
+	SSInvalidPassword SecureSessionStatus = 0x01
+	SSTooManySessions SecureSessionStatus = 0x02
+	SSRequestTimeout  SecureSessionStatus = 0x03
+	SSInvalidOptions  SecureSessionStatus = 0x04
+)
+
+func (s SecureSessionStatus) String() string {
+	switch s {
+	case SSSuccess:
+		return "Success"
+	case SSInvalidPassword:
+		return "InvalidPassword"
+	case SSTooManySessions:
+		return "TooManySessions"
+	case SSRequestTimeout:
+		return "RequestTimeout"
+	case SSInvalidOptions:
+		return "InvalidOptions"
+	}
+	return fmt.Sprintf("SecureSessionStatus(%d)", s)
+}
+
+func (s SecureSessionStatus) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// SecureSessionControl starts or ends a secure session with a remote
+// module. The timeout is in units of 100ms.
+type SecureSessionControl struct {
+	DestinationAddress Addr64
+	Options            SecureSessionOption
+	Timeout            uint16
+	Password           []byte
+}
+
+func (f *SecureSessionControl) FrameType() byte {
+	return TypeSecureSessionControl
+}
+
+func (f *SecureSessionControl) AppendData(b []byte) ([]byte, error) {
+	b = binary.BigEndian.AppendUint64(b, uint64(f.DestinationAddress))
+	b = append(b, byte(f.Options))
+	b = binary.BigEndian.AppendUint16(b, f.Timeout)
+	return append(b, f.Password...), nil
+}
+
+func decodeSecureSessionControl(b []byte) (Frame, error) {
+	if err := checkLen(b, 12); err != nil {
+		return nil, err
+	}
+	return &SecureSessionControl{
+		DestinationAddress: Addr64(binary.BigEndian.Uint64(b[1:])),
+		Options:            SecureSessionOption(b[9]),
+		Timeout:            binary.BigEndian.Uint16(b[10:]),
+		Password:           b[12:],
+	}, nil
+}
+
+// SecureSessionResponse is the outcome of a SecureSessionControl request.
+type SecureSessionResponse struct {
+	Logout        bool // response to a logout rather than a login
+	SourceAddress Addr64
+	Status        SecureSessionStatus
+}
+
+func (f *SecureSessionResponse) FrameType() byte {
+	return TypeSecureSessionResponse
+}
+
+func (f *SecureSessionResponse) AppendData(b []byte) ([]byte, error) {
+	var typ byte
+	if f.Logout {
+		typ = 1
+	}
+	b = binary.BigEndian.AppendUint64(append(b, typ), uint64(f.SourceAddress))
+	return append(b, byte(f.Status)), nil
+}
+
+func decodeSecureSessionResponse(b []byte) (Frame, error) {
+	if err := checkLen(b, 11); err != nil {
+		return nil, err
+	}
+	return &SecureSessionResponse{
+		Logout:        b[1] != 0,
+		SourceAddress: Addr64(binary.BigEndian.Uint64(b[2:])),
+		Status:        SecureSessionStatus(b[10]),
+	}, nil
+}
@@ -0,0 +1,96 @@
+package frames
+
+import (
+	"bytes"
+	"errors"
+)
+
+// Frames used by XBee Cellular modules for SMS.
+
+// phoneNumberLen is the size of the null padded phone number field.
+const phoneNumberLen = 20
+
+var errPhoneNumberTooLong = errors.New("frames: phone number longer than 20 characters")
+
+func appendPhoneNumber(b []byte, num string) ([]byte, error) {
+	if len(num) > phoneNumberLen {
+		return nil, errPhoneNumberTooLong
+	}
+	b = append(b, num...)
+	for i := len(num); i < phoneNumberLen; i++ {
+		b = append(b, 0)
+	}
+	return b, nil
+}
+
+func phoneNumber(b []byte) string {
+	b = b[:phoneNumberLen]
+	if ix := bytes.IndexByte(b, 0); ix >= 0 {
+		b = b[:ix]
+	}
+	return string(b)
+}
+
+// SMSTransmitRequest sends an SMS message.
+type SMSTransmitRequest struct {
+	FrameID     byte
+	Options     byte // reserved
+	PhoneNumber string
+	Data        []byte
+}
+
+func (f *SMSTransmitRequest) FrameType() byte {
+	return TypeSMSTransmitRequest
+}
+
+func (f *SMSTransmitRequest) ID() byte {
+	return f.FrameID
+}
+
+func (f *SMSTransmitRequest) AppendData(b []byte) ([]byte, error) {
+	b, err := appendPhoneNumber(append(b, f.FrameID, f.Options), f.PhoneNumber)
+	if err != nil {
+		return nil, err
+	}
+	return append(b, f.Data...), nil
+}
+
+func decodeSMSTransmitRequest(b []byte) (Frame, error) {
+	if err := checkLen(b, 3+phoneNumberLen); err != nil {
+		return nil, err
+	}
+	return &SMSTransmitRequest{
+		FrameID:     b[1],
+		Options:     b[2],
+		PhoneNumber: phoneNumber(b[3:]),
+		Data:        b[3+phoneNumberLen:],
+	}, nil
+}
+
+// SMSReceivePacket is a received SMS message.
+type SMSReceivePacket struct {
+	PhoneNumber string
+	Data        []byte
+}
+
+func (f *SMSReceivePacket) FrameType() byte {
+	return TypeSMSReceivePacket
+}
+
+func (f *SMSReceivePacket) AppendData(b []byte) ([]byte, error) {
+	b, err := appendPhoneNumber(b, f.PhoneNumber)
+	if err != nil {
+		return nil, err
+	}
+	return append(b, f.Data...), nil
+}
+
+func decodeSMSReceivePacket(b []byte) (Frame, error) {
+	if err := checkLen(b, 1+phoneNumberLen); err != nil {
+		return nil, err
+	}
+	return &SMSReceivePacket{
+		PhoneNumber: phoneNumber(b[1:]),
+		Data:        b[1+phoneNumberLen:],
+	}, nil
+}
@@ -0,0 +1,151 @@
+package frames
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// A tap file starts with tapMagic followed by records made up of a
+// big-endian 64-bit timestamp (Unix nanoseconds), the direction, a
+// big-endian 16-bit length, and the frame data (frame type and frame
+// specific fields).
+const (
+	tapMagic     = "XBEETAP1"
+	tapHeaderLen = 8 + 1 + 2
+)
+
+var ErrBadTap = errors.New("frames: not a tap file")
+
+// Direction is the direction of a recorded frame relative to the host.
+type Direction byte
+
+const (
+	Sent     Direction = 'S'
+	Received Direction = 'R'
+)
+
+func (d Direction) String() string {
+	switch d {
+	case Sent:
+		return "Sent"
+	case Received:
+		return "Received"
+	}
+	return fmt.Sprintf("Direction(%d)", byte(d))
+}
+
+func (d Direction) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// Record is a frame read from a tap file.
+type Record struct {
+	Time      time.Time
+	Direction Direction
+	Data      []byte
+}
+
+// TapWriter records frames to a file. It's safe for concurrent use.
+type TapWriter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	buf    []byte
+	header bool
+}
+
+func NewTapWriter(w io.Writer) *TapWriter {
+	return &TapWriter{w: w}
+}
+
+// NewAppendTapWriter returns a TapWriter appending records to an existing
+// tap file which already starts with the header.
+func NewAppendTapWriter(w io.Writer) *TapWriter {
+	return &TapWriter{w: w, header: true}
+}
+
+// WriteRecord records frame data sent or received at the current time.
+func (t *TapWriter) WriteRecord(dir Direction, data []byte) error {
+	ts := time.Now()
+	if len(data) > MaxDataLength {
+		return ErrTooLong
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	b := t.buf[:0]
+	if !t.header {
+		b = append(b, tapMagic...)
+	}
+	b = binary.BigEndian.AppendUint64(b, uint64(ts.UnixNano()))
+	b = append(b, byte(dir))
+	b = binary.BigEndian.AppendUint16(b, uint16(len(data)))
+	b = append(b, data...)
+	t.buf = b
+	if _, err := t.w.Write(b); err != nil {
+		return err
+	}
+	t.header = true
+	return nil
+}
+
+// TapReader reads records from a tap file.
+type TapReader struct {
+	rd     *bufio.Reader
+	header bool
+}
+
+func NewTapReader(r io.Reader) *TapReader {
+	return &TapReader{rd: bufio.NewReader(r)}
+}
+
+// ReadRecord returns the next record or io.EOF at the end of the file.
+func (t *TapReader) ReadRecord() (*Record, error) {
+	if !t.header {
+		var magic [len(tapMagic)]byte
+		if _, err := io.ReadFull(t.rd, magic[:]); err == io.EOF {
+			return nil, io.EOF
+		} else if err != nil || string(magic[:]) != tapMagic {
+			return nil, ErrBadTap
+		}
+		t.header = true
+	}
+	var hdr [tapHeaderLen]byte
+	if _, err := io.ReadFull(t.rd, hdr[:]); err != nil {
+		return nil, err
+	}
+	data := make([]byte, binary.BigEndian.Uint16(hdr[9:]))
+	if _, err := io.ReadFull(t.rd, data); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return &Record{
+		Time:      time.Unix(0, int64(binary.BigEndian.Uint64(hdr[:]))),
+		Direction: Direction(hdr[8]),
+		Data:      data,
+	}, nil
+}
+
+// Replay decodes every record in a tap file calling fn with the record
+// and the decoded frame (or decoding error). Replay stops at the end of
+// the file or when fn returns an error.
+func Replay(r io.Reader, fn func(rec *Record, f Frame, err error) error) error {
+	tr := NewTapReader(r)
+	for {
+		rec, err := tr.ReadRecord()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		f, err := Decode(rec.Data)
+		if err := fn(rec, f, err); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package frames
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+type TransmitOption byte
+
+const (
+	TODisableRetriesAndRouteRepair TransmitOption = 0x01
+	TOMulticast                    TransmitOption = 0x08 // explicit addressing only, 16-bit destination is the group ID
+	TOEnableAPSEncryption          TransmitOption = 0x20
+	TOExtendedTxTimeout            TransmitOption = 0x40
+)
+
+func (o TransmitOption) Has(opt TransmitOption) bool {
+	return (o & opt) != 0
+}
+
+func (o TransmitOption) String() string {
+	if o == 0 {
+		return "None"
+	}
+	var opts []string
+	if o.Has(TODisableRetriesAndRouteRepair) {
+		opts = append(opts, "DisableRetriesAndRouteRepair")
+		o &^= TODisableRetriesAndRouteRepair
+	}
+	if o.Has(TOMulticast) {
+		opts = append(opts, "Multicast")
+		o &^= TOMulticast
+	}
+	if o.Has(TOEnableAPSEncryption) {
+		opts = append(opts, "EnableAPSEncryption")
+		o &^= TOEnableAPSEncryption
+	}
+	if o.Has(TOExtendedTxTimeout) {
+		opts = append(opts, "ExtendedTxTimeout")
+		o &^= TOExtendedTxTimeout
+	}
+	if o != 0 {
+		opts = append(opts, fmt.Sprintf("TransmitOption(%d)", o))
+	}
+	return strings.Join(opts, "|")
+}
+
+func (o TransmitOption) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
+}
+
+type DeliveryStatus byte
+
+const (
+	DSSuccess                               DeliveryStatus = 0x00
+	DSMACACKFailure                         DeliveryStatus = 0x01
+	DSCCAFailure                            DeliveryStatus = 0x02
+	DSInvalidDestinationEndpoint            DeliveryStatus = 0x15
+	DSNetworkACKFailure                     DeliveryStatus = 0x21
+	DSNotJoinedToNetwork                    DeliveryStatus = 0x22
+	DSSelfAddressed                         DeliveryStatus = 0x23
+	DSAddressNotFound                       DeliveryStatus = 0x24
+	DSRouteNotFound                         DeliveryStatus = 0x25
+	DSBroadcastFail                         DeliveryStatus = 0x26 // Broadcast source failed to hear a neighbor relay the message
+	DSInvalidBindingTableIndex              DeliveryStatus = 0x2B
+	DSResourceError                         DeliveryStatus = 0x2C // Resource error lack of free buffers, timers, and so forth.
+	DSAttemptedBroadcastWithAPSTransmittion DeliveryStatus = 0x2D
+	DSAttemptedUnicastWithAPSTransmission   DeliveryStatus = 0x2E // Attempted unicast with APS transmission, but EE=0
+	DSResourceError2                        DeliveryStatus = 0x32 // Resource error lack of free buffers, timers, and so
+	DSDataPayloadTooLarge                   DeliveryStatus = 0x74
+)
+
+func (ds DeliveryStatus) String() string {
+	switch ds {
+	case DSSuccess:
+		return "Success"
+	case DSMACACKFailure:
+		return "MACACKFailure"
+	case DSCCAFailure:
+		return "CCAFailure"
+	case DSInvalidDestinationEndpoint:
+		return "InvalidDestinationEndpoint"
+	case DSNetworkACKFailure:
+		return "NetworkACKFailure"
+	case DSNotJoinedToNetwork:
+		return "NotJoinedToNetwork"
+	case DSSelfAddressed:
+		return "SelfAddressed"
+	case DSAddressNotFound:
+		return "AddressNotFound"
+	case DSRouteNotFound:
+		return "RouteNotFound"
+	case DSBroadcastFail:
+		return "BroadcastFail"
+	case DSInvalidBindingTableIndex:
+		return "InvalidBindingTableIndex"
+	case DSResourceError:
+		return "ResourceError"
+	case DSAttemptedBroadcastWithAPSTransmittion:
+		return "AttemptedBroadcastWithAPSTransmittion"
+	case DSAttemptedUnicastWithAPSTransmission:
+		return "AttemptedUnicastWithAPSTransmission"
+	case DSResourceError2:
+		return "ResourceError2"
+	case DSDataPayloadTooLarge:
+		return "DataPayloadTooLarge"
+	}
+	return fmt.Sprintf("DeliveryStatus(%d)", ds)
+}
+
+func (ds DeliveryStatus) MarshalText() ([]byte, error) {
+	return []byte(ds.String()), nil
+}
+
+type DiscoveryStatus byte
+
+const (
+	DSNoDiscoveryOverhead      DiscoveryStatus = 0x00
+	DSAddressDiscovery         DiscoveryStatus = 0x01
+	DSRouteDiscovery           DiscoveryStatus = 0x02
+	DSAddressAndRoute          DiscoveryStatus = 0x03
+	DSExtendedTimeoutDiscovery DiscoveryStatus = 0x40
+)
+
+func (ds DiscoveryStatus) String() string {
+	switch ds {
+	case DSNoDiscoveryOverhead:
+		return "NoDiscoveryOverhead"
+	case DSAddressDiscovery:
+		return "AddressDiscovery"
+	case DSRouteDiscovery:
+		return "RouteDiscovery"
+	case DSAddressAndRoute:
+		return "AddressAndRoute"
+	case DSExtendedTimeoutDiscovery:
+		return "ExtendedTimeoutDiscovery"
+	}
+	return fmt.Sprintf("DiscoveryStatus(%d)", ds)
+}
+
+func (ds DiscoveryStatus) MarshalText() ([]byte, error) {
+	return []byte(ds.String()), nil
+}
+
+// TransmitRequest sends data to a remote device.
+type TransmitRequest struct {
+	FrameID              byte
+	DestinationAddress   Addr64
+	DestinationAddress16 Addr16
+	BroadcastRadius      byte // 0 for the maximum number of hops
+	Options              TransmitOption
+	Data                 []byte
+}
+
+func (f *TransmitRequest) FrameType() byte {
+	return TypeTransmitRequest
+}
+
+func (f *TransmitRequest) ID() byte {
+	return f.FrameID
+}
+
+func (f *TransmitRequest) AppendData(b []byte) ([]byte, error) {
+	b = append(b, f.FrameID)
+	b = binary.BigEndian.AppendUint64(b, uint64(f.DestinationAddress))
+	b = binary.BigEndian.AppendUint16(b, uint16(f.DestinationAddress16))
+	b = append(b, f.BroadcastRadius, byte(f.Options))
+	return append(b, f.Data...), nil
+}
+
+func decodeTransmitRequest(b []byte) (Frame, error) {
+	if err := checkLen(b, 14); err != nil {
+		return nil, err
+	}
+	return &TransmitRequest{
+		FrameID:              b[1],
+		DestinationAddress:   Addr64(binary.BigEndian.Uint64(b[2:])),
+		DestinationAddress16: Addr16(binary.BigEndian.Uint16(b[10:])),
+		BroadcastRadius:      b[12],
+		Options:              TransmitOption(b[13]),
+		Data:                 b[14:],
+	}, nil
+}
+
+// ExplicitTransmitRequest sends data to a remote device with application
+// layer addressing (endpoints, cluster, and profile).
+type ExplicitTransmitRequest struct {
+	FrameID              byte
+	DestinationAddress   Addr64
+	DestinationAddress16 Addr16
+	SourceEndpoint       byte
+	DestinationEndpoint  byte
+	ClusterID            uint16
+	ProfileID            uint16
+	BroadcastRadius      byte // 0 for the maximum number of hops
+	Options              TransmitOption
+	Data                 []byte
+}
+
+func (f *ExplicitTransmitRequest) FrameType() byte {
+	return TypeExplicitTransmit
+}
+
+func (f *ExplicitTransmitRequest) ID() byte {
+	return f.FrameID
+}
+
+func (f *ExplicitTransmitRequest) AppendData(b []byte) ([]byte, error) {
+	b = append(b, f.FrameID)
+	b = binary.BigEndian.AppendUint64(b, uint64(f.DestinationAddress))
+	b = binary.BigEndian.AppendUint16(b, uint16(f.DestinationAddress16))
+	b = append(b, f.SourceEndpoint, f.DestinationEndpoint)
+	b = binary.BigEndian.AppendUint16(b, f.ClusterID)
+	b = binary.BigEndian.AppendUint16(b, f.ProfileID)
+	b = append(b, f.BroadcastRadius, byte(f.Options))
+	return append(b, f.Data...), nil
+}
+
+func decodeExplicitTransmitRequest(b []byte) (Frame, error) {
+	if err := checkLen(b, 20); err != nil {
+		return nil, err
+	}
+	return &ExplicitTransmitRequest{
+		FrameID:              b[1],
+		DestinationAddress:   Addr64(binary.BigEndian.Uint64(b[2:])),
+		DestinationAddress16: Addr16(binary.BigEndian.Uint16(b[10:])),
+		SourceEndpoint:       b[12],
+		DestinationEndpoint:  b[13],
+		ClusterID:            binary.BigEndian.Uint16(b[14:]),
+		ProfileID:            binary.BigEndian.Uint16(b[16:]),
+		BroadcastRadius:      b[18],
+		Options:              TransmitOption(b[19]),
+		Data:                 b[20:],
+	}, nil
+}
+
+// TransmitStatus reports the outcome of a transmit request.
+type TransmitStatus struct {
+	FrameID            byte
+	DestinationAddress Addr16
+	RetryCount         int
+	DeliveryStatus     DeliveryStatus
+	DiscoveryStatus    DiscoveryStatus
+}
+
+func (f *TransmitStatus) FrameType() byte {
+	return TypeTransmitStatus
+}
+
+func (f *TransmitStatus) ID() byte {
+	return f.FrameID
+}
+
+func (f *TransmitStatus) AppendData(b []byte) ([]byte, error) {
+	b = append(b, f.FrameID)
+	b = binary.BigEndian.AppendUint16(b, uint16(f.DestinationAddress))
+	return append(b, byte(f.RetryCount), byte(f.DeliveryStatus), byte(f.DiscoveryStatus)), nil
+}
+
+func decodeTransmitStatus(b []byte) (Frame, error) {
+	if err := checkLen(b, 7); err != nil {
+		return nil, err
+	}
+	return &TransmitStatus{
+		FrameID:            b[1],
+		DestinationAddress: Addr16(binary.BigEndian.Uint16(b[2:])),
+		RetryCount:         int(b[4]),
+		DeliveryStatus:     DeliveryStatus(b[5]),
+		DiscoveryStatus:    DiscoveryStatus(b[6]),
+	}, nil
+}
@@ -0,0 +1,188 @@
+package xbee
+
+import "github.com/samuel/go-xbee/xbee/frames"
+
+// The frame types and values are defined in the frames package. They're
+// aliased here so the events delivered by XBee can be used without
+// importing it.
+
+type (
+	Addr64                      = frames.Addr64
+	Addr16                      = frames.Addr16
+	ATCommand                   = frames.ATCommand
+	CommandStatus               = frames.CommandStatus
+	ModemStatus                 = frames.ModemStatus
+	DeliveryStatus              = frames.DeliveryStatus
+	DiscoveryStatus             = frames.DiscoveryStatus
+	TransmitOption              = frames.TransmitOption
+	ReceiveOption               = frames.ReceiveOption
+	ATCommandResponse           = frames.ATCommandResponse
+	TransmitStatus              = frames.TransmitStatus
+	ReceivePacket               = frames.ReceivePacket
+	ExplicitReceivePacket       = frames.ExplicitReceivePacket
+	IODataSampleIndicator       = frames.IODataSampleIndicator
+	NodeIdentificationIndicator = frames.NodeIdentificationIndicator
+	NodeIDEvent                 = frames.NodeIDEvent
+	UnknownFrame                = frames.UnknownFrame
+	ShortFrameError             = frames.ShortFrameError
+	IPProtocol                  = frames.IPProtocol
+	IPTransmitOption            = frames.IPTransmitOption
+	IPv4ReceivePacket           = frames.IPv4ReceivePacket
+	IPTransmitStatus            = frames.IPTransmitStatus
+	IPRemoteATCommandResponse   = frames.IPRemoteATCommandResponse
+	RemoteATCommandOption       = frames.RemoteATCommandOption
+	SMSReceivePacket            = frames.SMSReceivePacket
+	RelayInterface              = frames.RelayInterface
+	UserDataRelayOutput         = frames.UserDataRelayOutput
+	RemoteATCommandResponse     = frames.RemoteATCommandResponse
+	SecureSessionOption         = frames.SecureSessionOption
+	SecureSessionStatus         = frames.SecureSessionStatus
+	SecureSessionResponse       = frames.SecureSessionResponse
+	RegisterJoiningDeviceOption = frames.RegisterJoiningDeviceOption
+	RegisterJoiningDeviceStatus = frames.RegisterJoiningDeviceStatus
+	FileSystemCommand           = frames.FileSystemCommand
+	FileSystemStatus            = frames.FileSystemStatus
+	Frame                       = frames.Frame
+	DecodeFunc                  = frames.DecodeFunc
+)
+
+const (
+	AddressCoordinator         = frames.AddressCoordinator
+	AddressBroadcast           = frames.AddressBroadcast
+	AddressUnknown             = frames.AddressUnknown
+	Address16Unknown           = frames.Address16Unknown
+	Address16Broadcast         = frames.Address16Broadcast
+	Address16BroadcastRxOnIdle = frames.Address16BroadcastRxOnIdle
+	Address16BroadcastRouters  = frames.Address16BroadcastRouters
+)
+
+const (
+	CSOK               = frames.CSOK
+	CSError            = frames.CSError
+	CSInvalidCommand   = frames.CSInvalidCommand
+	CSInvalidParameter = frames.CSInvalidParameter
+	CSTxFailure        = frames.CSTxFailure
+)
+
+const (
+	MSHardwareReset              = frames.MSHardwareReset
+	MSWatchdogTimerReset         = frames.MSWatchdogTimerReset
+	MSJoinedNetwork              = frames.MSJoinedNetwork
+	MSDisassociated              = frames.MSDisassociated
+	MSCoordinatorStarted         = frames.MSCoordinatorStarted
+	MSNetworkKeyUpdated          = frames.MSNetworkKeyUpdated
+	MSVoltageSupplyLimitExceeded = frames.MSVoltageSupplyLimitExceeded
+	MSConfigChangeDuringJoin     = frames.MSConfigChangeDuringJoin
+)
+
+const (
+	DSSuccess                               = frames.DSSuccess
+	DSMACACKFailure                         = frames.DSMACACKFailure
+	DSCCAFailure                            = frames.DSCCAFailure
+	DSInvalidDestinationEndpoint            = frames.DSInvalidDestinationEndpoint
+	DSNetworkACKFailure                     = frames.DSNetworkACKFailure
+	DSNotJoinedToNetwork                    = frames.DSNotJoinedToNetwork
+	DSSelfAddressed                         = frames.DSSelfAddressed
+	DSAddressNotFound                       = frames.DSAddressNotFound
+	DSRouteNotFound                         = frames.DSRouteNotFound
+	DSBroadcastFail                         = frames.DSBroadcastFail
+	DSInvalidBindingTableIndex              = frames.DSInvalidBindingTableIndex
+	DSResourceError                         = frames.DSResourceError
+	DSAttemptedBroadcastWithAPSTransmittion = frames.DSAttemptedBroadcastWithAPSTransmittion
+	DSAttemptedUnicastWithAPSTransmission   = frames.DSAttemptedUnicastWithAPSTransmission
+	DSResourceError2                        = frames.DSResourceError2
+	DSDataPayloadTooLarge                   = frames.DSDataPayloadTooLarge
+)
+
+const (
+	DSNoDiscoveryOverhead      = frames.DSNoDiscoveryOverhead
+	DSAddressDiscovery         = frames.DSAddressDiscovery
+	DSRouteDiscovery           = frames.DSRouteDiscovery
+	DSAddressAndRoute          = frames.DSAddressAndRoute
+	DSExtendedTimeoutDiscovery = frames.DSExtendedTimeoutDiscovery
+)
+
+const (
+	TODisableRetriesAndRouteRepair = frames.TODisableRetriesAndRouteRepair
+	TOMulticast                    = frames.TOMulticast
+	TOEnableAPSEncryption          = frames.TOEnableAPSEncryption
+	TOExtendedTxTimeout            = frames.TOExtendedTxTimeout
+)
+
+const (
+	ROAcknowledged  = frames.ROAcknowledged
+	ROBroadcast     = frames.ROBroadcast
+	ROEncrypted     = frames.ROEncrypted
+	ROFromEndDevice = frames.ROFromEndDevice
+)
+
+const (
+	NIEPushbutton = frames.NIEPushbutton
+	NIEJoined     = frames.NIEJoined
+	NIEPowerCycle = frames.NIEPowerCycle
+)
+
+const (
+	IPProtocolUDP   = frames.IPProtocolUDP
+	IPProtocolTCP   = frames.IPProtocolTCP
+	IPProtocolSSL   = frames.IPProtocolSSL
+	IPTOCloseSocket = frames.IPTOCloseSocket
+)
+
+const (
+	RATODisableACK      = frames.RATODisableACK
+	RATOApplyChanges    = frames.RATOApplyChanges
+	RATOSecureSession   = frames.RATOSecureSession
+	RATOExtendedTimeout = frames.RATOExtendedTimeout
+)
+
+const (
+	SSOLogout            = frames.SSOLogout
+	SSOServerTermination = frames.SSOServerTermination
+	SSOFixedTimeout      = frames.SSOFixedTimeout
+)
+
+const (
+	SSSuccess         = frames.SSSuccess
+	SSInvalidPassword = frames.SSInvalidPassword
+	SSTooManySessions = frames.SSTooManySessions
+	SSRequestTimeout  = frames.SSRequestTimeout
+	SSInvalidOptions  = frames.SSInvalidOptions
+)
+
+const (
+	RelaySerial      = frames.RelaySerial
+	RelayBLE         = frames.RelayBLE
+	RelayMicroPython = frames.RelayMicroPython
+)
+
+const (
+	RJOLinkKey     = frames.RJOLinkKey
+	RJOInstallCode = frames.RJOInstallCode
+)
+
+const (
+	RJSuccess            = frames.RJSuccess
+	RJKeyTooLong         = frames.RJKeyTooLong
+	RJAddressNotFound    = frames.RJAddressNotFound
+	RJInvalidKey         = frames.RJInvalidKey
+	RJInvalidAddress     = frames.RJInvalidAddress
+	RJKeyTableFull       = frames.RJKeyTableFull
+	RJInvalidInstallCode = frames.RJInvalidInstallCode
+	RJKeyNotFound        = frames.RJKeyNotFound
+)
+
+func ParseAddr64(s string) (Addr64, error) {
+	return frames.ParseAddr64(s)
+}
+
+func ParseAddr16(s string) (Addr16, error) {
+	return frames.ParseAddr16(s)
+}
+
+// RegisterFrameType registers a decoder for a frame type. Frames of the
+// type are delivered as events (or responses if they're identified)
+// instead of UnknownFrame. See frames.RegisterFrameType.
+func RegisterFrameType(typ byte, fn DecodeFunc) {
+	frames.RegisterFrameType(typ, fn)
+}
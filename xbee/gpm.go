@@ -0,0 +1,189 @@
+package xbee
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// GPM (general purpose memory) commands are sent to the Digi device
+// endpoint. A response has the high bit of the command set.
+const (
+	EndpointDigiDevice byte   = 0xE6
+	ClusterGPM         uint16 = 0x0023
+
+	gpmPlatformInfo      byte = 0x00
+	gpmErase             byte = 0x01
+	gpmWrite             byte = 0x02
+	gpmFirmwareVerify    byte = 0x05
+	gpmVerifyAndInstall  byte = 0x06
+	gpmResponse          byte = 0x80
+	gpmOptionError       byte = 0x01
+	gpmHeaderLen              = 8
+	gpmDefaultChunkSize       = 64
+	gpmMaxDataPayloadLen      = 84 - gpmHeaderLen
+)
+
+var gpmAddress = ExplicitAddress{
+	SourceEndpoint:      EndpointDigiDevice,
+	DestinationEndpoint: EndpointDigiDevice,
+	ClusterID:           ClusterGPM,
+	ProfileID:           ProfileDigi,
+}
+
+// GPMError is returned when a remote module reports a GPM command failed.
+type GPMError struct {
+	Command byte
+	Block   uint16
+	Index   uint16
+}
+
+func (e *GPMError) Error() string {
+	return fmt.Sprintf("xbee: GPM command 0x%02x failed (block %d, index %d)", e.Command, e.Block, e.Index)
+}
+
+// GPMPlatformInfo describes the flash of a remote module.
+type GPMPlatformInfo struct {
+	Blocks        int
+	BytesPerBlock int
+}
+
+type gpmResult struct {
+	block uint16
+	index uint16
+	data  []byte
+}
+
+// gpmCommand sends a GPM command to dest and waits for the response. It's
+// retried if no response is received.
+func (xb *XBee) gpmCommand(ctx context.Context, dest Addr64, cmd byte, block, index, count uint16, data []byte) (*gpmResult, error) {
+	req := []byte{cmd, 0}
+	req = binary.BigEndian.AppendUint16(req, block)
+	req = binary.BigEndian.AppendUint16(req, index)
+	req = binary.BigEndian.AppendUint16(req, count)
+	req = append(req, data...)
+
+	m := xb.explicitMatcher(dest, ClusterGPM, ProfileDigi)
+	defer xb.unregisterMatcher(m)
+
+	var err error
+	for i := 0; i < firmwareRetries; i++ {
+		if err = xb.transmitExplicit(PriorityBulk, dest, Address16Unknown, gpmAddress, 0, 0, req); err != nil {
+			return nil, err
+		}
+		for {
+			var rx *ExplicitReceivePacket
+			rx, err = waitExplicit(ctx, m, firmwareTimeout)
+			if err != nil {
+				break
+			}
+			if len(rx.Data) < gpmHeaderLen || rx.Data[0] != cmd|gpmResponse {
+				// Stale response to an earlier attempt or a different command.
+				continue
+			}
+			res := &gpmResult{
+				block: binary.BigEndian.Uint16(rx.Data[2:]),
+				index: binary.BigEndian.Uint16(rx.Data[4:]),
+				data:  rx.Data[gpmHeaderLen:],
+			}
+			if rx.Data[1]&gpmOptionError != 0 {
+				return nil, &GPMError{Command: cmd, Block: res.block, Index: res.index}
+			}
+			return res, nil
+		}
+		if !errors.Is(err, ErrTimeout) {
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+// GPMPlatformInfo returns the size of the flash available for firmware
+// images on a remote module.
+func (xb *XBee) GPMPlatformInfo(ctx context.Context, dest Addr64) (*GPMPlatformInfo, error) {
+	res, err := xb.gpmCommand(ctx, dest, gpmPlatformInfo, 0, 0, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &GPMPlatformInfo{Blocks: int(res.block), BytesPerBlock: int(res.index)}, nil
+}
+
+// GPMUpdate configures a firmware update using GPM.
+type GPMUpdate struct {
+	// Image is the contents of the .ebl firmware file.
+	Image []byte
+	// Offset is the number of bytes of Image already written to the
+	// module (as last reported by Progress). When non-zero the flash
+	// isn't erased and the transfer resumes from the offset.
+	Offset int
+	// ChunkSize is the number of bytes written per request. It defaults
+	// to 64 and is limited by the maximum RF payload.
+	ChunkSize int
+	// Progress is called as the update progresses (optional).
+	Progress func(FirmwareProgress)
+}
+
+// UpdateFirmwareGPM writes a firmware image to the flash of a remote
+// module, verifies it, and tells the module to install it. The module
+// reboots once the new firmware is installed. If the update fails part way
+// through it can be resumed by setting Offset to the last reported offset.
+func (xb *XBee) UpdateFirmwareGPM(ctx context.Context, dest Addr64, u *GPMUpdate) error {
+	progress := func(state FirmwareUpdateState, offset int) {
+		if u.Progress != nil {
+			u.Progress(FirmwareProgress{State: state, Offset: offset, Total: len(u.Image)})
+		}
+	}
+	chunk := u.ChunkSize
+	if chunk <= 0 {
+		chunk = gpmDefaultChunkSize
+	} else if chunk > gpmMaxDataPayloadLen {
+		chunk = gpmMaxDataPayloadLen
+	}
+
+	info, err := xb.GPMPlatformInfo(ctx, dest)
+	if err != nil {
+		return err
+	}
+	if info.BytesPerBlock <= 0 {
+		return fmt.Errorf("xbee.UpdateFirmwareGPM: %w: invalid block size %d", ErrInvalidParameter, info.BytesPerBlock)
+	}
+	if len(u.Image) > info.Blocks*info.BytesPerBlock {
+		return fmt.Errorf("xbee.UpdateFirmwareGPM: %w: image size %d exceeds flash size %d", ErrInvalidParameter, len(u.Image), info.Blocks*info.BytesPerBlock)
+	}
+
+	offset := u.Offset
+	if offset < 0 || offset > len(u.Image) {
+		return fmt.Errorf("xbee.UpdateFirmwareGPM: %w: invalid offset %d", ErrInvalidParameter, offset)
+	}
+	if offset == 0 {
+		progress(FirmwareErasing, 0)
+		// A byte count of 0 erases all blocks.
+		if _, err := xb.gpmCommand(ctx, dest, gpmErase, 0, 0, 0, nil); err != nil {
+			return err
+		}
+	}
+
+	for offset < len(u.Image) {
+		progress(FirmwareTransferring, offset)
+		block := offset / info.BytesPerBlock
+		index := offset % info.BytesPerBlock
+		// Writes can't cross a block boundary.
+		n := min(chunk, info.BytesPerBlock-index, len(u.Image)-offset)
+		if _, err := xb.gpmCommand(ctx, dest, gpmWrite, uint16(block), uint16(index), uint16(n), u.Image[offset:offset+n]); err != nil {
+			return err
+		}
+		offset += n
+	}
+
+	progress(FirmwareVerifying, offset)
+	if _, err := xb.gpmCommand(ctx, dest, gpmFirmwareVerify, 0, 0, 0, nil); err != nil {
+		return err
+	}
+	progress(FirmwareInstalling, offset)
+	if _, err := xb.gpmCommand(ctx, dest, gpmVerifyAndInstall, 0, 0, 0, nil); err != nil {
+		return err
+	}
+	progress(FirmwareDone, offset)
+	return nil
+}
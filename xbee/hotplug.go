@@ -0,0 +1,183 @@
+package xbee
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+type DeviceEventType int
+
+const (
+	// DeviceConnected is sent when the device was opened. The event holds
+	// the new XBee.
+	DeviceConnected DeviceEventType = iota
+	// DeviceDisconnected is sent when the device node disappeared. The
+	// XBee from the previous DeviceConnected event must no longer be used.
+	DeviceDisconnected
+	// DeviceOpenFailed is sent when the device node exists but opening it
+	// failed. It's retried on the next change or poll.
+	DeviceOpenFailed
+)
+
+func (t DeviceEventType) String() string {
+	switch t {
+	case DeviceConnected:
+		return "Connected"
+	case DeviceDisconnected:
+		return "Disconnected"
+	case DeviceOpenFailed:
+		return "OpenFailed"
+	}
+	return fmt.Sprintf("DeviceEventType(%d)", int(t))
+}
+
+func (t DeviceEventType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// DeviceEvent reports a change in the state of a watched device.
+type DeviceEvent struct {
+	Type  DeviceEventType
+	Path  string
+	XBee  *XBee // for DeviceConnected
+	Error error // for DeviceOpenFailed
+}
+
+const (
+	// How long to wait after a device change notification for the
+	// device node to be created.
+	hotplugSettle = 500 * time.Millisecond
+)
+
+// DeviceWatcher keeps a module on a USB serial device open across the
+// adapter being unplugged or re-enumerated. On Linux it listens for
+// kernel device notifications, elsewhere it polls for the device node.
+type DeviceWatcher struct {
+	path   string
+	baud   int
+	opts   []Option
+	events chan DeviceEvent
+	stop   chan struct{}
+	done   chan struct{}
+
+	mu   sync.Mutex // protects xb and port
+	xb   *XBee
+	port io.Closer
+}
+
+// WatchDevice opens the module at path (if present) and reopens it
+// whenever the device reappears. Events must be read from Events to
+// avoid missing state changes.
+func WatchDevice(path string, baud int, opts ...Option) (*DeviceWatcher, error) {
+	notify, closeNotify, err := deviceNotifier()
+	if err != nil {
+		return nil, err
+	}
+	w := &DeviceWatcher{
+		path:   path,
+		baud:   baud,
+		opts:   opts,
+		events: make(chan DeviceEvent, 8),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go w.run(notify, closeNotify)
+	return w, nil
+}
+
+// Events returns the channel on which device events are delivered. It's
+// closed when the watcher is closed.
+func (w *DeviceWatcher) Events() <-chan DeviceEvent {
+	return w.events
+}
+
+// XBee returns the currently open module or nil if the device isn't
+// connected.
+func (w *DeviceWatcher) XBee() *XBee {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.xb
+}
+
+// Close stops watching and closes the device.
+func (w *DeviceWatcher) Close() error {
+	close(w.stop)
+	<-w.done
+	return nil
+}
+
+func (w *DeviceWatcher) run(notify <-chan struct{}, closeNotify func()) {
+	defer close(w.done)
+	defer close(w.events)
+	defer closeNotify()
+	defer w.closeDevice()
+
+	poll := time.NewTicker(devicePollInterval)
+	defer poll.Stop()
+	var settle <-chan time.Time
+	w.check()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-notify:
+			settle = time.After(hotplugSettle)
+		case <-settle:
+			settle = nil
+			w.check()
+		case <-poll.C:
+			w.check()
+		}
+	}
+}
+
+// check opens or closes the device to match the presence of the device
+// node.
+func (w *DeviceWatcher) check() {
+	_, err := os.Stat(w.path)
+	present := err == nil
+	w.mu.Lock()
+	open := w.xb != nil
+	w.mu.Unlock()
+	switch {
+	case present && !open:
+		port, err := OpenPort(w.path, w.baud)
+		if err != nil {
+			w.send(DeviceEvent{Type: DeviceOpenFailed, Path: w.path, Error: err})
+			return
+		}
+		xb, err := Open(port, w.opts...)
+		if err != nil {
+			port.Close()
+			w.send(DeviceEvent{Type: DeviceOpenFailed, Path: w.path, Error: err})
+			return
+		}
+		w.mu.Lock()
+		w.xb, w.port = xb, port
+		w.mu.Unlock()
+		w.send(DeviceEvent{Type: DeviceConnected, Path: w.path, XBee: xb})
+	case !present && open:
+		w.closeDevice()
+		w.send(DeviceEvent{Type: DeviceDisconnected, Path: w.path})
+	}
+}
+
+func (w *DeviceWatcher) closeDevice() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.port != nil {
+		// Closing the port stops the read loop.
+		w.port.Close()
+	}
+	w.xb, w.port = nil, nil
+}
+
+func (w *DeviceWatcher) send(ev DeviceEvent) {
+	select {
+	case w.events <- ev:
+	case <-w.stop:
+	}
+}
@@ -0,0 +1,10 @@
+package xbee
+
+import "time"
+
+const devicePollInterval = time.Second
+
+// deviceNotifier returns no notifications so the device is polled.
+func deviceNotifier() (<-chan struct{}, func(), error) {
+	return nil, func() {}, nil
+}
@@ -0,0 +1,46 @@
+package xbee
+
+import (
+	"bytes"
+	"os"
+	"syscall"
+	"time"
+)
+
+// Polling is only a fallback for missed notifications.
+const devicePollInterval = 5 * time.Second
+
+// deviceNotifier listens for kernel uevents for tty devices.
+func deviceNotifier() (<-chan struct{}, func(), error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, syscall.NETLINK_KOBJECT_UEVENT)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK, Groups: 1}); err != nil {
+		syscall.Close(fd)
+		return nil, nil, err
+	}
+	// Non-blocking so reads use the poller and are interrupted by Close.
+	if err := syscall.SetNonblock(fd, true); err != nil {
+		syscall.Close(fd)
+		return nil, nil, err
+	}
+	f := os.NewFile(uintptr(fd), "uevent")
+	ch := make(chan struct{}, 1)
+	go func() {
+		buf := make([]byte, 8192)
+		for {
+			n, err := f.Read(buf)
+			if err != nil {
+				return
+			}
+			if bytes.Contains(buf[:n], []byte("SUBSYSTEM=tty\x00")) {
+				select {
+				case ch <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return ch, func() { f.Close() }, nil
+}
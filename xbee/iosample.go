@@ -0,0 +1,137 @@
+package xbee
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"iter"
+	"math/bits"
+	"time"
+)
+
+// PinMode is the configuration of a DIO pin (D0 to D9 and P0 to P2).
+// Not every pin supports every mode.
+type PinMode byte
+
+const (
+	PinDisabled     PinMode = 0
+	PinPeripheral   PinMode = 1 // e.g. commissioning button, RSSI PWM, or flow control
+	PinADC          PinMode = 2
+	PinDigitalInput PinMode = 3
+	PinDigitalLow   PinMode = 4
+	PinDigitalHigh  PinMode = 5
+)
+
+func (m PinMode) String() string {
+	switch m {
+	case PinDisabled:
+		return "Disabled"
+	case PinPeripheral:
+		return "Peripheral"
+	case PinADC:
+		return "ADC"
+	case PinDigitalInput:
+		return "DigitalInput"
+	case PinDigitalLow:
+		return "DigitalLow"
+	case PinDigitalHigh:
+		return "DigitalHigh"
+	}
+	return fmt.Sprintf("PinMode(%d)", m)
+}
+
+func (m PinMode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// maxIOSampleRate is the largest sample rate (IR) in milliseconds.
+const maxIOSampleRate = 0xffff
+
+// pinCommand returns the AT command that configures DIO pin n: D0 to D9
+// for DIO0 to DIO9 and P0 to P2 for DIO10 to DIO12.
+func pinCommand(n int) (ATCommand, error) {
+	switch {
+	case n >= 0 && n <= 9:
+		return ATCommand{'D', byte('0' + n)}, nil
+	case n >= 10 && n <= 12:
+		return ATCommand{'P', byte('0' + n - 10)}, nil
+	}
+	return ATCommand{}, fmt.Errorf("xbee: %w: no DIO pin %d", ErrInvalidParameter, n)
+}
+
+// SetPinMode configures DIO pin n (0 to 12) of a remote module and applies
+// the change, e.g. PinDigitalHigh to drive an output high.
+func (xb *XBee) SetPinMode(dest Addr64, n int, mode PinMode) error {
+	cmd, err := pinCommand(n)
+	if err != nil {
+		return err
+	}
+	_, err = xb.RemoteATCommand(dest, Address16Unknown, cmd, []byte{byte(mode)}, RATOApplyChanges)
+	return err
+}
+
+// ConfigureIOSampling sets how often a remote module sends I/O samples of
+// its enabled pins (IR, 0 to disable, rounded to milliseconds) and which
+// digital pins send a sample when they change (IC, bit n for DIOn). Samples
+// are sent to the module's destination address (DH and DL) and received
+// as IODataSampleIndicator events.
+func (xb *XBee) ConfigureIOSampling(dest Addr64, rate time.Duration, changeDetect uint16) error {
+	ms := rate.Milliseconds()
+	if ms < 0 || ms > maxIOSampleRate {
+		return fmt.Errorf("xbee.ConfigureIOSampling: %w: sample rate %s out of range", ErrInvalidParameter, rate)
+	}
+	if _, err := xb.RemoteATCommand(dest, Address16Unknown, atIOSampleRate, []byte{byte(ms >> 8), byte(ms)}, 0); err != nil {
+		return err
+	}
+	_, err := xb.RemoteATCommand(dest, Address16Unknown, atIOChangeDetection, []byte{byte(changeDetect >> 8), byte(changeDetect)}, RATOApplyChanges)
+	return err
+}
+
+// IOSamples returns an iterator over received I/O samples. While
+// iterating the samples are consumed by the iterator rather than delivered
+// by EventChan. It stops when ctx is done or the XBee is closed.
+func (xb *XBee) IOSamples(ctx context.Context) iter.Seq[*IODataSampleIndicator] {
+	return matchSeq[*IODataSampleIndicator](ctx, xb)
+}
+
+// ForceSample reads the enabled I/O pins of a remote module (IS) returning
+// the sample as if it had been received from the module.
+func (xb *XBee) ForceSample(dest Addr64) (*IODataSampleIndicator, error) {
+	res, err := xb.RemoteATCommand(dest, Address16Unknown, atForceSample, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	return decodeForcedSample(dest, res)
+}
+
+// decodeForcedSample decodes the response to IS which has the sample
+// fields of an I/O data sample indicator frame.
+func decodeForcedSample(src Addr64, b []byte) (*IODataSampleIndicator, error) {
+	if len(b) < 4 {
+		return nil, fmt.Errorf("xbee: %w: IS response too short (%d bytes)", ErrMalformedFrame, len(b))
+	}
+	s := &IODataSampleIndicator{
+		SourceAddress:   src,
+		SourceAddress16: Address16Unknown,
+		NumSamples:      b[0],
+		DigitalMask:     binary.BigEndian.Uint16(b[1:]),
+		AnalogMask:      b[3],
+	}
+	n := 4 + 2*bits.OnesCount8(s.AnalogMask)
+	if s.DigitalMask != 0 {
+		n += 2
+	}
+	if len(b) < n {
+		return nil, fmt.Errorf("xbee: %w: IS response too short (%d bytes)", ErrMalformedFrame, len(b))
+	}
+	b = b[4:]
+	if s.DigitalMask != 0 {
+		s.Digital = binary.BigEndian.Uint16(b)
+		b = b[2:]
+	}
+	for len(s.Analog) < bits.OnesCount8(s.AnalogMask) {
+		s.Analog = append(s.Analog, binary.BigEndian.Uint16(b))
+		b = b[2:]
+	}
+	return s, nil
+}
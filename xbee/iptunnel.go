@@ -0,0 +1,220 @@
+package xbee
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultIPTunnelAddress is the application addressing used by IP tunnels
+// unless configured otherwise.
+var DefaultIPTunnelAddress = ExplicitAddress{
+	SourceEndpoint:      EndpointDigiData,
+	DestinationEndpoint: EndpointDigiData,
+	ClusterID:           0x0116,
+	ProfileID:           ProfileDigi,
+}
+
+const (
+	// DefaultIPTunnelMTU is the largest IP packet carried unless
+	// configured otherwise, the minimum every IPv4 host must accept.
+	DefaultIPTunnelMTU = 576
+	// Each fragment starts with the packet ID, the fragment index, and
+	// the number of fragments.
+	ipTunnelHeaderLen         = 3
+	defaultIPTunnelReassembly = 5 * time.Second
+	ipTunnelQueue             = 32
+)
+
+// IPTunnelConfig configures an IPTunnel. The zero value uses the
+// defaults.
+type IPTunnelConfig struct {
+	// Address is the application addressing used for fragments. The
+	// default is DefaultIPTunnelAddress.
+	Address *ExplicitAddress
+	// MTU is the largest packet WritePacket accepts. The default is
+	// DefaultIPTunnelMTU.
+	MTU int
+	// ReassemblyTimeout drops a packet whose fragments haven't all
+	// arrived in this time. The default is 5s.
+	ReassemblyTimeout time.Duration
+}
+
+// IPTunnel carries IP packets (or any datagrams) to a peer running an
+// IPTunnel over a point-to-point link, fragmenting them to fit the RF
+// payload. A packet is lost if any of its fragments is, leaving recovery to
+// the protocols above as on any IP link. Combined with a TUN device it lets
+// low-rate IP services such as SSH run across the link. It's experimental.
+// Fragments are received as explicit packets so explicit receive (AO=1)
+// must be enabled.
+type IPTunnel struct {
+	xb     *XBee
+	remote Addr64
+	addr   ExplicitAddress
+	cfg    IPTunnelConfig
+	frag   int // payload bytes per fragment
+	m      *matcher
+	ch     chan []byte
+	done   chan struct{}
+	once   sync.Once
+
+	wmu    sync.Mutex // serializes writes so fragments aren't interleaved
+	nextID byte
+
+	partial map[byte]*ipTunnelPacket // only used by receiveLoop
+}
+
+type ipTunnelPacket struct {
+	frags   [][]byte
+	missing int
+	started time.Time
+}
+
+// NewIPTunnel starts a tunnel to remote. cfg may be nil to use the
+// defaults.
+func (xb *XBee) NewIPTunnel(remote Addr64, cfg *IPTunnelConfig) (*IPTunnel, error) {
+	if remote.IsBroadcast() || remote == AddressUnknown {
+		return nil, ErrInvalidParameter
+	}
+	t := &IPTunnel{
+		xb:      xb,
+		remote:  remote,
+		addr:    DefaultIPTunnelAddress,
+		ch:      make(chan []byte, ipTunnelQueue),
+		done:    make(chan struct{}),
+		partial: make(map[byte]*ipTunnelPacket),
+	}
+	if cfg != nil {
+		t.cfg = *cfg
+	}
+	if t.cfg.Address != nil {
+		t.addr = *t.cfg.Address
+	}
+	if t.cfg.MTU <= 0 {
+		t.cfg.MTU = DefaultIPTunnelMTU
+	}
+	if t.cfg.ReassemblyTimeout <= 0 {
+		t.cfg.ReassemblyTimeout = defaultIPTunnelReassembly
+	}
+	np, err := xb.MaximumRFPayloadBytes()
+	if err != nil {
+		return nil, err
+	}
+	t.frag = np - ipTunnelHeaderLen
+	if t.frag <= 0 || (t.cfg.MTU+t.frag-1)/t.frag > 255 {
+		return nil, ErrInvalidParameter
+	}
+	addr := t.addr
+	t.m = xb.registerMatcher(matchSession, func(ev Event) bool {
+		rx, ok := ev.(*ExplicitReceivePacket)
+		return ok && rx.SourceAddress == remote && rx.DestinationEndpoint == addr.DestinationEndpoint &&
+			rx.ClusterID == addr.ClusterID && rx.ProfileID == addr.ProfileID && len(rx.Data) > ipTunnelHeaderLen
+	})
+	go t.receiveLoop()
+	return t, nil
+}
+
+// MTU returns the largest packet WritePacket accepts.
+func (t *IPTunnel) MTU() int {
+	return t.cfg.MTU
+}
+
+// WritePacket sends a packet to the peer waiting for each fragment to be
+// delivered.
+func (t *IPTunnel) WritePacket(p []byte) error {
+	if len(p) == 0 || len(p) > t.cfg.MTU {
+		return ErrInvalidParameter
+	}
+	t.wmu.Lock()
+	defer t.wmu.Unlock()
+	t.nextID++
+	count := (len(p) + t.frag - 1) / t.frag
+	buf := make([]byte, 0, ipTunnelHeaderLen+t.frag)
+	for i := 0; i < count; i++ {
+		chunk := p[i*t.frag : min(len(p), (i+1)*t.frag)]
+		buf = append(buf[:0], t.nextID, byte(i), byte(count))
+		buf = append(buf, chunk...)
+		if err := t.xb.transmitExplicitStatus(t.remote, t.addr, buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadPacket returns the next packet received from the peer.
+func (t *IPTunnel) ReadPacket() ([]byte, error) {
+	select {
+	case p := <-t.ch:
+		return p, nil
+	case <-t.done:
+		return nil, ErrClosed
+	case <-t.xb.closed:
+		return nil, ErrClosed
+	}
+}
+
+// Close stops the tunnel.
+func (t *IPTunnel) Close() error {
+	t.once.Do(func() {
+		t.xb.unregisterMatcher(t.m)
+		close(t.done)
+	})
+	return nil
+}
+
+func (t *IPTunnel) receiveLoop() {
+	expire := time.NewTicker(t.cfg.ReassemblyTimeout / 2)
+	defer expire.Stop()
+	for {
+		select {
+		case ev := <-t.m.ch:
+			if p := t.reassemble(ev.(*ExplicitReceivePacket).Data, time.Now()); p != nil {
+				select {
+				case t.ch <- p:
+				default:
+					t.xb.log.Warn("xbee: IP tunnel queue full, dropping packet")
+				}
+			}
+		case now := <-expire.C:
+			for id, p := range t.partial {
+				if now.Sub(p.started) >= t.cfg.ReassemblyTimeout {
+					delete(t.partial, id)
+				}
+			}
+		case <-t.done:
+			return
+		case <-t.xb.closed:
+			return
+		}
+	}
+}
+
+// reassemble adds a fragment returning the packet once it's complete.
+func (t *IPTunnel) reassemble(b []byte, now time.Time) []byte {
+	id, index, count := b[0], int(b[1]), int(b[2])
+	if count == 0 || index >= count {
+		return nil
+	}
+	data := b[ipTunnelHeaderLen:]
+	if count == 1 {
+		return append([]byte(nil), data...)
+	}
+	p := t.partial[id]
+	if p == nil || len(p.frags) != count {
+		// A new packet, or the ID wrapped around to a stale one.
+		p = &ipTunnelPacket{frags: make([][]byte, count), missing: count, started: now}
+		t.partial[id] = p
+	}
+	if p.frags[index] == nil {
+		p.frags[index] = append([]byte(nil), data...)
+		p.missing--
+	}
+	if p.missing > 0 {
+		return nil
+	}
+	delete(t.partial, id)
+	var pkt []byte
+	for _, f := range p.frags {
+		pkt = append(pkt, f...)
+	}
+	return pkt
+}
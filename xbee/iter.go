@@ -0,0 +1,96 @@
+package xbee
+
+import (
+	"context"
+	"iter"
+)
+
+// Events returns an iterator over the events delivered by EventChan. It
+// stops when ctx is done or the XBee is closed. With WithPooledBuffers an
+// event is released when yield returns so it must be copied to be kept.
+func (xb *XBee) Events(ctx context.Context) iter.Seq[Event] {
+	return func(yield func(Event) bool) {
+		for {
+			select {
+			case ev, ok := <-xb.eventCh:
+				if !ok {
+					return
+				}
+				more := yield(ev)
+				xb.Release(ev)
+				if !more {
+					return
+				}
+			case <-ctx.Done():
+				return
+			case <-xb.closed:
+				return
+			}
+		}
+	}
+}
+
+// Packets returns an iterator over received data packets. While iterating
+// the packets are consumed by the iterator rather than delivered by
+// EventChan. It stops when ctx is done or the XBee is closed.
+func (xb *XBee) Packets(ctx context.Context) iter.Seq[*ReceivePacket] {
+	return matchSeq[*ReceivePacket](ctx, xb)
+}
+
+// ExplicitPackets returns an iterator over data packets received with
+// explicit addressing (AO=1). While iterating the packets are consumed by
+// the iterator rather than delivered by EventChan. It stops when ctx is
+// done or the XBee is closed.
+func (xb *XBee) ExplicitPackets(ctx context.Context) iter.Seq[*ExplicitReceivePacket] {
+	return matchSeq[*ExplicitReceivePacket](ctx, xb)
+}
+
+// PacketsFrom returns an iterator over the data packets received from the
+// node with the address. While iterating they're consumed by the iterator
+// rather than delivered by EventChan while packets from other nodes are
+// delivered as usual. It takes precedence over Packets and Receive for
+// packets from the node. It stops when ctx is done or the XBee is closed.
+func (xb *XBee) PacketsFrom(ctx context.Context, addr Addr64) iter.Seq[*ReceivePacket] {
+	return matchSeqFunc(ctx, xb, matchSource, func(rx *ReceivePacket) bool {
+		return rx.SourceAddress == addr
+	})
+}
+
+// ExplicitPacketsFrom is like PacketsFrom for packets received with
+// explicit addressing (AO=1).
+func (xb *XBee) ExplicitPacketsFrom(ctx context.Context, addr Addr64) iter.Seq[*ExplicitReceivePacket] {
+	return matchSeqFunc(ctx, xb, matchSource, func(rx *ExplicitReceivePacket) bool {
+		return rx.SourceAddress == addr
+	})
+}
+
+// matchSeq iterates over the received frames of type T. The matcher is
+// only registered while iterating.
+func matchSeq[T Event](ctx context.Context, xb *XBee) iter.Seq[T] {
+	return matchSeqFunc(ctx, xb, matchAny, func(T) bool { return true })
+}
+
+// matchSeqFunc iterates over the received frames of type T for which
+// match returns true. Frames matched but not yet yielded when iteration
+// stops are delivered by EventChan.
+func matchSeqFunc[T Event](ctx context.Context, xb *XBee, scope matchScope, match func(T) bool) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		m := xb.registerMatcher(scope, func(ev Event) bool {
+			t, ok := ev.(T)
+			return ok && match(t)
+		})
+		defer xb.unregisterAndRedeliver(m)
+		for {
+			select {
+			case ev := <-m.ch:
+				if !yield(ev.(T)) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			case <-xb.closed:
+				return
+			}
+		}
+	}
+}
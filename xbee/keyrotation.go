@@ -0,0 +1,65 @@
+package xbee
+
+import (
+	"context"
+	"time"
+)
+
+// keyUpdateWait is how long RotateNetworkKey waits for the module to report
+// the new key is in use before checking the nodes anyway.
+const keyUpdateWait = 30 * time.Second
+
+// KeyRotationReport is the outcome of RotateNetworkKey.
+type KeyRotationReport struct {
+	// KeyUpdated is true if the module reported the network key was
+	// updated (MSNetworkKeyUpdated).
+	KeyUpdated bool
+	// Rekeyed are the nodes that responded using the new key.
+	Rekeyed []Addr64
+	// Failed are the nodes that didn't respond, e.g. because they missed
+	// the new key or are sleeping end devices.
+	Failed map[Addr64]error
+}
+
+// RotateNetworkKey changes the network key on the coordinator (NK) to key,
+// or a random key if nil, and saves it (WR). The coordinator distributes
+// the new key to the network. Once the module reports the key was updated
+// (or after 30s), every node known from NodeStats is queried with a
+// remote AT command to confirm it's using the new key.
+func (xb *XBee) RotateNetworkKey(ctx context.Context, key []byte) (*KeyRotationReport, error) {
+	m := xb.registerMatcher(matchAny, func(ev Event) bool {
+		ms, ok := ev.(ModemStatus)
+		return ok && ms == MSNetworkKeyUpdated
+	})
+	defer xb.unregisterMatcher(m)
+	if err := xb.SetNetworkEncryptionKey(key); err != nil {
+		return nil, err
+	}
+	if err := xb.Write(); err != nil {
+		return nil, err
+	}
+
+	report := &KeyRotationReport{Failed: make(map[Addr64]error)}
+	t := time.NewTimer(keyUpdateWait)
+	select {
+	case <-m.ch:
+		t.Stop()
+		report.KeyUpdated = true
+	case <-t.C:
+	case <-ctx.Done():
+		t.Stop()
+		return nil, ctx.Err()
+	}
+
+	for _, n := range xb.NodeStats() {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		if _, err := xb.RemoteATCommand(n.Address, Address16Unknown, atFirmwareVersion, nil, 0); err != nil {
+			report.Failed[n.Address] = err
+		} else {
+			report.Rekeyed = append(report.Rekeyed, n.Address)
+		}
+	}
+	return report, nil
+}
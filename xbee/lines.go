@@ -0,0 +1,155 @@
+package xbee
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxLineLen = 1024
+	lineQueue         = 64
+)
+
+// Line is a delimited record received from a node, e.g. an NMEA sentence
+// from a GPS behind a module in transparent mode.
+type Line struct {
+	Source Addr64
+	Text   string // without the delimiter or a trailing carriage return
+	Time   time.Time
+}
+
+// LineReassembler joins the packet payloads of each source back into
+// lines, which the radio splits and joins without regard for line
+// boundaries. The zero value splits on newlines.
+type LineReassembler struct {
+	// Delimiter ends a line. The default is '\n'.
+	Delimiter byte
+	// MaxLen drops lines longer than MaxLen bytes, e.g. when the
+	// delimiter is lost. The default is 1024.
+	MaxLen int
+
+	mu   sync.Mutex
+	bufs map[Addr64][]byte
+	skip map[Addr64]bool // dropping the rest of an overlong line
+}
+
+// Feed adds data received from src returning the lines it completes.
+func (r *LineReassembler) Feed(src Addr64, data []byte) []Line {
+	delim, maxLen := r.Delimiter, r.MaxLen
+	if delim == 0 {
+		delim = '\n'
+	}
+	if maxLen <= 0 {
+		maxLen = defaultMaxLineLen
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.bufs == nil {
+		r.bufs = make(map[Addr64][]byte)
+		r.skip = make(map[Addr64]bool)
+	}
+	now := time.Now()
+	buf := r.bufs[src]
+	var lines []Line
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, delim)
+		if i < 0 {
+			buf = append(buf, data...)
+			break
+		}
+		buf = append(buf, data[:i]...)
+		data = data[i+1:]
+		if !r.skip[src] && len(buf) <= maxLen {
+			text := buf
+			if delim == '\n' {
+				text = bytes.TrimSuffix(text, []byte{'\r'})
+			}
+			lines = append(lines, Line{Source: src, Text: string(text), Time: now})
+		}
+		delete(r.skip, src)
+		buf = buf[:0]
+	}
+	if len(buf) > maxLen {
+		r.skip[src] = true
+		buf = buf[:0]
+	}
+	if len(buf) == 0 {
+		delete(r.bufs, src)
+	} else {
+		r.bufs[src] = buf
+	}
+	return lines
+}
+
+// Reset drops the partial line buffered for src.
+func (r *LineReassembler) Reset(src Addr64) {
+	r.mu.Lock()
+	delete(r.bufs, src)
+	delete(r.skip, src)
+	r.mu.Unlock()
+}
+
+// Lines returns a channel on which the lines received from src
+// (AddressUnknown for every node) on the serial data cluster are delivered
+// until ctx is done. Those packets aren't delivered by EventChan
+// meanwhile. Lines are dropped if the channel is full. r may be nil to
+// split on newlines.
+func (xb *XBee) Lines(ctx context.Context, src Addr64, r *LineReassembler) <-chan Line {
+	if r == nil {
+		r = &LineReassembler{}
+	}
+	m := xb.registerMatcher(sourceScope(src), func(ev Event) bool {
+		from, _, ok := serialData(ev)
+		return ok && (src == AddressUnknown || from == src)
+	})
+	ch := make(chan Line, lineQueue)
+	go func() {
+		defer close(ch)
+		defer xb.unregisterMatcher(m)
+		for {
+			var ev Event
+			select {
+			case ev = <-m.ch:
+			case <-ctx.Done():
+				return
+			case <-xb.closed:
+				return
+			}
+			from, data, _ := serialData(ev)
+			for _, line := range r.Feed(from, data) {
+				select {
+				case ch <- line:
+				default:
+					xb.log.Warn("xbee: line channel full, dropping line", "source", from)
+				}
+			}
+		}
+	}()
+	return ch
+}
+
+// ValidNMEA reports whether s is an NMEA 0183 sentence ($ or ! followed
+// by the fields) whose checksum, if present after a *, matches.
+func ValidNMEA(s string) bool {
+	if len(s) < 2 || (s[0] != '$' && s[0] != '!') {
+		return false
+	}
+	i := strings.LastIndexByte(s, '*')
+	if i < 0 {
+		return true
+	}
+	body, sum := s[1:i], s[i+1:]
+	want, err := strconv.ParseUint(sum, 16, 8)
+	if err != nil || len(sum) != 2 {
+		return false
+	}
+	var got byte
+	for i := 0; i < len(body); i++ {
+		got ^= body[i]
+	}
+	return got == byte(want)
+}
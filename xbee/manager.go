@@ -0,0 +1,256 @@
+package xbee
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+const managerQueueLen = 16
+
+// ErrNoRoute is returned by Manager.Transmit when no radio has seen the
+// destination and by Manager.TransmitNetwork when no radio is on the
+// network.
+var ErrNoRoute = errors.New("xbee: no radio for destination")
+
+// Radio is a module owned by a Manager.
+type Radio struct {
+	Name string
+	XBee *XBee
+	// Network is the extended PAN ID of the network the radio is on. If
+	// 0 when added it's read from the module (OP).
+	Network uint64
+}
+
+// RadioEvent is an event delivered by Manager.Events tagged with the
+// radio that delivered it.
+type RadioEvent struct {
+	Radio string
+	Event Event
+}
+
+// RadioNodeStats is a node seen by a radio of a Manager.
+type RadioNodeStats struct {
+	Radio   string
+	Network uint64
+	NodeStats
+}
+
+// Manager owns several modules, e.g. the coordinators of different PANs,
+// merging their events into one stream and routing transmits to the
+// radio that can reach the destination.
+type Manager struct {
+	events chan RadioEvent
+	closed chan struct{}
+	wg     sync.WaitGroup
+
+	mu     sync.Mutex
+	radios map[string]*managedRadio
+}
+
+type managedRadio struct {
+	Radio
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewManager returns a Manager without radios.
+func NewManager() *Manager {
+	return &Manager{
+		events: make(chan RadioEvent, managerQueueLen),
+		closed: make(chan struct{}),
+		radios: make(map[string]*managedRadio),
+	}
+}
+
+// Add takes ownership of r.XBee. From then on its events are delivered
+// by Events rather than by its EventChan.
+func (m *Manager) Add(r Radio) error {
+	if r.Name == "" || r.XBee == nil {
+		return errors.New("xbee: radio needs a name and module")
+	}
+	if r.Network == 0 {
+		id, err := r.XBee.OperatingExtendedPANID()
+		if err != nil {
+			return fmt.Errorf("xbee: reading the network of radio %s: %w", r.Name, err)
+		}
+		r.Network = id
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	select {
+	case <-m.closed:
+		return ErrClosed
+	default:
+	}
+	if _, ok := m.radios[r.Name]; ok {
+		return fmt.Errorf("xbee: radio %s already added", r.Name)
+	}
+	mr := &managedRadio{Radio: r, stop: make(chan struct{}), done: make(chan struct{})}
+	m.radios[r.Name] = mr
+	m.wg.Add(1)
+	go m.forward(mr)
+	return nil
+}
+
+// Remove stops managing the named radio returning its module, which the
+// caller then owns, or nil if there's no such radio.
+func (m *Manager) Remove(name string) *XBee {
+	m.mu.Lock()
+	mr := m.radios[name]
+	delete(m.radios, name)
+	m.mu.Unlock()
+	if mr == nil {
+		return nil
+	}
+	close(mr.stop)
+	<-mr.done
+	return mr.XBee
+}
+
+// Radio returns the named radio's module or nil.
+func (m *Manager) Radio(name string) *XBee {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if mr := m.radios[name]; mr != nil {
+		return mr.XBee
+	}
+	return nil
+}
+
+// Radios returns the radios ordered by name.
+func (m *Manager) Radios() []Radio {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	radios := make([]Radio, 0, len(m.radios))
+	for _, mr := range m.radios {
+		radios = append(radios, mr.Radio)
+	}
+	sort.Slice(radios, func(i, j int) bool {
+		return radios[i].Name < radios[j].Name
+	})
+	return radios
+}
+
+// Events returns the events of all radios. It's closed by Close. With
+// WithPooledBuffers received packets must be passed to the Release of the
+// radio's XBee once they're no longer used.
+func (m *Manager) Events() <-chan RadioEvent {
+	return m.events
+}
+
+func (m *Manager) forward(mr *managedRadio) {
+	defer m.wg.Done()
+	defer close(mr.done)
+	for {
+		select {
+		case ev, ok := <-mr.XBee.eventCh:
+			if !ok {
+				return
+			}
+			select {
+			case m.events <- RadioEvent{Radio: mr.Name, Event: ev}:
+			case <-mr.stop:
+				mr.XBee.Release(ev)
+				return
+			case <-m.closed:
+				mr.XBee.Release(ev)
+				return
+			}
+		case <-mr.stop:
+			return
+		case <-m.closed:
+			return
+		}
+	}
+}
+
+// Route returns the radio that most recently received from dest.
+func (m *Manager) Route(dest Addr64) (Radio, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var best *managedRadio
+	var bestSeen int64
+	for _, mr := range m.radios {
+		mr.XBee.nodes.mu.Lock()
+		n := mr.XBee.nodes.nodes[dest]
+		var seen int64
+		if n != nil {
+			seen = n.LastSeen.UnixNano()
+		}
+		mr.XBee.nodes.mu.Unlock()
+		if n != nil && (best == nil || seen > bestSeen) {
+			best, bestSeen = mr, seen
+		}
+	}
+	if best == nil {
+		return Radio{}, fmt.Errorf("%w %s", ErrNoRoute, dest)
+	}
+	return best.Radio, nil
+}
+
+// Transmit sends data to dest through the radio chosen by Route.
+func (m *Manager) Transmit(dest Addr64, data []byte, opts ...TxOption) error {
+	r, err := m.Route(dest)
+	if err != nil {
+		return err
+	}
+	return r.XBee.Transmit(dest, data, opts...)
+}
+
+// TransmitNetwork sends data to dest through a radio on the network with
+// the extended PAN ID network.
+func (m *Manager) TransmitNetwork(network uint64, dest Addr64, data []byte, opts ...TxOption) error {
+	m.mu.Lock()
+	var xb *XBee
+	for _, mr := range m.radios {
+		if mr.Network == network {
+			xb = mr.XBee
+			break
+		}
+	}
+	m.mu.Unlock()
+	if xb == nil {
+		return fmt.Errorf("%w on network %016x", ErrNoRoute, network)
+	}
+	return xb.Transmit(dest, data, opts...)
+}
+
+// NodeStats returns the nodes seen by every radio ordered by address and
+// radio. A node seen by more than one radio is returned for each.
+func (m *Manager) NodeStats() []RadioNodeStats {
+	var stats []RadioNodeStats
+	for _, r := range m.Radios() {
+		for _, n := range r.XBee.NodeStats() {
+			stats = append(stats, RadioNodeStats{Radio: r.Name, Network: r.Network, NodeStats: n})
+		}
+	}
+	sort.SliceStable(stats, func(i, j int) bool {
+		return stats[i].Address < stats[j].Address
+	})
+	return stats
+}
+
+// Close stops delivering events and closes every radio. The ports of the
+// radios must be closed by the caller first: Close waits for the read loop
+// of each radio to stop (Done) before closing it.
+func (m *Manager) Close() {
+	m.mu.Lock()
+	select {
+	case <-m.closed:
+		m.mu.Unlock()
+		return
+	default:
+	}
+	close(m.closed)
+	radios := m.radios
+	m.radios = nil
+	m.mu.Unlock()
+	m.wg.Wait()
+	close(m.events)
+	for _, mr := range radios {
+		<-mr.XBee.Done()
+		mr.XBee.Close()
+	}
+}
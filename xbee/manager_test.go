@@ -0,0 +1,59 @@
+package xbee_test
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/samuel/go-xbee/xbee"
+	"github.com/samuel/go-xbee/xbee/xbeetest"
+)
+
+func TestManager(t *testing.T) {
+	m := xbee.NewManager()
+	radios := map[string]*xbeetest.Radio{"a": xbeetest.NewRadio(), "b": xbeetest.NewRadio()}
+	for name, r := range radios {
+		xb, err := xbee.Open(r, xbee.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := m.Add(xbee.Radio{Name: name, XBee: xb, Network: 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	radios["a"].InjectReceive(routerAddr, 1, []byte("a"))
+	radios["b"].InjectReceive(coordAddr, 0, []byte("b"))
+
+	for range radios {
+		select {
+		case ev := <-m.Events():
+			rx, ok := ev.Event.(*xbee.ReceivePacket)
+			if !ok || string(rx.Data) != ev.Radio {
+				t.Errorf("radio %s delivered %+v", ev.Radio, ev.Event)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("no event")
+		}
+	}
+	if r, err := m.Route(coordAddr); err != nil || r.Name != "b" {
+		t.Errorf("Route(%s) = %s, %v, want b", coordAddr, r.Name, err)
+	}
+
+	for _, r := range radios {
+		r.Close()
+	}
+	done := make(chan struct{})
+	go func() {
+		m.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close didn't return")
+	}
+	if _, ok := <-m.Events(); ok {
+		t.Error("Events not closed")
+	}
+}
@@ -0,0 +1,159 @@
+package xbee
+
+import (
+	"slices"
+	"sync"
+)
+
+// matchScope is how specific a matcher is. An event several matchers
+// match goes to the most specific of them and of those to the first
+// registered.
+type matchScope int
+
+const (
+	matchAny     matchScope = iota // every event of a type
+	matchSource                    // events from one node
+	matchService                   // packets for an endpoint and cluster
+	matchSession                   // packets for a service from one node, or a single response
+)
+
+// sourceScope returns the scope of a matcher for events from src or from
+// every node if src is AddressUnknown.
+func sourceScope(src Addr64) matchScope {
+	if src == AddressUnknown {
+		return matchAny
+	}
+	return matchSource
+}
+
+// matcher receives events without a frame ID (e.g. responses that are
+// correlated by address) for which match returns true. Matched events are
+// queued without limit and delivered in order by ch so the read loop never
+// blocks on or drops events for a slow consumer such as a link protocol.
+type matcher struct {
+	match func(Event) bool
+	scope matchScope
+	ch    chan Event
+
+	mu    sync.Mutex
+	queue []Event
+	wake  chan struct{} // signaled when an event is queued
+	stop  chan struct{} // closed by unregisterMatcher
+	done  chan struct{} // closed when pump returns
+	once  sync.Once
+}
+
+func newMatcher(scope matchScope, match func(Event) bool) *matcher {
+	return &matcher{
+		match: match,
+		scope: scope,
+		ch:    make(chan Event),
+		wake:  make(chan struct{}, 1),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+}
+
+func (xb *XBee) registerMatcher(scope matchScope, match func(Event) bool) *matcher {
+	m := newMatcher(scope, match)
+	xb.mu.Lock()
+	// Keep the matchers ordered by scope, most specific first, and in
+	// order of registration within a scope.
+	i := slices.IndexFunc(xb.matchers, func(o *matcher) bool { return o.scope < scope })
+	if i < 0 {
+		i = len(xb.matchers)
+	}
+	xb.matchers = slices.Insert(xb.matchers, i, m)
+	xb.mu.Unlock()
+	go m.pump(xb.closed)
+	return m
+}
+
+// registerObserver registers a matcher that receives the events for which
+// match returns true without consuming them: they're still dispatched to
+// a matcher or delivered by EventChan.
+func (xb *XBee) registerObserver(match func(Event) bool) *matcher {
+	m := newMatcher(matchAny, match)
+	xb.mu.Lock()
+	xb.observers = append(xb.observers, m)
+	xb.mu.Unlock()
+	go m.pump(xb.closed)
+	return m
+}
+
+// unregisterMatcher stops delivering events to a matcher or observer
+// returning those queued but not yet received from ch.
+func (xb *XBee) unregisterMatcher(m *matcher) []Event {
+	xb.mu.Lock()
+	if i := slices.Index(xb.matchers, m); i >= 0 {
+		xb.matchers = slices.Delete(xb.matchers, i, i+1)
+	} else if i := slices.Index(xb.observers, m); i >= 0 {
+		xb.observers = slices.Delete(xb.observers, i, i+1)
+	}
+	xb.mu.Unlock()
+	m.once.Do(func() { close(m.stop) })
+	<-m.done
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	q := m.queue
+	m.queue = nil
+	return q
+}
+
+// unregisterAndRedeliver unregisters m delivering the events it matched
+// but weren't received by EventChan rather than losing them.
+func (xb *XBee) unregisterAndRedeliver(m *matcher) {
+	for _, ev := range xb.unregisterMatcher(m) {
+		select {
+		case <-xb.closed:
+			return
+		default:
+		}
+		xb.sendEvent(ev)
+	}
+}
+
+// push queues an event. It never blocks.
+func (m *matcher) push(ev Event) {
+	m.mu.Lock()
+	m.queue = append(m.queue, ev)
+	m.mu.Unlock()
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+// pump delivers the queued events by ch until the matcher is unregistered
+// or the XBee closed. An event is only removed from the queue once
+// received so none are lost when it stops.
+func (m *matcher) pump(closed <-chan struct{}) {
+	defer close(m.done)
+	for {
+		m.mu.Lock()
+		if len(m.queue) == 0 {
+			m.mu.Unlock()
+			select {
+			case <-m.wake:
+				continue
+			case <-m.stop:
+				return
+			case <-closed:
+				return
+			}
+		}
+		ev := m.queue[0]
+		m.mu.Unlock()
+		select {
+		case m.ch <- ev:
+			m.mu.Lock()
+			m.queue[0] = nil
+			m.queue = m.queue[1:]
+			m.mu.Unlock()
+		case <-m.stop:
+			return
+		case <-closed:
+			return
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package xbee
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+)
+
+const (
+	mavlinkV1Magic     = 0xfe
+	mavlinkV2Magic     = 0xfd
+	mavlinkV1Overhead  = 8  // header (6) and checksum
+	mavlinkV2Overhead  = 12 // header (10) and checksum
+	mavlinkSignatureV2 = 13
+	mavlinkSigned      = 0x01 // incompatibility flag of signed v2 messages
+	mavlinkQueue       = 64
+)
+
+// MAVLinkMessage is a MAVLink v1 or v2 message framed from the packets
+// received from a node.
+type MAVLinkMessage struct {
+	Source      Addr64
+	Version     int // 1 or 2
+	Seq         byte
+	SystemID    byte
+	ComponentID byte
+	MessageID   uint32
+	Payload     []byte
+	Raw         []byte // the whole message including the checksum and signature
+}
+
+// MAVLinkParser frames MAVLink messages from the packet payloads of each
+// source, which the radio splits and joins without regard for message
+// boundaries. Bytes that don't start a message are skipped. The zero value
+// is ready to use but can't check checksums.
+type MAVLinkParser struct {
+	// CRCExtra returns the CRC_EXTRA byte of a message ID from the
+	// dialect in use. If set, messages with a bad checksum or an unknown
+	// ID are dropped; otherwise messages are framed by length only.
+	CRCExtra func(msgID uint32) (byte, bool)
+
+	mu   sync.Mutex
+	bufs map[Addr64][]byte
+}
+
+// Feed adds data received from src returning the messages it completes.
+func (p *MAVLinkParser) Feed(src Addr64, data []byte) []*MAVLinkMessage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.bufs == nil {
+		p.bufs = make(map[Addr64][]byte)
+	}
+	buf := append(p.bufs[src], data...)
+	var msgs []*MAVLinkMessage
+	for len(buf) > 0 {
+		if buf[0] != mavlinkV1Magic && buf[0] != mavlinkV2Magic {
+			buf = buf[1:]
+			continue
+		}
+		n := mavlinkLen(buf)
+		if n == 0 || len(buf) < n {
+			break
+		}
+		m := p.decode(src, buf[:n])
+		if m == nil {
+			buf = buf[1:] // not a message after all, resync
+			continue
+		}
+		msgs = append(msgs, m)
+		buf = buf[n:]
+	}
+	if len(buf) == 0 {
+		delete(p.bufs, src)
+	} else {
+		p.bufs[src] = append([]byte(nil), buf...)
+	}
+	return msgs
+}
+
+// Reset drops the partial message buffered for src.
+func (p *MAVLinkParser) Reset(src Addr64) {
+	p.mu.Lock()
+	delete(p.bufs, src)
+	p.mu.Unlock()
+}
+
+// mavlinkLen returns the length of the message starting b, or 0 if the
+// header is incomplete.
+func mavlinkLen(b []byte) int {
+	if len(b) < 3 {
+		return 0
+	}
+	if b[0] == mavlinkV1Magic {
+		return mavlinkV1Overhead + int(b[1])
+	}
+	n := mavlinkV2Overhead + int(b[1])
+	if b[2]&mavlinkSigned != 0 {
+		n += mavlinkSignatureV2
+	}
+	return n
+}
+
+func (p *MAVLinkParser) decode(src Addr64, b []byte) *MAVLinkMessage {
+	raw := append([]byte(nil), b...)
+	m := &MAVLinkMessage{Source: src, Raw: raw}
+	var end int // end of the payload
+	if raw[0] == mavlinkV1Magic {
+		m.Version = 1
+		m.Seq, m.SystemID, m.ComponentID = raw[2], raw[3], raw[4]
+		m.MessageID = uint32(raw[5])
+		end = 6 + int(raw[1])
+		m.Payload = raw[6:end]
+	} else {
+		m.Version = 2
+		m.Seq, m.SystemID, m.ComponentID = raw[4], raw[5], raw[6]
+		m.MessageID = uint32(raw[7]) | uint32(raw[8])<<8 | uint32(raw[9])<<16
+		end = 10 + int(raw[1])
+		m.Payload = raw[10:end]
+	}
+	if p.CRCExtra != nil {
+		extra, ok := p.CRCExtra(m.MessageID)
+		if !ok {
+			return nil
+		}
+		crc := mavlinkCRC(raw[1:end])
+		crc = mavlinkCRCAccumulate(crc, extra)
+		if crc != binary.LittleEndian.Uint16(raw[end:]) {
+			return nil
+		}
+	}
+	return m
+}
+
+// mavlinkCRC returns the X.25 CRC used by MAVLink.
+func mavlinkCRC(b []byte) uint16 {
+	crc := uint16(0xffff)
+	for _, c := range b {
+		crc = mavlinkCRCAccumulate(crc, c)
+	}
+	return crc
+}
+
+func mavlinkCRCAccumulate(crc uint16, c byte) uint16 {
+	tmp := c ^ byte(crc)
+	tmp ^= tmp << 4
+	return crc>>8 ^ uint16(tmp)<<8 ^ uint16(tmp)<<3 ^ uint16(tmp)>>4
+}
+
+// MAVLinkMessages returns a channel on which the MAVLink messages received
+// from src (AddressUnknown for every node) on the serial data cluster are
+// delivered until ctx is done. Those packets aren't delivered by EventChan
+// meanwhile. Messages are dropped if the channel is full. p may be nil to
+// frame messages by length only.
+func (xb *XBee) MAVLinkMessages(ctx context.Context, src Addr64, p *MAVLinkParser) <-chan *MAVLinkMessage {
+	if p == nil {
+		p = &MAVLinkParser{}
+	}
+	m := xb.registerMatcher(sourceScope(src), func(ev Event) bool {
+		from, _, ok := serialData(ev)
+		return ok && (src == AddressUnknown || from == src)
+	})
+	ch := make(chan *MAVLinkMessage, mavlinkQueue)
+	go func() {
+		defer close(ch)
+		defer xb.unregisterMatcher(m)
+		for {
+			var ev Event
+			select {
+			case ev = <-m.ch:
+			case <-ctx.Done():
+				return
+			case <-xb.closed:
+				return
+			}
+			from, data, _ := serialData(ev)
+			for _, msg := range p.Feed(from, data) {
+				select {
+				case ch <- msg:
+				default:
+					xb.log.Warn("xbee: MAVLink channel full, dropping message", "source", from)
+				}
+			}
+		}
+	}()
+	return ch
+}
+
+// MAVLinkWriter sends MAVLink messages to a node packing whole messages
+// into as few packets as possible; only messages longer than a packet are
+// split. Packets are sent without waiting for the transmit status since
+// telemetry favors latency over delivery. It's safe for concurrent use.
+type MAVLinkWriter struct {
+	xb   *XBee
+	dest Addr64
+	np   int
+
+	mu  sync.Mutex
+	buf []byte // partial message from the last Write
+}
+
+// NewMAVLinkWriter returns a writer sending to dest with packets of the
+// module's maximum payload (NP).
+func (xb *XBee) NewMAVLinkWriter(dest Addr64) (*MAVLinkWriter, error) {
+	np, err := xb.MaximumRFPayloadBytes()
+	if err != nil {
+		return nil, err
+	}
+	if np <= 0 {
+		return nil, ErrInvalidParameter
+	}
+	return &MAVLinkWriter{xb: xb, dest: dest, np: np}, nil
+}
+
+// Write sends the complete messages in b. A message cut short at the end
+// of b is kept until the rest is written, and bytes that don't start a
+// message are sent as they are.
+func (w *MAVLinkWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	buf := append(w.buf, b...)
+	var packet []byte
+	flush := func() error {
+		if len(packet) == 0 {
+			return nil
+		}
+		err := w.xb.Transmit(w.dest, packet)
+		packet = nil
+		return err
+	}
+	for len(buf) > 0 {
+		n := 1
+		if buf[0] == mavlinkV1Magic || buf[0] == mavlinkV2Magic {
+			n = mavlinkLen(buf)
+			if n == 0 || len(buf) < n {
+				break
+			}
+		}
+		msg := buf[:n]
+		buf = buf[n:]
+		if len(packet)+len(msg) > w.np {
+			if err := flush(); err != nil {
+				return 0, err
+			}
+		}
+		for len(msg) > w.np {
+			if err := w.xb.Transmit(w.dest, msg[:w.np]); err != nil {
+				return 0, err
+			}
+			msg = msg[w.np:]
+		}
+		packet = append(packet, msg...)
+	}
+	if err := flush(); err != nil {
+		return 0, err
+	}
+	w.buf = append([]byte(nil), buf...)
+	return len(b), nil
+}
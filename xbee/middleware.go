@@ -0,0 +1,83 @@
+package xbee
+
+import (
+	"reflect"
+	"slices"
+)
+
+// Middleware processes an event on its way to EventChan, e.g. to filter,
+// deduplicate, enrich, or count events. It passes the event, or another
+// in its place, on by calling next and drops it by not calling next. It's
+// called from the goroutine reading frames so it mustn't block.
+//
+// Only unsolicited events pass through middleware. Responses to requests
+// and frames consumed by Receive, iterators, and the link protocols don't.
+//
+// With WithPooledBuffers the buffer of a frame that middleware drops or
+// replaces is returned to the pool once the chain returns, so an event
+// passed on in its place must copy any data it keeps from the frame.
+type Middleware func(ev Event, next func(Event))
+
+// WithMiddleware installs middleware as if by Use.
+func WithMiddleware(mw ...Middleware) Option {
+	return func(o *options) {
+		o.middleware = append(o.middleware, mw...)
+	}
+}
+
+// Use appends middleware to the chain events pass through. The first
+// installed is called first.
+func (xb *XBee) Use(mw ...Middleware) {
+	for {
+		old := xb.middleware.Load()
+		var mws []Middleware
+		if old != nil {
+			mws = slices.Clip(*old)
+		}
+		mws = append(mws, mw...)
+		if xb.middleware.CompareAndSwap(old, &mws) {
+			return
+		}
+	}
+}
+
+// FilterEvents returns middleware that drops the events for which keep
+// returns false.
+func FilterEvents(keep func(Event) bool) Middleware {
+	return func(ev Event, next func(Event)) {
+		if keep(ev) {
+			next(ev)
+		}
+	}
+}
+
+// sendEvent delivers an unsolicited event through the middleware to
+// EventChan returning false if the event itself wasn't delivered because
+// the middleware dropped or replaced it or the event channel is full. A
+// pooled buffer the event references is then the caller's to release.
+func (xb *XBee) sendEvent(ev Event) bool {
+	mws := xb.middleware.Load()
+	if mws == nil {
+		return xb.deliverEvent(ev)
+	}
+	delivered := false
+	var call func(i int, e Event)
+	call = func(i int, e Event) {
+		if i == len(*mws) {
+			if xb.deliverEvent(e) && sameEvent(e, ev) {
+				delivered = true
+			}
+			return
+		}
+		(*mws)[i](e, func(e Event) { call(i+1, e) })
+	}
+	call(0, ev)
+	return delivered
+}
+
+// sameEvent returns whether a and b are the same event without panicking
+// on events of an uncomparable type such as UnknownFrame.
+func sameEvent(a, b Event) bool {
+	t := reflect.TypeOf(a)
+	return t != nil && t == reflect.TypeOf(b) && t.Comparable() && a == b
+}
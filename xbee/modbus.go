@@ -0,0 +1,518 @@
+package xbee
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Modbus RTU is carried over the link to remote modules in transparent
+// mode wired to Modbus devices, the way XBee serial adapters are used to
+// tunnel Modbus. Frames split into several packets by the remote module
+// are reassembled using the length implied by the function code, or when
+// no data arrives for the inter-frame gap.
+
+const (
+	// DefaultModbusGap is the inter-frame gap unless configured otherwise.
+	// It's much longer than the 3.5 character times of a serial line to
+	// allow for the packetization timeout (RO) of the remote module and
+	// RF latency.
+	DefaultModbusGap     = 100 * time.Millisecond
+	defaultModbusTimeout = time.Second
+	minModbusFrameLen    = 4   // address, function code, and CRC
+	maxModbusFrameLen    = 256 // RTU ADU limit
+)
+
+var (
+	// ErrModbusCRC is returned for Modbus frames with a bad CRC.
+	ErrModbusCRC = errors.New("xbee: bad Modbus CRC")
+	// ErrModbusFrame is returned for Modbus frames that are too short or
+	// too long.
+	ErrModbusFrame = errors.New("xbee: malformed Modbus frame")
+)
+
+// ModbusException is returned by ModbusClient.Do for an exception
+// response.
+type ModbusException struct {
+	Function byte // function code of the request
+	Code     byte // exception code, e.g. 2 for an illegal data address
+}
+
+func (e *ModbusException) Error() string {
+	return fmt.Sprintf("xbee: Modbus function 0x%02x failed with exception %d", e.Function, e.Code)
+}
+
+// ModbusCRC returns the Modbus CRC-16 of b.
+func ModbusCRC(b []byte) uint16 {
+	crc := uint16(0xffff)
+	for _, c := range b {
+		crc ^= uint16(c)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0xa001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc
+}
+
+// ModbusFrame returns the RTU frame of a PDU for a unit (slave address).
+func ModbusFrame(unit byte, pdu []byte) []byte {
+	b := make([]byte, 0, len(pdu)+3)
+	b = append(b, unit)
+	b = append(b, pdu...)
+	return binary.LittleEndian.AppendUint16(b, ModbusCRC(b))
+}
+
+// ParseModbusFrame checks the length and CRC of an RTU frame returning
+// the unit and PDU.
+func ParseModbusFrame(b []byte) (unit byte, pdu []byte, err error) {
+	if len(b) < minModbusFrameLen || len(b) > maxModbusFrameLen {
+		return 0, nil, ErrModbusFrame
+	}
+	n := len(b) - 2
+	if ModbusCRC(b[:n]) != binary.LittleEndian.Uint16(b[n:]) {
+		return 0, nil, ErrModbusCRC
+	}
+	return b[0], b[1:n], nil
+}
+
+// modbusRequestLen returns the length of the request frame starting b, or
+// 0 if it can't be told from the bytes so far.
+func modbusRequestLen(b []byte) int {
+	if len(b) < 2 {
+		return 0
+	}
+	switch b[1] {
+	case 0x01, 0x02, 0x03, 0x04, 0x05, 0x06:
+		return 8
+	case 0x0f, 0x10: // write multiple coils or registers
+		if len(b) >= 7 {
+			return 9 + int(b[6])
+		}
+	case 0x17: // read/write multiple registers
+		if len(b) >= 11 {
+			return 13 + int(b[10])
+		}
+	}
+	return 0
+}
+
+// modbusResponseLen returns the length of the response frame starting b,
+// or 0 if it can't be told from the bytes so far.
+func modbusResponseLen(b []byte) int {
+	if len(b) < 2 {
+		return 0
+	}
+	if b[1]&0x80 != 0 {
+		return 5
+	}
+	switch b[1] {
+	case 0x01, 0x02, 0x03, 0x04, 0x17:
+		if len(b) >= 3 {
+			return 5 + int(b[2])
+		}
+	case 0x05, 0x06, 0x0f, 0x10:
+		return 8
+	}
+	return 0
+}
+
+// ModbusConfig configures a ModbusConn, ModbusClient, or ModbusServer.
+// The zero value uses the defaults.
+type ModbusConfig struct {
+	// Gap is how long without data ends a frame whose length can't be
+	// told from its function code. The default is DefaultModbusGap.
+	Gap time.Duration
+	// Timeout is how long a ModbusClient waits for a response. The
+	// default is 1s.
+	Timeout time.Duration
+	// Retry is the retry policy of each transmit (see WithRetry). The
+	// default is a single attempt since Modbus masters retry requests
+	// themselves.
+	Retry *RetryPolicy
+}
+
+func (c *ModbusConfig) withDefaults() ModbusConfig {
+	var cfg ModbusConfig
+	if c != nil {
+		cfg = *c
+	}
+	if cfg.Gap <= 0 {
+		cfg.Gap = DefaultModbusGap
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultModbusTimeout
+	}
+	if cfg.Retry == nil {
+		cfg.Retry = &RetryPolicy{MaxAttempts: 1}
+	}
+	return cfg
+}
+
+// modbusAssembler reassembles the frames of one source.
+type modbusAssembler struct {
+	gap     time.Duration
+	lenFunc func([]byte) int
+	buf     []byte
+	last    time.Time // when the last data arrived
+}
+
+// add appends data returning the complete frames.
+func (a *modbusAssembler) add(data []byte, now time.Time) (frames [][]byte, err error) {
+	if len(a.buf) > 0 && now.Sub(a.last) >= a.gap {
+		// The rest of the previous frame never arrived.
+		frames = append(frames, a.buf)
+		a.buf = nil
+	}
+	a.buf = append(a.buf, data...)
+	a.last = now
+	for {
+		n := a.lenFunc(a.buf)
+		if n == 0 || len(a.buf) < n {
+			break
+		}
+		frames = append(frames, a.buf[:n:n])
+		a.buf = a.buf[n:]
+	}
+	if len(a.buf) > maxModbusFrameLen {
+		a.buf = nil
+		return frames, ErrModbusFrame
+	}
+	return frames, nil
+}
+
+// flush returns the partial frame if the gap has passed.
+func (a *modbusAssembler) flush(now time.Time) []byte {
+	if len(a.buf) == 0 || now.Sub(a.last) < a.gap {
+		return nil
+	}
+	frame := a.buf
+	a.buf = nil
+	return frame
+}
+
+// ModbusConn exchanges RTU frames with one remote module, for Modbus
+// libraries that expect a serial port. Each Write is sent as a frame and
+// each Read returns a whole frame. Packets from the remote module aren't
+// delivered by EventChan while the conn is open.
+type ModbusConn struct {
+	xb     *XBee
+	remote Addr64
+	cfg    ModbusConfig
+	m      *matcher
+	done   chan struct{}
+	once   sync.Once
+
+	mu     sync.Mutex // serializes reads
+	asm    modbusAssembler
+	frames [][]byte // complete frames not yet read
+}
+
+// NewModbusConn returns a conn to a remote module attached to a Modbus
+// slave, so reads return response frames. cfg may be nil to use the
+// defaults.
+func (xb *XBee) NewModbusConn(remote Addr64, cfg *ModbusConfig) *ModbusConn {
+	c := &ModbusConn{xb: xb, remote: remote, cfg: cfg.withDefaults(), done: make(chan struct{})}
+	c.asm = modbusAssembler{gap: c.cfg.Gap, lenFunc: modbusResponseLen}
+	c.m = xb.registerMatcher(matchSource, func(ev Event) bool {
+		src, _, ok := serialData(ev)
+		return ok && src == remote
+	})
+	return c
+}
+
+// Write sends a frame to the remote module and waits for it to be
+// delivered.
+func (c *ModbusConn) Write(b []byte) (int, error) {
+	if err := c.xb.Transmit(c.remote, b, WithRetry(c.cfg.Retry)); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Read reads a frame waiting up to the configured timeout. The frame is
+// truncated if b is too short.
+func (c *ModbusConn) Read(b []byte) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.cfg.Timeout)
+	defer cancel()
+	frame, err := c.ReadFrame(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = ErrTimeout
+	}
+	return copy(b, frame), err
+}
+
+// ReadFrame returns the next frame received.
+func (c *ModbusConn) ReadFrame(ctx context.Context) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	gap := time.NewTimer(c.cfg.Gap)
+	defer gap.Stop()
+	for {
+		if len(c.frames) > 0 {
+			frame := c.frames[0]
+			c.frames = c.frames[1:]
+			return frame, nil
+		}
+		gap.Reset(c.cfg.Gap)
+		select {
+		case ev := <-c.m.ch:
+			_, data, _ := serialData(ev)
+			frames, err := c.asm.add(data, time.Now())
+			c.frames = append(c.frames, frames...)
+			if err != nil {
+				return nil, err
+			}
+		case now := <-gap.C:
+			if frame := c.asm.flush(now); frame != nil {
+				return frame, nil
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-c.done:
+			return nil, ErrClosed
+		case <-c.xb.closed:
+			return nil, ErrClosed
+		}
+	}
+}
+
+// discard drops the frames and data received so far, such as a late
+// response to an earlier request.
+func (c *ModbusConn) discard() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for {
+		select {
+		case <-c.m.ch:
+		default:
+			c.frames, c.asm.buf = nil, nil
+			return
+		}
+	}
+}
+
+// Close stops receiving packets from the remote module.
+func (c *ModbusConn) Close() error {
+	c.once.Do(func() {
+		c.xb.unregisterMatcher(c.m)
+		close(c.done)
+	})
+	return nil
+}
+
+// ModbusClient is a Modbus master sending requests to slaves behind
+// remote modules. Requests to the same remote module are sent one at a
+// time.
+type ModbusClient struct {
+	xb  *XBee
+	cfg *ModbusConfig
+
+	mu    sync.Mutex
+	conns map[Addr64]*modbusClientConn
+}
+
+type modbusClientConn struct {
+	mu sync.Mutex // one request at a time
+	c  *ModbusConn
+}
+
+// NewModbusClient returns a Modbus master. cfg may be nil to use the
+// defaults.
+func (xb *XBee) NewModbusClient(cfg *ModbusConfig) *ModbusClient {
+	return &ModbusClient{xb: xb, cfg: cfg, conns: make(map[Addr64]*modbusClientConn)}
+}
+
+// Do sends a request PDU to a unit behind the remote module dest and
+// returns the response PDU. An exception response is returned as a
+// *ModbusException. Requests to unit 0 are broadcasts which have no
+// response.
+func (mc *ModbusClient) Do(ctx context.Context, dest Addr64, unit byte, pdu []byte) ([]byte, error) {
+	if len(pdu) == 0 {
+		return nil, ErrInvalidParameter
+	}
+	mc.mu.Lock()
+	cc := mc.conns[dest]
+	if cc == nil {
+		cc = &modbusClientConn{c: mc.xb.NewModbusConn(dest, mc.cfg)}
+		mc.conns[dest] = cc
+	}
+	mc.mu.Unlock()
+
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	c := cc.c
+	c.discard()
+	if _, err := c.Write(ModbusFrame(unit, pdu)); err != nil {
+		return nil, err
+	}
+	if unit == 0 {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+	for {
+		frame, err := c.ReadFrame(ctx)
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, ErrTimeout
+		} else if err != nil {
+			return nil, err
+		}
+		u, res, err := ParseModbusFrame(frame)
+		if err != nil {
+			return nil, err
+		}
+		if u != unit || res[0]&0x7f != pdu[0] {
+			continue // not the response to this request
+		}
+		if res[0]&0x80 != 0 {
+			if len(res) < 2 {
+				return nil, ErrModbusFrame
+			}
+			return nil, &ModbusException{Function: pdu[0], Code: res[1]}
+		}
+		return res, nil
+	}
+}
+
+// ReadHoldingRegisters reads count registers starting at addr (function
+// 3).
+func (mc *ModbusClient) ReadHoldingRegisters(ctx context.Context, dest Addr64, unit byte, addr, count uint16) ([]uint16, error) {
+	pdu := binary.BigEndian.AppendUint16([]byte{0x03}, addr)
+	pdu = binary.BigEndian.AppendUint16(pdu, count)
+	res, err := mc.Do(ctx, dest, unit, pdu)
+	if err != nil {
+		return nil, err
+	}
+	if len(res) < 2 || int(res[1]) != 2*int(count) || len(res) < 2+int(res[1]) {
+		return nil, ErrModbusFrame
+	}
+	regs := make([]uint16, count)
+	for i := range regs {
+		regs[i] = binary.BigEndian.Uint16(res[2+2*i:])
+	}
+	return regs, nil
+}
+
+// WriteRegister writes a single register (function 6).
+func (mc *ModbusClient) WriteRegister(ctx context.Context, dest Addr64, unit byte, addr, value uint16) error {
+	pdu := binary.BigEndian.AppendUint16([]byte{0x06}, addr)
+	pdu = binary.BigEndian.AppendUint16(pdu, value)
+	_, err := mc.Do(ctx, dest, unit, pdu)
+	return err
+}
+
+// Close closes the client's conns.
+func (mc *ModbusClient) Close() error {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	for addr, cc := range mc.conns {
+		cc.c.Close()
+		delete(mc.conns, addr)
+	}
+	return nil
+}
+
+// ModbusHandler answers a request PDU for a unit from a remote master
+// returning the response PDU, or nil to send no response.
+type ModbusHandler func(src Addr64, unit byte, pdu []byte) []byte
+
+// ModbusServer answers Modbus requests from masters behind remote
+// modules. Packets on the serial data cluster from the served nodes
+// aren't delivered by EventChan while the server runs.
+type ModbusServer struct {
+	xb      *XBee
+	cfg     ModbusConfig
+	handler ModbusHandler
+	m       *matcher
+	done    chan struct{}
+	once    sync.Once
+}
+
+// NewModbusServer starts answering requests from nodes with handler. nodes
+// may be nil to serve every node. cfg may be nil to use the defaults.
+// Frames with a bad CRC are dropped as a serial slave would.
+func (xb *XBee) NewModbusServer(nodes []Addr64, handler ModbusHandler, cfg *ModbusConfig) *ModbusServer {
+	s := &ModbusServer{xb: xb, cfg: cfg.withDefaults(), handler: handler, done: make(chan struct{})}
+	var served map[Addr64]bool
+	scope := matchAny
+	if nodes != nil {
+		scope = matchSource
+		served = make(map[Addr64]bool, len(nodes))
+		for _, addr := range nodes {
+			served[addr] = true
+		}
+	}
+	s.m = xb.registerMatcher(scope, func(ev Event) bool {
+		src, _, ok := serialData(ev)
+		return ok && (served == nil || served[src])
+	})
+	go s.serve()
+	return s
+}
+
+// Close stops the server.
+func (s *ModbusServer) Close() error {
+	s.once.Do(func() {
+		s.xb.unregisterMatcher(s.m)
+		close(s.done)
+	})
+	return nil
+}
+
+func (s *ModbusServer) serve() {
+	asms := make(map[Addr64]*modbusAssembler)
+	t := time.NewTicker(s.cfg.Gap / 2)
+	defer t.Stop()
+	for {
+		select {
+		case ev := <-s.m.ch:
+			src, data, _ := serialData(ev)
+			a := asms[src]
+			if a == nil {
+				a = &modbusAssembler{gap: s.cfg.Gap, lenFunc: modbusRequestLen}
+				asms[src] = a
+			}
+			frames, _ := a.add(data, time.Now())
+			for _, f := range frames {
+				s.handle(src, f)
+			}
+		case now := <-t.C:
+			for src, a := range asms {
+				if f := a.flush(now); f != nil {
+					s.handle(src, f)
+				}
+				if len(a.buf) == 0 && now.Sub(a.last) > time.Minute {
+					delete(asms, src)
+				}
+			}
+		case <-s.done:
+			return
+		case <-s.xb.closed:
+			return
+		}
+	}
+}
+
+func (s *ModbusServer) handle(src Addr64, frame []byte) {
+	unit, pdu, err := ParseModbusFrame(frame)
+	if err != nil {
+		s.xb.log.Warn("xbee: dropping Modbus request", "source", src, "err", err)
+		return
+	}
+	res := s.handler(src, unit, pdu)
+	if res == nil || unit == 0 {
+		return
+	}
+	go func() {
+		if err := s.xb.Transmit(src, ModbusFrame(unit, res), WithRetry(s.cfg.Retry)); err != nil {
+			s.xb.log.Warn("xbee: failed to send Modbus response", "dest", src, "err", err)
+		}
+	}()
+}
@@ -0,0 +1,289 @@
+package xbee
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMonitorInterval  = 30 * time.Second
+	defaultMonitorTimeout   = 5 * time.Second
+	defaultMonitorDownAfter = 3
+	monitorQueueLen         = 16
+)
+
+// loopbackAddress is the application addressing of the loopback cluster
+// which Digi firmware answers by echoing the data back to the sender.
+var loopbackAddress = ExplicitAddress{
+	SourceEndpoint:      EndpointDigiData,
+	DestinationEndpoint: EndpointDigiData,
+	ClusterID:           ClusterLoopback,
+	ProfileID:           ProfileDigi,
+}
+
+// PingResult is the outcome of Ping.
+type PingResult struct {
+	RTT time.Duration
+	// Retries is the number of MAC retransmissions of the request
+	// reported by the transmit status.
+	Retries int
+	// Discovery is the address and route discovery needed to deliver
+	// the request.
+	Discovery DiscoveryStatus
+}
+
+// Ping sends data to the loopback cluster of dest and waits for it to be
+// echoed back returning the round trip time and the retries needed to
+// deliver the request. data may be nil to send a unique payload. The echo
+// is received as an explicit packet so explicit receive (AO=1) must be
+// enabled. Concurrent pings to the same node should use different data.
+func (xb *XBee) Ping(ctx context.Context, dest Addr64, data []byte) (PingResult, error) {
+	if dest.IsBroadcast() || dest == AddressUnknown {
+		return PingResult{}, ErrInvalidParameter
+	}
+	if data == nil {
+		var b [8]byte
+		binary.BigEndian.PutUint32(b[:], xb.pingSeq.Add(1))
+		binary.BigEndian.PutUint32(b[4:], uint32(time.Now().UnixNano()))
+		data = b[:]
+	}
+	m := xb.registerMatcher(matchSession, func(ev Event) bool {
+		rx, ok := ev.(*ExplicitReceivePacket)
+		return ok && sentBy(dest, rx.SourceAddress, rx.SourceAddress16) && rx.ClusterID == ClusterLoopback &&
+			rx.ProfileID == ProfileDigi && bytes.Equal(rx.Data, data)
+	})
+	defer xb.unregisterMatcher(m)
+	start := time.Now()
+	ts, err := xb.transmitExplicitStatusFrame(dest, loopbackAddress, data)
+	if err != nil {
+		return PingResult{}, err
+	}
+	res := PingResult{Retries: ts.RetryCount, Discovery: ts.DiscoveryStatus}
+	select {
+	case <-m.ch:
+		res.RTT = time.Since(start)
+		return res, nil
+	case <-ctx.Done():
+		return res, ctx.Err()
+	case <-xb.closed:
+		return res, ErrClosed
+	}
+}
+
+// MonitorConfig configures a Monitor. The zero value uses the defaults.
+type MonitorConfig struct {
+	// Nodes are the nodes to monitor. The default is every node returned
+	// by NodeStats at the time of each check.
+	Nodes []Addr64
+	// Interval is the time between checks. Nodes heard from since the
+	// last check aren't pinged. The default is 30s.
+	Interval time.Duration
+	// Timeout is how long to wait for each ping. The default is 5s.
+	Timeout time.Duration
+	// DownAfter is the number of consecutive failed pings before a node
+	// is reported down. The default is 3.
+	DownAfter int
+	// RemoteAT pings nodes with a remote AT command (VR) rather than the
+	// loopback cluster for networks without explicit receive enabled.
+	RemoteAT bool
+}
+
+// NodeUp is delivered by a Monitor when a node responds for the first
+// time or after being reported down.
+type NodeUp struct {
+	Address Addr64
+	Latency time.Duration // zero if the node was heard from without a ping
+}
+
+// NodeDown is delivered by a Monitor when a node fails to respond to
+// DownAfter consecutive pings.
+type NodeDown struct {
+	Address  Addr64
+	LastSeen time.Time // zero if never seen
+}
+
+// NodeHealth is the state of a node tracked by a Monitor.
+type NodeHealth struct {
+	Address  Addr64
+	Up       bool
+	Latency  time.Duration // round trip time of the last successful ping
+	Failures int           // consecutive failed pings
+	LastSeen time.Time
+}
+
+// Monitor periodically checks that nodes are reachable and reports
+// changes as NodeUp and NodeDown events.
+type Monitor struct {
+	xb     *XBee
+	cfg    MonitorConfig
+	events chan Event
+	done   chan struct{}
+	once   sync.Once
+
+	mu    sync.Mutex
+	nodes map[Addr64]*NodeHealth
+	seq   uint32
+}
+
+// NewMonitor starts monitoring nodes. cfg may be nil to use the defaults.
+// Events must be read from Events or checks stop.
+func (xb *XBee) NewMonitor(cfg *MonitorConfig) *Monitor {
+	m := &Monitor{
+		xb:     xb,
+		events: make(chan Event, monitorQueueLen),
+		done:   make(chan struct{}),
+		nodes:  make(map[Addr64]*NodeHealth),
+	}
+	if cfg != nil {
+		m.cfg = *cfg
+	}
+	if m.cfg.Interval <= 0 {
+		m.cfg.Interval = defaultMonitorInterval
+	}
+	if m.cfg.Timeout <= 0 {
+		m.cfg.Timeout = defaultMonitorTimeout
+	}
+	if m.cfg.DownAfter <= 0 {
+		m.cfg.DownAfter = defaultMonitorDownAfter
+	}
+	go m.loop()
+	return m
+}
+
+// Events returns the channel on which NodeUp and NodeDown events are
+// delivered. It's closed by Close.
+func (m *Monitor) Events() <-chan Event {
+	return m.events
+}
+
+// Health returns the state of the monitored nodes ordered by address.
+func (m *Monitor) Health() []NodeHealth {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	health := make([]NodeHealth, 0, len(m.nodes))
+	for _, h := range m.nodes {
+		health = append(health, *h)
+	}
+	sort.Slice(health, func(i, j int) bool {
+		return health[i].Address < health[j].Address
+	})
+	return health
+}
+
+// Close stops monitoring.
+func (m *Monitor) Close() error {
+	m.once.Do(func() {
+		close(m.done)
+	})
+	return nil
+}
+
+func (m *Monitor) loop() {
+	defer close(m.events)
+	t := time.NewTicker(m.cfg.Interval)
+	defer t.Stop()
+	for {
+		if !m.checkAll() {
+			return
+		}
+		select {
+		case <-t.C:
+		case <-m.done:
+			return
+		case <-m.xb.closed:
+			return
+		}
+	}
+}
+
+// checkAll checks every node returning false if the monitor was closed.
+func (m *Monitor) checkAll() bool {
+	lastSeen := make(map[Addr64]time.Time)
+	for _, n := range m.xb.NodeStats() {
+		lastSeen[n.Address] = n.LastSeen
+	}
+	nodes := m.cfg.Nodes
+	if nodes == nil {
+		for addr := range lastSeen {
+			nodes = append(nodes, addr)
+		}
+	}
+	for _, addr := range nodes {
+		if ev := m.check(addr, lastSeen[addr]); ev != nil {
+			select {
+			case m.events <- ev:
+			case <-m.done:
+				return false
+			}
+		}
+		select {
+		case <-m.done:
+			return false
+		default:
+		}
+	}
+	return true
+}
+
+// check updates the state of a node pinging it unless it's been heard from
+// since the last check, and returns the event to report if any.
+func (m *Monitor) check(addr Addr64, seen time.Time) Event {
+	m.mu.Lock()
+	h := m.nodes[addr]
+	if h == nil {
+		h = &NodeHealth{Address: addr}
+		m.nodes[addr] = h
+	}
+	heard := seen.After(h.LastSeen) && time.Since(seen) < m.cfg.Interval
+	if seen.After(h.LastSeen) {
+		h.LastSeen = seen
+	}
+	m.seq++
+	seq := m.seq
+	m.mu.Unlock()
+
+	var latency time.Duration
+	var err error
+	if !heard {
+		latency, err = m.ping(addr, seq)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		h.Failures++
+		if h.Failures != m.cfg.DownAfter {
+			return nil
+		}
+		h.Up = false
+		return &NodeDown{Address: addr, LastSeen: h.LastSeen}
+	}
+	h.Failures = 0
+	if !heard {
+		h.Latency = latency
+		h.LastSeen = time.Now()
+	}
+	if h.Up {
+		return nil
+	}
+	h.Up = true
+	return &NodeUp{Address: addr, Latency: latency}
+}
+
+func (m *Monitor) ping(addr Addr64, seq uint32) (time.Duration, error) {
+	if m.cfg.RemoteAT {
+		start := time.Now()
+		_, err := m.xb.remoteATCommand(m.cfg.Timeout, addr, Address16Unknown, atFirmwareVersion, nil, 0)
+		return time.Since(start), err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), m.cfg.Timeout)
+	defer cancel()
+	var data [4]byte
+	binary.BigEndian.PutUint32(data[:], seq)
+	res, err := m.xb.Ping(ctx, addr, data[:])
+	return res.RTT, err
+}
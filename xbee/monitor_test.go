@@ -0,0 +1,40 @@
+package xbee_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/samuel/go-xbee/xbee"
+)
+
+func TestPing(t *testing.T) {
+	coord, router := openPAN(t)
+	tests := []struct {
+		xb   *xbee.XBee
+		dest xbee.Addr64
+	}{
+		{router, coordAddr},
+		{router, xbee.AddressCoordinator},
+		{coord, routerAddr},
+	}
+	for _, tc := range tests {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		res, err := tc.xb.Ping(ctx, tc.dest, nil)
+		cancel()
+		if err != nil {
+			t.Fatalf("Ping(%s): %v", tc.dest, err)
+		}
+		if res.RTT <= 0 {
+			t.Errorf("Ping(%s) RTT = %s, want > 0", tc.dest, res.RTT)
+		}
+	}
+}
+
+func TestPingUnreachable(t *testing.T) {
+	_, router := openPAN(t)
+	_, err := router.Ping(context.Background(), 0x0013a20040000099, nil)
+	if de, ok := err.(*xbee.DeliveryError); !ok || de.Status != xbee.DSAddressNotFound {
+		t.Fatalf("Ping = %v, want DeliveryError %s", err, xbee.DSAddressNotFound)
+	}
+}
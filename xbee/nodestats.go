@@ -0,0 +1,166 @@
+package xbee
+
+import (
+	"encoding/binary"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/samuel/go-xbee/xbee/frames"
+)
+
+// NodeStats describes a remote node seen in node discovery, a device
+// announcement, or a received packet.
+type NodeStats struct {
+	Address         Addr64
+	Address16       Addr16
+	NodeID          string     // only known from node discovery
+	DeviceType      DeviceType // DeviceTypeUnknown until discovered or announced
+	LastSeen        time.Time
+	PacketsReceived uint64
+	// RSSI is the signal strength in dBm of the last hop of a packet
+	// received from the node. It's only sampled if enabled with
+	// WithRSSISampling and is 0 if unknown.
+	RSSI int
+}
+
+const rssiTimeout = time.Second
+
+type nodeTable struct {
+	mu    sync.Mutex
+	nodes map[Addr64]*NodeStats
+	dirty bool // changed since last saved
+}
+
+// nodeLocked returns the entry for addr adding it if necessary.
+func (t *nodeTable) nodeLocked(addr Addr64) *NodeStats {
+	if t.nodes == nil {
+		t.nodes = make(map[Addr64]*NodeStats)
+	}
+	n := t.nodes[addr]
+	if n == nil {
+		n = &NodeStats{Address: addr, DeviceType: DeviceTypeUnknown}
+		t.nodes[addr] = n
+	}
+	t.dirty = true
+	return n
+}
+
+// frameReceived records a frame from a remote node returning the address
+// of the node or false if the frame didn't come from one.
+func (t *nodeTable) frameReceived(f frames.Frame) (Addr64, bool) {
+	var addr Addr64
+	var addr16 Addr16
+	var announce []byte
+	switch f := f.(type) {
+	case *frames.ReceivePacket:
+		addr, addr16 = f.SourceAddress, f.SourceAddress16
+	case *frames.ExplicitReceivePacket:
+		addr, addr16 = f.SourceAddress, f.SourceAddress16
+		if f.ProfileID == ProfileZDO && f.ClusterID == clusterDeviceAnnounce {
+			announce = f.Data
+		}
+	case *frames.RemoteATCommandResponse:
+		addr, addr16 = f.SourceAddress, f.SourceAddress16
+	default:
+		return 0, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n := t.nodeLocked(addr)
+	n.Address16 = addr16
+	n.LastSeen = time.Now()
+	n.PacketsReceived++
+	// Device_annce: sequence number, little-endian 16 and 64-bit
+	// addresses, and the capability flags with bit 1 set for routers.
+	if len(announce) >= 12 {
+		n := t.nodeLocked(Addr64(binary.LittleEndian.Uint64(announce[3:])))
+		n.Address16 = Addr16(binary.LittleEndian.Uint16(announce[1:]))
+		n.LastSeen = time.Now()
+		if announce[11]&0x02 != 0 {
+			n.DeviceType = Router
+		} else {
+			n.DeviceType = EndDevice
+		}
+	}
+	return addr, true
+}
+
+// discovered records a node found by node discovery.
+func (t *nodeTable) discovered(nd *Node, addr16 Addr16) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n := t.nodeLocked(nd.SerialNumber)
+	n.Address16 = addr16
+	n.NodeID = nd.NodeID
+	n.DeviceType = nd.DeviceType
+	n.LastSeen = time.Now()
+}
+
+func (t *nodeTable) setRSSI(addr Addr64, rssi int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if n := t.nodes[addr]; n != nil {
+		n.RSSI = rssi
+	}
+}
+
+// NodeStats returns the nodes that have been seen ordered by address. It
+// includes nodes loaded from the store set by WithNodeStore.
+func (xb *XBee) NodeStats() []NodeStats {
+	xb.nodes.mu.Lock()
+	defer xb.nodes.mu.Unlock()
+	stats := make([]NodeStats, 0, len(xb.nodes.nodes))
+	for _, n := range xb.nodes.nodes {
+		stats = append(stats, *n)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Address < stats[j].Address
+	})
+	return stats
+}
+
+// sampleRSSI queries the signal strength of the last received packet
+// (DB) after packets are received attributing it to the node that sent
+// the packet. If packets arrive faster than they can be sampled then only
+// the latest is sampled so the value is approximate on a busy network.
+func (xb *XBee) sampleRSSI() {
+	for addr := range xb.rssiCh {
+		if rssi, ok := xb.lastRSSI(); ok {
+			xb.nodes.setRSSI(addr, rssi)
+		}
+	}
+}
+
+// lastRSSI returns the signal strength in dBm of the last received packet.
+func (xb *XBee) lastRSSI() (int, bool) {
+	ev, err := xb.request(rssiTimeout, func(frameID byte) frames.Frame {
+		return &frames.ATCommandRequest{FrameID: frameID, ATCommand: atReceivedSignalStrength}
+	})
+	if err != nil {
+		return 0, false
+	}
+	res, ok := ev.(*ATCommandResponse)
+	if !ok || validateATResponse(atReceivedSignalStrength, res) != nil || len(res.Data) == 0 {
+		return 0, false
+	}
+	return -int(decodeUint(res.Data)), true
+}
+
+// queueRSSI requests a sample for addr replacing any pending request so
+// readLoop never blocks.
+func (xb *XBee) queueRSSI(addr Addr64) {
+	select {
+	case xb.rssiCh <- addr:
+		return
+	default:
+	}
+	select {
+	case <-xb.rssiCh:
+	default:
+	}
+	select {
+	case xb.rssiCh <- addr:
+	default:
+	}
+}
@@ -0,0 +1,117 @@
+package xbee
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// nodeSaveInterval is how often changes to the node registry are saved.
+const nodeSaveInterval = time.Minute
+
+// NodeStore persists the registry of nodes returned by NodeStats so it
+// survives a restart.
+type NodeStore interface {
+	// Load returns the saved nodes. It should return no nodes and no
+	// error if nothing has been saved.
+	Load() ([]NodeStats, error)
+	Save(nodes []NodeStats) error
+}
+
+// JSONFileStore is a NodeStore that saves the nodes as JSON to a file.
+type JSONFileStore struct {
+	Path string
+}
+
+func (s *JSONFileStore) Load() ([]NodeStats, error) {
+	b, err := os.ReadFile(s.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var nodes []NodeStats
+	if err := json.Unmarshal(b, &nodes); err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// Save writes the nodes to a temporary file that replaces the file so it's
+// never left partially written.
+func (s *JSONFileStore) Save(nodes []NodeStats) error {
+	b, err := json.MarshalIndent(nodes, "", "\t")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.Path, b)
+}
+
+// writeFileAtomic writes a temporary file that replaces the named file.
+func writeFileAtomic(name string, b []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
+
+// load adds nodes from a store keeping newer information already in the
+// table.
+func (t *nodeTable) load(nodes []NodeStats) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.nodes == nil {
+		t.nodes = make(map[Addr64]*NodeStats)
+	}
+	for _, n := range nodes {
+		if cur := t.nodes[n.Address]; cur == nil || cur.LastSeen.Before(n.LastSeen) {
+			n := n
+			t.nodes[n.Address] = &n
+		}
+	}
+}
+
+// SaveNodes saves the node registry to the store set by WithNodeStore.
+// Changes are also saved periodically and by Close.
+func (xb *XBee) SaveNodes() error {
+	if xb.nodeStore == nil {
+		return nil
+	}
+	xb.nodes.mu.Lock()
+	xb.nodes.dirty = false
+	xb.nodes.mu.Unlock()
+	return xb.nodeStore.Save(xb.NodeStats())
+}
+
+func (xb *XBee) saveNodesLoop() {
+	t := time.NewTicker(nodeSaveInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+		case <-xb.closed:
+			return
+		}
+		xb.nodes.mu.Lock()
+		dirty := xb.nodes.dirty
+		xb.nodes.mu.Unlock()
+		if dirty {
+			if err := xb.SaveNodes(); err != nil {
+				xb.log.Warn("xbee: failed to save nodes", "err", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package xbee
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/samuel/go-xbee/xbee/frames"
+)
+
+// Option configures an XBee when passed to Open.
+type Option func(*options)
+
+type options struct {
+	mode      APIMode
+	detect    bool
+	configure bool
+	tap       *frames.TapWriter
+	recorder  *Recorder
+	logger    *slog.Logger
+	tracer    FrameTracer
+	reqTracer RequestTracer
+	rssi      bool
+	nodeStore NodeStore
+	pooled    bool
+
+	middleware []Middleware
+
+	dedupWindow time.Duration
+	dedupMode   DedupMode
+
+	writeQueue  int
+	maxInFlight int
+
+	rate      float64
+	burst     int
+	dutyCycle float64
+	bitrate   int
+}
+
+// WithAPIMode sets the API mode of the module. The default is
+// APIModeUnescaped (AP=1).
+func WithAPIMode(mode APIMode) Option {
+	return func(o *options) {
+		o.mode = mode
+	}
+}
+
+// WithAutoDetect probes the module using DetectAPIMode to select the API
+// mode. If the module is in transparent mode and configure is true then
+// it's switched to API mode (AP=1) and the setting saved, otherwise Open
+// returns ErrTransparentMode. Probing enters command mode first which
+// takes a few seconds of guard time.
+func WithAutoDetect(configure bool) Option {
+	return func(o *options) {
+		o.detect = true
+		o.configure = configure
+	}
+}
+
+// WithTap records every frame sent and received to t.
+func WithTap(t *frames.TapWriter) Option {
+	return func(o *options) {
+		o.tap = t
+	}
+}
+
+// WithRecorder records every packet and I/O sample received with r. r
+// isn't closed by Close.
+func WithRecorder(r *Recorder) Option {
+	return func(o *options) {
+		o.recorder = r
+	}
+}
+
+// WithLogger sets the logger used for problems that aren't returned as
+// errors such as corrupt frames and dropped events. The default is
+// slog.Default(). Use a handler with a higher level to silence warnings.
+func WithLogger(l *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// WithFrameTracer calls t for every frame sent and received.
+func WithFrameTracer(t FrameTracer) Option {
+	return func(o *options) {
+		o.tracer = t
+	}
+}
+
+// WithRequestTracer calls t to trace the round trip of every request.
+func WithRequestTracer(t RequestTracer) Option {
+	return func(o *options) {
+		o.reqTracer = t
+	}
+}
+
+// WithWriteQueue sets the number of frames of each priority that can be
+// queued to be written before sending blocks. The default is DefaultWriteQueue.
+func WithWriteQueue(n int) Option {
+	return func(o *options) {
+		o.writeQueue = n
+	}
+}
+
+// WithMaxInFlight limits the number of frames with a frame ID written to
+// the module that haven't received a response (e.g. transmits awaiting a
+// transmit status) to avoid overflowing the module's buffers. Sending
+// blocks while the limit is reached. A frame without a response stops
+// counting against the limit after 10 seconds. The default of 0 is no
+// limit.
+func WithMaxInFlight(n int) Option {
+	return func(o *options) {
+		o.maxInFlight = n
+	}
+}
+
+// WithTransmitRateLimit limits frames that are transmitted over the air
+// (transmits, remote AT commands, and remote file system requests) to rate
+// per second allowing bursts of up to burst frames. Sending blocks until
+// the frame is allowed.
+func WithTransmitRateLimit(rate float64, burst int) Option {
+	return func(o *options) {
+		o.rate = rate
+		o.burst = burst
+	}
+}
+
+// WithDutyCycle tracks the airtime of transmitted frames estimated from the
+// RF data rate in bits per second (e.g. 10000 or 80000 for the XBee SX 868)
+// and rejects frames with ErrDutyCycle that would exceed fraction (e.g.
+// 0.01 for 1%) of the last hour. The estimate includes a fixed allowance
+// for headers but not retries so a margin should be left below the
+// regulatory limit.
+func WithDutyCycle(fraction float64, bitrate int) Option {
+	return func(o *options) {
+		o.dutyCycle = fraction
+		o.bitrate = bitrate
+	}
+}
+
+// WithNodeStore loads the node registry returned by NodeStats from s when
+// opening and saves it when it changes (at most once a minute) and on
+// Close.
+func WithNodeStore(s NodeStore) Option {
+	return func(o *options) {
+		o.nodeStore = s
+	}
+}
+
+// WithRSSISampling queries the signal strength (DB) after packets are
+// received from remote nodes to report it in NodeStats. This adds a local
+// AT command for every received packet (or burst of packets).
+func WithRSSISampling() Option {
+	return func(o *options) {
+		o.rssi = true
+	}
+}
+
+// WithPooledBuffers reads frames into buffers from a pool to reduce
+// allocations on busy networks. ReceivePacket, ExplicitReceivePacket, and
+// IPv4ReceivePacket events delivered by EventChan reference a pooled
+// buffer and must be passed to Release once they're no longer used.
+// Frames dropped or replaced by middleware are released when the chain
+// returns and those yielded by Events when yield returns. Once 256 frames
+// are leased without being released further frames aren't pooled and a
+// warning is logged.
+func WithPooledBuffers() Option {
+	return func(o *options) {
+		o.pooled = true
+	}
+}
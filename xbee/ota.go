@@ -0,0 +1,209 @@
+package xbee
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ClusterOTA is the Zigbee OTA upgrade cluster. XBee3 modules act as the
+// OTA client on the Digi data endpoint requesting images from a server.
+const ClusterOTA uint16 = 0x0019
+
+const (
+	otaFileMagic     = 0x0BEEF11E
+	otaMinHeaderLen  = 56
+	otaMaxBlockSize  = 64
+	otaQueryJitter   = 100
+	otaIdleTimeout   = time.Minute
+	otaUpgradeNow    = 0
+	otaManufSpecific = 0x04
+
+	// ZCL frame control for cluster specific commands from the server to
+	// the client with the default response disabled.
+	zclServerToClient = 0x19
+
+	otaImageNotify        byte = 0x00
+	otaQueryNextImageReq  byte = 0x01
+	otaQueryNextImageResp byte = 0x02
+	otaImageBlockReq      byte = 0x03
+	otaImageBlockResp     byte = 0x05
+	otaUpgradeEndReq      byte = 0x06
+	otaUpgradeEndResp     byte = 0x07
+
+	otaStatusSuccess          byte = 0x00
+	otaStatusMalformed        byte = 0x80
+	otaStatusNoImageAvailable byte = 0x98
+)
+
+var otaAddress = ExplicitAddress{
+	SourceEndpoint:      EndpointDigiData,
+	DestinationEndpoint: EndpointDigiData,
+	ClusterID:           ClusterOTA,
+	ProfileID:           ProfileDigi,
+}
+
+// ErrNoImageUpdate is returned by ServeOTAImage when the module reports it
+// already runs the image or it doesn't match the module.
+var ErrNoImageUpdate = errors.New("xbee: OTA image not applicable to module")
+
+// OTAUpgradeError is returned when a module aborts an OTA upgrade.
+type OTAUpgradeError struct {
+	Status byte
+}
+
+func (e *OTAUpgradeError) Error() string {
+	return fmt.Sprintf("xbee: OTA upgrade failed with status 0x%02x", e.Status)
+}
+
+// OTAImage is a Zigbee OTA upgrade file (.ota).
+type OTAImage struct {
+	ManufacturerCode uint16
+	ImageType        uint16
+	FileVersion      uint32
+	HeaderString     string
+	Data             []byte // the complete file including the header
+}
+
+// ParseOTAImage parses the header of a Zigbee OTA upgrade file.
+func ParseOTAImage(b []byte) (*OTAImage, error) {
+	if len(b) < otaMinHeaderLen {
+		return nil, fmt.Errorf("xbee.ParseOTAImage: %w: file too short (%d bytes)", ErrMalformedFrame, len(b))
+	}
+	if binary.LittleEndian.Uint32(b) != otaFileMagic {
+		return nil, fmt.Errorf("xbee.ParseOTAImage: %w: bad file identifier", ErrMalformedFrame)
+	}
+	size := binary.LittleEndian.Uint32(b[52:])
+	if int(size) != len(b) {
+		return nil, fmt.Errorf("xbee.ParseOTAImage: %w: header size %d does not match file size %d", ErrMalformedFrame, size, len(b))
+	}
+	hdr := b[20:52]
+	for i, c := range hdr {
+		if c == 0 {
+			hdr = hdr[:i]
+			break
+		}
+	}
+	return &OTAImage{
+		ManufacturerCode: binary.LittleEndian.Uint16(b[10:]),
+		ImageType:        binary.LittleEndian.Uint16(b[12:]),
+		FileVersion:      binary.LittleEndian.Uint32(b[14:]),
+		HeaderString:     string(hdr),
+		Data:             b,
+	}, nil
+}
+
+func (img *OTAImage) appendID(b []byte) []byte {
+	b = binary.LittleEndian.AppendUint16(b, img.ManufacturerCode)
+	b = binary.LittleEndian.AppendUint16(b, img.ImageType)
+	return binary.LittleEndian.AppendUint32(b, img.FileVersion)
+}
+
+func (img *OTAImage) matches(manuf, imageType uint16) bool {
+	return img.ManufacturerCode == manuf && img.ImageType == imageType
+}
+
+// ServeOTAImage acts as the OTA server for a single remote XBee3 module.
+// It notifies the module of the image then serves the blocks it requests
+// until the module reports the upgrade finished. The module tracks the
+// offset itself so an interrupted upgrade resumes where it left off when
+// ServeOTAImage is called again. It returns ErrTimeout if the module stops
+// requesting blocks.
+func (xb *XBee) ServeOTAImage(ctx context.Context, dest Addr64, img *OTAImage, progress func(FirmwareProgress)) error {
+	m := xb.explicitMatcher(dest, ClusterOTA, ProfileDigi)
+	defer xb.unregisterMatcher(m)
+
+	var seq byte
+	send := func(cmd byte, payload []byte) error {
+		b := append([]byte{zclServerToClient, seq, cmd}, payload...)
+		return xb.transmitExplicit(PriorityBulk, dest, Address16Unknown, otaAddress, 0, 0, b)
+	}
+	if err := send(otaImageNotify, []byte{0, otaQueryJitter}); err != nil {
+		return err
+	}
+
+	for {
+		rx, err := waitExplicit(ctx, m, otaIdleTimeout)
+		if err != nil {
+			return err
+		}
+		b := rx.Data
+		hdrLen := 3
+		if len(b) > 0 && b[0]&otaManufSpecific != 0 {
+			hdrLen = 5
+		}
+		if len(b) < hdrLen {
+			continue
+		}
+		seq = b[hdrLen-2]
+		cmd := b[hdrLen-1]
+		b = b[hdrLen:]
+
+		switch cmd {
+		case otaQueryNextImageReq:
+			if len(b) < 9 {
+				if err := send(otaQueryNextImageResp, []byte{otaStatusMalformed}); err != nil {
+					return err
+				}
+				continue
+			}
+			manuf := binary.LittleEndian.Uint16(b[1:])
+			imageType := binary.LittleEndian.Uint16(b[3:])
+			version := binary.LittleEndian.Uint32(b[5:])
+			if !img.matches(manuf, imageType) || version == img.FileVersion {
+				send(otaQueryNextImageResp, []byte{otaStatusNoImageAvailable})
+				return ErrNoImageUpdate
+			}
+			res := img.appendID([]byte{otaStatusSuccess})
+			res = binary.LittleEndian.AppendUint32(res, uint32(len(img.Data)))
+			if err := send(otaQueryNextImageResp, res); err != nil {
+				return err
+			}
+		case otaImageBlockReq:
+			if len(b) < 14 {
+				if err := send(otaImageBlockResp, []byte{otaStatusMalformed}); err != nil {
+					return err
+				}
+				continue
+			}
+			offset := int(binary.LittleEndian.Uint32(b[9:]))
+			if offset > len(img.Data) {
+				if err := send(otaImageBlockResp, []byte{otaStatusMalformed}); err != nil {
+					return err
+				}
+				continue
+			}
+			n := min(int(b[13]), otaMaxBlockSize, len(img.Data)-offset)
+			res := img.appendID([]byte{otaStatusSuccess})
+			res = binary.LittleEndian.AppendUint32(res, uint32(offset))
+			res = append(res, byte(n))
+			res = append(res, img.Data[offset:offset+n]...)
+			if err := send(otaImageBlockResp, res); err != nil {
+				return err
+			}
+			if progress != nil {
+				progress(FirmwareProgress{State: FirmwareTransferring, Offset: offset + n, Total: len(img.Data)})
+			}
+		case otaUpgradeEndReq:
+			if len(b) < 1 {
+				continue
+			}
+			if b[0] != otaStatusSuccess {
+				return &OTAUpgradeError{Status: b[0]}
+			}
+			res := img.appendID(nil)
+			res = binary.LittleEndian.AppendUint32(res, 0)
+			res = binary.LittleEndian.AppendUint32(res, otaUpgradeNow)
+			if err := send(otaUpgradeEndResp, res); err != nil {
+				return err
+			}
+			if progress != nil {
+				progress(FirmwareProgress{State: FirmwareInstalling, Offset: len(img.Data), Total: len(img.Data)})
+				progress(FirmwareProgress{State: FirmwareDone, Offset: len(img.Data), Total: len(img.Data)})
+			}
+			return nil
+		}
+	}
+}
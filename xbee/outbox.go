@@ -0,0 +1,407 @@
+package xbee
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultOutboxTTL           = 24 * time.Hour
+	defaultOutboxRetryInterval = time.Minute
+	defaultOutboxMaxMessages   = 1000
+	outboxCheckInterval        = 5 * time.Second
+	outboxQueueLen             = 16
+)
+
+// ErrOutboxFull is returned by Outbox.Send when the outbox holds
+// MaxMessages messages.
+var ErrOutboxFull = errors.New("xbee: outbox full")
+
+// OutboxMessage is a message queued in an Outbox.
+type OutboxMessage struct {
+	ID        uint64
+	Dest      Addr64
+	Data      []byte
+	Queued    time.Time
+	Expires   time.Time
+	Attempts  int    // failed delivery attempts
+	LastError string `json:",omitempty"`
+}
+
+// OutboxStore persists the messages of an Outbox so they survive a
+// restart.
+type OutboxStore interface {
+	// Load returns the saved messages. It should return no messages and
+	// no error if nothing has been saved.
+	Load() ([]OutboxMessage, error)
+	Save(msgs []OutboxMessage) error
+}
+
+// JSONFileOutboxStore is an OutboxStore that saves the messages as JSON
+// to a file.
+type JSONFileOutboxStore struct {
+	Path string
+}
+
+func (s *JSONFileOutboxStore) Load() ([]OutboxMessage, error) {
+	b, err := os.ReadFile(s.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var msgs []OutboxMessage
+	if err := json.Unmarshal(b, &msgs); err != nil {
+		return nil, err
+	}
+	return msgs, nil
+}
+
+// Save writes the messages to a temporary file that replaces the file so
+// it's never left partially written.
+func (s *JSONFileOutboxStore) Save(msgs []OutboxMessage) error {
+	b, err := json.Marshal(msgs)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.Path, b)
+}
+
+// OutboxConfig configures an Outbox. The zero value uses the defaults.
+type OutboxConfig struct {
+	// Store persists the queued messages. The default keeps them in
+	// memory only.
+	Store OutboxStore
+	// TTL is how long a message is kept before it's dropped undelivered.
+	// The default is 24h.
+	TTL time.Duration
+	// RetryInterval is the time between attempts for a destination that
+	// hasn't been heard from. Destinations are retried as soon as a
+	// frame is received from them (e.g. a device announce or a ping
+	// response) or Retry is called. The default is 1m.
+	RetryInterval time.Duration
+	// Retry is the retry policy of each attempt (see WithRetry).
+	Retry *RetryPolicy
+	// MaxMessages is the most messages queued at once. The default is
+	// 1000.
+	MaxMessages int
+}
+
+// OutboxDelivered is delivered by an Outbox when a message is delivered.
+type OutboxDelivered struct {
+	Message OutboxMessage
+}
+
+// OutboxExpired is delivered by an Outbox when a message is dropped
+// because its TTL passed.
+type OutboxExpired struct {
+	Message OutboxMessage
+}
+
+// Outbox is a durable store-and-forward queue of outgoing messages.
+// Messages are delivered in order for each destination; while the first
+// message for a destination can't be delivered the rest wait behind it.
+type Outbox struct {
+	xb     *XBee
+	cfg    OutboxConfig
+	events chan Event
+	kick   chan struct{}
+	done   chan struct{}
+	once   sync.Once
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	msgs   []OutboxMessage // in queued order
+	nextID uint64
+	tried  map[Addr64]time.Time // last attempt by destination
+	due    map[Addr64]bool      // destinations to retry immediately
+}
+
+// NewOutbox loads the messages saved in the configured store and starts
+// delivering them. cfg may be nil to use the defaults. Events must be read
+// from Events or delivery stops.
+func (xb *XBee) NewOutbox(cfg *OutboxConfig) (*Outbox, error) {
+	o := &Outbox{
+		xb:     xb,
+		events: make(chan Event, outboxQueueLen),
+		kick:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+		tried:  make(map[Addr64]time.Time),
+		due:    make(map[Addr64]bool),
+	}
+	if cfg != nil {
+		o.cfg = *cfg
+	}
+	if o.cfg.TTL <= 0 {
+		o.cfg.TTL = defaultOutboxTTL
+	}
+	if o.cfg.RetryInterval <= 0 {
+		o.cfg.RetryInterval = defaultOutboxRetryInterval
+	}
+	if o.cfg.MaxMessages <= 0 {
+		o.cfg.MaxMessages = defaultOutboxMaxMessages
+	}
+	if o.cfg.Store != nil {
+		msgs, err := o.cfg.Store.Load()
+		if err != nil {
+			return nil, fmt.Errorf("xbee.NewOutbox: %w", err)
+		}
+		o.msgs = msgs
+		for _, m := range msgs {
+			o.nextID = max(o.nextID, m.ID)
+			o.due[m.Dest] = true
+		}
+	}
+	o.ctx, o.cancel = context.WithCancel(context.Background())
+	go o.loop()
+	return o, nil
+}
+
+// Send queues data for dest returning the ID of the message. Delivery is
+// attempted right away.
+func (o *Outbox) Send(dest Addr64, data []byte) (uint64, error) {
+	if dest.IsBroadcast() || dest == AddressUnknown {
+		return 0, ErrInvalidParameter
+	}
+	o.mu.Lock()
+	if len(o.msgs) >= o.cfg.MaxMessages {
+		o.mu.Unlock()
+		return 0, ErrOutboxFull
+	}
+	now := time.Now()
+	o.nextID++
+	m := OutboxMessage{
+		ID:      o.nextID,
+		Dest:    dest,
+		Data:    append([]byte(nil), data...),
+		Queued:  now,
+		Expires: now.Add(o.cfg.TTL),
+	}
+	o.msgs = append(o.msgs, m)
+	o.due[dest] = true
+	err := o.saveLocked()
+	o.mu.Unlock()
+	o.wake()
+	return m.ID, err
+}
+
+// Messages returns the queued messages in the order they were queued.
+func (o *Outbox) Messages() []OutboxMessage {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]OutboxMessage(nil), o.msgs...)
+}
+
+// Destinations returns the number of queued messages by destination.
+func (o *Outbox) Destinations() map[Addr64]int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	n := make(map[Addr64]int)
+	for _, m := range o.msgs {
+		n[m.Dest]++
+	}
+	return n
+}
+
+// Cancel removes a queued message reporting whether it was queued.
+func (o *Outbox) Cancel(id uint64) (bool, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for i, m := range o.msgs {
+		if m.ID == id {
+			o.msgs = append(o.msgs[:i], o.msgs[i+1:]...)
+			return true, o.saveLocked()
+		}
+	}
+	return false, nil
+}
+
+// Retry attempts delivery to dest right away, e.g. when a Monitor reports
+// it up.
+func (o *Outbox) Retry(dest Addr64) {
+	o.mu.Lock()
+	o.due[dest] = true
+	o.mu.Unlock()
+	o.wake()
+}
+
+// Events returns the channel on which OutboxDelivered and OutboxExpired
+// events are delivered. It's closed by Close.
+func (o *Outbox) Events() <-chan Event {
+	return o.events
+}
+
+// Close stops delivering messages. Queued messages are kept in the store.
+func (o *Outbox) Close() error {
+	o.once.Do(func() {
+		close(o.done)
+		o.cancel()
+	})
+	return nil
+}
+
+func (o *Outbox) wake() {
+	select {
+	case o.kick <- struct{}{}:
+	default:
+	}
+}
+
+func (o *Outbox) saveLocked() error {
+	if o.cfg.Store == nil {
+		return nil
+	}
+	if err := o.cfg.Store.Save(o.msgs); err != nil {
+		o.xb.log.Warn("xbee: failed to save outbox", "err", err)
+		return err
+	}
+	return nil
+}
+
+func (o *Outbox) loop() {
+	defer close(o.events)
+	t := time.NewTicker(outboxCheckInterval)
+	defer t.Stop()
+	for {
+		if !o.process() {
+			return
+		}
+		select {
+		case <-t.C:
+		case <-o.kick:
+		case <-o.done:
+			return
+		case <-o.xb.closed:
+			return
+		}
+	}
+}
+
+// process drops expired messages and delivers the messages of the
+// destinations that are due returning false if the outbox was closed.
+func (o *Outbox) process() bool {
+	now := time.Now()
+	seen := make(map[Addr64]time.Time)
+	for _, n := range o.xb.NodeStats() {
+		seen[n.Address] = n.LastSeen
+	}
+
+	o.mu.Lock()
+	var expired []Event
+	var dests []Addr64
+	pending := make(map[Addr64]bool)
+	kept := o.msgs[:0]
+	for _, m := range o.msgs {
+		if now.After(m.Expires) {
+			expired = append(expired, &OutboxExpired{Message: m})
+			continue
+		}
+		kept = append(kept, m)
+		if !pending[m.Dest] {
+			pending[m.Dest] = true
+			tried := o.tried[m.Dest]
+			if o.due[m.Dest] || seen[m.Dest].After(tried) || now.Sub(tried) >= o.cfg.RetryInterval {
+				dests = append(dests, m.Dest)
+			}
+		}
+	}
+	clear(o.msgs[len(kept):])
+	o.msgs = kept
+	for addr := range o.tried {
+		if !pending[addr] {
+			delete(o.tried, addr)
+		}
+	}
+	clear(o.due)
+	if len(expired) > 0 {
+		o.saveLocked()
+	}
+	o.mu.Unlock()
+
+	for _, ev := range expired {
+		if !o.send(ev) {
+			return false
+		}
+	}
+	sort.Slice(dests, func(i, j int) bool { return dests[i] < dests[j] })
+	for _, dest := range dests {
+		if !o.deliver(dest) {
+			return false
+		}
+	}
+	return true
+}
+
+// deliver sends the messages for dest in order until one fails returning
+// false if the outbox was closed.
+func (o *Outbox) deliver(dest Addr64) bool {
+	for {
+		o.mu.Lock()
+		var m *OutboxMessage
+		for i := range o.msgs {
+			if o.msgs[i].Dest == dest {
+				m = &o.msgs[i]
+				break
+			}
+		}
+		if m == nil {
+			o.mu.Unlock()
+			return true
+		}
+		msg := *m
+		o.mu.Unlock()
+
+		err := o.xb.Transmit(dest, msg.Data, WithRetry(o.cfg.Retry), WithContext(o.ctx))
+		if o.ctx.Err() != nil {
+			return false
+		}
+
+		o.mu.Lock()
+		o.tried[dest] = time.Now()
+		i := o.index(msg.ID)
+		if err != nil {
+			if i >= 0 {
+				o.msgs[i].Attempts++
+				o.msgs[i].LastError = err.Error()
+				o.saveLocked()
+			}
+			o.mu.Unlock()
+			return true
+		}
+		if i >= 0 {
+			o.msgs = append(o.msgs[:i], o.msgs[i+1:]...)
+			o.saveLocked()
+		}
+		o.mu.Unlock()
+		if !o.send(&OutboxDelivered{Message: msg}) {
+			return false
+		}
+	}
+}
+
+// index returns the index of a message in msgs or -1 if it was removed.
+func (o *Outbox) index(id uint64) int {
+	for i, m := range o.msgs {
+		if m.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (o *Outbox) send(ev Event) bool {
+	select {
+	case o.events <- ev:
+		return true
+	case <-o.done:
+		return false
+	}
+}
@@ -0,0 +1,179 @@
+package xbee
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const defaultPANMonitorInterval = 30 * time.Second
+
+// ConflictReport returns the number of PAN ID conflict reports the network
+// manager must receive within a minute to change the PAN ID (CR).
+func (xb *XBee) ConflictReport() (int, error) {
+	b, err := xb.atCommand(atConflictReport, nil)
+	if err != nil {
+		return 0, err
+	}
+	return int(decodeUint(b)), nil
+}
+
+// SetConflictReport sets the number of PAN ID conflict reports that
+// trigger a PAN ID change (CR) from 1 to 63. Higher values make a change
+// caused by corrupt beacons less likely.
+func (xb *XBee) SetConflictReport(n int) error {
+	if n < 1 || n > 0x3f {
+		return fmt.Errorf("xbee.SetConflictReport: %w: %d isn't between 1 and 63", ErrInvalidParameter, n)
+	}
+	_, err := xb.atCommand(atConflictReport, []byte{byte(n)})
+	return err
+}
+
+// OperatingPANID returns the 16-bit PAN ID the module is operating on
+// (OI).
+func (xb *XBee) OperatingPANID() (uint16, error) {
+	b, err := xb.atCommand(atOperatingPANID, nil)
+	if err != nil {
+		return 0, err
+	}
+	return uint16(decodeUint(b)), nil
+}
+
+// PANMonitorConfig configures a PANMonitor. The zero value uses the
+// defaults.
+type PANMonitorConfig struct {
+	// Interval is the time between polls of the operating PAN IDs (OI
+	// and OP). The default is 30s. They're also checked after modem
+	// statuses reporting that the module started, joined, or left a
+	// network.
+	Interval time.Duration
+}
+
+// PANChanged is delivered by a PANMonitor when the module's operating PAN
+// IDs change, e.g. because the network manager moved the network to a new
+// 16-bit PAN ID after conflict reports (see SetConflictReport) or the
+// module joined another network.
+type PANChanged struct {
+	PAN                 uint16 // operating 16-bit PAN ID (OI)
+	ExtendedPAN         uint64 // operating extended PAN ID (OP)
+	PreviousPAN         uint16
+	PreviousExtendedPAN uint64
+	// Status is the modem status that led to the check or 0xff for a
+	// periodic poll.
+	Status ModemStatus
+}
+
+// PANMonitor watches the operating PAN IDs of the module for changes.
+type PANMonitor struct {
+	xb     *XBee
+	cfg    PANMonitorConfig
+	events chan Event
+	status *matcher // modem statuses that may change the PAN
+	done   chan struct{}
+	once   sync.Once
+
+	known       bool
+	pan         uint16
+	extendedPAN uint64
+}
+
+// NewPANMonitor starts watching the operating PAN IDs. cfg may be nil to
+// use the defaults. Events must be read from Events or checks stop. Modem
+// statuses are still delivered by EventChan.
+func (xb *XBee) NewPANMonitor(cfg *PANMonitorConfig) *PANMonitor {
+	m := &PANMonitor{
+		xb:     xb,
+		events: make(chan Event, monitorQueueLen),
+		done:   make(chan struct{}),
+	}
+	if cfg != nil {
+		m.cfg = *cfg
+	}
+	if m.cfg.Interval <= 0 {
+		m.cfg.Interval = defaultPANMonitorInterval
+	}
+	m.status = xb.registerObserver(func(ev Event) bool {
+		ms, ok := ev.(ModemStatus)
+		return ok && panStatus(ms)
+	})
+	go m.loop()
+	return m
+}
+
+// Events returns the channel on which PANChanged events are delivered.
+// It's closed by Close.
+func (m *PANMonitor) Events() <-chan Event {
+	return m.events
+}
+
+// Close stops watching.
+func (m *PANMonitor) Close() error {
+	m.once.Do(func() {
+		close(m.done)
+		m.xb.unregisterMatcher(m.status)
+	})
+	return nil
+}
+
+// panStatus returns whether a modem status may follow a change of PAN.
+func panStatus(ms ModemStatus) bool {
+	switch ms {
+	case MSJoinedNetwork, MSDisassociated, MSCoordinatorStarted, MSHardwareReset, MSWatchdogTimerReset:
+		return true
+	}
+	return false
+}
+
+func (m *PANMonitor) loop() {
+	defer close(m.events)
+	t := time.NewTicker(m.cfg.Interval)
+	defer t.Stop()
+	status := ModemStatus(0xff)
+	for {
+		if ev := m.check(status); ev != nil {
+			select {
+			case m.events <- ev:
+			case <-m.done:
+				return
+			}
+		}
+		select {
+		case <-t.C:
+			status = 0xff
+		case ev := <-m.status.ch:
+			status = ev.(ModemStatus)
+		case <-m.done:
+			return
+		case <-m.xb.closed:
+			return
+		}
+	}
+}
+
+// check polls the operating PAN IDs returning a PANChanged event if they
+// changed since the last poll.
+func (m *PANMonitor) check(status ModemStatus) Event {
+	pan, err := m.xb.OperatingPANID()
+	if err != nil {
+		m.xb.log.Warn("xbee: failed to read operating PAN ID", "err", err)
+		return nil
+	}
+	ext, err := m.xb.OperatingExtendedPANID()
+	if err != nil {
+		m.xb.log.Warn("xbee: failed to read operating extended PAN ID", "err", err)
+		return nil
+	}
+	known := m.known
+	prevPAN, prevExt := m.pan, m.extendedPAN
+	m.known, m.pan, m.extendedPAN = true, pan, ext
+	if !known || (pan == prevPAN && ext == prevExt) {
+		return nil
+	}
+	return &PANChanged{
+		PAN:                 pan,
+		ExtendedPAN:         ext,
+		PreviousPAN:         prevPAN,
+		PreviousExtendedPAN: prevExt,
+		Status:              status,
+	}
+}
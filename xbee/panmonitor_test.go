@@ -0,0 +1,45 @@
+package xbee_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/samuel/go-xbee/xbee"
+	"github.com/samuel/go-xbee/xbee/xbeetest"
+)
+
+func TestPANMonitor(t *testing.T) {
+	r := xbeetest.NewRadio()
+	r.SetRegister("OI", []byte{0x12, 0x34})
+	xb := openRadio(t, r)
+	m := xb.NewPANMonitor(nil)
+	defer m.Close()
+
+	// Change the PAN until a check after the first reports it.
+	timeout := time.After(5 * time.Second)
+	var ev *xbee.PANChanged
+	for pan := 0x1235; ev == nil; pan++ {
+		r.SetRegister("OI", []byte{byte(pan >> 8), byte(pan)})
+		r.Inject(xbee.MSCoordinatorStarted)
+		select {
+		case e := <-m.Events():
+			ev = e.(*xbee.PANChanged)
+			if ev.PAN != uint16(pan) || ev.Status != xbee.MSCoordinatorStarted {
+				t.Fatalf("PANChanged = %+v, want PAN %04x after %s", ev, pan, xbee.MSCoordinatorStarted)
+			}
+		case <-time.After(50 * time.Millisecond):
+		case <-timeout:
+			t.Fatal("no PANChanged event")
+		}
+	}
+
+	// The modem statuses are still delivered by EventChan.
+	select {
+	case ev := <-xb.EventChan():
+		if ev != xbee.MSCoordinatorStarted {
+			t.Errorf("EventChan delivered %v, want %s", ev, xbee.MSCoordinatorStarted)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("modem status not delivered by EventChan")
+	}
+}
@@ -0,0 +1,117 @@
+package xbee
+
+import (
+	"time"
+
+	"github.com/samuel/go-xbee/xbee/frames"
+)
+
+// PortInfo describes a USB serial port.
+type PortInfo struct {
+	Path         string
+	VendorID     uint16 // 0 if unknown
+	ProductID    uint16 // 0 if unknown
+	SerialNumber string // USB serial number
+	Product      string
+}
+
+type usbID struct {
+	vendor, product uint16
+}
+
+// USB to serial bridges used on XBee development and adapter boards.
+var xbeeUSBIDs = map[usbID]string{
+	{0x0403, 0x6001}: "FTDI FT232R",
+	{0x0403, 0x6015}: "FTDI FT231X", // Digi XBee development boards
+	{0x10c4, 0xea60}: "Silicon Labs CP210x",
+}
+
+// IsCandidate returns true if the port uses a USB bridge known to be used
+// by XBee boards or the USB IDs aren't known.
+func (p *PortInfo) IsCandidate() bool {
+	if p.VendorID == 0 && p.ProductID == 0 {
+		return true
+	}
+	_, ok := xbeeUSBIDs[usbID{p.VendorID, p.ProductID}]
+	return ok
+}
+
+// ListPorts returns the USB serial ports on the system. On Linux the USB
+// IDs are read from sysfs. On other systems they're unknown.
+func ListPorts() ([]PortInfo, error) {
+	return listPorts()
+}
+
+// DiscoveredXBee is a module found by FindXBees.
+type DiscoveredXBee struct {
+	Port            PortInfo
+	SerialNumber    Addr64
+	FirmwareVersion uint16
+	HardwareVersion uint16
+}
+
+const probeTimeout = time.Second
+
+// FindXBees probes each candidate port at the given baud rate for a
+// module in API mode (AP=1) and returns the modules that responded.
+func FindXBees(baud int) ([]DiscoveredXBee, error) {
+	ports, err := ListPorts()
+	if err != nil {
+		return nil, err
+	}
+	var found []DiscoveredXBee
+	for _, p := range ports {
+		if !p.IsCandidate() {
+			continue
+		}
+		if d, err := probePort(p, baud); err == nil {
+			found = append(found, *d)
+		}
+	}
+	return found, nil
+}
+
+func probePort(p PortInfo, baud int) (*DiscoveredXBee, error) {
+	port, err := OpenPort(p.Path, baud)
+	if err != nil {
+		return nil, err
+	}
+	// Closing the port stops the read loop.
+	defer port.Close()
+	xb, err := Open(port)
+	if err != nil {
+		return nil, err
+	}
+	d := &DiscoveredXBee{Port: p}
+	var vals [4]uint64
+	for i, cmd := range []ATCommand{atSerialNumberHigh, atSerialNumberLow, atFirmwareVersion, atHardwareVersion} {
+		b, err := xb.probeATCommand(cmd)
+		if err != nil {
+			return nil, err
+		}
+		vals[i] = decodeUint(b)
+	}
+	d.SerialNumber = Addr64(vals[0]<<32 | vals[1])
+	d.FirmwareVersion = uint16(vals[2])
+	d.HardwareVersion = uint16(vals[3])
+	return d, nil
+}
+
+// probeATCommand queries a register giving up after probeTimeout as the
+// port may not have a module attached.
+func (xb *XBee) probeATCommand(cmd ATCommand) ([]byte, error) {
+	ev, err := xb.request(probeTimeout, func(frameID byte) frames.Frame {
+		return &frames.ATCommandRequest{FrameID: frameID, ATCommand: cmd}
+	})
+	if err != nil {
+		return nil, err
+	}
+	res, ok := ev.(*ATCommandResponse)
+	if !ok {
+		return nil, wrongFrame("AT response", ev)
+	}
+	if err := validateATResponse(cmd, res); err != nil {
+		return nil, err
+	}
+	return res.Data, nil
+}
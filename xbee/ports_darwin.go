@@ -0,0 +1,25 @@
+package xbee
+
+import "path/filepath"
+
+// USB serial devices are named by the driver. The callout (cu) devices are
+// used as they don't block waiting for carrier detect.
+var portPatterns = []string{
+	"/dev/cu.usbserial*",
+	"/dev/cu.SLAB_USBtoUART*",
+	"/dev/cu.usbmodem*",
+}
+
+func listPorts() ([]PortInfo, error) {
+	var ports []PortInfo
+	for _, pat := range portPatterns {
+		matches, err := filepath.Glob(pat)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range matches {
+			ports = append(ports, PortInfo{Path: m})
+		}
+	}
+	return ports, nil
+}
@@ -0,0 +1,54 @@
+package xbee
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+func listPorts() ([]PortInfo, error) {
+	entries, err := os.ReadDir("/sys/class/tty")
+	if err != nil {
+		return nil, err
+	}
+	var ports []PortInfo
+	for _, e := range entries {
+		dev, err := filepath.EvalSymlinks(filepath.Join("/sys/class/tty", e.Name(), "device"))
+		if err != nil || !strings.Contains(dev, "/usb") {
+			continue
+		}
+		p := PortInfo{Path: "/dev/" + e.Name()}
+		// The USB device is an ancestor of the interface bound to the tty.
+		for dir := dev; dir != "/" && dir != "."; dir = filepath.Dir(dir) {
+			vid, err := readSysfsHex(filepath.Join(dir, "idVendor"))
+			if err != nil {
+				continue
+			}
+			p.VendorID = vid
+			p.ProductID, _ = readSysfsHex(filepath.Join(dir, "idProduct"))
+			p.SerialNumber = readSysfsString(filepath.Join(dir, "serial"))
+			p.Product = readSysfsString(filepath.Join(dir, "product"))
+			break
+		}
+		ports = append(ports, p)
+	}
+	return ports, nil
+}
+
+func readSysfsString(path string) string {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+func readSysfsHex(path string) (uint16, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseUint(strings.TrimSpace(string(b)), 16, 16)
+	return uint16(v), err
+}
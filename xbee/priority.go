@@ -0,0 +1,54 @@
+package xbee
+
+import (
+	"fmt"
+
+	"github.com/samuel/go-xbee/xbee/frames"
+)
+
+// Priority orders the frames waiting to be written. Frames of a higher
+// priority are written before any queued frames of a lower priority.
+type Priority int
+
+const (
+	// PriorityHigh is used for local and remote AT commands and other
+	// control frames.
+	PriorityHigh Priority = iota
+	// PriorityNormal is used for transmits.
+	PriorityNormal
+	// PriorityBulk is used for large transfers such as firmware updates.
+	PriorityBulk
+
+	numPriorities = 3
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityHigh:
+		return "High"
+	case PriorityNormal:
+		return "Normal"
+	case PriorityBulk:
+		return "Bulk"
+	}
+	return fmt.Sprintf("Priority(%d)", int(p))
+}
+
+// framePriority returns the default priority for a frame.
+func framePriority(f frames.Frame) Priority {
+	switch f.(type) {
+	case *frames.TransmitRequest, *frames.ExplicitTransmitRequest,
+		*frames.IPv4TransmitRequest, *frames.SMSTransmitRequest, *frames.UserDataRelay:
+		return PriorityNormal
+	}
+	return PriorityHigh
+}
+
+// SendFrame writes a frame with the given priority. The frame ID (if any)
+// must be allocated by the caller and responses are delivered as events.
+func (xb *XBee) SendFrame(f Frame, p Priority) error {
+	if p < 0 || p >= numPriorities {
+		return ErrInvalidParameter
+	}
+	return xb.writeFramePriority(f, p)
+}
@@ -0,0 +1,93 @@
+package xbee_test
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/samuel/go-xbee/xbee"
+	"github.com/samuel/go-xbee/xbee/frames"
+)
+
+// gatedPort blocks the first write until the gate is opened.
+type gatedPort struct {
+	entered chan struct{}
+	gate    chan struct{}
+	closed  chan struct{}
+	once    sync.Once
+
+	mu      sync.Mutex
+	written bytes.Buffer
+}
+
+func (p *gatedPort) Read(b []byte) (int, error) {
+	<-p.closed
+	return 0, io.EOF
+}
+
+func (p *gatedPort) Write(b []byte) (int, error) {
+	p.once.Do(func() {
+		close(p.entered)
+		<-p.gate
+	})
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.written.Write(b)
+}
+
+func TestWritePriority(t *testing.T) {
+	p := &gatedPort{entered: make(chan struct{}), gate: make(chan struct{}), closed: make(chan struct{})}
+	xb, err := xbee.Open(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		xb.Close()
+		close(p.closed)
+	}()
+	send := func(wg *sync.WaitGroup, cmd string, pri xbee.Priority) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := xb.SendFrame(&frames.ATCommandRequest{ATCommand: xbee.ATCommand{cmd[0], cmd[1]}}, pri); err != nil {
+				t.Errorf("SendFrame(%s): %v", cmd, err)
+			}
+		}()
+	}
+
+	var wg sync.WaitGroup
+	send(&wg, "B0", xbee.PriorityBulk)
+	<-p.entered
+	// The writer is blocked writing B0 while the rest are queued.
+	send(&wg, "B1", xbee.PriorityBulk)
+	send(&wg, "N0", xbee.PriorityNormal)
+	send(&wg, "H0", xbee.PriorityHigh)
+	time.Sleep(50 * time.Millisecond)
+	close(p.gate)
+	wg.Wait()
+
+	var got []string
+	rd := frames.NewReader(bytes.NewReader(p.written.Bytes()))
+	for {
+		data, err := rd.ReadData()
+		if err != nil {
+			break
+		}
+		f, err := frames.Decode(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, f.(*frames.ATCommandRequest).ATCommand.String())
+	}
+	want := []string{"B0", "H0", "N0", "B1"}
+	if len(got) != len(want) {
+		t.Fatalf("wrote %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("wrote %v, want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package xbee
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Profile is a device configuration: the values of AT command registers by
+// command. Numeric values are big-endian as returned by the module.
+type Profile map[ATCommand][]byte
+
+// ProfileRegisters are the registers read by ReadProfile by default: the
+// network, security, RF, serial, I/O, and sleep settings.
+var ProfileRegisters = []ATCommand{
+	// Networking
+	{'I', 'D'}, {'S', 'C'}, {'S', 'D'}, {'Z', 'S'}, {'N', 'J'}, {'N', 'W'},
+	{'J', 'V'}, {'J', 'N'}, {'C', 'E'}, {'D', 'H'}, {'D', 'L'}, {'N', 'I'},
+	{'N', 'H'}, {'B', 'H'}, {'A', 'R'}, {'N', 'T'}, {'N', 'O'}, {'C', 'R'},
+	// Security
+	{'E', 'E'}, {'E', 'O'},
+	// RF interface
+	{'P', 'L'}, {'P', 'M'},
+	// Serial interfacing
+	{'A', 'P'}, {'A', 'O'}, {'B', 'D'}, {'N', 'B'}, {'S', 'B'}, {'R', 'O'},
+	// I/O
+	{'D', '0'}, {'D', '1'}, {'D', '2'}, {'D', '3'}, {'D', '4'}, {'D', '5'},
+	{'D', '6'}, {'D', '7'}, {'P', '0'}, {'P', '1'}, {'P', '2'}, {'P', 'R'},
+	{'L', 'T'}, {'I', 'R'}, {'I', 'C'},
+	// Sleep
+	{'S', 'M'}, {'S', 'N'}, {'S', 'O'}, {'S', 'P'}, {'S', 'T'},
+}
+
+// ProfileChange is a register that differs between two profiles. Old or
+// New is nil if the register is only in one of them.
+type ProfileChange struct {
+	Command ATCommand
+	Old     []byte
+	New     []byte
+}
+
+// ReadProfile reads the registers (ProfileRegisters if nil) from the
+// module. Registers the firmware doesn't support are left out.
+func (xb *XBee) ReadProfile(registers []ATCommand) (Profile, error) {
+	if registers == nil {
+		registers = ProfileRegisters
+	}
+	p := make(Profile, len(registers))
+	for _, cmd := range registers {
+		b, err := xb.atCommand(cmd, nil)
+		var invalid ErrInvalidCommand
+		if errors.As(err, &invalid) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		p[cmd] = b
+	}
+	return p, nil
+}
+
+// ApplyProfile writes the registers in p that differ from the module's
+// current values, applies the changes (AC), and saves them with
+// VerifiedWrite. It returns the registers that were written. Write-only
+// registers (e.g. KY) are always written.
+func (xb *XBee) ApplyProfile(p Profile) ([]ATCommand, error) {
+	var written []ATCommand
+	for _, cmd := range p.Commands() {
+		if !writeOnlyRegisters[cmd] {
+			cur, err := xb.atCommand(cmd, nil)
+			if err == nil && registerEqual(cur, p[cmd]) {
+				continue
+			}
+		}
+		if _, err := xb.atCommandQueue(cmd, p[cmd], true); err != nil {
+			return written, err
+		}
+		written = append(written, cmd)
+	}
+	if len(written) == 0 {
+		return nil, nil
+	}
+	if _, err := xb.atCommand(atApplyChanges, nil); err != nil {
+		return written, err
+	}
+	changed := make(Profile, len(written))
+	for _, cmd := range written {
+		changed[cmd] = p[cmd]
+	}
+	return written, xb.VerifiedWrite(changed)
+}
+
+// Commands returns the commands in the profile in alphabetical order.
+func (p Profile) Commands() []ATCommand {
+	cmds := make([]ATCommand, 0, len(p))
+	for cmd := range p {
+		cmds = append(cmds, cmd)
+	}
+	sort.Slice(cmds, func(i, j int) bool {
+		return bytes.Compare(cmds[i][:], cmds[j][:]) < 0
+	})
+	return cmds
+}
+
+// Diff returns the registers that differ from p in q in alphabetical
+// order. Numeric values of different lengths (e.g. 0x0003 and 0x03) are
+// equal.
+func (p Profile) Diff(q Profile) []ProfileChange {
+	all := make(Profile, len(p)+len(q))
+	for cmd, v := range p {
+		all[cmd] = v
+	}
+	for cmd, v := range q {
+		all[cmd] = v
+	}
+	var changes []ProfileChange
+	for _, cmd := range all.Commands() {
+		old, inP := p[cmd]
+		cur, inQ := q[cmd]
+		if inP && inQ && registerEqual(old, cur) {
+			continue
+		}
+		changes = append(changes, ProfileChange{Command: cmd, Old: old, New: cur})
+	}
+	return changes
+}
+
+// registerEqual compares register values ignoring leading zeros since the
+// module returns numbers in as few bytes as needed.
+func registerEqual(a, b []byte) bool {
+	return bytes.Equal(bytes.TrimLeft(a, "\x00"), bytes.TrimLeft(b, "\x00"))
+}
+
+// writeOnlyRegisters can be set but not read back.
+var writeOnlyRegisters = map[ATCommand]bool{
+	atNetworkEncryptionKey: true,
+	atLinkKey:              true,
+}
+
+// RegisterMismatch is a register that didn't read back with the value
+// written.
+type RegisterMismatch struct {
+	Command ATCommand
+	Want    []byte
+	Got     []byte // nil if Err is set
+	Err     error  // error reading the register back
+}
+
+// VerifyError is returned by VerifiedWrite when registers don't read back
+// with the intended values.
+type VerifyError struct {
+	Mismatches []RegisterMismatch
+}
+
+func (e *VerifyError) Error() string {
+	var b strings.Builder
+	b.WriteString("xbee: write not verified:")
+	for _, m := range e.Mismatches {
+		if m.Err != nil {
+			fmt.Fprintf(&b, " %s: %s;", m.Command, m.Err)
+		} else {
+			fmt.Fprintf(&b, " %s: want %x got %x;", m.Command, m.Want, m.Got)
+		}
+	}
+	return strings.TrimSuffix(b.String(), ";")
+}
+
+// VerifiedWrite saves the settings to non-volatile memory (WR) and reads
+// back the registers in want returning a *VerifyError if any don't have
+// the intended value. Write-only registers (NK and KY) aren't checked.
+func (xb *XBee) VerifiedWrite(want Profile) error {
+	if err := xb.Write(); err != nil {
+		return err
+	}
+	var mismatches []RegisterMismatch
+	for _, cmd := range want.Commands() {
+		if writeOnlyRegisters[cmd] {
+			continue
+		}
+		got, err := xb.atCommand(cmd, nil)
+		if err != nil {
+			mismatches = append(mismatches, RegisterMismatch{Command: cmd, Want: want[cmd], Err: err})
+		} else if !registerEqual(got, want[cmd]) {
+			mismatches = append(mismatches, RegisterMismatch{Command: cmd, Want: want[cmd], Got: got})
+		}
+	}
+	if len(mismatches) != 0 {
+		return &VerifyError{Mismatches: mismatches}
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package xbee
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Support for the profile files written by XCTU so configurations can be
+// moved between XCTU and Go tooling. Values are hexadecimal except for
+// string registers such as NI.
+
+// xproProfileName is the name of the settings file in an .xpro archive.
+const xproProfileName = "profile.xml"
+
+// stringRegisters are registers with text values in profile files.
+var stringRegisters = map[ATCommand]bool{
+	{'N', 'I'}: true,
+}
+
+type xproData struct {
+	XMLName xml.Name    `xml:"data"`
+	Profile xproProfile `xml:"profile"`
+}
+
+type xproProfile struct {
+	DescriptionFile string        `xml:"description_file,omitempty"`
+	Settings        []xproSetting `xml:"settings>setting"`
+}
+
+type xproSetting struct {
+	Command string `xml:"command,attr"`
+	Value   string `xml:",chardata"`
+}
+
+// ReadXPRO reads the settings from an XCTU .xpro profile (a zip archive)
+// ignoring any firmware it includes.
+func ReadXPRO(r io.ReaderAt, size int64) (Profile, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	f, err := zr.Open(xproProfileName)
+	if err != nil {
+		return nil, fmt.Errorf("xbee.ReadXPRO: %w", err)
+	}
+	defer f.Close()
+	var d xproData
+	if err := xml.NewDecoder(f).Decode(&d); err != nil {
+		return nil, fmt.Errorf("xbee.ReadXPRO: %w", err)
+	}
+	p := make(Profile, len(d.Profile.Settings))
+	for _, s := range d.Profile.Settings {
+		if err := p.setText(s.Command, s.Value); err != nil {
+			return nil, fmt.Errorf("xbee.ReadXPRO: %w", err)
+		}
+	}
+	return p, nil
+}
+
+// WriteXPRO writes p as an XCTU .xpro profile.
+func WriteXPRO(w io.Writer, p Profile) error {
+	d := xproData{}
+	for _, cmd := range p.Commands() {
+		d.Profile.Settings = append(d.Profile.Settings, xproSetting{Command: cmd.String(), Value: p.text(cmd)})
+	}
+	zw := zip.NewWriter(w)
+	f, err := zw.Create(xproProfileName)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(f, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(f)
+	enc.Indent("", "  ")
+	if err := enc.Encode(d); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// ReadPRO reads a legacy X-CTU .pro profile: lines of COMMAND=VALUE with
+// [section] headers and ; comments ignored.
+func ReadPRO(r io.Reader) (Profile, error) {
+	p := make(Profile)
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == ';' || line[0] == '[' {
+			continue
+		}
+		cmd, val, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("xbee.ReadPRO: line %d: expected COMMAND=VALUE", n)
+		}
+		if err := p.setText(strings.TrimSpace(cmd), strings.TrimSpace(val)); err != nil {
+			return nil, fmt.Errorf("xbee.ReadPRO: line %d: %w", n, err)
+		}
+	}
+	return p, sc.Err()
+}
+
+// WritePRO writes p as a legacy X-CTU .pro profile.
+func WritePRO(w io.Writer, p Profile) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "[Settings]")
+	for _, cmd := range p.Commands() {
+		fmt.Fprintf(bw, "%s=%s\n", cmd, p.text(cmd))
+	}
+	return bw.Flush()
+}
+
+// setText sets a register from its text form in a profile file.
+func (p Profile) setText(name, val string) error {
+	var cmd ATCommand
+	if err := cmd.UnmarshalText([]byte(strings.ToUpper(name))); err != nil {
+		return err
+	}
+	if stringRegisters[cmd] {
+		p[cmd] = []byte(val)
+		return nil
+	}
+	if len(val)%2 != 0 {
+		val = "0" + val
+	}
+	b, err := hex.DecodeString(val)
+	if err != nil {
+		return fmt.Errorf("invalid value for %s: %w", cmd, err)
+	}
+	p[cmd] = b
+	return nil
+}
+
+// text returns the text form of a register for a profile file.
+func (p Profile) text(cmd ATCommand) string {
+	if stringRegisters[cmd] {
+		return string(p[cmd])
+	}
+	v := strings.TrimLeft(strings.ToUpper(hex.EncodeToString(p[cmd])), "0")
+	if v == "" {
+		return "0"
+	}
+	return v
+}
@@ -0,0 +1,187 @@
+package xbee
+
+import (
+	"context"
+	"crypto/aes"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+const (
+	defaultJoinWindow  = time.Minute
+	joinPollInterval   = time.Second
+	maxJoinWindow      = 0xfe * time.Second // 0xFF allows joining always
+	aiJoined           = 0x00
+	aiJoinFailedFirst  = 0xab // device didn't respond and secure join errors
+	aiJoinFailedLast   = 0xaf
+	linkKeyLen         = 16
+	mmoBlockLen        = aes.BlockSize
+	mmoLengthFieldSize = 2
+)
+
+// JoinError is returned by WaitJoined when the association indication (AI)
+// reports that joining failed, e.g. 0xAD when the network key wasn't
+// received.
+type JoinError struct {
+	Status int // AI value
+}
+
+func (e *JoinError) Error() string {
+	return fmt.Sprintf("xbee: join failed: association indication 0x%02x", e.Status)
+}
+
+// ProvisionConfig is the security configuration applied by a Provisioner.
+type ProvisionConfig struct {
+	// NetworkKey is the 128-bit network key (NK). nil lets the
+	// coordinator pick a random key.
+	NetworkKey []byte
+	// LinkKey is the preconfigured 128-bit trust center link key (KY)
+	// shared by the coordinator and joining devices. nil sends the network
+	// key unencrypted during joins unless install codes are used.
+	LinkKey []byte
+	// TrustCenter enables the coordinator as the trust center (EO bit 1).
+	TrustCenter bool
+	// JoinWindow is how long joining is allowed by OpenJoinWindow (NJ).
+	// The default is 1m and the maximum 254s.
+	JoinWindow time.Duration
+}
+
+// Provisioner applies a consistent security configuration to a
+// coordinator and the devices that join its network. The usual sequence
+// is SetupCoordinator, SetupJoiner or AddJoiner for each device,
+// OpenJoinWindow, and WaitJoined.
+type Provisioner struct {
+	coord *XBee
+	cfg   ProvisionConfig
+}
+
+// NewProvisioner returns a Provisioner for the network formed by coord.
+func NewProvisioner(coord *XBee, cfg ProvisionConfig) (*Provisioner, error) {
+	if cfg.NetworkKey != nil && len(cfg.NetworkKey) != linkKeyLen {
+		return nil, fmt.Errorf("xbee.NewProvisioner: %w: network key must be 16 bytes not %d", ErrInvalidParameter, len(cfg.NetworkKey))
+	}
+	if cfg.LinkKey != nil && len(cfg.LinkKey) != linkKeyLen {
+		return nil, fmt.Errorf("xbee.NewProvisioner: %w: link key must be 16 bytes not %d", ErrInvalidParameter, len(cfg.LinkKey))
+	}
+	if cfg.JoinWindow <= 0 {
+		cfg.JoinWindow = defaultJoinWindow
+	}
+	cfg.JoinWindow = min(cfg.JoinWindow, maxJoinWindow)
+	return &Provisioner{coord: coord, cfg: cfg}, nil
+}
+
+// SetupCoordinator enables encryption (EE), sets the encryption options
+// (EO), network key (NK), and link key (KY) on the coordinator, and saves
+// the settings (WR).
+func (p *Provisioner) SetupCoordinator() error {
+	var opts SecurityOption
+	if p.cfg.TrustCenter {
+		opts |= SOUseTrustCenter
+	}
+	if p.cfg.LinkKey == nil {
+		opts |= SOSendUnsecureKeyOTA
+	}
+	if err := p.coord.SetEncryptionEnabled(true); err != nil {
+		return err
+	}
+	if err := p.coord.SetEncryptionOptions(opts); err != nil {
+		return err
+	}
+	if err := p.coord.SetNetworkEncryptionKey(p.cfg.NetworkKey); err != nil {
+		return err
+	}
+	if p.cfg.LinkKey != nil {
+		if err := p.coord.SetLinkKey(p.cfg.LinkKey); err != nil {
+			return err
+		}
+	}
+	return p.coord.Write()
+}
+
+// SetupJoiner configures a device that's about to join: it enables
+// encryption (EE) and sets the link key (KY), derived from installCode if
+// not nil (see InstallCodeLinkKey) or otherwise the configured link key,
+// and saves the settings (WR). The install code must include its CRC.
+func (p *Provisioner) SetupJoiner(dev *XBee, installCode []byte) error {
+	key := p.cfg.LinkKey
+	if installCode != nil {
+		key = InstallCodeLinkKey(installCode)
+	}
+	if err := dev.SetEncryptionEnabled(true); err != nil {
+		return err
+	}
+	if err := dev.SetEncryptionOptions(0); err != nil {
+		return err
+	}
+	if key != nil {
+		if err := dev.SetLinkKey(key); err != nil {
+			return err
+		}
+	}
+	return dev.Write()
+}
+
+// AddJoiner registers a device with the coordinator's trust center using
+// its install code including the CRC (see AppendInstallCodeCRC).
+func (p *Provisioner) AddJoiner(addr Addr64, installCode []byte) error {
+	return p.coord.RegisterJoiningDevice(addr, installCode, RJOInstallCode)
+}
+
+// OpenJoinWindow allows devices to join the coordinator for the
+// configured join window (NJ).
+func (p *Provisioner) OpenJoinWindow() error {
+	_, err := p.coord.atCommand(atNodeJoinTime, []byte{byte(p.cfg.JoinWindow / time.Second)})
+	return err
+}
+
+// WaitJoined polls the association indication (AI) of a joining device
+// until it has joined returning a *JoinError if joining fails (AI 0xAB to
+// 0xAF).
+func (p *Provisioner) WaitJoined(ctx context.Context, dev *XBee) error {
+	t := time.NewTicker(joinPollInterval)
+	defer t.Stop()
+	for {
+		ai, err := dev.AssociationIndication()
+		if err != nil {
+			return err
+		}
+		if ai == aiJoined {
+			return nil
+		} else if ai >= aiJoinFailedFirst && ai <= aiJoinFailedLast {
+			return &JoinError{Status: ai}
+		}
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// InstallCodeLinkKey derives the link key from an install code including
+// its CRC using the Matyas-Meyer-Oseas hash as defined by the Zigbee
+// specification.
+func InstallCodeLinkKey(installCode []byte) []byte {
+	// Pad with a 1 bit, zeros, and the message length in bits so the
+	// length is a multiple of the block size.
+	n := len(installCode) + 1 + mmoLengthFieldSize
+	n += (mmoBlockLen - n%mmoBlockLen) % mmoBlockLen
+	m := make([]byte, n)
+	copy(m, installCode)
+	m[len(installCode)] = 0x80
+	binary.BigEndian.PutUint16(m[n-mmoLengthFieldSize:], uint16(len(installCode)*8))
+
+	h := make([]byte, mmoBlockLen)
+	for i := 0; i < n; i += mmoBlockLen {
+		c, _ := aes.NewCipher(h)
+		block := m[i : i+mmoBlockLen]
+		next := make([]byte, mmoBlockLen)
+		c.Encrypt(next, block)
+		for j := range next {
+			next[j] ^= block[j]
+		}
+		h = next
+	}
+	return h
+}
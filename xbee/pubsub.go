@@ -0,0 +1,243 @@
+package xbee
+
+import (
+	"sync"
+)
+
+// DefaultPubSubAddress is the application addressing used by PubSub unless
+// configured otherwise.
+var DefaultPubSubAddress = ExplicitAddress{
+	SourceEndpoint:      EndpointDigiData,
+	DestinationEndpoint: EndpointDigiData,
+	ClusterID:           0x0114,
+	ProfileID:           ProfileDigi,
+}
+
+const (
+	psPublish     byte = 0x00
+	psSubscribe   byte = 0x01
+	psUnsubscribe byte = 0x02
+
+	maxTopicLen       = 255
+	subscriptionQueue = 16
+)
+
+// PubSubConfig configures a PubSub. The zero value uses the defaults.
+type PubSubConfig struct {
+	// Address is the application addressing used for messages. The
+	// default is DefaultPubSubAddress.
+	Address *ExplicitAddress
+	// Broadcast publishes every message as a broadcast instead of
+	// unicasting to known subscribers.
+	Broadcast bool
+}
+
+// PubSubMessage is a message published to a topic.
+type PubSubMessage struct {
+	Source Addr64
+	Topic  string
+	Data   []byte
+}
+
+// PubSub publishes messages to string topics. Subscribers announce their
+// topics by broadcast when subscribing, and by unicast to a publisher when
+// they receive a broadcast from it. A message is unicast to the known
+// subscribers of its topic or broadcast if there are none. Subscribers that
+// can't be reached are forgotten.
+//
+// Each message starts with the message type and the length prefixed topic.
+// Messages are received as explicit packets so explicit receive (AO=1)
+// must be enabled.
+type PubSub struct {
+	xb   *XBee
+	cfg  PubSubConfig
+	addr ExplicitAddress
+	m    *matcher
+	done chan struct{}
+	once sync.Once
+
+	mu          sync.Mutex
+	subscribers map[string]map[Addr64]struct{} // remote by topic
+	local       map[string]map[*Subscription]struct{}
+}
+
+// Subscription receives the messages published to a topic.
+type Subscription struct {
+	ps    *PubSub
+	topic string
+	ch    chan *PubSubMessage
+	once  sync.Once
+}
+
+// NewPubSub starts a pub/sub layer. cfg may be nil to use the defaults.
+func (xb *XBee) NewPubSub(cfg *PubSubConfig) *PubSub {
+	ps := &PubSub{
+		xb:          xb,
+		addr:        DefaultPubSubAddress,
+		done:        make(chan struct{}),
+		subscribers: make(map[string]map[Addr64]struct{}),
+		local:       make(map[string]map[*Subscription]struct{}),
+	}
+	if cfg != nil {
+		ps.cfg = *cfg
+	}
+	if ps.cfg.Address != nil {
+		ps.addr = *ps.cfg.Address
+	}
+	ps.m = xb.registerMatcher(matchService, func(ev Event) bool {
+		rx, ok := ev.(*ExplicitReceivePacket)
+		return ok && rx.DestinationEndpoint == ps.addr.DestinationEndpoint &&
+			rx.ClusterID == ps.addr.ClusterID && rx.ProfileID == ps.addr.ProfileID
+	})
+	go ps.receiveLoop()
+	return ps
+}
+
+// Close stops receiving messages and closes all subscriptions.
+func (ps *PubSub) Close() error {
+	ps.once.Do(func() {
+		ps.xb.unregisterMatcher(ps.m)
+		close(ps.done)
+	})
+	return nil
+}
+
+func encodePubSub(kind byte, topic string, data []byte) ([]byte, error) {
+	if topic == "" || len(topic) > maxTopicLen {
+		return nil, ErrInvalidParameter
+	}
+	b := make([]byte, 0, 2+len(topic)+len(data))
+	b = append(b, kind, byte(len(topic)))
+	b = append(b, topic...)
+	return append(b, data...), nil
+}
+
+// Publish sends data to the subscribers of topic.
+func (ps *PubSub) Publish(topic string, data []byte) error {
+	b, err := encodePubSub(psPublish, topic, data)
+	if err != nil {
+		return err
+	}
+	if !ps.cfg.Broadcast {
+		ps.mu.Lock()
+		var subs []Addr64
+		for addr := range ps.subscribers[topic] {
+			subs = append(subs, addr)
+		}
+		ps.mu.Unlock()
+		var delivered int
+		for _, addr := range subs {
+			if err := ps.xb.transmitExplicitStatus(addr, ps.addr, b); err != nil {
+				ps.removeSubscriber(topic, addr)
+				continue
+			}
+			delivered++
+		}
+		if delivered != 0 {
+			return nil
+		}
+	}
+	return ps.xb.TransmitExplicit(AddressBroadcast, Address16Unknown, ps.addr, 0, 0, b)
+}
+
+// Subscribe returns a subscription to topic. Messages are dropped if the
+// subscription's channel is full.
+func (ps *PubSub) Subscribe(topic string) (*Subscription, error) {
+	b, err := encodePubSub(psSubscribe, topic, nil)
+	if err != nil {
+		return nil, err
+	}
+	s := &Subscription{ps: ps, topic: topic, ch: make(chan *PubSubMessage, subscriptionQueue)}
+	ps.mu.Lock()
+	if ps.local[topic] == nil {
+		ps.local[topic] = make(map[*Subscription]struct{})
+	}
+	ps.local[topic][s] = struct{}{}
+	ps.mu.Unlock()
+	return s, ps.xb.TransmitExplicit(AddressBroadcast, Address16Unknown, ps.addr, 0, 0, b)
+}
+
+// C returns the channel on which messages are delivered.
+func (s *Subscription) C() <-chan *PubSubMessage {
+	return s.ch
+}
+
+// Unsubscribe stops delivery of messages. Remote publishers are told when
+// the last subscription to the topic is removed.
+func (s *Subscription) Unsubscribe() error {
+	var err error
+	s.once.Do(func() {
+		ps := s.ps
+		ps.mu.Lock()
+		delete(ps.local[s.topic], s)
+		last := len(ps.local[s.topic]) == 0
+		if last {
+			delete(ps.local, s.topic)
+		}
+		ps.mu.Unlock()
+		if last {
+			b, _ := encodePubSub(psUnsubscribe, s.topic, nil)
+			err = ps.xb.TransmitExplicit(AddressBroadcast, Address16Unknown, ps.addr, 0, 0, b)
+		}
+	})
+	return err
+}
+
+func (ps *PubSub) removeSubscriber(topic string, addr Addr64) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	delete(ps.subscribers[topic], addr)
+	if len(ps.subscribers[topic]) == 0 {
+		delete(ps.subscribers, topic)
+	}
+}
+
+func (ps *PubSub) receiveLoop() {
+	for {
+		var ev Event
+		select {
+		case ev = <-ps.m.ch:
+		case <-ps.done:
+			return
+		}
+		rx := ev.(*ExplicitReceivePacket)
+		if len(rx.Data) < 2 || len(rx.Data) < 2+int(rx.Data[1]) {
+			continue
+		}
+		topic := string(rx.Data[2 : 2+rx.Data[1]])
+		switch rx.Data[0] {
+		case psPublish:
+			ps.deliver(rx, topic, rx.Data[2+len(topic):])
+		case psSubscribe:
+			ps.mu.Lock()
+			if ps.subscribers[topic] == nil {
+				ps.subscribers[topic] = make(map[Addr64]struct{})
+			}
+			ps.subscribers[topic][rx.SourceAddress] = struct{}{}
+			ps.mu.Unlock()
+		case psUnsubscribe:
+			ps.removeSubscriber(topic, rx.SourceAddress)
+		}
+	}
+}
+
+func (ps *PubSub) deliver(rx *ExplicitReceivePacket, topic string, data []byte) {
+	msg := &PubSubMessage{Source: rx.SourceAddress, Topic: topic, Data: data}
+	ps.mu.Lock()
+	subscribed := len(ps.local[topic]) != 0
+	for s := range ps.local[topic] {
+		select {
+		case s.ch <- msg:
+		default:
+			ps.xb.log.Warn("xbee: subscription channel full, dropping message", "topic", topic)
+		}
+	}
+	ps.mu.Unlock()
+	if subscribed && rx.ReceiveOptions.Has(ROBroadcast) {
+		// Let the publisher know so it can unicast to us
+		b, _ := encodePubSub(psSubscribe, topic, nil)
+		if err := ps.xb.TransmitExplicit(rx.SourceAddress, rx.SourceAddress16, ps.addr, 0, 0, b); err != nil {
+			ps.xb.log.Warn("xbee: failed to send subscription", "err", err)
+		}
+	}
+}
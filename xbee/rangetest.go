@@ -0,0 +1,164 @@
+package xbee
+
+import (
+	"context"
+	"encoding/binary"
+	"time"
+)
+
+const (
+	defaultRangeTestCount       = 100
+	defaultRangeTestPayloadSize = 32
+	defaultRangeTestInterval    = 500 * time.Millisecond
+	defaultRangeTestTimeout     = 2 * time.Second
+	rangeTestHeaderLen          = 4 // run and sequence number
+)
+
+// RangeTestConfig configures RangeTest. The zero value uses the defaults.
+type RangeTestConfig struct {
+	// Count is the number of packets to send. The default is 100.
+	Count int
+	// PayloadSize is the size of each packet. The default is 32 bytes
+	// and the minimum is 4 for the header numbering the packets.
+	PayloadSize int
+	// Interval is the delay between packets. The default is 500ms.
+	Interval time.Duration
+	// Timeout is how long to wait for each packet to be echoed. The
+	// default is 2s.
+	Timeout time.Duration
+	// RemoteRSSI queries the signal strength at the remote node (DB)
+	// after each packet. This adds a remote AT command per packet.
+	RemoteRSSI bool
+	// Progress, if not nil, is called with the result of each packet.
+	Progress func(RangeTestPacket)
+}
+
+// RangeTestPacket is the result of a single range test packet. The signal
+// strengths are in dBm and zero if unknown.
+type RangeTestPacket struct {
+	Seq        int
+	Err        error // nil if the packet was echoed
+	RTT        time.Duration
+	LocalRSSI  int // of the echoed packet
+	RemoteRSSI int // of the last packet received by the remote node
+}
+
+// RangeTestResult summarizes a range test. The round trip times and
+// signal strengths are over the packets that were echoed.
+type RangeTestResult struct {
+	Packets       []RangeTestPacket
+	Sent          int
+	Received      int
+	MinRTT        time.Duration
+	MaxRTT        time.Duration
+	AvgRTT        time.Duration
+	AvgLocalRSSI  float64
+	AvgRemoteRSSI float64
+}
+
+// SuccessRate returns the fraction of packets that were echoed.
+func (r *RangeTestResult) SuccessRate() float64 {
+	if r.Sent == 0 {
+		return 0
+	}
+	return float64(r.Received) / float64(r.Sent)
+}
+
+// RangeTest measures the link to dest by sending numbered packets to its
+// loopback cluster like the XCTU range test. Explicit receive (AO=1) must
+// be enabled (see Ping). If ctx is done the result of the packets sent so
+// far is returned along with the error. cfg may be nil to use the
+// defaults.
+func (xb *XBee) RangeTest(ctx context.Context, dest Addr64, cfg *RangeTestConfig) (*RangeTestResult, error) {
+	if dest.IsBroadcast() || dest == AddressUnknown {
+		return nil, ErrInvalidParameter
+	}
+	var c RangeTestConfig
+	if cfg != nil {
+		c = *cfg
+	}
+	if c.Count <= 0 {
+		c.Count = defaultRangeTestCount
+	}
+	if c.PayloadSize <= 0 {
+		c.PayloadSize = defaultRangeTestPayloadSize
+	}
+	c.PayloadSize = max(c.PayloadSize, rangeTestHeaderLen)
+	if c.Interval <= 0 {
+		c.Interval = defaultRangeTestInterval
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = defaultRangeTestTimeout
+	}
+
+	res := &RangeTestResult{}
+	// A prefix keeps echoes of an earlier test from matching.
+	run := uint16(time.Now().UnixNano())
+	data := make([]byte, c.PayloadSize)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	var localCount, remoteCount int
+	var totalRTT time.Duration
+	var totalLocal, totalRemote int
+	var err error
+	for seq := 0; seq < c.Count && err == nil; seq++ {
+		if seq > 0 {
+			t := time.NewTimer(c.Interval)
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				t.Stop()
+				err = ctx.Err()
+				continue
+			}
+		}
+		binary.BigEndian.PutUint16(data, run)
+		binary.BigEndian.PutUint16(data[2:], uint16(seq))
+		p := RangeTestPacket{Seq: seq}
+		pctx, cancel := context.WithTimeout(ctx, c.Timeout)
+		var pr PingResult
+		pr, p.Err = xb.Ping(pctx, dest, data)
+		p.RTT = pr.RTT
+		cancel()
+		if err = ctx.Err(); err != nil {
+			continue
+		}
+		res.Sent++
+		if p.Err == nil {
+			res.Received++
+			totalRTT += p.RTT
+			if res.Received == 1 || p.RTT < res.MinRTT {
+				res.MinRTT = p.RTT
+			}
+			res.MaxRTT = max(res.MaxRTT, p.RTT)
+			if rssi, ok := xb.lastRSSI(); ok {
+				p.LocalRSSI = rssi
+				totalLocal += rssi
+				localCount++
+			}
+			if c.RemoteRSSI {
+				b, rerr := xb.remoteATCommand(c.Timeout, dest, Address16Unknown, atReceivedSignalStrength, nil, 0)
+				if rerr == nil && len(b) > 0 {
+					p.RemoteRSSI = -int(decodeUint(b))
+					totalRemote += p.RemoteRSSI
+					remoteCount++
+				}
+			}
+		}
+		res.Packets = append(res.Packets, p)
+		if c.Progress != nil {
+			c.Progress(p)
+		}
+	}
+	if res.Received > 0 {
+		res.AvgRTT = totalRTT / time.Duration(res.Received)
+	}
+	if localCount > 0 {
+		res.AvgLocalRSSI = float64(totalLocal) / float64(localCount)
+	}
+	if remoteCount > 0 {
+		res.AvgRemoteRSSI = float64(totalRemote) / float64(remoteCount)
+	}
+	return res, err
+}
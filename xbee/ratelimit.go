@@ -0,0 +1,153 @@
+package xbee
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/samuel/go-xbee/xbee/frames"
+)
+
+var ErrDutyCycle = errors.New("xbee: transmit would exceed the duty cycle limit")
+
+const (
+	dutyCycleWindow = time.Hour
+	// rfOverheadBytes approximates the MAC and network headers added to
+	// the payload of an RF packet.
+	rfOverheadBytes = 30
+)
+
+// rfPayloadLen returns the length of the payload of frames that cause the
+// module to transmit and false for frames that are handled locally.
+func rfPayloadLen(f frames.Frame) (int, bool) {
+	switch f := f.(type) {
+	case *frames.TransmitRequest:
+		return len(f.Data), true
+	case *frames.ExplicitTransmitRequest:
+		return len(f.Data), true
+	case *frames.RemoteATCommandRequest:
+		return len(f.Parameter), true
+	case *frames.RemoteFileSystemRequest:
+		return len(f.Data), true
+	}
+	return 0, false
+}
+
+// rateLimiter is a token bucket limiting the rate of RF frames.
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	return &rateLimiter{rate: rate, burst: float64(burst), tokens: float64(burst)}
+}
+
+// reserve takes a token returning how long to wait before using it.
+func (l *rateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if !l.last.IsZero() {
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// dutyCycle tracks the estimated airtime of RF frames over the last hour.
+type dutyCycle struct {
+	mu      sync.Mutex
+	limit   time.Duration // per window
+	bitrate int
+	sent    []airtime
+}
+
+type airtime struct {
+	t time.Time
+	d time.Duration
+}
+
+func newDutyCycle(fraction float64, bitrate int) *dutyCycle {
+	return &dutyCycle{
+		limit:   time.Duration(fraction * float64(dutyCycleWindow)),
+		bitrate: bitrate,
+	}
+}
+
+func (dc *dutyCycle) airtime(n int) time.Duration {
+	return time.Duration((n + rfOverheadBytes) * 8 * int(time.Second) / dc.bitrate)
+}
+
+// usedLocked returns the airtime used in the window after dropping older
+// transmissions.
+func (dc *dutyCycle) usedLocked(now time.Time) time.Duration {
+	i := 0
+	for i < len(dc.sent) && now.Sub(dc.sent[i].t) >= dutyCycleWindow {
+		i++
+	}
+	dc.sent = dc.sent[i:]
+	var used time.Duration
+	for _, a := range dc.sent {
+		used += a.d
+	}
+	return used
+}
+
+// add records the transmission of an n byte payload returning
+// ErrDutyCycle if it would exceed the limit.
+func (dc *dutyCycle) add(n int) error {
+	now := time.Now()
+	d := dc.airtime(n)
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	if dc.usedLocked(now)+d > dc.limit {
+		return ErrDutyCycle
+	}
+	dc.sent = append(dc.sent, airtime{t: now, d: d})
+	return nil
+}
+
+// limitRF applies the transmit rate limit and duty cycle to RF frames.
+func (xb *XBee) limitRF(f frames.Frame) error {
+	n, ok := rfPayloadLen(f)
+	if !ok {
+		return nil
+	}
+	if xb.dutyCycle != nil {
+		if err := xb.dutyCycle.add(n); err != nil {
+			return err
+		}
+	}
+	if xb.limiter != nil {
+		if d := xb.limiter.reserve(); d > 0 {
+			t := time.NewTimer(d)
+			defer t.Stop()
+			select {
+			case <-t.C:
+			case <-xb.closed:
+				return ErrClosed
+			}
+		}
+	}
+	return nil
+}
+
+// DutyCycle returns the estimated airtime used in the last hour and the
+// limit set by WithDutyCycle. Both are 0 if duty cycle tracking isn't
+// enabled.
+func (xb *XBee) DutyCycle() (used, limit time.Duration) {
+	if xb.dutyCycle == nil {
+		return 0, 0
+	}
+	xb.dutyCycle.mu.Lock()
+	defer xb.dutyCycle.mu.Unlock()
+	return xb.dutyCycle.usedLocked(time.Now()), xb.dutyCycle.limit
+}
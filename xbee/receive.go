@@ -0,0 +1,33 @@
+package xbee
+
+import "context"
+
+// Receive waits for the next data packet from any node. Packets received
+// while no Receive or ReceiveFrom call is waiting are delivered by
+// EventChan as usual.
+func (xb *XBee) Receive(ctx context.Context) (*ReceivePacket, error) {
+	return xb.receive(ctx, matchAny, func(*ReceivePacket) bool { return true })
+}
+
+// ReceiveFrom waits for the next data packet from the node with the
+// address. Packets from other nodes are delivered by EventChan.
+func (xb *XBee) ReceiveFrom(ctx context.Context, addr Addr64) (*ReceivePacket, error) {
+	return xb.receive(ctx, matchSource, func(rx *ReceivePacket) bool { return rx.SourceAddress == addr })
+}
+
+func (xb *XBee) receive(ctx context.Context, scope matchScope, match func(*ReceivePacket) bool) (*ReceivePacket, error) {
+	m := xb.registerMatcher(scope, func(ev Event) bool {
+		rx, ok := ev.(*ReceivePacket)
+		return ok && match(rx)
+	})
+	// Packets that matched after the first go to EventChan.
+	defer xb.unregisterAndRedeliver(m)
+	select {
+	case ev := <-m.ch:
+		return ev.(*ReceivePacket), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-xb.closed:
+		return nil, ErrClosed
+	}
+}
@@ -0,0 +1,259 @@
+package xbee
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/samuel/go-xbee/xbee/frames"
+)
+
+// RecordFormat is the format of the files written by a Recorder.
+type RecordFormat int
+
+const (
+	// RecordJSON writes a line of JSON for each frame with the time, the
+	// frame type, and the decoded frame.
+	RecordJSON RecordFormat = iota
+	// RecordBinary writes tap files (see frames.TapWriter) that can be
+	// read with frames.TapReader and replayed.
+	RecordBinary
+)
+
+// rotatedTimeFormat is the suffix added to the name of rotated files.
+const rotatedTimeFormat = "20060102-150405.000"
+
+// RecorderConfig configures a Recorder.
+type RecorderConfig struct {
+	// Path is the file being written. Rotated files are renamed to
+	// PATH.TIME with the time they were rotated.
+	Path   string
+	Format RecordFormat
+	// MaxSize rotates the file once it reaches MaxSize bytes if positive.
+	MaxSize int64
+	// MaxAge rotates the file once it's been written for MaxAge if
+	// positive.
+	MaxAge time.Duration
+	// Gzip compresses rotated files adding .gz to their names.
+	Gzip bool
+	// MaxFiles removes the oldest rotated files to keep at most MaxFiles
+	// if positive.
+	MaxFiles int
+}
+
+// Recorder appends every packet and I/O sample received (ReceivePacket,
+// ExplicitReceivePacket, IPv4ReceivePacket, SMSReceivePacket, and
+// IODataSampleIndicator frames) to size and time rotated files. Pass it to
+// Open with WithRecorder. It's safe for concurrent use.
+type Recorder struct {
+	cfg RecorderConfig
+
+	mu      sync.Mutex
+	f       *os.File
+	tap     *frames.TapWriter
+	size    int64
+	opened  time.Time
+	rotated time.Time // time of the last rotation to keep names unique
+	wg      sync.WaitGroup
+
+	cleanMu sync.Mutex // serializes compressing and removing rotated files
+}
+
+// NewRecorder opens the file at cfg.Path for appending, creating it if
+// necessary.
+func NewRecorder(cfg RecorderConfig) (*Recorder, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("xbee.NewRecorder: %w: no path", ErrInvalidParameter)
+	}
+	r := &Recorder{cfg: cfg}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Recorder) open() error {
+	f, err := os.OpenFile(r.cfg.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.f, r.size, r.opened = f, fi.Size(), time.Now()
+	if r.cfg.Format == RecordBinary {
+		if r.size > 0 {
+			r.tap = frames.NewAppendTapWriter(countWriter{f, &r.size})
+		} else {
+			r.tap = frames.NewTapWriter(countWriter{f, &r.size})
+		}
+	}
+	return nil
+}
+
+// recorded reports whether a Recorder records frame f.
+func recorded(f Frame) bool {
+	switch f.(type) {
+	case *ReceivePacket, *ExplicitReceivePacket, *IPv4ReceivePacket, *SMSReceivePacket, *IODataSampleIndicator:
+		return true
+	}
+	return false
+}
+
+type recorderRecord struct {
+	Time  time.Time `json:"time"`
+	Type  string    `json:"type"`
+	Frame Frame     `json:"frame"`
+}
+
+// record writes a received frame with its data rotating the file first if
+// it's due.
+func (r *Recorder) record(data []byte, f Frame) error {
+	now := time.Now()
+	var line []byte
+	if r.cfg.Format == RecordJSON {
+		var err error
+		if line, err = json.Marshal(recorderRecord{Time: now, Type: fmt.Sprintf("%T", f), Frame: f}); err != nil {
+			return err
+		}
+		line = append(line, '\n')
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return ErrClosed
+	}
+	if (r.cfg.MaxSize > 0 && r.size >= r.cfg.MaxSize) || (r.cfg.MaxAge > 0 && now.Sub(r.opened) >= r.cfg.MaxAge) {
+		if err := r.rotateLocked(now); err != nil {
+			return err
+		}
+	}
+	if r.tap != nil {
+		return r.tap.WriteRecord(frames.Received, data)
+	}
+	n, err := r.f.Write(line)
+	r.size += int64(n)
+	return err
+}
+
+// Rotate closes the current file, renames it, and starts a new one.
+func (r *Recorder) Rotate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return ErrClosed
+	}
+	return r.rotateLocked(time.Now())
+}
+
+func (r *Recorder) rotateLocked(now time.Time) error {
+	if err := r.f.Close(); err != nil {
+		return err
+	}
+	r.f = nil
+	if !now.After(r.rotated) {
+		now = r.rotated.Add(time.Millisecond)
+	}
+	r.rotated = now
+	name := r.cfg.Path + "." + now.Format(rotatedTimeFormat)
+	if err := os.Rename(r.cfg.Path, name); err != nil {
+		return err
+	}
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.cleanMu.Lock()
+		defer r.cleanMu.Unlock()
+		if r.cfg.Gzip {
+			// The file may already have been removed by MaxFiles.
+			if err := gzipFile(name); err != nil && !errors.Is(err, os.ErrNotExist) {
+				slog.Warn("xbee: failed to compress recording", "file", name, "err", err)
+			}
+		}
+		r.removeOld()
+	}()
+	return r.open()
+}
+
+// removeOld removes the oldest rotated files beyond MaxFiles.
+func (r *Recorder) removeOld() {
+	if r.cfg.MaxFiles <= 0 {
+		return
+	}
+	names, err := filepath.Glob(r.cfg.Path + ".*")
+	if err != nil {
+		return
+	}
+	// The names sort by rotation time.
+	sort.Strings(names)
+	for len(names) > r.cfg.MaxFiles {
+		os.Remove(names[0])
+		names = names[1:]
+	}
+}
+
+// Close closes the current file and waits for rotated files to be
+// compressed.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	var err error
+	if r.f != nil {
+		err = r.f.Close()
+		r.f = nil
+	}
+	r.mu.Unlock()
+	r.wg.Wait()
+	return err
+}
+
+// gzipFile compresses a file to NAME.gz removing the original.
+func gzipFile(name string) error {
+	in, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(name+".gz", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	zw.Name = filepath.Base(name)
+	if _, err := io.Copy(zw, in); err != nil {
+		out.Close()
+		os.Remove(out.Name())
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		os.Remove(out.Name())
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(out.Name())
+		return err
+	}
+	return os.Remove(name)
+}
+
+// countWriter adds the number of bytes written to n.
+type countWriter struct {
+	w io.Writer
+	n *int64
+}
+
+func (w countWriter) Write(b []byte) (int, error) {
+	n, err := w.w.Write(b)
+	*w.n += int64(n)
+	return n, err
+}
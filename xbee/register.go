@@ -0,0 +1,95 @@
+package xbee
+
+import (
+	"fmt"
+
+	"github.com/samuel/go-xbee/xbee/frames"
+)
+
+// Support for registering joining devices with a trust center (Zigbee 3.0
+// secure joins).
+
+// RegisterJoiningDeviceError is returned when the trust center rejects a
+// RegisterJoiningDevice request.
+type RegisterJoiningDeviceError struct {
+	Address Addr64
+	Status  RegisterJoiningDeviceStatus
+}
+
+func (e *RegisterJoiningDeviceError) Error() string {
+	return fmt.Sprintf("xbee: register joining device %s failed: %s", e.Address, e.Status)
+}
+
+// RegisterJoiningDevice adds a device to the coordinator's key table so
+// it's able to join the network. For RJOLinkKey the key is the 128-bit
+// link key. For RJOInstallCode the key is the install code including its
+// CRC (see AppendInstallCodeCRC).
+func (xb *XBee) RegisterJoiningDevice(addr Addr64, key []byte, options RegisterJoiningDeviceOption) error {
+	switch options {
+	case RJOLinkKey:
+		if len(key) == 0 || len(key) > 16 {
+			return fmt.Errorf("xbee.RegisterJoiningDevice: %w: link key must be 1 to 16 bytes not %d", ErrInvalidParameter, len(key))
+		}
+	case RJOInstallCode:
+		switch len(key) {
+		case 8, 10, 14, 18:
+		default:
+			return fmt.Errorf("xbee.RegisterJoiningDevice: %w: install code with CRC must be 8, 10, 14, or 18 bytes not %d", ErrInvalidParameter, len(key))
+		}
+	}
+	return xb.registerJoiningDevice(addr, key, options)
+}
+
+// DeregisterJoiningDevice removes a device from the coordinator's key table.
+func (xb *XBee) DeregisterJoiningDevice(addr Addr64) error {
+	return xb.registerJoiningDevice(addr, nil, RJOLinkKey)
+}
+
+func (xb *XBee) registerJoiningDevice(addr Addr64, key []byte, options RegisterJoiningDeviceOption) error {
+	ev, err := xb.request(remoteATTimeout, func(frameID byte) frames.Frame {
+		return &frames.RegisterJoiningDevice{
+			FrameID:              frameID,
+			DestinationAddress:   addr,
+			DestinationAddress16: Address16Unknown,
+			Options:              options,
+			Key:                  key,
+		}
+	})
+	if err != nil {
+		return err
+	}
+	res, ok := ev.(*frames.RegisterJoiningDeviceResponse)
+	if !ok {
+		return wrongFrame("register joining device response", ev)
+	}
+	if res.Status != RJSuccess {
+		return &RegisterJoiningDeviceError{Address: addr, Status: res.Status}
+	}
+	return nil
+}
+
+// InstallCodeCRC returns the CRC of an install code (CRC-16/X-25 as
+// defined by the Zigbee 3.0 specification).
+func InstallCodeCRC(code []byte) uint16 {
+	crc := uint16(0xffff)
+	for _, b := range code {
+		crc ^= uint16(b)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = (crc >> 1) ^ 0x8408
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return ^crc
+}
+
+// AppendInstallCodeCRC returns the install code followed by its CRC in the
+// byte order expected by RegisterJoiningDevice and printed on devices.
+func AppendInstallCodeCRC(code []byte) []byte {
+	crc := InstallCodeCRC(code)
+	b := make([]byte, 0, len(code)+2)
+	b = append(b, code...)
+	return append(b, byte(crc), byte(crc>>8))
+}
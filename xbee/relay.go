@@ -0,0 +1,17 @@
+package xbee
+
+import "github.com/samuel/go-xbee/xbee/frames"
+
+// SendUserDataRelay sends data to another interface (BLE or MicroPython)
+// on an XBee3 module. Data relayed back to the host is received as
+// UserDataRelayOutput events.
+func (xb *XBee) SendUserDataRelay(target RelayInterface, data []byte) error {
+	if err := xb.require(FeatureUserDataRelay); err != nil {
+		return err
+	}
+	return xb.writeFrame(&frames.UserDataRelay{
+		FrameID:     xb.nextFrameID(),
+		Destination: target,
+		Data:        data,
+	})
+}
@@ -0,0 +1,224 @@
+package xbee
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	reliableHeaderLen         = 4 // session and sequence number
+	defaultReliableRetries    = 5
+	defaultReliableBackoff    = 100 * time.Millisecond
+	defaultReliableMaxBackoff = 5 * time.Second
+	reliableQueueLen          = 32
+)
+
+// DefaultReliableAddress is the application addressing used by
+// ReliableConn unless configured otherwise. The cluster isn't used by Digi
+// firmware.
+var DefaultReliableAddress = ExplicitAddress{
+	SourceEndpoint:      EndpointDigiData,
+	DestinationEndpoint: EndpointDigiData,
+	ClusterID:           0x0111,
+	ProfileID:           ProfileDigi,
+}
+
+// DeliveryError is returned when a transmit status reports the packet
+// wasn't delivered.
+type DeliveryError struct {
+	Status DeliveryStatus
+}
+
+func (e *DeliveryError) Error() string {
+	return fmt.Sprintf("xbee: delivery failed: %s", e.Status)
+}
+
+// ReliableConfig configures a ReliableConn. The zero value uses the
+// defaults.
+type ReliableConfig struct {
+	// Address is the application addressing used for messages. Both
+	// ends must use the same. The default is DefaultReliableAddress.
+	Address *ExplicitAddress
+	// Retries is the number of times a message is retransmitted after a
+	// failed delivery with a retryable status (see RetryableStatus) or
+	// without a transmit status. The default is 5.
+	Retries int
+	// Backoff is the delay before the first retransmission. It's doubled
+	// for each retry up to MaxBackoff. The defaults are 100ms and 5s.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// ReliableMessage is a message received by a ReliableConn.
+type ReliableMessage struct {
+	Source   Addr64
+	Source16 Addr16 // network address of the source
+	Data     []byte
+}
+
+// ReliableConn sends messages that are retransmitted with exponential
+// backoff until the transmit status reports they were delivered, and
+// receives messages with duplicates suppressed. Messages to a destination
+// are sent one at a time so they're delivered in order. A message that
+// can't be delivered after all retries is skipped by the receiver.
+//
+// Each message carries a 4 byte header with a session ID chosen randomly
+// when the ReliableConn is created and a sequence number. Messages are
+// received as explicit packets so explicit receive (AO=1) must be enabled.
+type ReliableConn struct {
+	xb      *XBee
+	cfg     ReliableConfig
+	addr    ExplicitAddress
+	session uint16
+	m       *matcher
+	msgs    chan *ReliableMessage
+	done    chan struct{}
+	once    sync.Once
+
+	mu      sync.Mutex
+	senders map[Addr64]*reliableSender
+	peers   map[Addr64]*reliablePeer
+}
+
+type reliableSender struct {
+	mu  sync.Mutex // held while sending to keep messages in order
+	seq uint16
+}
+
+type reliablePeer struct {
+	session uint16
+	next    uint16
+}
+
+// NewReliableConn starts a reliable messaging layer. cfg may be nil to use
+// the defaults.
+func (xb *XBee) NewReliableConn(cfg *ReliableConfig) *ReliableConn {
+	c := &ReliableConn{
+		xb:      xb,
+		addr:    DefaultReliableAddress,
+		session: uint16(rand.Uint32()),
+		msgs:    make(chan *ReliableMessage, reliableQueueLen),
+		done:    make(chan struct{}),
+		senders: make(map[Addr64]*reliableSender),
+		peers:   make(map[Addr64]*reliablePeer),
+	}
+	if cfg != nil {
+		c.cfg = *cfg
+	}
+	if c.cfg.Address != nil {
+		c.addr = *c.cfg.Address
+	}
+	if c.cfg.Retries <= 0 {
+		c.cfg.Retries = defaultReliableRetries
+	}
+	if c.cfg.Backoff <= 0 {
+		c.cfg.Backoff = defaultReliableBackoff
+	}
+	if c.cfg.MaxBackoff <= 0 {
+		c.cfg.MaxBackoff = defaultReliableMaxBackoff
+	}
+	c.m = xb.registerMatcher(matchService, func(ev Event) bool {
+		rx, ok := ev.(*ExplicitReceivePacket)
+		return ok && rx.DestinationEndpoint == c.addr.DestinationEndpoint &&
+			rx.ClusterID == c.addr.ClusterID && rx.ProfileID == c.addr.ProfileID
+	})
+	go c.receiveLoop()
+	return c
+}
+
+// Messages returns the channel on which received messages are delivered.
+// It's closed by Close.
+func (c *ReliableConn) Messages() <-chan *ReliableMessage {
+	return c.msgs
+}
+
+// Close stops receiving messages.
+func (c *ReliableConn) Close() error {
+	c.once.Do(func() {
+		c.xb.unregisterMatcher(c.m)
+		close(c.done)
+	})
+	return nil
+}
+
+// Send sends data to dest retrying until the transmit status reports it
+// was delivered. Only failures RetryableStatus considers transient are
+// retried. It returns the last error if all retries fail.
+func (c *ReliableConn) Send(ctx context.Context, dest Addr64, data []byte) error {
+	if dest.IsBroadcast() || dest == AddressUnknown {
+		return ErrInvalidParameter
+	}
+	c.mu.Lock()
+	s := c.senders[dest]
+	if s == nil {
+		s = &reliableSender{}
+		c.senders[dest] = s
+	}
+	c.mu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pkt := make([]byte, reliableHeaderLen, reliableHeaderLen+len(data))
+	binary.BigEndian.PutUint16(pkt, c.session)
+	binary.BigEndian.PutUint16(pkt[2:], s.seq)
+	pkt = append(pkt, data...)
+	// The sequence number is used even if delivery fails since the
+	// message may have been received without the acknowledgement.
+	s.seq++
+
+	p := &RetryPolicy{
+		MaxAttempts: c.cfg.Retries + 1,
+		Backoff:     c.cfg.Backoff,
+		MaxBackoff:  c.cfg.MaxBackoff,
+	}
+	return p.do(ctx, func() error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return c.xb.transmitExplicitStatus(dest, c.addr, pkt)
+	})
+}
+
+func (c *ReliableConn) receiveLoop() {
+	defer close(c.msgs)
+	for {
+		var ev Event
+		select {
+		case ev = <-c.m.ch:
+		case <-c.done:
+			return
+		}
+		rx := ev.(*ExplicitReceivePacket)
+		if len(rx.Data) < reliableHeaderLen || !c.accept(rx.SourceAddress, rx.Data) {
+			continue
+		}
+		select {
+		case c.msgs <- &ReliableMessage{Source: rx.SourceAddress, Source16: rx.SourceAddress16, Data: rx.Data[reliableHeaderLen:]}:
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// accept returns false for duplicate messages. A new session from the
+// source (e.g. after it restarted) resets the expected sequence number.
+func (c *ReliableConn) accept(src Addr64, pkt []byte) bool {
+	session := binary.BigEndian.Uint16(pkt)
+	seq := binary.BigEndian.Uint16(pkt[2:])
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p := c.peers[src]
+	if p == nil || p.session != session {
+		p = &reliablePeer{session: session, next: seq}
+		c.peers[src] = p
+	}
+	if int16(seq-p.next) < 0 {
+		return false
+	}
+	p.next = seq + 1
+	return true
+}
@@ -0,0 +1,57 @@
+package xbee_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/samuel/go-xbee/xbee"
+	"github.com/samuel/go-xbee/xbee/xbeetest"
+)
+
+func TestReliableConnSendPermanentFailure(t *testing.T) {
+	r := xbeetest.NewRadio()
+	r.SetDeliveryStatus(xbee.DSDataPayloadTooLarge)
+	xb := openRadio(t, r)
+	c := xb.NewReliableConn(nil)
+	defer c.Close()
+
+	err := c.Send(context.Background(), routerAddr, []byte("hello"))
+	if de, ok := err.(*xbee.DeliveryError); !ok || de.Status != xbee.DSDataPayloadTooLarge {
+		t.Fatalf("Send = %v, want DeliveryError %s", err, xbee.DSDataPayloadTooLarge)
+	}
+	if n := len(r.Transmitted()); n != 1 {
+		t.Errorf("transmitted %d times, want 1", n)
+	}
+}
+
+func TestReliableConnSendRetries(t *testing.T) {
+	r := xbeetest.NewRadio()
+	r.SetDeliveryStatus(xbee.DSNetworkACKFailure)
+	xb := openRadio(t, r)
+	c := xb.NewReliableConn(&xbee.ReliableConfig{Retries: 2, Backoff: 1, MaxBackoff: 1})
+	defer c.Close()
+
+	err := c.Send(context.Background(), routerAddr, []byte("hello"))
+	if de, ok := err.(*xbee.DeliveryError); !ok || de.Status != xbee.DSNetworkACKFailure {
+		t.Fatalf("Send = %v, want DeliveryError %s", err, xbee.DSNetworkACKFailure)
+	}
+	if n := len(r.Transmitted()); n != 3 {
+		t.Errorf("transmitted %d times, want 3", n)
+	}
+}
+
+func TestReliableConnSendCanceled(t *testing.T) {
+	r := xbeetest.NewRadio()
+	xb := openRadio(t, r)
+	c := xb.NewReliableConn(nil)
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := c.Send(ctx, routerAddr, []byte("hello")); err != context.Canceled {
+		t.Fatalf("Send = %v, want %v", err, context.Canceled)
+	}
+	if n := len(r.Transmitted()); n != 0 {
+		t.Errorf("transmitted %d times, want 0", n)
+	}
+}
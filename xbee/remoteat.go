@@ -0,0 +1,42 @@
+package xbee
+
+import (
+	"time"
+
+	"github.com/samuel/go-xbee/xbee/frames"
+)
+
+// RemoteATCommand issues an AT command to a remote device and returns the
+// response data. Use Address16Unknown for net if the 16-bit address of
+// the device is not known to use the address cached from earlier frames
+// (see Address16) or have the module discover it.
+func (xb *XBee) RemoteATCommand(dest Addr64, net Addr16, cmd ATCommand, param []byte, options RemoteATCommandOption) ([]byte, error) {
+	return xb.remoteATCommand(remoteATTimeout, dest, net, cmd, param, options)
+}
+
+func (xb *XBee) remoteATCommand(timeout time.Duration, dest Addr64, net Addr16, cmd ATCommand, param []byte, options RemoteATCommandOption) ([]byte, error) {
+	ev, err := xb.request(timeout, func(frameID byte) frames.Frame {
+		return &frames.RemoteATCommandRequest{
+			FrameID:              frameID,
+			DestinationAddress:   dest,
+			DestinationAddress16: net,
+			Options:              options,
+			ATCommand:            cmd,
+			Parameter:            param,
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	res, ok := ev.(*RemoteATCommandResponse)
+	if !ok {
+		return nil, wrongFrame("remote AT response", ev)
+	}
+	if res.ATCommand != cmd {
+		return nil, wrongCommand(cmd, res.ATCommand)
+	}
+	if err := commandStatusError(cmd, res.CommandStatus); err != nil {
+		return nil, err
+	}
+	return res.Data, nil
+}
@@ -0,0 +1,104 @@
+package xbee
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	defaultRetryAttempts   = 3
+	defaultRetryBackoff    = 100 * time.Millisecond
+	defaultRetryMaxBackoff = 2 * time.Second
+)
+
+// RetryPolicy configures how Transmit retries failed deliveries (see
+// WithRetry). The zero value uses the defaults.
+type RetryPolicy struct {
+	// MaxAttempts is the number of transmits including the first. The
+	// default is 3.
+	MaxAttempts int
+	// Backoff is the delay before the first retry. It's doubled for each
+	// retry up to MaxBackoff. The defaults are 100ms and 2s.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Retryable reports whether a failed delivery is worth retrying. The
+	// default is RetryableStatus.
+	Retryable func(DeliveryStatus) bool
+}
+
+// RetryableStatus reports whether a delivery status is likely transient:
+// RF failures, missing routes or addresses, and lack of buffers. Failures
+// that will happen again (e.g. DSDataPayloadTooLarge or
+// DSInvalidDestinationEndpoint) aren't retryable.
+func RetryableStatus(s DeliveryStatus) bool {
+	switch s {
+	case DSMACACKFailure, DSCCAFailure, DSNetworkACKFailure, DSNotJoinedToNetwork,
+		DSAddressNotFound, DSRouteNotFound, DSResourceError, DSResourceError2:
+		return true
+	}
+	return false
+}
+
+// WithRetry makes Transmit wait for the transmit status retrying
+// deliveries that fail with a retryable status or time out. Transmit then
+// returns the last error if all attempts fail. p may be nil to use the
+// defaults. It overrides WithAck(false).
+func WithRetry(p *RetryPolicy) TxOption {
+	return func(o *txOptions) {
+		o.wait = true
+		o.retry = p
+	}
+}
+
+// WithContext stops Transmit waiting to retry with WithRetry once ctx is
+// done returning ctx.Err().
+func WithContext(ctx context.Context) TxOption {
+	return func(o *txOptions) {
+		o.ctx = ctx
+	}
+}
+
+// do calls fn until it succeeds, fails with an error that isn't
+// retryable, or the attempts run out.
+func (p *RetryPolicy) do(ctx context.Context, fn func() error) error {
+	var rp RetryPolicy
+	if p != nil {
+		rp = *p
+	}
+	if rp.MaxAttempts <= 0 {
+		rp.MaxAttempts = defaultRetryAttempts
+	}
+	if rp.Backoff <= 0 {
+		rp.Backoff = defaultRetryBackoff
+	}
+	if rp.MaxBackoff <= 0 {
+		rp.MaxBackoff = defaultRetryMaxBackoff
+	}
+	if rp.Retryable == nil {
+		rp.Retryable = RetryableStatus
+	}
+	backoff := rp.Backoff
+	for i := 1; ; i++ {
+		err := fn()
+		if err == nil || i >= rp.MaxAttempts || !rp.retryable(err) {
+			return err
+		}
+		t := time.NewTimer(backoff)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+		backoff = min(backoff*2, rp.MaxBackoff)
+	}
+}
+
+func (p *RetryPolicy) retryable(err error) bool {
+	var de *DeliveryError
+	if errors.As(err, &de) {
+		return p.Retryable(de.Status)
+	}
+	return errors.Is(err, ErrTimeout)
+}
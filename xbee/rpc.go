@@ -0,0 +1,285 @@
+package xbee
+
+import (
+	"context"
+	"encoding/binary"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// DefaultRPCAddress is the application addressing used by RPC unless
+// configured otherwise.
+var DefaultRPCAddress = ExplicitAddress{
+	SourceEndpoint:      EndpointDigiData,
+	DestinationEndpoint: EndpointDigiData,
+	ClusterID:           0x0113,
+	ProfileID:           ProfileDigi,
+}
+
+const (
+	rpcRequest  byte = 0x00
+	rpcResponse byte = 0x01
+	rpcError    byte = 0x02
+
+	rpcHeaderLen      = 3 // kind and correlation ID
+	rpcRequestQueue   = 16
+	defaultRPCTimeout = 2 * time.Second
+	defaultRPCRetries = 2
+	// rpcCacheTime is how long a server remembers a response so a retried
+	// request is answered without calling the handler again.
+	rpcCacheTime = time.Minute
+)
+
+// RPCError is returned by Call when the remote handler returns an error.
+type RPCError struct {
+	Message string
+}
+
+func (e *RPCError) Error() string {
+	return "xbee: remote error: " + e.Message
+}
+
+// RPCHandler handles a request from src returning the response.
+type RPCHandler func(ctx context.Context, src Addr64, req []byte) ([]byte, error)
+
+// RPCConfig configures an RPC. The zero value uses the defaults.
+type RPCConfig struct {
+	// Address is the application addressing used for requests and
+	// responses. The default is DefaultRPCAddress.
+	Address *ExplicitAddress
+	// Timeout is how long to wait for a response before retrying. The
+	// default is 2s.
+	Timeout time.Duration
+	// Retries is the number of times a request is resent without a
+	// response. The default is 2.
+	Retries int
+}
+
+// RPC sends requests to remote nodes and waits for the matching response,
+// and serves requests from remote nodes. Requests and responses are
+// correlated by a 16-bit ID. A request that's retried because the response
+// was lost is answered from a cache rather than handled again.
+//
+// Messages are received as explicit packets so explicit receive (AO=1)
+// must be enabled. Requests and responses must fit in a single packet.
+type RPC struct {
+	xb   *XBee
+	cfg  RPCConfig
+	addr ExplicitAddress
+	m    *matcher
+	reqs chan *ExplicitReceivePacket
+	done chan struct{}
+	once sync.Once
+
+	mu     sync.Mutex
+	nextID uint16
+	calls  map[uint16]*rpcCall
+	served map[rpcKey]*rpcServed
+}
+
+// rpcCall is a call waiting for a response. Responses are correlated by
+// the call ID and checked to come from the destination, which is the
+// coordinator's serial number rather than dest if dest is
+// AddressCoordinator.
+type rpcCall struct {
+	dest Addr64
+	ch   chan *ExplicitReceivePacket
+}
+
+type rpcKey struct {
+	addr Addr64
+	id   uint16
+}
+
+type rpcServed struct {
+	t        time.Time
+	response []byte // nil while the handler is running
+}
+
+// NewRPC starts an RPC client and server. cfg may be nil to use the
+// defaults.
+func (xb *XBee) NewRPC(cfg *RPCConfig) *RPC {
+	r := &RPC{
+		xb:   xb,
+		addr: DefaultRPCAddress,
+		// A random first ID avoids a restarted client being answered
+		// from the server's cache.
+		nextID: uint16(rand.Uint32()),
+		reqs:   make(chan *ExplicitReceivePacket, rpcRequestQueue),
+		done:   make(chan struct{}),
+		calls:  make(map[uint16]*rpcCall),
+		served: make(map[rpcKey]*rpcServed),
+	}
+	if cfg != nil {
+		r.cfg = *cfg
+	}
+	if r.cfg.Address != nil {
+		r.addr = *r.cfg.Address
+	}
+	if r.cfg.Timeout <= 0 {
+		r.cfg.Timeout = defaultRPCTimeout
+	}
+	if r.cfg.Retries < 0 {
+		r.cfg.Retries = 0
+	} else if r.cfg.Retries == 0 {
+		r.cfg.Retries = defaultRPCRetries
+	}
+	r.m = xb.registerMatcher(matchService, func(ev Event) bool {
+		rx, ok := ev.(*ExplicitReceivePacket)
+		return ok && rx.DestinationEndpoint == r.addr.DestinationEndpoint &&
+			rx.ClusterID == r.addr.ClusterID && rx.ProfileID == r.addr.ProfileID
+	})
+	go r.receiveLoop()
+	return r
+}
+
+// Close stops the RPC. Pending calls return ErrClosed.
+func (r *RPC) Close() error {
+	r.once.Do(func() {
+		r.xb.unregisterMatcher(r.m)
+		close(r.done)
+	})
+	return nil
+}
+
+func (r *RPC) send(dest Addr64, kind byte, id uint16, payload []byte) error {
+	b := make([]byte, rpcHeaderLen, rpcHeaderLen+len(payload))
+	b[0] = kind
+	binary.BigEndian.PutUint16(b[1:], id)
+	return r.xb.TransmitExplicit(dest, Address16Unknown, r.addr, 0, 0, append(b, payload...))
+}
+
+// Call sends a request to dest and returns the response.
+func (r *RPC) Call(ctx context.Context, dest Addr64, req []byte) ([]byte, error) {
+	call := &rpcCall{dest: dest, ch: make(chan *ExplicitReceivePacket, 1)}
+	r.mu.Lock()
+	r.nextID++
+	id := r.nextID
+	r.calls[id] = call
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		delete(r.calls, id)
+		r.mu.Unlock()
+	}()
+
+	for i := 0; i <= r.cfg.Retries; i++ {
+		if err := r.send(dest, rpcRequest, id, req); err != nil {
+			return nil, err
+		}
+		t := time.NewTimer(r.cfg.Timeout)
+		select {
+		case rx := <-call.ch:
+			t.Stop()
+			if rx.Data[0] == rpcError {
+				return nil, &RPCError{Message: string(rx.Data[rpcHeaderLen:])}
+			}
+			return rx.Data[rpcHeaderLen:], nil
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-r.done:
+			t.Stop()
+			return nil, ErrClosed
+		}
+	}
+	return nil, ErrTimeout
+}
+
+// Serve handles requests with h until ctx is done. Each request is handled
+// in its own goroutine. Requests received while not serving are queued and
+// dropped once the queue is full.
+func (r *RPC) Serve(ctx context.Context, h RPCHandler) error {
+	for {
+		select {
+		case rx := <-r.reqs:
+			go r.handle(ctx, h, rx)
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-r.done:
+			return ErrClosed
+		}
+	}
+}
+
+func (r *RPC) handle(ctx context.Context, h RPCHandler, rx *ExplicitReceivePacket) {
+	key := rpcKey{addr: rx.SourceAddress, id: binary.BigEndian.Uint16(rx.Data[1:])}
+	kind := rpcResponse
+	res, err := h(ctx, rx.SourceAddress, rx.Data[rpcHeaderLen:])
+	if err != nil {
+		kind = rpcError
+		res = []byte(err.Error())
+	}
+	r.mu.Lock()
+	r.served[key] = &rpcServed{t: time.Now(), response: append([]byte{kind}, res...)}
+	r.mu.Unlock()
+	if err := r.send(rx.SourceAddress, kind, key.id, res); err != nil {
+		r.xb.log.Warn("xbee: failed to send RPC response", "err", err)
+	}
+}
+
+func (r *RPC) receiveLoop() {
+	for {
+		var ev Event
+		select {
+		case ev = <-r.m.ch:
+		case <-r.done:
+			return
+		}
+		rx := ev.(*ExplicitReceivePacket)
+		if len(rx.Data) < rpcHeaderLen {
+			continue
+		}
+		key := rpcKey{addr: rx.SourceAddress, id: binary.BigEndian.Uint16(rx.Data[1:])}
+		switch rx.Data[0] {
+		case rpcResponse, rpcError:
+			r.mu.Lock()
+			call := r.calls[key.id]
+			r.mu.Unlock()
+			if call != nil && sentBy(call.dest, rx.SourceAddress, rx.SourceAddress16) {
+				select {
+				case call.ch <- rx:
+				default:
+					// Duplicate response
+				}
+			}
+		case rpcRequest:
+			r.request(key, rx)
+		}
+	}
+}
+
+// request queues a request for Serve unless it's a retry of a request
+// that's being handled or was answered recently.
+func (r *RPC) request(key rpcKey, rx *ExplicitReceivePacket) {
+	now := time.Now()
+	r.mu.Lock()
+	for k, s := range r.served {
+		if s.response != nil && now.Sub(s.t) > rpcCacheTime {
+			delete(r.served, k)
+		}
+	}
+	s := r.served[key]
+	if s == nil {
+		r.served[key] = &rpcServed{t: now}
+	}
+	r.mu.Unlock()
+	if s != nil {
+		if s.response != nil {
+			if err := r.send(key.addr, s.response[0], key.id, s.response[1:]); err != nil {
+				r.xb.log.Warn("xbee: failed to send RPC response", "err", err)
+			}
+		}
+		return
+	}
+	select {
+	case r.reqs <- rx:
+	default:
+		r.mu.Lock()
+		delete(r.served, key)
+		r.mu.Unlock()
+		r.xb.log.Warn("xbee: RPC request queue full, dropping request", "source", key.addr)
+	}
+}
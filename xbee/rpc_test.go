@@ -0,0 +1,53 @@
+package xbee_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/samuel/go-xbee/xbee"
+)
+
+func TestRPCCall(t *testing.T) {
+	coord, router := openPAN(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	srv := coord.NewRPC(nil)
+	defer srv.Close()
+	go srv.Serve(ctx, func(ctx context.Context, src xbee.Addr64, req []byte) ([]byte, error) {
+		if src != routerAddr {
+			return nil, fmt.Errorf("request from %s", src)
+		}
+		return append([]byte("echo "), req...), nil
+	})
+	cli := router.NewRPC(nil)
+	defer cli.Close()
+
+	for _, dest := range []xbee.Addr64{coordAddr, xbee.AddressCoordinator} {
+		res, err := cli.Call(ctx, dest, []byte("hello"))
+		if err != nil {
+			t.Fatalf("Call(%s): %v", dest, err)
+		}
+		if string(res) != "echo hello" {
+			t.Errorf("Call(%s) = %q, want %q", dest, res, "echo hello")
+		}
+	}
+}
+
+func TestRPCCallError(t *testing.T) {
+	coord, router := openPAN(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	srv := coord.NewRPC(nil)
+	defer srv.Close()
+	go srv.Serve(ctx, func(ctx context.Context, src xbee.Addr64, req []byte) ([]byte, error) {
+		return nil, fmt.Errorf("no %s", req)
+	})
+	cli := router.NewRPC(nil)
+	defer cli.Close()
+
+	_, err := cli.Call(ctx, xbee.AddressCoordinator, []byte("thanks"))
+	if re, ok := err.(*xbee.RPCError); !ok || re.Message != "no thanks" {
+		t.Fatalf("Call = %v, want RPCError %q", err, "no thanks")
+	}
+}
@@ -0,0 +1,236 @@
+package xbee
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPollInterval         = time.Minute
+	defaultPollTimeout          = 5 * time.Second
+	defaultPollConcurrency      = 4
+	schedulerQueueLen           = 16
+	pingDataPrefix         byte = 'P' // distinguishes scheduler pings from monitor pings
+)
+
+// PollKind is the operation of a PollJob.
+type PollKind int
+
+const (
+	// PollATCommand reads a register of each node with a remote AT
+	// command, e.g. %V for the supply voltage or DB for the signal
+	// strength.
+	PollATCommand PollKind = iota
+	// PollSample reads the I/O pins of each node (IS).
+	PollSample
+	// PollPing pings each node (see Ping).
+	PollPing
+)
+
+func (k PollKind) String() string {
+	switch k {
+	case PollATCommand:
+		return "ATCommand"
+	case PollSample:
+		return "Sample"
+	case PollPing:
+		return "Ping"
+	}
+	return fmt.Sprintf("PollKind(%d)", k)
+}
+
+func (k PollKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// PollJob is an operation run periodically against a set of nodes.
+type PollJob struct {
+	Name    string
+	Kind    PollKind
+	Command ATCommand // register read by PollATCommand
+	// Nodes are the nodes to poll. The default is every node returned by
+	// NodeStats at the time of each run.
+	Nodes []Addr64
+	// Interval is the time between runs. The default is 1m.
+	Interval time.Duration
+	// Jitter delays polling each node by a random time up to Jitter so
+	// nodes aren't all polled at once.
+	Jitter time.Duration
+}
+
+// SchedulerConfig configures a Scheduler. The zero value uses the
+// defaults.
+type SchedulerConfig struct {
+	Jobs []PollJob
+	// Concurrency is the most operations in flight at once across all
+	// jobs. The default is 4.
+	Concurrency int
+	// Timeout is how long to wait for each operation. The default is 5s.
+	Timeout time.Duration
+}
+
+// PollResult is delivered by a Scheduler for each node polled by a job.
+type PollResult struct {
+	Job     string
+	Kind    PollKind
+	Address Addr64
+	Time    time.Time
+	Command ATCommand              `json:",omitempty"` // for PollATCommand
+	Value   []byte                 `json:",omitempty"` // response to PollATCommand
+	Sample  *IODataSampleIndicator `json:",omitempty"` // for PollSample
+	Latency time.Duration          // round trip time of the operation
+	Err     error                  `json:"-"`
+}
+
+// Scheduler runs poll jobs and reports their results as PollResult
+// events.
+type Scheduler struct {
+	xb     *XBee
+	cfg    SchedulerConfig
+	events chan Event
+	done   chan struct{}
+	once   sync.Once
+	wg     sync.WaitGroup
+	sem    chan struct{}
+
+	mu  sync.Mutex
+	seq uint32
+}
+
+// NewScheduler starts running poll jobs. cfg may be nil to use the
+// defaults though a scheduler without jobs does nothing. Events must be
+// read from Events or polling stops.
+func (xb *XBee) NewScheduler(cfg *SchedulerConfig) *Scheduler {
+	s := &Scheduler{
+		xb:     xb,
+		events: make(chan Event, schedulerQueueLen),
+		done:   make(chan struct{}),
+	}
+	if cfg != nil {
+		s.cfg = *cfg
+	}
+	if s.cfg.Concurrency <= 0 {
+		s.cfg.Concurrency = defaultPollConcurrency
+	}
+	if s.cfg.Timeout <= 0 {
+		s.cfg.Timeout = defaultPollTimeout
+	}
+	s.sem = make(chan struct{}, s.cfg.Concurrency)
+	for _, job := range s.cfg.Jobs {
+		if job.Interval <= 0 {
+			job.Interval = defaultPollInterval
+		}
+		s.wg.Add(1)
+		go s.loop(job)
+	}
+	go func() {
+		s.wg.Wait()
+		close(s.events)
+	}()
+	return s
+}
+
+// Events returns the channel on which PollResult events are delivered.
+// It's closed once the scheduler is closed and operations in flight have
+// finished.
+func (s *Scheduler) Events() <-chan Event {
+	return s.events
+}
+
+// Close stops polling.
+func (s *Scheduler) Close() error {
+	s.once.Do(func() {
+		close(s.done)
+	})
+	return nil
+}
+
+func (s *Scheduler) loop(job PollJob) {
+	defer s.wg.Done()
+	t := time.NewTicker(job.Interval)
+	defer t.Stop()
+	for {
+		s.run(job)
+		select {
+		case <-t.C:
+		case <-s.done:
+			return
+		case <-s.xb.closed:
+			return
+		}
+	}
+}
+
+// run polls every node of a job once.
+func (s *Scheduler) run(job PollJob) {
+	nodes := job.Nodes
+	if nodes == nil {
+		for _, n := range s.xb.NodeStats() {
+			nodes = append(nodes, n.Address)
+		}
+	}
+	var wg sync.WaitGroup
+	for _, addr := range nodes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if job.Jitter > 0 && !s.sleep(time.Duration(rand.Int63n(int64(job.Jitter)))) {
+				return
+			}
+			select {
+			case s.sem <- struct{}{}:
+			case <-s.done:
+				return
+			}
+			res := s.poll(job, addr)
+			<-s.sem
+			select {
+			case s.events <- res:
+			case <-s.done:
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// sleep waits for d returning false if the scheduler was closed first.
+func (s *Scheduler) sleep(d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-s.done:
+		return false
+	}
+}
+
+func (s *Scheduler) poll(job PollJob, addr Addr64) *PollResult {
+	res := &PollResult{Job: job.Name, Kind: job.Kind, Address: addr, Time: time.Now()}
+	switch job.Kind {
+	case PollATCommand:
+		res.Command = job.Command
+		res.Value, res.Err = s.xb.remoteATCommand(s.cfg.Timeout, addr, Address16Unknown, job.Command, nil, 0)
+	case PollSample:
+		var b []byte
+		if b, res.Err = s.xb.remoteATCommand(s.cfg.Timeout, addr, Address16Unknown, atForceSample, nil, 0); res.Err == nil {
+			res.Sample, res.Err = decodeForcedSample(addr, b)
+		}
+	case PollPing:
+		s.mu.Lock()
+		s.seq++
+		data := binary.BigEndian.AppendUint32([]byte{pingDataPrefix}, s.seq)
+		s.mu.Unlock()
+		ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+		_, res.Err = s.xb.Ping(ctx, addr, data)
+		cancel()
+	default:
+		res.Err = fmt.Errorf("xbee: %w: unknown poll kind %d", ErrInvalidParameter, job.Kind)
+	}
+	res.Latency = time.Since(res.Time)
+	return res
+}
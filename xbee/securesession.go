@@ -0,0 +1,79 @@
+package xbee
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/samuel/go-xbee/xbee/frames"
+)
+
+// SecureSessionError is returned when a secure session login or logout
+// fails.
+type SecureSessionError struct {
+	Address Addr64
+	Status  SecureSessionStatus
+}
+
+func (e *SecureSessionError) Error() string {
+	return fmt.Sprintf("xbee: secure session with %s failed: %s", e.Address, e.Status)
+}
+
+// SecureSessionLogin starts a secure session with a password protected
+// remote XBee3 module. Once established, remote AT commands sent with the
+// RATOSecureSession option are accepted by the module. The session ends
+// after timeout (rounded to 100ms, maximum ~109 minutes) of inactivity
+// unless SSOFixedTimeout is set in which case it ends after timeout
+// regardless of activity.
+func (xb *XBee) SecureSessionLogin(dest Addr64, password string, timeout time.Duration, options SecureSessionOption) error {
+	if err := xb.require(FeatureSecureSession); err != nil {
+		return err
+	}
+	if password == "" {
+		return fmt.Errorf("xbee.SecureSessionLogin: %w: password is required", ErrInvalidParameter)
+	}
+	t := timeout / (100 * time.Millisecond)
+	if t <= 0 || t > 0xffff {
+		return fmt.Errorf("xbee.SecureSessionLogin: %w: invalid timeout %s", ErrInvalidParameter, timeout)
+	}
+	return xb.secureSession(&frames.SecureSessionControl{
+		DestinationAddress: dest,
+		Options:            options &^ SSOLogout,
+		Timeout:            uint16(t),
+		Password:           []byte(password),
+	})
+}
+
+// SecureSessionLogout ends a secure session with a remote module.
+func (xb *XBee) SecureSessionLogout(dest Addr64) error {
+	return xb.secureSession(&frames.SecureSessionControl{
+		DestinationAddress: dest,
+		Options:            SSOLogout,
+	})
+}
+
+func (xb *XBee) secureSession(f *frames.SecureSessionControl) error {
+	logout := f.Options&SSOLogout != 0
+	// Secure session frames don't have a frame ID so the response is
+	// matched by the address.
+	m := xb.registerMatcher(matchSession, func(ev Event) bool {
+		res, ok := ev.(*SecureSessionResponse)
+		return ok && res.SourceAddress == f.DestinationAddress && res.Logout == logout
+	})
+	defer xb.unregisterMatcher(m)
+	if err := xb.writeFrame(f); err != nil {
+		return err
+	}
+	// SRP authentication involves several round trips with the remote
+	// module so allow extra time.
+	var res *SecureSessionResponse
+	select {
+	case ev := <-m.ch:
+		res = ev.(*SecureSessionResponse)
+	case <-time.After(2 * remoteATTimeout):
+		return ErrTimeout
+	}
+	if res.Status != SSSuccess {
+		return &SecureSessionError{Address: res.SourceAddress, Status: res.Status}
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+package xbee
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultBridgeReconnect = 2 * time.Second
+	defaultBridgeLinkRetry = time.Second
+)
+
+// SerialBridgeConfig configures a SerialBridge.
+type SerialBridgeConfig struct {
+	// Open opens the local device, e.g. a serial port or pty. It's called
+	// again to reconnect after the device fails. It's required.
+	Open func() (io.ReadWriteCloser, error)
+	// Reconnect is the delay before reopening the device after it fails.
+	// The default is 2s.
+	Reconnect time.Duration
+	// LinkRetry is the delay before resending data the remote node didn't
+	// acknowledge after the retry policy gave up. The default is 1s.
+	LinkRetry time.Duration
+	// Retry is the retry policy of each transmit (see WithRetry).
+	Retry *RetryPolicy
+}
+
+// SerialBridgeStats are the counters of a SerialBridge.
+type SerialBridgeStats struct {
+	BytesSent     uint64 // from the device delivered to the remote node
+	BytesReceived uint64 // from the remote node written to the device
+	Dropped       uint64 // packets from the remote node dropped because the device was slow or closed
+	Reconnects    uint64 // times the device was reopened
+}
+
+// SerialBridge pipes a local device over the RF link to the UART of a
+// remote module in transparent mode, a wireless serial cable. Data read
+// from the device is split into packets of at most NP bytes and each is
+// delivered before more is read, so a slow or unreachable link pushes
+// back on the device rather than losing data. Packets from the remote node
+// (ReceivePacket or ExplicitReceivePacket on the serial data cluster) are
+// written to the device and aren't delivered by EventChan.
+type SerialBridge struct {
+	xb     *XBee
+	remote Addr64
+	cfg    SerialBridgeConfig
+	chunk  int
+	m      *matcher
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	sent, received, dropped, reconnects atomic.Uint64
+
+	mu  sync.Mutex
+	dev io.ReadWriteCloser // nil while reconnecting
+}
+
+// NewSerialBridge opens the device and starts bridging it to remote.
+func (xb *XBee) NewSerialBridge(remote Addr64, cfg SerialBridgeConfig) (*SerialBridge, error) {
+	if cfg.Open == nil || remote.IsBroadcast() || remote == AddressUnknown {
+		return nil, ErrInvalidParameter
+	}
+	if cfg.Reconnect <= 0 {
+		cfg.Reconnect = defaultBridgeReconnect
+	}
+	if cfg.LinkRetry <= 0 {
+		cfg.LinkRetry = defaultBridgeLinkRetry
+	}
+	np, err := xb.MaximumRFPayloadBytes()
+	if err != nil {
+		return nil, err
+	}
+	if np <= 0 {
+		return nil, ErrInvalidParameter
+	}
+	dev, err := cfg.Open()
+	if err != nil {
+		return nil, err
+	}
+	b := &SerialBridge{xb: xb, remote: remote, cfg: cfg, chunk: np, dev: dev}
+	b.ctx, b.cancel = context.WithCancel(context.Background())
+	b.m = xb.registerMatcher(matchSource, func(ev Event) bool {
+		src, _, ok := serialData(ev)
+		return ok && src == remote
+	})
+	b.wg.Add(2)
+	go b.readLoop(dev)
+	go b.writeLoop()
+	return b, nil
+}
+
+// Stats returns the bridge's counters.
+func (b *SerialBridge) Stats() SerialBridgeStats {
+	return SerialBridgeStats{
+		BytesSent:     b.sent.Load(),
+		BytesReceived: b.received.Load(),
+		Dropped:       b.dropped.Load(),
+		Reconnects:    b.reconnects.Load(),
+	}
+}
+
+// Close stops bridging and closes the device.
+func (b *SerialBridge) Close() error {
+	b.cancel()
+	b.xb.unregisterMatcher(b.m)
+	b.mu.Lock()
+	var err error
+	if b.dev != nil {
+		err = b.dev.Close()
+		b.dev = nil
+	}
+	b.mu.Unlock()
+	b.wg.Wait()
+	return err
+}
+
+// readLoop sends data read from the device to the remote node reopening
+// the device when it fails.
+func (b *SerialBridge) readLoop(dev io.ReadWriteCloser) {
+	defer b.wg.Done()
+	buf := make([]byte, b.chunk)
+	for {
+		n, err := dev.Read(buf)
+		if n > 0 && !b.send(buf[:n]) {
+			return
+		}
+		if err == nil {
+			continue
+		}
+		if b.ctx.Err() != nil {
+			return
+		}
+		b.xb.log.Warn("xbee: serial bridge device failed", "err", err)
+		if dev = b.reopen(dev); dev == nil {
+			return
+		}
+	}
+}
+
+// send delivers data to the remote node retrying until it's delivered,
+// returning false if the bridge was closed first.
+func (b *SerialBridge) send(data []byte) bool {
+	for {
+		err := b.xb.Transmit(b.remote, data, WithRetry(b.cfg.Retry), WithContext(b.ctx))
+		if err == nil {
+			b.sent.Add(uint64(len(data)))
+			return true
+		}
+		if b.ctx.Err() != nil || errors.Is(err, ErrClosed) {
+			return false
+		}
+		b.xb.log.Warn("xbee: serial bridge link failed", "remote", b.remote, "err", err)
+		if !b.sleep(b.cfg.LinkRetry) {
+			return false
+		}
+	}
+}
+
+// reopen closes a failed device and opens it again, returning nil if the
+// bridge was closed first.
+func (b *SerialBridge) reopen(old io.ReadWriteCloser) io.ReadWriteCloser {
+	b.mu.Lock()
+	if b.dev == old {
+		old.Close()
+		b.dev = nil
+	}
+	b.mu.Unlock()
+	for {
+		if !b.sleep(b.cfg.Reconnect) {
+			return nil
+		}
+		dev, err := b.cfg.Open()
+		if err != nil {
+			b.xb.log.Warn("xbee: serial bridge failed to reopen device", "err", err)
+			continue
+		}
+		b.mu.Lock()
+		if b.ctx.Err() != nil {
+			b.mu.Unlock()
+			dev.Close()
+			return nil
+		}
+		b.dev = dev
+		b.mu.Unlock()
+		b.reconnects.Add(1)
+		return dev
+	}
+}
+
+// writeLoop writes the packets received from the remote node to the
+// device dropping them while the device is being reopened.
+func (b *SerialBridge) writeLoop() {
+	defer b.wg.Done()
+	for {
+		var ev Event
+		select {
+		case ev = <-b.m.ch:
+		case <-b.ctx.Done():
+			return
+		case <-b.xb.closed:
+			return
+		}
+		_, data, _ := serialData(ev)
+		b.mu.Lock()
+		dev := b.dev
+		b.mu.Unlock()
+		if dev == nil {
+			b.dropped.Add(1)
+			continue
+		}
+		// A write error is handled by readLoop when the read fails too.
+		if _, err := dev.Write(data); err != nil {
+			b.dropped.Add(1)
+			continue
+		}
+		b.received.Add(uint64(len(data)))
+	}
+}
+
+func (b *SerialBridge) sleep(d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-b.ctx.Done():
+		return false
+	}
+}
+
+// serialData returns the source and data of a packet received on the
+// serial data cluster, which is what a module in transparent mode sends.
+func serialData(ev Event) (Addr64, []byte, bool) {
+	switch rx := ev.(type) {
+	case *ReceivePacket:
+		return rx.SourceAddress, rx.Data, true
+	case *ExplicitReceivePacket:
+		return rx.SourceAddress, rx.Data, rx.ClusterID == ClusterSerialData && rx.ProfileID == ProfileDigi
+	}
+	return 0, nil, false
+}
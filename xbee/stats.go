@@ -0,0 +1,89 @@
+package xbee
+
+import (
+	"expvar"
+	"sync/atomic"
+
+	"github.com/samuel/go-xbee/xbee/frames"
+)
+
+// Stats is a snapshot of the counters describing the health of the link
+// to the module and the RF network.
+type Stats struct {
+	FramesSent     map[byte]uint64 // by frame type
+	FramesReceived map[byte]uint64 // by frame type
+	ChecksumErrors uint64
+	Resyncs        uint64 // rescans for a frame delimiter after a corrupt frame
+	DecodeErrors   uint64 // empty or malformed frames
+	DroppedEvents  uint64 // events dropped because a channel was full
+	FilteredFrames uint64 // frames dropped by the receive filter
+	Duplicates     uint64 // repeated broadcasts detected by WithBroadcastDedup
+	// Retransmissions is the total of the retry counts reported by
+	// transmit status frames.
+	Retransmissions uint64
+	// DeliveryFailures is the number of transmit status frames reporting
+	// a status other than success.
+	DeliveryFailures uint64
+}
+
+type stats struct {
+	sent             [256]atomic.Uint64
+	received         [256]atomic.Uint64
+	checksumErrors   atomic.Uint64
+	resyncs          atomic.Uint64
+	decodeErrors     atomic.Uint64
+	droppedEvents    atomic.Uint64
+	filteredFrames   atomic.Uint64
+	duplicates       atomic.Uint64
+	retransmissions  atomic.Uint64
+	deliveryFailures atomic.Uint64
+}
+
+// frameReceived updates the counters for a decoded frame.
+func (s *stats) frameReceived(f frames.Frame) {
+	s.received[f.FrameType()].Add(1)
+	switch f := f.(type) {
+	case *frames.TransmitStatus:
+		s.retransmissions.Add(uint64(f.RetryCount))
+		if f.DeliveryStatus != frames.DSSuccess {
+			s.deliveryFailures.Add(1)
+		}
+	case *frames.IPTransmitStatus:
+		if f.Status != frames.DSSuccess {
+			s.deliveryFailures.Add(1)
+		}
+	}
+}
+
+// Stats returns a snapshot of the link counters since Open.
+func (xb *XBee) Stats() Stats {
+	st := Stats{
+		FramesSent:       make(map[byte]uint64),
+		FramesReceived:   make(map[byte]uint64),
+		ChecksumErrors:   xb.stats.checksumErrors.Load(),
+		Resyncs:          xb.stats.resyncs.Load(),
+		DecodeErrors:     xb.stats.decodeErrors.Load(),
+		DroppedEvents:    xb.stats.droppedEvents.Load(),
+		FilteredFrames:   xb.stats.filteredFrames.Load(),
+		Duplicates:       xb.stats.duplicates.Load(),
+		Retransmissions:  xb.stats.retransmissions.Load(),
+		DeliveryFailures: xb.stats.deliveryFailures.Load(),
+	}
+	for i := range xb.stats.sent {
+		if n := xb.stats.sent[i].Load(); n != 0 {
+			st.FramesSent[byte(i)] = n
+		}
+		if n := xb.stats.received[i].Load(); n != 0 {
+			st.FramesReceived[byte(i)] = n
+		}
+	}
+	return st
+}
+
+// PublishExpvar publishes the stats as an expvar variable with the given
+// name. Like expvar.Publish it panics if the name is already used.
+func (xb *XBee) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() any {
+		return xb.Stats()
+	}))
+}
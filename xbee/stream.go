@@ -0,0 +1,344 @@
+package xbee
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+var ErrListening = errors.New("xbee: already listening")
+
+// streamAddress is the application addressing used by streams. It's kept
+// apart from DefaultReliableAddress so streams and a ReliableConn can be
+// used at the same time.
+var streamAddress = ExplicitAddress{
+	SourceEndpoint:      EndpointDigiData,
+	DestinationEndpoint: EndpointDigiData,
+	ClusterID:           0x0112,
+	ProfileID:           ProfileDigi,
+}
+
+const acceptQueueLen = 8
+
+// NetAddr is the net.Addr of a stream endpoint.
+type NetAddr struct {
+	Addr Addr64
+}
+
+func (a NetAddr) Network() string {
+	return "xbee"
+}
+
+func (a NetAddr) String() string {
+	return a.Addr.String()
+}
+
+// streamMux routes messages received by the stream ReliableConn to the
+// Conn for the source.
+type streamMux struct {
+	rc    *ReliableConn
+	local Addr64
+	chunk int
+
+	mu       sync.Mutex
+	conns    map[Addr64]*Conn
+	listener *Listener
+}
+
+// streamMux returns the stream multiplexer creating it on first use.
+func (xb *XBee) streamMux() (*streamMux, error) {
+	xb.smu.Lock()
+	defer xb.smu.Unlock()
+	if xb.streams != nil {
+		return xb.streams, nil
+	}
+	local, err := xb.SerialNumber()
+	if err != nil {
+		return nil, err
+	}
+	np, err := xb.MaximumRFPayloadBytes()
+	if err != nil {
+		return nil, err
+	}
+	if np <= reliableHeaderLen {
+		return nil, ErrInvalidParameter
+	}
+	mux := &streamMux{
+		rc:    xb.NewReliableConn(&ReliableConfig{Address: &streamAddress}),
+		local: local,
+		chunk: np - reliableHeaderLen,
+		conns: make(map[Addr64]*Conn),
+	}
+	go mux.run()
+	xb.streams = mux
+	return mux, nil
+}
+
+func (mux *streamMux) run() {
+	for msg := range mux.rc.Messages() {
+		mux.mu.Lock()
+		c := mux.connLocked(msg)
+		var l *Listener
+		if c == nil && mux.listener != nil {
+			c = mux.newConnLocked(msg.Source)
+			l = mux.listener
+		}
+		mux.mu.Unlock()
+		if c == nil {
+			continue
+		}
+		c.deliver(msg.Data)
+		if l == nil {
+			continue
+		}
+		// Wait for Accept rather than dropping the stream so received
+		// messages queue up behind it.
+		select {
+		case l.accept <- c:
+		case <-l.done:
+			mux.mu.Lock()
+			if mux.conns[msg.Source] == c {
+				delete(mux.conns, msg.Source)
+			}
+			mux.mu.Unlock()
+		}
+	}
+}
+
+// connLocked returns the stream for the source of msg. Messages from the
+// coordinator go to a stream dialed to AddressCoordinator if there isn't
+// one for its serial number.
+func (mux *streamMux) connLocked(msg *ReliableMessage) *Conn {
+	if c := mux.conns[msg.Source]; c != nil {
+		return c
+	}
+	if sentBy(AddressCoordinator, msg.Source, msg.Source16) {
+		return mux.conns[AddressCoordinator]
+	}
+	return nil
+}
+
+func (mux *streamMux) newConnLocked(remote Addr64) *Conn {
+	c := &Conn{
+		mux:    mux,
+		remote: remote,
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	mux.conns[remote] = c
+	return c
+}
+
+// Dial returns a stream to a remote node. Writes are split into messages
+// no larger than the maximum RF payload (NP) and sent reliably in order
+// (see ReliableConn) and reads return the data received from the node.
+// There's no connection handshake so the remote node must have dialed
+// this module or be accepting streams with Listen. Only one stream to a
+// node can be open at a time. Explicit receive (AO=1) must be enabled.
+// A stream dialed to AddressCoordinator receives the data sent by the
+// coordinator.
+func (xb *XBee) Dial(addr Addr64) (*Conn, error) {
+	mux, err := xb.streamMux()
+	if err != nil {
+		return nil, err
+	}
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+	if c := mux.conns[addr]; c != nil {
+		return c, nil
+	}
+	return mux.newConnLocked(addr), nil
+}
+
+// Listen returns a listener that accepts a stream for every node that sends
+// data without a stream having been dialed to it.
+func (xb *XBee) Listen() (*Listener, error) {
+	mux, err := xb.streamMux()
+	if err != nil {
+		return nil, err
+	}
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+	if mux.listener != nil {
+		return nil, ErrListening
+	}
+	mux.listener = &Listener{
+		mux:    mux,
+		accept: make(chan *Conn, acceptQueueLen),
+		done:   make(chan struct{}),
+	}
+	return mux.listener, nil
+}
+
+// Listener accepts streams from remote nodes. It implements net.Listener.
+type Listener struct {
+	mux    *streamMux
+	accept chan *Conn
+	done   chan struct{}
+	once   sync.Once
+}
+
+func (l *Listener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.accept:
+		return c, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *Listener) Close() error {
+	l.once.Do(func() {
+		l.mux.mu.Lock()
+		l.mux.listener = nil
+		l.mux.mu.Unlock()
+		close(l.done)
+	})
+	return nil
+}
+
+func (l *Listener) Addr() net.Addr {
+	return NetAddr{Addr: l.mux.local}
+}
+
+// Conn is a stream to a remote node. It implements net.Conn.
+type Conn struct {
+	mux    *streamMux
+	remote Addr64
+	notify chan struct{} // signalled when data arrives or a deadline changes
+	done   chan struct{}
+	once   sync.Once
+
+	mu            sync.Mutex
+	buf           []byte
+	readDeadline  time.Time
+	writeDeadline time.Time
+}
+
+func (c *Conn) deliver(data []byte) {
+	c.mu.Lock()
+	c.buf = append(c.buf, data...)
+	c.mu.Unlock()
+	c.signal()
+}
+
+func (c *Conn) signal() {
+	select {
+	case c.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (c *Conn) Read(p []byte) (int, error) {
+	for {
+		c.mu.Lock()
+		if len(c.buf) != 0 {
+			n := copy(p, c.buf)
+			c.buf = c.buf[n:]
+			c.mu.Unlock()
+			return n, nil
+		}
+		deadline := c.readDeadline
+		c.mu.Unlock()
+
+		var t *time.Timer
+		var timeout <-chan time.Time
+		if !deadline.IsZero() {
+			d := time.Until(deadline)
+			if d <= 0 {
+				return 0, os.ErrDeadlineExceeded
+			}
+			t = time.NewTimer(d)
+			timeout = t.C
+		}
+		select {
+		case <-c.notify:
+		case <-timeout:
+		case <-c.done:
+			return 0, net.ErrClosed
+		}
+		if t != nil {
+			t.Stop()
+		}
+	}
+}
+
+func (c *Conn) Write(p []byte) (int, error) {
+	select {
+	case <-c.done:
+		return 0, net.ErrClosed
+	default:
+	}
+	ctx := context.Background()
+	c.mu.Lock()
+	deadline := c.writeDeadline
+	c.mu.Unlock()
+	if !deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+	var n int
+	for n < len(p) {
+		chunk := p[n:min(len(p), n+c.mux.chunk)]
+		if err := c.mux.rc.Send(ctx, c.remote, chunk); err != nil {
+			if err == context.DeadlineExceeded {
+				err = os.ErrDeadlineExceeded
+			}
+			return n, err
+		}
+		n += len(chunk)
+	}
+	return n, nil
+}
+
+// Close closes the stream. Data received from the node after Close is
+// accepted by the listener if there is one.
+func (c *Conn) Close() error {
+	c.once.Do(func() {
+		c.mux.mu.Lock()
+		if c.mux.conns[c.remote] == c {
+			delete(c.mux.conns, c.remote)
+		}
+		c.mux.mu.Unlock()
+		close(c.done)
+	})
+	return nil
+}
+
+func (c *Conn) LocalAddr() net.Addr {
+	return NetAddr{Addr: c.mux.local}
+}
+
+func (c *Conn) RemoteAddr() net.Addr {
+	return NetAddr{Addr: c.remote}
+}
+
+func (c *Conn) SetDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.readDeadline, c.writeDeadline = t, t
+	c.mu.Unlock()
+	c.signal()
+	return nil
+}
+
+// SetReadDeadline sets the deadline for Read.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.readDeadline = t
+	c.mu.Unlock()
+	c.signal()
+	return nil
+}
+
+// SetWriteDeadline sets the deadline for Write. A write that times out may
+// have delivered part of the data.
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.writeDeadline = t
+	c.mu.Unlock()
+	return nil
+}
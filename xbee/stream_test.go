@@ -0,0 +1,50 @@
+package xbee_test
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/samuel/go-xbee/xbee"
+)
+
+func TestDialCoordinator(t *testing.T) {
+	coord, router := openPAN(t)
+	l, err := coord.Listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	c, err := router.Dial(xbee.AddressCoordinator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	c.SetDeadline(deadline)
+
+	if _, err := c.Write([]byte("hello")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	sc, err := l.Accept()
+	if err != nil {
+		t.Fatalf("Accept: %v", err)
+	}
+	defer sc.Close()
+	sc.SetDeadline(deadline)
+	if got := sc.RemoteAddr().String(); got != routerAddr.String() {
+		t.Errorf("RemoteAddr = %s, want %s", got, routerAddr)
+	}
+	buf := make([]byte, 5)
+	if _, err := io.ReadFull(sc, buf); err != nil || string(buf) != "hello" {
+		t.Fatalf("coordinator read %q, %v, want %q", buf, err, "hello")
+	}
+
+	// The reply comes from the coordinator's serial number.
+	if _, err := sc.Write([]byte("world")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if _, err := io.ReadFull(c, buf); err != nil || string(buf) != "world" {
+		t.Fatalf("router read %q, %v, want %q", buf, err, "world")
+	}
+}
@@ -0,0 +1,378 @@
+package xbee
+
+import (
+	"encoding/binary"
+	"errors"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	tcpDialTimeout       = 10 * time.Second
+	tcpKeepAlive         = 30 * time.Second
+	tcpMaxReconnectDelay = 30 * time.Second
+)
+
+// Telnet and RFC 2217 (COM port control) constants
+const (
+	telnetSE   = 240
+	telnetSB   = 250
+	telnetWILL = 251
+	telnetWONT = 252
+	telnetDO   = 253
+	telnetDONT = 254
+	telnetIAC  = 255
+
+	telnetOptBinary          = 0
+	telnetOptSuppressGoAhead = 3
+	telnetOptComPort         = 44
+
+	comPortSetBaudRate = 1
+	comPortSetDataSize = 2
+	comPortSetParity   = 3
+	comPortSetStopSize = 4
+)
+
+var errPortClosed = errors.New("xbee: port closed")
+
+// TCPPort is a serial port accessed through a serial-over-TCP bridge
+// such as ser2net or a Digi terminal server. It reconnects if the
+// connection drops. Data written or read while reconnecting is delayed
+// until the connection is restored.
+type TCPPort struct {
+	addr    string
+	rfc2217 bool
+
+	log *slog.Logger
+
+	mu       sync.Mutex // protects baud, conn, closed, and deadline
+	baud     int
+	conn     net.Conn
+	closed   bool
+	deadline time.Time
+
+	// Telnet parser state (only accessed by Read)
+	state  int
+	optCmd byte
+}
+
+const (
+	tsData = iota
+	tsIAC
+	tsOption
+	tsSub
+	tsSubIAC
+)
+
+// OpenTCP connects to a raw serial-over-TCP bridge (e.g. ser2net in raw
+// mode). The returned port can be passed to Open.
+func OpenTCP(addr string) (*TCPPort, error) {
+	return openTCP(addr, 0)
+}
+
+// OpenRFC2217 connects to a serial-over-TCP bridge that supports the
+// telnet COM port control option (RFC 2217, e.g. ser2net in telnet mode)
+// and sets the remote serial port to baud 8N1.
+func OpenRFC2217(addr string, baud int) (*TCPPort, error) {
+	if baud <= 0 {
+		return nil, ErrInvalidParameter
+	}
+	return openTCP(addr, baud)
+}
+
+func openTCP(addr string, baud int) (*TCPPort, error) {
+	p := &TCPPort{addr: addr, rfc2217: baud != 0, baud: baud, log: slog.Default()}
+	conn, err := p.dial(baud)
+	if err != nil {
+		return nil, err
+	}
+	p.conn = conn
+	return p, nil
+}
+
+func (p *TCPPort) dial(baud int) (net.Conn, error) {
+	d := net.Dialer{Timeout: tcpDialTimeout, KeepAlive: tcpKeepAlive}
+	conn, err := d.Dial("tcp", p.addr)
+	if err != nil {
+		return nil, err
+	}
+	if p.rfc2217 {
+		if _, err := conn.Write(rfc2217Setup(baud)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// rfc2217Setup returns the negotiation for binary mode and COM port
+// control followed by the serial port settings.
+func rfc2217Setup(baud int) []byte {
+	b := []byte{
+		telnetIAC, telnetWILL, telnetOptBinary,
+		telnetIAC, telnetDO, telnetOptBinary,
+		telnetIAC, telnetWILL, telnetOptComPort,
+		telnetIAC, telnetSB, telnetOptComPort, comPortSetBaudRate,
+	}
+	b = appendTelnetEscaped(b, binary.BigEndian.AppendUint32(nil, uint32(baud)))
+	b = append(b, telnetIAC, telnetSE)
+	b = append(b, telnetIAC, telnetSB, telnetOptComPort, comPortSetDataSize, 8, telnetIAC, telnetSE)
+	b = append(b, telnetIAC, telnetSB, telnetOptComPort, comPortSetParity, 1, telnetIAC, telnetSE)
+	b = append(b, telnetIAC, telnetSB, telnetOptComPort, comPortSetStopSize, 1, telnetIAC, telnetSE)
+	return b
+}
+
+func appendTelnetEscaped(b, data []byte) []byte {
+	for _, c := range data {
+		if c == telnetIAC {
+			b = append(b, telnetIAC)
+		}
+		b = append(b, c)
+	}
+	return b
+}
+
+// SetBaudRate changes the baud rate of the remote serial port. It's only
+// supported for ports opened with OpenRFC2217.
+func (p *TCPPort) SetBaudRate(baud int) error {
+	if !p.rfc2217 || baud <= 0 {
+		return ErrInvalidParameter
+	}
+	b := []byte{telnetIAC, telnetSB, telnetOptComPort, comPortSetBaudRate}
+	b = appendTelnetEscaped(b, binary.BigEndian.AppendUint32(nil, uint32(baud)))
+	b = append(b, telnetIAC, telnetSE)
+	conn, err := p.getConn()
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.baud = baud
+	p.mu.Unlock()
+	_, err = conn.Write(b)
+	return err
+}
+
+// SetLogger sets the logger for connection problems. The default is
+// slog.Default(). It must be called before the port is used.
+func (p *TCPPort) SetLogger(l *slog.Logger) {
+	p.log = l
+}
+
+// SetReadDeadline sets the deadline for Read. Reads that time out aren't
+// treated as a dropped connection.
+func (p *TCPPort) SetReadDeadline(t time.Time) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.deadline = t
+	if p.conn == nil {
+		return nil
+	}
+	return p.conn.SetReadDeadline(t)
+}
+
+func (p *TCPPort) getConn() (net.Conn, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil, errPortClosed
+	}
+	return p.conn, nil
+}
+
+// reconnect replaces the broken connection old unless another goroutine
+// already did so.
+func (p *TCPPort) reconnect(old net.Conn, cause error) (net.Conn, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, errPortClosed
+	}
+	if p.conn != old {
+		conn := p.conn
+		p.mu.Unlock()
+		return conn, nil
+	}
+	old.Close()
+	baud := p.baud
+	p.mu.Unlock()
+
+	p.log.Warn("xbee: connection lost", "addr", p.addr, "err", cause)
+	delay := time.Second
+	for {
+		conn, err := p.dial(baud)
+		if err == nil {
+			p.mu.Lock()
+			if p.closed {
+				p.mu.Unlock()
+				conn.Close()
+				return nil, errPortClosed
+			}
+			conn.SetReadDeadline(p.deadline)
+			p.conn = conn
+			p.mu.Unlock()
+			return conn, nil
+		}
+		p.log.Warn("xbee: reconnect failed", "addr", p.addr, "err", err)
+		time.Sleep(delay)
+		if p.isClosed() {
+			return nil, errPortClosed
+		}
+		delay = min(delay*2, tcpMaxReconnectDelay)
+	}
+}
+
+func (p *TCPPort) isClosed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.closed
+}
+
+func (p *TCPPort) Read(b []byte) (int, error) {
+	conn, err := p.getConn()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		n, err := conn.Read(b)
+		if p.rfc2217 {
+			n = p.filterTelnet(conn, b[:n])
+		}
+		if n > 0 {
+			return n, nil
+		}
+		if err == nil {
+			continue
+		}
+		if isTimeout(err) || p.isClosed() {
+			return 0, err
+		}
+		if conn, err = p.reconnect(conn, err); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// filterTelnet removes telnet commands from b in place, replying to option
+// negotiation, and returns the number of data bytes.
+func (p *TCPPort) filterTelnet(conn net.Conn, b []byte) int {
+	var n int
+	var reply []byte
+	for _, c := range b {
+		switch p.state {
+		case tsData:
+			if c == telnetIAC {
+				p.state = tsIAC
+			} else {
+				b[n] = c
+				n++
+			}
+		case tsIAC:
+			switch c {
+			case telnetIAC:
+				b[n] = c
+				n++
+				p.state = tsData
+			case telnetWILL, telnetWONT, telnetDO, telnetDONT:
+				p.optCmd = c
+				p.state = tsOption
+			case telnetSB:
+				p.state = tsSub
+			default:
+				p.state = tsData
+			}
+		case tsOption:
+			reply = append(reply, telnetReply(p.optCmd, c)...)
+			p.state = tsData
+		case tsSub:
+			// Sub-negotiation (e.g. COM port notifications) is ignored
+			if c == telnetIAC {
+				p.state = tsSubIAC
+			}
+		case tsSubIAC:
+			if c == telnetSE {
+				p.state = tsData
+			} else {
+				p.state = tsSub
+			}
+		}
+	}
+	if len(reply) > 0 {
+		conn.Write(reply)
+	}
+	return n
+}
+
+// telnetReply returns the response to an option negotiation request. Only
+// the options used for RFC 2217 are accepted. Acknowledgements of
+// options already requested aren't answered to avoid negotiation loops.
+func telnetReply(cmd, opt byte) []byte {
+	switch cmd {
+	case telnetDO:
+		if opt == telnetOptBinary || opt == telnetOptComPort {
+			return nil
+		}
+		return []byte{telnetIAC, telnetWONT, opt}
+	case telnetWILL:
+		if opt == telnetOptBinary || opt == telnetOptSuppressGoAhead {
+			return nil
+		}
+		return []byte{telnetIAC, telnetDONT, opt}
+	}
+	return nil
+}
+
+// Write writes b reconnecting if the connection is lost. Only the part of
+// b not yet written to the lost connection is written to the new one.
+func (p *TCPPort) Write(b []byte) (int, error) {
+	conn, err := p.getConn()
+	if err != nil {
+		return 0, err
+	}
+	written := 0
+	for {
+		data := b[written:]
+		if p.rfc2217 {
+			data = appendTelnetEscaped(make([]byte, 0, len(data)), data)
+		}
+		n, err := conn.Write(data)
+		if err == nil {
+			return len(b), nil
+		}
+		if p.rfc2217 {
+			n = telnetUnescapedLen(b[written:], n)
+		}
+		written += n
+		if conn, err = p.reconnect(conn, err); err != nil {
+			return written, err
+		}
+	}
+}
+
+// telnetUnescapedLen returns how many bytes of b were written when n bytes
+// of their escaped form were. An escape sequence cut short isn't counted
+// so it's written again in full.
+func telnetUnescapedLen(b []byte, n int) int {
+	for i, c := range b {
+		w := 1
+		if c == telnetIAC {
+			w = 2
+		}
+		if n < w {
+			return i
+		}
+		n -= w
+	}
+	return len(b)
+}
+
+func (p *TCPPort) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return errPortClosed
+	}
+	p.closed = true
+	return p.conn.Close()
+}
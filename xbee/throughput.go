@@ -0,0 +1,82 @@
+package xbee
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// throughputWorkers is the number of transmits kept outstanding by
+// Throughput when the number of frames in flight isn't limited.
+const throughputWorkers = 4
+
+// ThroughputResult is the outcome of Throughput.
+type ThroughputResult struct {
+	Duration  time.Duration
+	Sent      int   // packets
+	Delivered int   // packets acknowledged by a successful transmit status
+	Bytes     int64 // payload bytes delivered
+}
+
+// BitsPerSecond returns the goodput: the payload bits delivered per second.
+func (r *ThroughputResult) BitsPerSecond() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Bytes*8) / r.Duration.Seconds()
+}
+
+// Throughput measures the goodput to dest by sending packets of
+// payloadSize bytes to its serial data cluster as fast as transmit
+// statuses allow for duration (the remote writes them to its serial port).
+// As many transmits are kept outstanding as the in-flight limit set by
+// WithMaxInFlight allows (4 if unlimited). If ctx is done the result so
+// far is returned along with the error.
+func (xb *XBee) Throughput(ctx context.Context, dest Addr64, duration time.Duration, payloadSize int) (*ThroughputResult, error) {
+	if dest.IsBroadcast() || dest == AddressUnknown || payloadSize <= 0 {
+		return nil, ErrInvalidParameter
+	}
+	workers := xb.maxInFlight
+	if workers <= 0 {
+		workers = throughputWorkers
+	}
+	tctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+	data := make([]byte, payloadSize)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	var mu sync.Mutex
+	var res ThroughputResult
+	var closedErr error
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for tctx.Err() == nil {
+				err := xb.transmitExplicitStatus(dest, DefaultExplicitAddress, data)
+				mu.Lock()
+				res.Sent++
+				if err == nil {
+					res.Delivered++
+					res.Bytes += int64(len(data))
+				} else if errors.Is(err, ErrClosed) {
+					closedErr = err
+					mu.Unlock()
+					return
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	res.Duration = time.Since(start)
+	if closedErr != nil {
+		return &res, closedErr
+	}
+	return &res, ctx.Err()
+}
@@ -0,0 +1,274 @@
+package xbee
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+	"time"
+)
+
+// DefaultTimeSyncAddress is the application addressing used by time sync
+// unless configured otherwise.
+var DefaultTimeSyncAddress = ExplicitAddress{
+	SourceEndpoint:      EndpointDigiData,
+	DestinationEndpoint: EndpointDigiData,
+	ClusterID:           0x0115,
+	ProfileID:           ProfileDigi,
+}
+
+// Time sync messages start with the message type. Times are nanoseconds
+// since the Unix epoch as big-endian signed 64-bit integers.
+//
+//	beacon:   0x00, server time
+//	request:  0x01, sequence (2), client transmit time (T1)
+//	response: 0x02, sequence (2), T1, server receive time (T2), server transmit time (T3)
+//
+// The client notes when the response arrives (T4) and computes the offset
+// and round trip time as NTP does (see TimeOffset).
+const (
+	tsBeacon   byte = 0x00
+	tsRequest  byte = 0x01
+	tsResponse byte = 0x02
+
+	tsBeaconLen   = 9
+	tsRequestLen  = 11
+	tsResponseLen = 27
+
+	timeSyncQueue          = 16
+	defaultTimeSyncTimeout = 2 * time.Second
+)
+
+// TimeSyncConfig configures a TimeServer. The zero value uses the
+// defaults.
+type TimeSyncConfig struct {
+	// Address is the application addressing used for messages. The
+	// default is DefaultTimeSyncAddress.
+	Address *ExplicitAddress
+	// BeaconInterval broadcasts a beacon with the current time at this
+	// interval if positive so nodes that can't afford a round trip can set
+	// their clocks roughly.
+	BeaconInterval time.Duration
+	// Now returns the current time. The default is time.Now.
+	Now func() time.Time
+}
+
+// TimeServer answers time sync requests and broadcasts time beacons,
+// typically on the coordinator. Messages are received as explicit packets
+// so explicit receive (AO=1) must be enabled.
+type TimeServer struct {
+	xb   *XBee
+	cfg  TimeSyncConfig
+	addr ExplicitAddress
+	m    *matcher
+	done chan struct{}
+	once sync.Once
+}
+
+// NewTimeServer starts a time server. cfg may be nil to use the defaults.
+func (xb *XBee) NewTimeServer(cfg *TimeSyncConfig) *TimeServer {
+	s := &TimeServer{xb: xb, addr: DefaultTimeSyncAddress, done: make(chan struct{})}
+	if cfg != nil {
+		s.cfg = *cfg
+	}
+	if s.cfg.Address != nil {
+		s.addr = *s.cfg.Address
+	}
+	if s.cfg.Now == nil {
+		s.cfg.Now = time.Now
+	}
+	addr := s.addr
+	s.m = xb.registerMatcher(matchService, func(ev Event) bool {
+		rx, ok := ev.(*ExplicitReceivePacket)
+		return ok && isTimeSync(rx, addr) && len(rx.Data) >= tsRequestLen && rx.Data[0] == tsRequest
+	})
+	go s.serve()
+	if s.cfg.BeaconInterval > 0 {
+		go s.beacons()
+	}
+	return s
+}
+
+// Close stops the server.
+func (s *TimeServer) Close() error {
+	s.once.Do(func() {
+		s.xb.unregisterMatcher(s.m)
+		close(s.done)
+	})
+	return nil
+}
+
+func (s *TimeServer) serve() {
+	for {
+		var ev Event
+		select {
+		case ev = <-s.m.ch:
+		case <-s.done:
+			return
+		}
+		t2 := s.cfg.Now()
+		rx := ev.(*ExplicitReceivePacket)
+		b := make([]byte, 0, tsResponseLen)
+		b = append(b, tsResponse)
+		b = append(b, rx.Data[1:tsRequestLen]...) // sequence and T1
+		b = binary.BigEndian.AppendUint64(b, uint64(t2.UnixNano()))
+		b = binary.BigEndian.AppendUint64(b, uint64(s.cfg.Now().UnixNano()))
+		if err := s.xb.TransmitExplicit(rx.SourceAddress, rx.SourceAddress16, s.addr, 0, 0, b); err != nil {
+			s.xb.log.Warn("xbee: failed to send time sync response", "err", err)
+		}
+	}
+}
+
+func (s *TimeServer) beacons() {
+	t := time.NewTicker(s.cfg.BeaconInterval)
+	defer t.Stop()
+	for {
+		b := binary.BigEndian.AppendUint64([]byte{tsBeacon}, uint64(s.cfg.Now().UnixNano()))
+		if err := s.xb.TransmitExplicit(AddressBroadcast, Address16Unknown, s.addr, 0, 0, b); err != nil {
+			s.xb.log.Warn("xbee: failed to send time beacon", "err", err)
+		}
+		select {
+		case <-t.C:
+		case <-s.done:
+			return
+		case <-s.xb.closed:
+			return
+		}
+	}
+}
+
+func isTimeSync(rx *ExplicitReceivePacket, addr ExplicitAddress) bool {
+	return rx.DestinationEndpoint == addr.DestinationEndpoint && rx.ClusterID == addr.ClusterID &&
+		rx.ProfileID == addr.ProfileID && len(rx.Data) > 0
+}
+
+// TimeOffset computes the offset of the server's clock from the client's
+// and the round trip time from the client transmit time (t1), server
+// receive time (t2), server transmit time (t3), and client receive time
+// (t4). Adding the offset to the client's clock gives the server's time;
+// the error is at most half the round trip time.
+func TimeOffset(t1, t2, t3, t4 time.Time) (offset, rtt time.Duration) {
+	offset = (t2.Sub(t1) + t3.Sub(t4)) / 2
+	rtt = t4.Sub(t1) - t3.Sub(t2)
+	return offset, rtt
+}
+
+// TimeSample is the result of a time sync exchange.
+type TimeSample struct {
+	Offset time.Duration // add to the local clock to get the server's time
+	RTT    time.Duration // round trip time excluding the server's processing
+}
+
+// SyncTime exchanges samples time sync requests with the time server at
+// dest and returns the sample with the shortest round trip, which has the
+// smallest error. Requests that time out are skipped; ErrTimeout is
+// returned if none are answered. The config's Address and Now are used if
+// cfg isn't nil.
+func (xb *XBee) SyncTime(ctx context.Context, dest Addr64, samples int, cfg *TimeSyncConfig) (TimeSample, error) {
+	addr, now := DefaultTimeSyncAddress, time.Now
+	if cfg != nil {
+		if cfg.Address != nil {
+			addr = *cfg.Address
+		}
+		if cfg.Now != nil {
+			now = cfg.Now
+		}
+	}
+	if samples <= 0 {
+		samples = 1
+	}
+	var best TimeSample
+	found := false
+	for i := 0; i < samples; i++ {
+		seq := uint16(xb.timeSeq.Add(1))
+		m := xb.registerMatcher(matchSession, func(ev Event) bool {
+			rx, ok := ev.(*ExplicitReceivePacket)
+			return ok && sentBy(dest, rx.SourceAddress, rx.SourceAddress16) && isTimeSync(rx, addr) && len(rx.Data) >= tsResponseLen &&
+				rx.Data[0] == tsResponse && binary.BigEndian.Uint16(rx.Data[1:]) == seq
+		})
+		t1 := now()
+		b := binary.BigEndian.AppendUint16([]byte{tsRequest}, seq)
+		b = binary.BigEndian.AppendUint64(b, uint64(t1.UnixNano()))
+		if err := xb.TransmitExplicit(dest, Address16Unknown, addr, 0, 0, b); err != nil {
+			xb.unregisterMatcher(m)
+			return TimeSample{}, err
+		}
+		t := time.NewTimer(defaultTimeSyncTimeout)
+		var ev Event
+		var err error
+		select {
+		case ev = <-m.ch:
+		case <-t.C:
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-xb.closed:
+			err = ErrClosed
+		}
+		t4 := now()
+		t.Stop()
+		xb.unregisterMatcher(m)
+		if err != nil {
+			return TimeSample{}, err
+		}
+		if ev == nil {
+			continue
+		}
+		d := ev.(*ExplicitReceivePacket).Data
+		t2 := time.Unix(0, int64(binary.BigEndian.Uint64(d[11:])))
+		t3 := time.Unix(0, int64(binary.BigEndian.Uint64(d[19:])))
+		offset, rtt := TimeOffset(t1, t2, t3, t4)
+		if !found || rtt < best.RTT {
+			best, found = TimeSample{Offset: offset, RTT: rtt}, true
+		}
+	}
+	if !found {
+		return TimeSample{}, ErrTimeout
+	}
+	return best, nil
+}
+
+// TimeBeacon is a time beacon received from a TimeServer.
+type TimeBeacon struct {
+	Source   Addr64
+	Time     time.Time // the server's time when sent
+	Received time.Time // the local time when received
+}
+
+// TimeBeacons returns a channel on which time beacons are delivered until
+// ctx is done. Beacons are dropped if the channel is full. addr may be nil
+// to use DefaultTimeSyncAddress.
+func (xb *XBee) TimeBeacons(ctx context.Context, addr *ExplicitAddress) <-chan *TimeBeacon {
+	a := DefaultTimeSyncAddress
+	if addr != nil {
+		a = *addr
+	}
+	m := xb.registerMatcher(matchService, func(ev Event) bool {
+		rx, ok := ev.(*ExplicitReceivePacket)
+		return ok && isTimeSync(rx, a) && len(rx.Data) >= tsBeaconLen && rx.Data[0] == tsBeacon
+	})
+	ch := make(chan *TimeBeacon, timeSyncQueue)
+	go func() {
+		defer close(ch)
+		defer xb.unregisterMatcher(m)
+		for {
+			var ev Event
+			select {
+			case ev = <-m.ch:
+			case <-ctx.Done():
+				return
+			case <-xb.closed:
+				return
+			}
+			rx := ev.(*ExplicitReceivePacket)
+			b := &TimeBeacon{
+				Source:   rx.SourceAddress,
+				Time:     time.Unix(0, int64(binary.BigEndian.Uint64(rx.Data[1:]))),
+				Received: time.Now(),
+			}
+			select {
+			case ch <- b:
+			default:
+			}
+		}
+	}()
+	return ch
+}
@@ -0,0 +1,27 @@
+package xbee_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/samuel/go-xbee/xbee"
+)
+
+func TestSyncTime(t *testing.T) {
+	coord, router := openPAN(t)
+	srv := coord.NewTimeServer(&xbee.TimeSyncConfig{
+		Now: func() time.Time { return time.Now().Add(time.Hour) },
+	})
+	defer srv.Close()
+
+	for _, dest := range []xbee.Addr64{coordAddr, xbee.AddressCoordinator} {
+		s, err := router.SyncTime(context.Background(), dest, 2, nil)
+		if err != nil {
+			t.Fatalf("SyncTime(%s): %v", dest, err)
+		}
+		if d := s.Offset - time.Hour; d < -s.RTT || d > s.RTT {
+			t.Errorf("SyncTime(%s) offset = %s, want 1h within %s", dest, s.Offset, s.RTT)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package xbee
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+const (
+	clusterMgmtLqiRequest   uint16 = 0x0031 // ZDO
+	clusterMgmtLqiResponse  uint16 = 0x8031 // ZDO
+	lqiEntryLen                    = 22
+	lqiHeaderLen                   = 5
+	defaultTopologyTimeout         = 5 * time.Second
+	defaultTopologyDiscover        = 6 * time.Second
+)
+
+// NeighborRelationship is how a node is related to a neighbor in its
+// neighbor table.
+type NeighborRelationship byte
+
+const (
+	RelationshipParent   NeighborRelationship = 0
+	RelationshipChild    NeighborRelationship = 1
+	RelationshipSibling  NeighborRelationship = 2
+	RelationshipNone     NeighborRelationship = 3
+	RelationshipPrevious NeighborRelationship = 4 // previous child
+)
+
+func (r NeighborRelationship) String() string {
+	switch r {
+	case RelationshipParent:
+		return "Parent"
+	case RelationshipChild:
+		return "Child"
+	case RelationshipSibling:
+		return "Sibling"
+	case RelationshipNone:
+		return "None"
+	case RelationshipPrevious:
+		return "PreviousChild"
+	}
+	return fmt.Sprintf("NeighborRelationship(%d)", r)
+}
+
+func (r NeighborRelationship) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// Neighbor is an entry in a node's neighbor table.
+type Neighbor struct {
+	Address      Addr64
+	Address16    Addr16
+	DeviceType   DeviceType
+	Relationship NeighborRelationship
+	Depth        byte
+	LQI          byte // link quality of the neighbor as seen by the node, higher values are better
+}
+
+// NeighborTable reads the neighbor table of a router or coordinator using
+// the ZDO Mgmt_Lqi request. Responses are received as explicit packets so
+// explicit receive (AO=1) must be enabled. A node can't be asked for its
+// own table so dest must be a remote node.
+func (xb *XBee) NeighborTable(ctx context.Context, dest Addr64) ([]Neighbor, error) {
+	var neighbors []Neighbor
+	for {
+		seq := byte(xb.zdoSeq.Add(1))
+		start := byte(len(neighbors))
+		m := xb.registerMatcher(matchSession, func(ev Event) bool {
+			rx, ok := ev.(*ExplicitReceivePacket)
+			return ok && rx.SourceAddress == dest && rx.ProfileID == ProfileZDO &&
+				rx.ClusterID == clusterMgmtLqiResponse && len(rx.Data) > 0 && rx.Data[0] == seq
+		})
+		err := xb.transmitExplicitStatus(dest, ExplicitAddress{
+			SourceEndpoint:      EndpointZDO,
+			DestinationEndpoint: EndpointZDO,
+			ClusterID:           clusterMgmtLqiRequest,
+			ProfileID:           ProfileZDO,
+		}, []byte{seq, start})
+		if err != nil {
+			xb.unregisterMatcher(m)
+			return nil, err
+		}
+		var ev Event
+		select {
+		case ev = <-m.ch:
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-xb.closed:
+			err = ErrClosed
+		}
+		xb.unregisterMatcher(m)
+		if err != nil {
+			return nil, err
+		}
+		entries, total, err := decodeMgmtLqiResponse(ev.(*ExplicitReceivePacket).Data)
+		if err != nil {
+			return nil, err
+		}
+		neighbors = append(neighbors, entries...)
+		if len(entries) == 0 || len(neighbors) >= total {
+			return neighbors, nil
+		}
+	}
+}
+
+func decodeMgmtLqiResponse(b []byte) ([]Neighbor, int, error) {
+	if len(b) < 2 {
+		return nil, 0, fmt.Errorf("xbee: %w: Mgmt_Lqi response too short (%d bytes)", ErrMalformedFrame, len(b))
+	}
+	if b[1] != 0 {
+		return nil, 0, fmt.Errorf("xbee: Mgmt_Lqi request failed with ZDO status 0x%02x", b[1])
+	}
+	if len(b) < lqiHeaderLen {
+		return nil, 0, fmt.Errorf("xbee: %w: Mgmt_Lqi response too short (%d bytes)", ErrMalformedFrame, len(b))
+	}
+	total, count := int(b[2]), int(b[4])
+	b = b[lqiHeaderLen:]
+	if len(b) < count*lqiEntryLen {
+		return nil, 0, fmt.Errorf("xbee: %w: Mgmt_Lqi response has %d bytes for %d entries", ErrMalformedFrame, len(b), count)
+	}
+	entries := make([]Neighbor, count)
+	for i := range entries {
+		e := b[i*lqiEntryLen:]
+		// Extended PAN ID (8), IEEE address (8), network address (2),
+		// device type/rx on when idle/relationship, permit joining,
+		// depth, and LQI. Multi-byte fields are little-endian.
+		entries[i] = Neighbor{
+			Address:      Addr64(binary.LittleEndian.Uint64(e[8:])),
+			Address16:    Addr16(binary.LittleEndian.Uint16(e[16:])),
+			DeviceType:   DeviceType(e[18] & 0x03),
+			Relationship: NeighborRelationship((e[18] >> 4) & 0x07),
+			Depth:        e[20],
+			LQI:          e[21],
+		}
+	}
+	return entries, total, nil
+}
+
+// TopologyConfig configures MapTopology. The zero value uses the defaults.
+type TopologyConfig struct {
+	// Discover is how long to wait for node discovery (ND) responses. The
+	// default is 6s.
+	Discover time.Duration
+	// Timeout is how long to wait for each neighbor table. The default is
+	// 5s.
+	Timeout time.Duration
+}
+
+// TopologyNode is a node in a Topology.
+type TopologyNode struct {
+	Address    Addr64
+	NodeID     string // only known for nodes that answered node discovery
+	DeviceType DeviceType
+	// Err is the error reading the node's neighbor table if it's a router
+	// or coordinator that couldn't be queried.
+	Err error `json:"-"`
+}
+
+// TopologyLink is a neighbor table entry of node From for node To.
+type TopologyLink struct {
+	From         Addr64
+	To           Addr64
+	LQI          byte
+	Relationship NeighborRelationship
+}
+
+// Topology is the mesh as seen by the routers' neighbor tables.
+type Topology struct {
+	Nodes map[Addr64]*TopologyNode
+	Links []TopologyLink
+}
+
+// MapTopology discovers the nodes on the network (ND) and reads the
+// neighbor table of every router and coordinator found, including ones
+// only seen in other neighbor tables. The local module's own table can't
+// be read so its links come from its neighbors. Explicit receive (AO=1)
+// must be enabled. Nodes whose table couldn't be read have Err set.
+func (xb *XBee) MapTopology(ctx context.Context, cfg *TopologyConfig) (*Topology, error) {
+	var c TopologyConfig
+	if cfg != nil {
+		c = *cfg
+	}
+	if c.Discover <= 0 {
+		c.Discover = defaultTopologyDiscover
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = defaultTopologyTimeout
+	}
+	local, err := xb.SerialNumber()
+	if err != nil {
+		return nil, err
+	}
+	localType := DeviceTypeUnknown
+	if ce, err := xb.atCommand(atCoordinatorEnable, nil); err == nil && decodeUint(ce) == 1 {
+		localType = Coordinator
+	}
+	found, err := xb.NodeDiscover(c.Discover)
+	if err != nil {
+		return nil, err
+	}
+
+	topo := &Topology{Nodes: map[Addr64]*TopologyNode{
+		local: {Address: local, DeviceType: localType},
+	}}
+	var queue []Addr64
+	add := func(addr Addr64, dt DeviceType) {
+		if n, ok := topo.Nodes[addr]; ok {
+			if n.DeviceType == DeviceTypeUnknown {
+				n.DeviceType = dt
+			}
+			return
+		}
+		topo.Nodes[addr] = &TopologyNode{Address: addr, DeviceType: dt}
+		if dt == Coordinator || dt == Router {
+			queue = append(queue, addr)
+		}
+	}
+	for _, n := range found {
+		add(n.SerialNumber, n.DeviceType)
+		topo.Nodes[n.SerialNumber].NodeID = n.NodeID
+	}
+	for len(queue) > 0 {
+		addr := queue[0]
+		queue = queue[1:]
+		qctx, cancel := context.WithTimeout(ctx, c.Timeout)
+		neighbors, err := xb.NeighborTable(qctx, addr)
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return topo, ctx.Err()
+			}
+			topo.Nodes[addr].Err = err
+			continue
+		}
+		for _, nb := range neighbors {
+			add(nb.Address, nb.DeviceType)
+			topo.Links = append(topo.Links, TopologyLink{From: addr, To: nb.Address, LQI: nb.LQI, Relationship: nb.Relationship})
+		}
+	}
+	return topo, nil
+}
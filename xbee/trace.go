@@ -0,0 +1,90 @@
+package xbee
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/samuel/go-xbee/xbee/frames"
+)
+
+// FrameTracer is called for every frame sent or received with the bytes
+// as they appear on the wire. The raw bytes are only valid for the
+// duration of the call. OnReceive is called with a nil frame and the error
+// for frames that fail the checksum or can't be decoded. Calls are made
+// from the goroutines sending and receiving so they must be fast.
+type FrameTracer interface {
+	OnSend(raw []byte, f Frame)
+	OnReceive(raw []byte, f Frame, err error)
+}
+
+// DumpTracer writes a hex dump and description of every frame to w.
+type DumpTracer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewDumpTracer(w io.Writer) *DumpTracer {
+	return &DumpTracer{w: w}
+}
+
+func (t *DumpTracer) OnSend(raw []byte, f Frame) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fmt.Fprintf(t.w, "> % x\n  %T %+v\n", raw, f, f)
+}
+
+func (t *DumpTracer) OnReceive(raw []byte, f Frame, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err != nil {
+		fmt.Fprintf(t.w, "< % x\n  error: %s\n", raw, err)
+		return
+	}
+	fmt.Fprintf(t.w, "< % x\n  %T %+v\n", raw, f, f)
+}
+
+// RequestTracer is called for every frame sent with a frame ID to trace
+// the round trip to the response (e.g. AT commands, remote AT commands,
+// and transmits). StartRequest is called before the frame is written and
+// the returned function is called once with the first response with the
+// same frame ID, or with an error if the write fails, the request times
+// out (ErrTimeout), or no response arrives before the frame ID is reused
+// (ErrNoResponse).
+type RequestTracer interface {
+	StartRequest(req Frame) func(res Frame, err error)
+}
+
+// startRequest calls the request tracer for f if it has a frame ID.
+func (xb *XBee) startRequest(f Frame) {
+	if xb.reqTracer == nil {
+		return
+	}
+	idf, ok := f.(frames.Identified)
+	if !ok || idf.ID() == 0 {
+		return
+	}
+	end := xb.reqTracer.StartRequest(f)
+	xb.mu.Lock()
+	prev := xb.pending[idf.ID()]
+	xb.pending[idf.ID()] = end
+	xb.mu.Unlock()
+	if prev != nil {
+		prev(nil, ErrNoResponse)
+	}
+}
+
+// endRequest completes the trace of the request with the frame ID if it's
+// still pending.
+func (xb *XBee) endRequest(frameID byte, res Frame, err error) {
+	if xb.reqTracer == nil {
+		return
+	}
+	xb.mu.Lock()
+	end := xb.pending[frameID]
+	delete(xb.pending, frameID)
+	xb.mu.Unlock()
+	if end != nil {
+		end(res, err)
+	}
+}
@@ -0,0 +1,147 @@
+package xbee
+
+import (
+	"errors"
+	"sync"
+)
+
+const trustCenterQueueLen = 16
+
+// JoinAttempt is a device that joined the network as reported by its node
+// identification indicator.
+type JoinAttempt struct {
+	Address    Addr64
+	Address16  Addr16
+	Parent     Addr16
+	NodeID     string
+	DeviceType DeviceType
+	// Allowed is false if the device was denied by the policy and told to
+	// leave the network.
+	Allowed bool
+	// Err is the error removing a denied device.
+	Err error
+}
+
+// TrustCenterConfig configures a TrustCenter.
+type TrustCenterConfig struct {
+	// Allow decides whether a device that joined may stay on the network.
+	// Denied devices are told to leave (DA) and their link key is removed
+	// from the key table. nil allows every device.
+	Allow func(*JoinAttempt) bool
+}
+
+// TrustCenter applies a join policy on the coordinator. Devices must send a
+// node identification indicator when they join (JN=1) for their joins to
+// be seen, and the indicators are consumed by the TrustCenter rather than
+// delivered by EventChan.
+type TrustCenter struct {
+	xb    *XBee
+	cfg   TrustCenterConfig
+	m     *matcher
+	joins chan *JoinAttempt
+	done  chan struct{}
+	once  sync.Once
+}
+
+// NewTrustCenter enables encryption (EE) and the trust center (EO) with
+// the additional options in opts and starts applying the join policy. cfg
+// may be nil to allow every device.
+func (xb *XBee) NewTrustCenter(opts SecurityOption, cfg *TrustCenterConfig) (*TrustCenter, error) {
+	if err := xb.SetEncryptionEnabled(true); err != nil {
+		return nil, err
+	}
+	if err := xb.SetEncryptionOptions(opts | SOUseTrustCenter); err != nil {
+		return nil, err
+	}
+	tc := &TrustCenter{
+		xb:    xb,
+		joins: make(chan *JoinAttempt, trustCenterQueueLen),
+		done:  make(chan struct{}),
+	}
+	if cfg != nil {
+		tc.cfg = *cfg
+	}
+	tc.m = xb.registerMatcher(matchAny, func(ev Event) bool {
+		ni, ok := ev.(*NodeIdentificationIndicator)
+		return ok && ni.SourceEvent == NIEJoined
+	})
+	go tc.loop()
+	return tc, nil
+}
+
+// Joins returns the channel on which join attempts are delivered after the
+// policy has been applied. Attempts are dropped if the channel is full. It's
+// closed by Close.
+func (tc *TrustCenter) Joins() <-chan *JoinAttempt {
+	return tc.joins
+}
+
+// RegisterLinkKey allows the device with the address to join using a
+// 128-bit link key.
+func (tc *TrustCenter) RegisterLinkKey(addr Addr64, key []byte) error {
+	return tc.xb.RegisterJoiningDevice(addr, key, RJOLinkKey)
+}
+
+// RegisterInstallCode allows the device with the address to join using its
+// install code including the CRC.
+func (tc *TrustCenter) RegisterInstallCode(addr Addr64, code []byte) error {
+	return tc.xb.RegisterJoiningDevice(addr, code, RJOInstallCode)
+}
+
+// Deregister removes the device's key from the key table.
+func (tc *TrustCenter) Deregister(addr Addr64) error {
+	return tc.xb.DeregisterJoiningDevice(addr)
+}
+
+// Close stops applying the join policy.
+func (tc *TrustCenter) Close() error {
+	tc.once.Do(func() {
+		tc.xb.unregisterMatcher(tc.m)
+		close(tc.done)
+	})
+	return nil
+}
+
+func (tc *TrustCenter) loop() {
+	defer close(tc.joins)
+	for {
+		var ev Event
+		select {
+		case ev = <-tc.m.ch:
+		case <-tc.done:
+			return
+		}
+		ni := ev.(*NodeIdentificationIndicator)
+		j := &JoinAttempt{
+			Address:    ni.RemoteAddress,
+			Address16:  ni.RemoteAddress16,
+			Parent:     ni.ParentAddress16,
+			NodeID:     ni.NodeID,
+			DeviceType: DeviceType(ni.DeviceType),
+			Allowed:    true,
+		}
+		if tc.cfg.Allow != nil && !tc.cfg.Allow(j) {
+			j.Allowed = false
+			j.Err = tc.remove(j.Address, j.Address16)
+		}
+		select {
+		case tc.joins <- j:
+		default:
+			tc.xb.stats.droppedEvents.Add(1)
+		}
+	}
+}
+
+// remove tells a denied device to leave the network and removes its key.
+func (tc *TrustCenter) remove(addr Addr64, addr16 Addr16) error {
+	if _, err := tc.xb.RemoteATCommand(addr, addr16, atForceDisassociation, nil, RATOApplyChanges); err != nil {
+		return err
+	}
+	var invalid *RegisterJoiningDeviceError
+	err := tc.Deregister(addr)
+	if errors.As(err, &invalid) && invalid.Status == RJKeyNotFound {
+		// Joined with a key that isn't in the table (e.g. KY).
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,134 @@
+package xbee
+
+import (
+	"fmt"
+	"net/netip"
+	"time"
+
+	"github.com/samuel/go-xbee/xbee/frames"
+)
+
+// Support for XBee Wi-Fi (S6B) modules.
+
+// remoteATTimeout is how long to wait for the response to a remote AT
+// command before giving up.
+const remoteATTimeout = 10 * time.Second
+
+// WiFiSecurity is the encryption used by a Wi-Fi access point.
+type WiFiSecurity byte
+
+const (
+	WiFiSecurityNone WiFiSecurity = 0
+	WiFiSecurityWPA  WiFiSecurity = 1
+	WiFiSecurityWPA2 WiFiSecurity = 2
+	WiFiSecurityWEP  WiFiSecurity = 3
+)
+
+func (s WiFiSecurity) String() string {
+	switch s {
+	case WiFiSecurityNone:
+		return "None"
+	case WiFiSecurityWPA:
+		return "WPA"
+	case WiFiSecurityWPA2:
+		return "WPA2"
+	case WiFiSecurityWEP:
+		return "WEP"
+	}
+	return fmt.Sprintf("WiFiSecurity(%d)", s)
+}
+
+func (s WiFiSecurity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// WiFiAccessPoint is an access point found by an active scan on a Wi-Fi
+// module (AS_type 1).
+type WiFiAccessPoint struct {
+	Channel    byte
+	Security   WiFiSecurity
+	LinkMargin int8 // dB above the receive sensitivity, higher values are better
+	SSID       string
+}
+
+const activeScanTypeWiFi = 1
+
+// ActiveScanWiFi scans for access points on a Wi-Fi module.
+func (xb *XBee) ActiveScanWiFi(wait time.Duration) ([]*WiFiAccessPoint, error) {
+	if err := xb.require(FeatureWiFiScan); err != nil {
+		return nil, err
+	}
+	var aps []*WiFiAccessPoint
+	err := xb.atCommandResponses(atActiveScan, wait, func(data []byte) error {
+		ap, err := decodeWiFiAccessPoint(data)
+		if err != nil {
+			return err
+		}
+		aps = append(aps, ap)
+		return nil
+	})
+	return aps, err
+}
+
+func decodeWiFiAccessPoint(data []byte) (*WiFiAccessPoint, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("xbee.ActiveScanWiFi: %w: access point frame should be at least 4 bytes, got %d", ErrMalformedFrame, len(data))
+	}
+	if data[0] != activeScanTypeWiFi {
+		return nil, fmt.Errorf("xbee.ActiveScanWiFi: %w: expected AS type %d got %d", ErrMalformedFrame, activeScanTypeWiFi, data[0])
+	}
+	return &WiFiAccessPoint{
+		Channel:    data[1],
+		Security:   WiFiSecurity(data[2]),
+		LinkMargin: int8(data[3]),
+		SSID:       string(data[4:]),
+	}, nil
+}
+
+// TransmitIPv4 sends data to a host over UDP or TCP. A srcPort of 0 uses
+// a random source port.
+func (xb *XBee) TransmitIPv4(dest netip.AddrPort, srcPort uint16, proto IPProtocol, options IPTransmitOption, data []byte) error {
+	if err := xb.require(FeatureIP); err != nil {
+		return err
+	}
+	return xb.writeFrame(&frames.IPv4TransmitRequest{
+		FrameID:            xb.nextFrameID(),
+		DestinationAddress: dest.Addr(),
+		DestinationPort:    dest.Port(),
+		SourcePort:         srcPort,
+		Protocol:           proto,
+		Options:            options,
+		Data:               data,
+	})
+}
+
+// IPRemoteATCommand issues an AT command to a remote Wi-Fi module and
+// returns the response data.
+func (xb *XBee) IPRemoteATCommand(dest netip.Addr, cmd ATCommand, param []byte, options RemoteATCommandOption) ([]byte, error) {
+	if err := xb.require(FeatureIP); err != nil {
+		return nil, err
+	}
+	ev, err := xb.request(remoteATTimeout, func(frameID byte) frames.Frame {
+		return &frames.IPRemoteATCommandRequest{
+			FrameID:            frameID,
+			DestinationAddress: dest,
+			Options:            options,
+			ATCommand:          cmd,
+			Parameter:          param,
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	res, ok := ev.(*IPRemoteATCommandResponse)
+	if !ok {
+		return nil, wrongFrame("remote AT response", ev)
+	}
+	if res.ATCommand != cmd {
+		return nil, wrongCommand(cmd, res.ATCommand)
+	}
+	if err := commandStatusError(cmd, res.CommandStatus); err != nil {
+		return nil, err
+	}
+	return res.Data, nil
+}
@@ -0,0 +1,170 @@
+package xbee
+
+import (
+	"errors"
+	"time"
+
+	"github.com/samuel/go-xbee/xbee/frames"
+)
+
+var ErrClosed = errors.New("xbee: closed")
+
+const (
+	// DefaultWriteQueue is the number of frames of each priority that can
+	// be queued for the writer before sending blocks.
+	DefaultWriteQueue = 16
+	// inFlightTimeout is how long a frame counts against the in-flight
+	// limit without a response.
+	inFlightTimeout = 10 * time.Second
+)
+
+type writeRequest struct {
+	f    frames.Frame
+	data []byte
+	done chan error
+}
+
+// writeFrame queues f with its default priority and waits for it to be
+// written.
+func (xb *XBee) writeFrame(f frames.Frame) error {
+	return xb.writeFramePriority(f, framePriority(f))
+}
+
+func (xb *XBee) writeFramePriority(f frames.Frame, p Priority) error {
+	xb.addrs.fill(f)
+	data, err := frames.Encode(f)
+	if err != nil {
+		return err
+	}
+	if err := xb.limitRF(f); err != nil {
+		return err
+	}
+	req := &writeRequest{f: f, data: data, done: make(chan error, 1)}
+	select {
+	case xb.writeQueues[p] <- req:
+	case <-xb.closed:
+		return ErrClosed
+	}
+	select {
+	case err := <-req.done:
+		return err
+	case <-xb.closed:
+		return ErrClosed
+	}
+}
+
+// writeLoop writes queued frames one at a time in priority order so frames
+// are never interleaved, waiting for an in-flight slot for frames with a frame ID
+// when the number of in-flight frames is limited.
+func (xb *XBee) writeLoop() {
+	for {
+		req := xb.nextWrite()
+		if req == nil {
+			return
+		}
+		var frameID byte
+		if idf, ok := req.f.(frames.Identified); ok {
+			frameID = idf.ID()
+		}
+		if frameID != 0 && xb.maxInFlight > 0 {
+			if !xb.acquireInFlight(frameID) {
+				req.done <- ErrClosed
+				return
+			}
+		}
+		err := xb.writeData(req.f, req.data)
+		if err != nil && frameID != 0 {
+			xb.releaseInFlight(frameID)
+		}
+		req.done <- err
+	}
+}
+
+// nextWrite returns the next frame to write taking the highest priority
+// frame queued or nil if closed.
+func (xb *XBee) nextWrite() *writeRequest {
+	for _, q := range xb.writeQueues {
+		select {
+		case req := <-q:
+			return req
+		default:
+		}
+	}
+	select {
+	case req := <-xb.writeQueues[PriorityHigh]:
+		return req
+	case req := <-xb.writeQueues[PriorityNormal]:
+		return req
+	case req := <-xb.writeQueues[PriorityBulk]:
+		return req
+	case <-xb.closed:
+		return nil
+	}
+}
+
+func (xb *XBee) writeData(f frames.Frame, data []byte) error {
+	if xb.tap != nil {
+		if err := xb.tap.WriteRecord(frames.Sent, data); err != nil {
+			xb.log.Warn("xbee: failed to record frame", "err", err)
+		}
+	}
+	if xb.tracer != nil {
+		if raw, err := xb.wr.AppendData(nil, data); err == nil {
+			xb.tracer.OnSend(raw, f)
+		}
+	}
+	xb.startRequest(f)
+	if err := xb.wr.WriteData(data); err != nil {
+		if idf, ok := f.(frames.Identified); ok {
+			xb.endRequest(idf.ID(), nil, err)
+		}
+		return err
+	}
+	xb.stats.sent[data[0]].Add(1)
+	return nil
+}
+
+// acquireInFlight waits until fewer than the maximum number of frames are
+// awaiting a response and records frameID as in flight. Frames without a
+// response after inFlightTimeout are forgotten. It returns false if the
+// XBee is closed while waiting.
+func (xb *XBee) acquireInFlight(frameID byte) bool {
+	for {
+		now := time.Now()
+		xb.mu.Lock()
+		for id, t := range xb.inFlight {
+			if now.Sub(t) > inFlightTimeout {
+				delete(xb.inFlight, id)
+			}
+		}
+		if len(xb.inFlight) < xb.maxInFlight {
+			xb.inFlight[frameID] = now
+			xb.mu.Unlock()
+			return true
+		}
+		xb.mu.Unlock()
+		select {
+		case <-xb.inFlightFreed:
+		case <-time.After(inFlightTimeout):
+		case <-xb.closed:
+			return false
+		}
+	}
+}
+
+func (xb *XBee) releaseInFlight(frameID byte) {
+	xb.mu.Lock()
+	xb.releaseInFlightLocked(frameID)
+	xb.mu.Unlock()
+}
+
+func (xb *XBee) releaseInFlightLocked(frameID byte) {
+	if _, ok := xb.inFlight[frameID]; !ok {
+		return
+	}
+	delete(xb.inFlight, frameID)
+	select {
+	case xb.inFlightFreed <- struct{}{}:
+	default:
+	}
+}
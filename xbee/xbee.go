@@ -1,33 +1,27 @@
 package xbee
 
 import (
-	"bufio"
 	"bytes"
+	"context"
 	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
-)
 
-const (
-	frameDelimiter             = 0x7e
-	frameATCommand             = 0x08
-	frameATCommandQueue        = 0x09
-	frameZigBeeTransmitRequest = 0x10
-	frameATCommandResponse     = 0x88
-	frameModemStatus           = 0x8a
-	frameZigBeeTransmitStatus  = 0x8b
-	frameZigBeeReceivePacket   = 0x90
+	"github.com/samuel/go-xbee/xbee/frames"
 )
 
 var (
 	ErrInvalidParameter = errors.New("xbee: invalid parameter")
 	ErrResponse         = errors.New("xbee: generic error response")
 	ErrTXFailure        = errors.New("xbee: TX failure")
+	ErrTimeout          = errors.New("xbee: timeout waiting for response")
 )
 
 type ErrInvalidCommand string
@@ -36,8 +30,11 @@ func (e ErrInvalidCommand) Error() string {
 	return fmt.Sprintf("xbee: invalid command %s", string(e))
 }
 
+// ActiveScanDevice is a network or access point found by ActiveScan.
+// Wi-Fi modules report access points (Type 1) in a different format so
+// only AccessPoint is set for them.
 type ActiveScanDevice struct {
-	Type         byte // 2 - ZB firmware uses a different format than Wi-Fi XBee, which is type 1
+	Type         byte // 2 for ZB firmware, 1 for Wi-Fi modules
 	Channel      byte
 	PAN          uint16
 	ExtendedPAN  uint64
@@ -45,12 +42,15 @@ type ActiveScanDevice struct {
 	StackProfile byte
 	LQI          byte // higher values are better
 	RSSI         int8 // lower values are better
+	AccessPoint  *WiFiAccessPoint
 }
 
+const activeScanTypeZigBee = 2
+
 type Node struct {
-	SerialNumber         uint64
+	SerialNumber         Addr64
 	NodeID               string
-	ParentNetworkAddress uint16
+	ParentNetworkAddress Addr16
 	DeviceType           DeviceType
 	Status               byte
 	ProfileID            uint16
@@ -63,6 +63,9 @@ const (
 	Coordinator DeviceType = 0
 	Router      DeviceType = 1
 	EndDevice   DeviceType = 2
+	// DeviceTypeUnknown is used by NodeStats for nodes that haven't been
+	// discovered or announced themselves.
+	DeviceTypeUnknown DeviceType = 0xff
 )
 
 func (dt DeviceType) String() string {
@@ -73,10 +76,37 @@ func (dt DeviceType) String() string {
 		return "Router"
 	case EndDevice:
 		return "EndDevice"
+	case DeviceTypeUnknown:
+		return "Unknown"
 	}
 	return fmt.Sprintf("DeviceType(%d)", dt)
 }
 
+func (dt DeviceType) MarshalText() ([]byte, error) {
+	return []byte(dt.String()), nil
+}
+
+// UnmarshalJSON accepts the name of a device type or the number used
+// before device types were marshaled as names.
+func (dt *DeviceType) UnmarshalJSON(b []byte) error {
+	var n byte
+	if err := json.Unmarshal(b, &n); err == nil {
+		*dt = DeviceType(n)
+		return nil
+	}
+	var name string
+	if err := json.Unmarshal(b, &name); err != nil {
+		return err
+	}
+	for _, t := range []DeviceType{Coordinator, Router, EndDevice, DeviceTypeUnknown} {
+		if t.String() == name {
+			*dt = t
+			return nil
+		}
+	}
+	return fmt.Errorf("xbee: %w: unknown device type %q", ErrInvalidParameter, name)
+}
+
 type NodeDiscoveryOption int
 
 const (
@@ -137,316 +167,200 @@ func (o SecurityOption) String() string {
 	return strings.Join(opts, "|")
 }
 
-type ModemStatus byte
-
-const (
-	MSHardwareReset              ModemStatus = 0
-	MSWatchdogTimerReset         ModemStatus = 1
-	MSJoinedNetwork              ModemStatus = 2 // routers and end devices
-	MSDisassociated              ModemStatus = 3
-	MSCoordinatorStarted         ModemStatus = 6
-	MSNetworkKeyUpdated          ModemStatus = 7
-	MSVoltageSupplyLimitExceeded ModemStatus = 0x0d // PRO S2B only
-	MSConfigChangeDuringJoin     ModemStatus = 0x11
-)
-
-func (ms ModemStatus) String() string {
-	switch ms {
-	case MSHardwareReset:
-		return "HardwareReset"
-	case MSWatchdogTimerReset:
-		return "WatchdogTimerReset"
-	case MSJoinedNetwork:
-		return "JoinedNetwork"
-	case MSDisassociated:
-		return "Disassociated"
-	case MSCoordinatorStarted:
-		return "CoordinatorStarted"
-	case MSNetworkKeyUpdated:
-		return "NetworkKeyUpdated"
-	case MSVoltageSupplyLimitExceeded:
-		return "VoltageSupplyLimitExceeded"
-	case MSConfigChangeDuringJoin:
-		return "ConfigChangeDuringJoin"
-	}
-	if ms >= 0x80 {
-		return "StackError"
-	}
-	return fmt.Sprintf("ModemStatus(%d)", ms)
-}
-
-type DeliveryStatus byte
-
-const (
-	DSSuccess                               DeliveryStatus = 0x00
-	DSMACACKFailure                         DeliveryStatus = 0x01
-	DSCCAFailure                            DeliveryStatus = 0x02
-	DSInvalidDestinationEndpoint            DeliveryStatus = 0x15
-	DSNetworkACKFailure                     DeliveryStatus = 0x21
-	DSNotJoinedToNetwork                    DeliveryStatus = 0x22
-	DSSelfAddressed                         DeliveryStatus = 0x23
-	DSAddressNotFound                       DeliveryStatus = 0x24
-	DSRouteNotFound                         DeliveryStatus = 0x25
-	DSBroadcastFail                         DeliveryStatus = 0x26 // Broadcast source failed to hear a neighbor relay the message
-	DSInvalidBindingTableIndex              DeliveryStatus = 0x2B
-	DSResourceError                         DeliveryStatus = 0x2C // Resource error lack of free buffers, timers, and so forth.
-	DSAttemptedBroadcastWithAPSTransmittion DeliveryStatus = 0x2D
-	DSAttemptedUnicastWithAPSTransmission   DeliveryStatus = 0x2E // Attempted unicast with APS transmission, but EE=0
-	DSResourceError2                        DeliveryStatus = 0x32 // Resource error lack of free buffers, timers, and so
-	DSDataPayloadTooLarge                   DeliveryStatus = 0x74
-)
-
-func (ds DeliveryStatus) String() string {
-	switch ds {
-	case DSSuccess:
-		return "Success"
-	case DSMACACKFailure:
-		return "MACACKFailure"
-	case DSCCAFailure:
-		return "CCAFailure"
-	case DSInvalidDestinationEndpoint:
-		return "InvalidDestinationEndpoint"
-	case DSNetworkACKFailure:
-		return "NetworkACKFailure"
-	case DSNotJoinedToNetwork:
-		return "NotJoinedToNetwork"
-	case DSSelfAddressed:
-		return "SelfAddressed"
-	case DSAddressNotFound:
-		return "AddressNotFound"
-	case DSRouteNotFound:
-		return "RouteNotFound"
-	case DSBroadcastFail:
-		return "BroadcastFail"
-	case DSInvalidBindingTableIndex:
-		return "InvalidBindingTableIndex"
-	case DSResourceError:
-		return "ResourceError"
-	case DSAttemptedBroadcastWithAPSTransmittion:
-		return "AttemptedBroadcastWithAPSTransmittion"
-	case DSAttemptedUnicastWithAPSTransmission:
-		return "AttemptedUnicastWithAPSTransmission"
-	case DSResourceError2:
-		return "ResourceError2"
-	case DSDataPayloadTooLarge:
-		return "DataPayloadTooLarge"
-	}
-	return fmt.Sprintf("DeliveryStatus(%d)", ds)
-}
-
-type DiscoveryStatus byte
-
-const (
-	DSNoDiscoveryOverhead      DiscoveryStatus = 0x00
-	DSAddressDiscovery         DiscoveryStatus = 0x01
-	DSRouteDiscovery           DiscoveryStatus = 0x02
-	DSAddressAndRoute          DiscoveryStatus = 0x03
-	DSExtendedTimeoutDiscovery DiscoveryStatus = 0x40
-)
-
-func (ds DiscoveryStatus) String() string {
-	switch ds {
-	case DSNoDiscoveryOverhead:
-		return "NoDiscoveryOverhead"
-	case DSAddressDiscovery:
-		return "AddressDiscovery"
-	case DSRouteDiscovery:
-		return "RouteDiscovery"
-	case DSAddressAndRoute:
-		return "AddressAndRoute"
-	case DSExtendedTimeoutDiscovery:
-		return "ExtendedTimeoutDiscovery"
-	}
-	return fmt.Sprintf("DiscoveryStatus(%d)", ds)
-}
-
-type TransmitStatus struct {
-	DestinationAddress uint16
-	RetryCount         int
-	DeliveryStatus     DeliveryStatus
-	DiscoveryStatus    DiscoveryStatus
-}
-
-type ReceiveOption byte
-
-const (
-	ROAcknowledged  ReceiveOption = 0x01
-	ROBroadcast     ReceiveOption = 0x02
-	ROEncrypted     ReceiveOption = 0x20
-	ROFromEndDevice ReceiveOption = 0x40
-)
-
-func (o ReceiveOption) Has(opt ReceiveOption) bool {
-	return (o & opt) != 0
-}
-
-func (o ReceiveOption) String() string {
-	if o == 0 {
-		return "None"
-	}
-	var opts []string
-	if o.Has(ROAcknowledged) {
-		opts = append(opts, "Acknowledged")
-		o &^= ROAcknowledged
-	}
-	if o.Has(ROBroadcast) {
-		opts = append(opts, "Broadcast")
-		o &^= ROBroadcast
-	}
-	if o.Has(ROEncrypted) {
-		opts = append(opts, "Encrypted")
-		o &^= ROEncrypted
-	}
-	if o.Has(ROFromEndDevice) {
-		opts = append(opts, "FromEndDevice")
-		o &^= ROFromEndDevice
-	}
-	if o != 0 {
-		opts = append(opts, fmt.Sprintf("ReceiveOption(%d)", o))
-	}
-	return strings.Join(opts, "|")
-}
-
-type ReceivePacket struct {
-	SourceAddress   uint64
-	SourceAddress16 uint16
-	ReceiveOptions  ReceiveOption
-	Data            []byte
-}
-
-type CommandStatus byte
-
-const (
-	CSOK               CommandStatus = 0
-	CSError            CommandStatus = 1
-	CSInvalidCommand   CommandStatus = 2
-	CSInvalidParameter CommandStatus = 3
-	CSTxFailure        CommandStatus = 4
-)
-
-func (cs CommandStatus) String() string {
-	switch cs {
-	case CSOK:
-		return "OK"
-	case CSError:
-		return "Error"
-	case CSInvalidCommand:
-		return "InvalidCommand"
-	case CSInvalidParameter:
-		return "InvalidParameter"
-	case CSTxFailure:
-		return "TxFailure"
-	}
-	return fmt.Sprintf("CommandStatus(%d)", cs)
-}
-
-type ATCommandResponse struct {
-	ATCommand     ATCommand
-	CommandStatus CommandStatus
-	Data          []byte
-}
-
-type UnknownFrame []byte
-
 type XBee struct {
-	port    io.ReadWriter
-	wbuf    []byte
-	frameID byte
-	eventCh chan Event
-	mu      sync.Mutex
-	idMap   map[byte]chan Event
+	port        io.ReadWriter
+	escaped     bool // API mode 2
+	tap         *frames.TapWriter
+	recorder    *Recorder
+	log         *slog.Logger
+	tracer      FrameTracer
+	reqTracer   RequestTracer
+	stats       stats
+	nodes       nodeTable
+	nodeStore   NodeStore
+	addrs       addrCache
+	rssiCh      chan Addr64 // nil unless RSSI sampling is enabled
+	pool        *bufferPool // nil unless buffers are pooled
+	limiter     *rateLimiter
+	dedup       *broadcastDedup // only used by readLoop
+	dutyCycle   *dutyCycle
+	zdoSeq      atomic.Uint32  // ZDO transaction sequence number
+	timeSeq     atomic.Uint32  // time sync request sequence number
+	pingSeq     atomic.Uint32  // Ping payload sequence number
+	wr          *frames.Writer // only used by writeLoop
+	writeQueues [numPriorities]chan *writeRequest
+	closed      chan struct{}
+	eventCh     chan Event
+	emu         sync.RWMutex  // held to send on eventCh, and to close it
+	eventsDone  bool          // eventCh is closed
+	readDone    chan struct{} // closed when the read loop stops
+	readErr     error         // why the read loop stopped, set before readDone is closed
+	mu          sync.Mutex    // protects frameID, idMap, matchers, observers, pending, and inFlight
+	frameID     byte
+	idMap       map[byte]chan Event
+	matchers    []*matcher                  // most specific first
+	observers   []*matcher                  // see events without consuming them
+	pending     map[byte]func(Frame, error) // traced requests by frame ID
+
+	maxInFlight   int
+	inFlight      map[byte]time.Time // frames awaiting a response by frame ID
+	inFlightFreed chan struct{}
+
+	smu     sync.Mutex // protects streams
+	streams *streamMux
+
+	middleware atomic.Pointer[[]Middleware] // installed by Use, nil if none
+	filter     atomic.Pointer[receiveFilter]
+	caps       atomic.Pointer[Capabilities] // set by Capabilities
 }
 
 type Event interface{}
 
-const (
-	AddressCoordinator uint64 = 0x0000000000000000
-	AddressBroadcast   uint64 = 0x000000000000FFFF
-	Address16Unknown   uint16 = 0xFFFE
-	Address16Broadcast uint16 = 0xFFFE
-)
-
-type TransmitOption byte
-
-const (
-	TODisableRetriesAndRouteRepair TransmitOption = 0x01
-	TOEnableAPSEncryption          TransmitOption = 0x20
-	TOExtendedTxTimeout            TransmitOption = 0x40
-)
-
-func (o TransmitOption) Has(opt TransmitOption) bool {
-	return (o & opt) != 0
+// MalformedFrame is delivered as an event for a frame received with a bad
+// checksum (frames.ErrChecksum), cut short by the start of another frame
+// (frames.ErrTruncated, Data is nil), with no data (frames.ErrEmpty), or
+// that couldn't be decoded (e.g. a *ShortFrameError for a frame too short
+// for its frame type).
+type MalformedFrame struct {
+	Data []byte // frame type and frame specific fields
+	Err  error
 }
 
-func (o TransmitOption) String() string {
-	if o == 0 {
-		return "None"
-	}
-	var opts []string
-	if o.Has(TODisableRetriesAndRouteRepair) {
-		opts = append(opts, "ExtendedTxTimeout")
-		o &^= TODisableRetriesAndRouteRepair
-	}
-	if o.Has(TOEnableAPSEncryption) {
-		opts = append(opts, "TOEnableAPSEncryption")
-		o &^= TOEnableAPSEncryption
+// Open starts communicating with a module in API mode over device. By
+// default the module is expected to be in API mode 1 (unescaped). See
+// WithAPIMode and WithAutoDetect.
+func Open(device io.ReadWriter, opts ...Option) (*XBee, error) {
+	o := options{mode: APIModeUnescaped, logger: slog.Default(), writeQueue: DefaultWriteQueue}
+	for _, opt := range opts {
+		opt(&o)
 	}
-	if o.Has(TOExtendedTxTimeout) {
-		opts = append(opts, "TOExtendedTxTimeout")
-		o &^= TOExtendedTxTimeout
+	mode, err := resolveAPIMode(device, &o)
+	if err != nil {
+		return nil, err
 	}
-	if o != 0 {
-		opts = append(opts, fmt.Sprintf("TransmitOption(%d)", o))
+	if mode != APIModeUnescaped && mode != APIModeEscaped {
+		return nil, fmt.Errorf("xbee.Open: unsupported API mode %s", mode)
 	}
-	return strings.Join(opts, "|")
-}
-
-func Open(device io.ReadWriter) (*XBee, error) {
 	xb := &XBee{
-		port:    device,
-		wbuf:    make([]byte, 65535+4),
-		eventCh: make(chan Event, 8),
-		idMap:   make(map[byte]chan Event),
+		port:      device,
+		escaped:   mode == APIModeEscaped,
+		tap:       o.tap,
+		recorder:  o.recorder,
+		log:       o.logger,
+		tracer:    o.tracer,
+		reqTracer: o.reqTracer,
+		wr:        frames.NewWriter(device),
+		closed:    make(chan struct{}),
+		eventCh:   make(chan Event, 8),
+		readDone:  make(chan struct{}),
+		idMap:     make(map[byte]chan Event),
+		pending:   make(map[byte]func(Frame, error)),
+
+		maxInFlight:   o.maxInFlight,
+		inFlight:      make(map[byte]time.Time),
+		inFlightFreed: make(chan struct{}, 1),
+	}
+	xb.wr.Escaped = xb.escaped
+	if len(o.middleware) > 0 {
+		xb.Use(o.middleware...)
+	}
+	if o.pooled {
+		xb.pool = newBufferPool(xb.log)
+	}
+	if o.dedupWindow > 0 {
+		xb.dedup = newBroadcastDedup(o.dedupWindow, o.dedupMode)
+	}
+	if o.rate > 0 {
+		xb.limiter = newRateLimiter(o.rate, max(o.burst, 1))
+	}
+	if o.dutyCycle > 0 {
+		xb.dutyCycle = newDutyCycle(o.dutyCycle, o.bitrate)
+	}
+	if o.rssi {
+		xb.rssiCh = make(chan Addr64, 1)
+		go xb.sampleRSSI()
+	}
+	for i := range xb.writeQueues {
+		xb.writeQueues[i] = make(chan *writeRequest, o.writeQueue)
+	}
+	if o.nodeStore != nil {
+		nodes, err := o.nodeStore.Load()
+		if err != nil {
+			return nil, fmt.Errorf("xbee.Open: failed to load nodes: %w", err)
+		}
+		xb.nodeStore = o.nodeStore
+		xb.nodes.load(nodes)
+		go xb.saveNodesLoop()
 	}
-	xb.wbuf[0] = frameDelimiter
+	go xb.writeLoop()
 	go func() {
 		err := xb.readLoop()
 		if err != nil {
-			log.Printf("Error during read loop: %s", err)
+			xb.log.Error("xbee: read loop failed", "err", err)
 		}
+		xb.readErr = err
+		xb.closeEvents()
+		close(xb.readDone)
 	}()
 	return xb, nil
 }
 
+// Done returns a channel that's closed when the XBee stops reading frames
+// because the port failed or was closed. Err returns the reason. Close
+// doesn't stop reading by itself so the port must be closed too.
+func (xb *XBee) Done() <-chan struct{} {
+	return xb.readDone
+}
+
+// Err returns the error that stopped reading frames once Done is closed,
+// or nil before.
+func (xb *XBee) Err() error {
+	select {
+	case <-xb.readDone:
+		return xb.readErr
+	default:
+		return nil
+	}
+}
+
+// Close stops delivering events and the requests waiting for responses.
+// EventChan is closed once reading stops (see Done).
 func (xb *XBee) Close() {
-	close(xb.eventCh)
+	close(xb.closed)
+	if err := xb.SaveNodes(); err != nil {
+		xb.log.Warn("xbee: failed to save nodes", "err", err)
+	}
 }
 
+// EventChan returns the channel on which unsolicited events are delivered.
+// With WithPooledBuffers received packets must be passed to Release once
+// they're no longer used.
 func (xb *XBee) EventChan() chan Event {
 	return xb.eventCh
 }
 
+// ATCommand issues an AT command to the local module and returns the
+// response data. A nil param reads the register.
+func (xb *XBee) ATCommand(cmd ATCommand, param []byte) ([]byte, error) {
+	return xb.atCommand(cmd, param)
+}
+
 func (xb *XBee) atCommand(cmd ATCommand, val []byte) ([]byte, error) {
-	if len(val) > 65536-8 {
-		return nil, fmt.Errorf("xbee: value too long for at command write (%d bytes)", len(val))
-	}
-	frameID := xb.nextFrameID()
-	xb.wbuf[3] = frameATCommand
-	xb.wbuf[4] = frameID
-	xb.wbuf[5] = cmd[0]
-	xb.wbuf[6] = cmd[1]
-	if len(val) != 0 {
-		copy(xb.wbuf[7:], val)
-	}
-	ch := xb.registerListener(frameID)
+	return xb.atCommandQueue(cmd, val, false)
+}
+
+// atCommandQueue issues an AT command. If queue is true a new value isn't
+// applied until changes are applied (AC).
+func (xb *XBee) atCommandQueue(cmd ATCommand, val []byte, queue bool) ([]byte, error) {
+	frameID, ch := xb.registerListener()
 	defer xb.unregisterListener(frameID)
-	if err := xb.writeFrame(4 + len(val)); err != nil {
+	if err := xb.writeFrame(&frames.ATCommandRequest{FrameID: frameID, ATCommand: cmd, Parameter: val, Queue: queue}); err != nil {
 		return nil, err
 	}
 	ev := <-ch
 	res, ok := ev.(*ATCommandResponse)
 	if !ok {
-		return nil, fmt.Errorf("xbee: wrong frame, expected AT response got %T", ev)
+		return nil, wrongFrame("AT response", ev)
 	}
 	if err := validateATResponse(cmd, res); err != nil {
 		return nil, err
@@ -454,13 +368,13 @@ func (xb *XBee) atCommand(cmd ATCommand, val []byte) ([]byte, error) {
 	return res.Data, nil
 }
 
-func (xb *XBee) SerialNumber() (uint64, error) {
+func (xb *XBee) SerialNumber() (Addr64, error) {
 	res, err := xb.atCommand(atSerialNumberHigh, nil)
 	if err != nil {
 		return 0, err
 	}
 	if len(res) != 4 {
-		return 0, fmt.Errorf("xbee.SerialNumber: expected 4 bytes got %d", len(res))
+		return 0, fmt.Errorf("xbee.SerialNumber: %w: expected 4 bytes got %d", ErrMalformedFrame, len(res))
 	}
 	serial := uint64(binary.BigEndian.Uint32(res)) << 32
 	res, err = xb.atCommand(atSerialNumberLow, nil)
@@ -468,10 +382,10 @@ func (xb *XBee) SerialNumber() (uint64, error) {
 		return 0, err
 	}
 	if len(res) != 4 {
-		return 0, fmt.Errorf("xbee.SerialNumber: expected 4 bytes got %d", len(res))
+		return 0, fmt.Errorf("xbee.SerialNumber: %w: expected 4 bytes got %d", ErrMalformedFrame, len(res))
 	}
 	serial |= uint64(binary.BigEndian.Uint32(res))
-	return serial, nil
+	return Addr64(serial), nil
 }
 
 func (xb *XBee) NodeIdentifier() (string, error) {
@@ -512,7 +426,7 @@ func (xb *XBee) FirmwareVersion() (uint16, error) {
 		return 0, err
 	}
 	if len(b) != 2 {
-		return 0, fmt.Errorf("xbee.FirmwareVersion: expected 2 byte response got %d", len(b))
+		return 0, fmt.Errorf("xbee.FirmwareVersion: %w: expected 2 byte response got %d", ErrMalformedFrame, len(b))
 	}
 	return (uint16(b[0]) << 8) | uint16(b[1]), nil
 }
@@ -523,7 +437,7 @@ func (xb *XBee) HardwareVersion() (uint16, error) {
 		return 0, err
 	}
 	if len(b) != 2 {
-		return 0, fmt.Errorf("xbee.HardwareVersion: expected 2 byte response got %d", len(b))
+		return 0, fmt.Errorf("xbee.HardwareVersion: %w: expected 2 byte response got %d", ErrMalformedFrame, len(b))
 	}
 	return (uint16(b[0]) << 8) | uint16(b[1]), nil
 }
@@ -571,6 +485,9 @@ func (xb *XBee) SetInterfaceDataRate(baud int) error {
 }
 
 func (xb *XBee) ExtendedPANID() (uint64, error) {
+	if err := xb.require(FeatureExtendedPANID); err != nil {
+		return 0, err
+	}
 	b, err := xb.atCommand(atExtendedPANID, nil)
 	if err != nil {
 		return 0, err
@@ -579,6 +496,9 @@ func (xb *XBee) ExtendedPANID() (uint64, error) {
 }
 
 func (xb *XBee) SetExtendedPANID(id uint64) error {
+	if err := xb.require(FeatureExtendedPANID); err != nil {
+		return err
+	}
 	b := []byte{
 		byte(id >> 56),
 		byte(id >> 48),
@@ -594,6 +514,9 @@ func (xb *XBee) SetExtendedPANID(id uint64) error {
 }
 
 func (xb *XBee) OperatingExtendedPANID() (uint64, error) {
+	if err := xb.require(FeatureExtendedPANID); err != nil {
+		return 0, err
+	}
 	b, err := xb.atCommand(atOperatingExtendedPANID, nil)
 	if err != nil {
 		return 0, err
@@ -601,6 +524,52 @@ func (xb *XBee) OperatingExtendedPANID() (uint64, error) {
 	return uint64(decodeUint(b)), nil
 }
 
+// InitialID returns the 16-bit PAN ID the coordinator tries first when
+// forming a network (II).
+func (xb *XBee) InitialID() (uint16, error) {
+	b, err := xb.atCommand(atInitialID, nil)
+	if err != nil {
+		return 0, err
+	}
+	return uint16(decodeUint(b)), nil
+}
+
+// SetInitialID sets the 16-bit PAN ID the coordinator tries first when
+// forming a network (II). 0xFFFF selects a random PAN ID.
+func (xb *XBee) SetInitialID(id uint16) error {
+	if _, err := xb.checkATParam(atInitialID, uint64(id)); err != nil {
+		return err
+	}
+	_, err := xb.atCommand(atInitialID, []byte{byte(id >> 8), byte(id)})
+	return err
+}
+
+// NetworkWatchdogTimeout returns the network watchdog timeout (NW) or 0 if
+// it's disabled.
+func (xb *XBee) NetworkWatchdogTimeout() (time.Duration, error) {
+	b, err := xb.atCommand(atNetworkWatchdogTimeout, nil)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(decodeUint(b)) * time.Minute, nil
+}
+
+// SetNetworkWatchdogTimeout sets the network watchdog timeout (NW) of a
+// router. If it doesn't hear from the coordinator for three timeouts it
+// leaves the network to look for a new one. The timeout is rounded to
+// minutes up to 0x64FF minutes. 0 disables the watchdog.
+func (xb *XBee) SetNetworkWatchdogTimeout(d time.Duration) error {
+	m := d / time.Minute
+	if m < 0 || m > 0x64ff {
+		return fmt.Errorf("xbee.SetNetworkWatchdogTimeout: %w: invalid timeout %s", ErrInvalidParameter, d)
+	}
+	if _, err := xb.checkATParam(atNetworkWatchdogTimeout, uint64(m)); err != nil {
+		return err
+	}
+	_, err := xb.atCommand(atNetworkWatchdogTimeout, []byte{byte(m >> 8), byte(m)})
+	return err
+}
+
 func (xb *XBee) MaximumRFPayloadBytes() (int, error) {
 	b, err := xb.atCommand(atMaximumRFPayloadBytes, nil)
 	if err != nil {
@@ -635,6 +604,9 @@ func (xb *XBee) EncryptionOptions() (SecurityOption, error) {
 }
 
 func (xb *XBee) SetEncryptionOptions(opt SecurityOption) error {
+	if _, err := xb.checkATParam(atEncryptionOptions, uint64(opt)); err != nil {
+		return err
+	}
 	_, err := xb.atCommand(atEncryptionOptions, []byte{byte(opt)})
 	return err
 }
@@ -644,7 +616,7 @@ func (xb *XBee) SetNetworkEncryptionKey(key []byte) error {
 		// The xbee will set a random key when provided with zero key
 		key = make([]byte, 16)
 	} else if len(key) != 16 {
-		return fmt.Errorf("xbee.SetLinkKey: key must be 128-bits (16 bytes) not %d-bits", len(key)*8)
+		return fmt.Errorf("xbee.SetNetworkEncryptionKey: %w: key must be 128-bits (16 bytes) not %d-bits", ErrInvalidParameter, len(key)*8)
 	}
 	_, err := xb.atCommand(atNetworkEncryptionKey, key)
 	return err
@@ -652,7 +624,7 @@ func (xb *XBee) SetNetworkEncryptionKey(key []byte) error {
 
 func (xb *XBee) SetLinkKey(key []byte) error {
 	if len(key) != 16 {
-		return fmt.Errorf("xbee.SetLinkKey: key must be 128-bits (16 bytes) not %d-bits", len(key)*8)
+		return fmt.Errorf("xbee.SetLinkKey: %w: key must be 128-bits (16 bytes) not %d-bits", ErrInvalidParameter, len(key)*8)
 	}
 	_, err := xb.atCommand(atLinkKey, key)
 	return err
@@ -675,6 +647,9 @@ func (xb *XBee) NodeDiscoveryOptions() (NodeDiscoveryOption, error) {
 }
 
 func (xb *XBee) SetNodeDiscoveryOptions(o NodeDiscoveryOption) error {
+	if _, err := xb.checkATParam(atNodeDiscoveryOptions, uint64(o)); err != nil {
+		return err
+	}
 	_, err := xb.atCommand(atNodeDiscoveryOptions, []byte{byte(o)})
 	return err
 }
@@ -689,38 +664,29 @@ func (xb *XBee) Write() error {
 	return err
 }
 
+// SoftwareReset resets the module (FR). The module responds before
+// resetting about two seconds later.
+func (xb *XBee) SoftwareReset() error {
+	_, err := xb.atCommand(atSoftwareReset, nil)
+	return err
+}
+
+// RestoreDefaults restores the parameters to their factory defaults (RE).
+// The defaults aren't saved until Write and aren't used until they're
+// applied or the module is reset.
+func (xb *XBee) RestoreDefaults() error {
+	_, err := xb.atCommand(atRestoreDefaults, nil)
+	return err
+}
+
 func (xb *XBee) NodeDiscover(wait time.Duration) ([]*Node, error) {
-	frameID := xb.nextFrameID()
-	xb.wbuf[3] = frameATCommand
-	xb.wbuf[4] = frameID
-	xb.wbuf[5] = atNodeDiscover[0]
-	xb.wbuf[6] = atNodeDiscover[1]
-	ch := xb.registerListener(frameID)
-	defer xb.unregisterListener(frameID)
-	if err := xb.writeFrame(4); err != nil {
-		return nil, err
-	}
-	waitCh := time.After(wait)
 	var nodes []*Node
-	for {
-		var ev Event
-		select {
-		case <-waitCh:
-			return nodes, nil
-		case ev = <-ch:
-		}
-		res, ok := ev.(*ATCommandResponse)
-		if !ok {
-			return nil, fmt.Errorf("xbee: wrong frame, expected AT response got %T", ev)
-		}
-		if err := validateATResponse(atNodeDiscover, res); err != nil {
-			return nodes, err
-		}
-		if len(res.Data) < 18 {
-			return nodes, fmt.Errorf("xbee.NodeDiscover: device frame should be at least 18 bytes, got %d", len(res.Data))
+	err := xb.atCommandResponses(atNodeDiscover, wait, func(data []byte) error {
+		if len(data) < 18 {
+			return fmt.Errorf("xbee.NodeDiscover: %w: device frame should be at least 18 bytes, got %d", ErrMalformedFrame, len(data))
 		}
 
-		// 2 bytes for what?
+		// uint16 network address
 		// uint64 serial number
 		// zero terminated node identifier
 		// uint16 parent network address
@@ -729,280 +695,561 @@ func (xb *XBee) NodeDiscover(wait time.Duration) ([]*Node, error) {
 		// uint16 profile ID
 		// uint16 manufacturer ID
 
-		n := &Node{SerialNumber: decodeUint(res.Data[2:10])}
-		res.Data = res.Data[10:]
-		ix := bytes.IndexByte(res.Data, 0)
+		addr16 := Addr16(decodeUint(data[0:2]))
+		n := &Node{SerialNumber: Addr64(decodeUint(data[2:10]))}
+		data = data[10:]
+		ix := bytes.IndexByte(data, 0)
 		if ix < 0 {
-			return nodes, errors.New("xbee.NodeDiscover: null terminator not found for node identifier")
+			return fmt.Errorf("xbee.NodeDiscover: %w: null terminator not found for node identifier", ErrMalformedFrame)
 		}
-		n.NodeID = string(res.Data[:ix])
-		res.Data = res.Data[ix+1:]
-		n.ParentNetworkAddress = (uint16(res.Data[0]) << 8) | uint16(res.Data[1])
-		n.DeviceType = DeviceType(res.Data[2])
-		n.Status = res.Data[3]
-		n.ProfileID = (uint16(res.Data[4]) << 8) | uint16(res.Data[5])
-		n.ManufacturerID = (uint16(res.Data[6]) << 8) | uint16(res.Data[7])
+		n.NodeID = string(data[:ix])
+		data = data[ix+1:]
+		n.ParentNetworkAddress = Addr16((uint16(data[0]) << 8) | uint16(data[1]))
+		n.DeviceType = DeviceType(data[2])
+		n.Status = data[3]
+		n.ProfileID = (uint16(data[4]) << 8) | uint16(data[5])
+		n.ManufacturerID = (uint16(data[6]) << 8) | uint16(data[7])
+		xb.nodes.discovered(n, addr16)
 		nodes = append(nodes, n)
-	}
+		return nil
+	})
+	return nodes, err
 }
 
+// ActiveScan scans for networks (or access points on Wi-Fi modules)
+// collecting the responses until wait has elapsed.
 func (xb *XBee) ActiveScan(wait time.Duration) ([]*ActiveScanDevice, error) {
-	frameID := xb.nextFrameID()
-	xb.wbuf[3] = frameATCommand
-	xb.wbuf[4] = frameID
-	xb.wbuf[5] = atActiveScan[0]
-	xb.wbuf[6] = atActiveScan[1]
-	ch := xb.registerListener(frameID)
+	var devices []*ActiveScanDevice
+	err := xb.atCommandResponses(atActiveScan, wait, func(data []byte) error {
+		if len(data) > 0 && data[0] == activeScanTypeWiFi {
+			ap, err := decodeWiFiAccessPoint(data)
+			if err != nil {
+				return err
+			}
+			devices = append(devices, &ActiveScanDevice{Type: data[0], Channel: ap.Channel, AccessPoint: ap})
+			return nil
+		}
+		if len(data) < 16 {
+			return fmt.Errorf("xbee.ActiveScan: %w: device frame should be at least 16 bytes, got %d", ErrMalformedFrame, len(data))
+		}
+		if data[0] != activeScanTypeZigBee {
+			return fmt.Errorf("xbee.ActiveScan: %w: unknown AS type %d", ErrMalformedFrame, data[0])
+		}
+		devices = append(devices, &ActiveScanDevice{
+			Type:         data[0],
+			Channel:      data[1],
+			PAN:          uint16(decodeUint(data[2:4])),
+			ExtendedPAN:  uint64(decodeUint(data[4:12])),
+			AllowJoin:    data[12] != 0,
+			StackProfile: data[13],
+			LQI:          data[14],
+			RSSI:         int8(data[15]),
+		})
+		return nil
+	})
+	return devices, err
+}
+
+// atCommandResponses issues an AT command that has multiple responses (e.g.
+// ND and AS) calling fn with the data of each response until wait has
+// elapsed or an error occurs.
+func (xb *XBee) atCommandResponses(cmd ATCommand, wait time.Duration, fn func(data []byte) error) error {
+	frameID, ch := xb.registerListener()
 	defer xb.unregisterListener(frameID)
-	if err := xb.writeFrame(4); err != nil {
-		return nil, err
+	if err := xb.writeFrame(&frames.ATCommandRequest{FrameID: frameID, ATCommand: cmd}); err != nil {
+		return err
 	}
 	waitCh := time.After(wait)
-	var devices []*ActiveScanDevice
 	for {
 		var ev Event
 		select {
 		case <-waitCh:
-			return devices, nil
+			return nil
 		case ev = <-ch:
 		}
 		res, ok := ev.(*ATCommandResponse)
 		if !ok {
-			return nil, fmt.Errorf("xbee: wrong frame, expected AT response got %T", ev)
+			return wrongFrame("AT response", ev)
 		}
-		if err := validateATResponse(atActiveScan, res); err != nil {
-			return devices, err
+		if err := validateATResponse(cmd, res); err != nil {
+			return err
 		}
-		if len(res.Data) < 16 {
-			return devices, fmt.Errorf("xbee.ActiveScan: device frame should be at least 16 bytes, got %d", len(res.Data))
+		if err := fn(res.Data); err != nil {
+			return err
 		}
-		devices = append(devices, &ActiveScanDevice{
-			Type:         res.Data[0],
-			Channel:      res.Data[1],
-			PAN:          uint16(decodeUint(res.Data[2:4])),
-			ExtendedPAN:  uint64(decodeUint(res.Data[4:12])),
-			AllowJoin:    res.Data[12] != 0,
-			StackProfile: res.Data[13],
-			LQI:          res.Data[14],
-			RSSI:         int8(res.Data[15]),
-		})
 	}
 }
 
 func validateATResponse(cmd ATCommand, res *ATCommandResponse) error {
 	if res.ATCommand != cmd {
-		return fmt.Errorf("xbee: expected AT command response cmd %02x%02x got %02x%02x", cmd[0], cmd[1], res.ATCommand[0], res.ATCommand[1])
-	}
-	switch res.CommandStatus {
-	case CSOK: // OK
-	case CSError:
-		return ErrResponse
-	case CSInvalidCommand:
-		return ErrInvalidCommand(string(cmd[:]))
-	case CSInvalidParameter:
-		return ErrInvalidParameter
-	default:
-		return fmt.Errorf("xbee: unknown error %d", res.CommandStatus)
-	}
-	return nil
-}
-
-func (xb *XBee) Transmit(dest uint64, net uint16, broadcastRadius byte, options TransmitOption, data []byte) error {
-	if len(data) > 65536-20 {
-		return fmt.Errorf("xbee: data too long for transmit (%d bytes)", len(data))
-	}
-	frameID := xb.nextFrameID()
-	xb.wbuf[3] = frameZigBeeTransmitRequest
-	xb.wbuf[4] = frameID
-	xb.wbuf[5] = byte(dest >> 56)
-	xb.wbuf[6] = byte(dest >> 48)
-	xb.wbuf[7] = byte(dest >> 40)
-	xb.wbuf[8] = byte(dest >> 32)
-	xb.wbuf[9] = byte(dest >> 24)
-	xb.wbuf[10] = byte(dest >> 16)
-	xb.wbuf[11] = byte(dest >> 8)
-	xb.wbuf[12] = byte(dest & 0xff)
-	xb.wbuf[13] = byte(net >> 8)
-	xb.wbuf[14] = byte(net & 0xff)
-	xb.wbuf[15] = broadcastRadius
-	xb.wbuf[16] = byte(options)
-	copy(xb.wbuf[17:], data)
-	// ch := xb.registerListener(frameID)
-	if err := xb.writeFrame(14 + len(data)); err != nil {
-		return err
+		return wrongCommand(cmd, res.ATCommand)
+	}
+	return commandStatusError(cmd, res.CommandStatus)
+}
+
+func commandStatusError(cmd ATCommand, status CommandStatus) error {
+	if status == CSOK {
+		return nil
+	}
+	return &ATError{Command: cmd, Status: status}
+}
+
+// TxOption configures a transmit sent by Transmit.
+type TxOption func(*txOptions)
+
+type txOptions struct {
+	net     Addr16
+	radius  byte
+	options TransmitOption
+	noAck   bool
+	wait    bool         // set by WithRetry
+	retry   *RetryPolicy // nil for the defaults
+	ctx     context.Context
+}
+
+// WithBroadcastRadius sets the maximum number of hops for a broadcast. The
+// default of 0 uses the network's maximum (NH).
+func WithBroadcastRadius(hops byte) TxOption {
+	return func(o *txOptions) {
+		o.radius = hops
+	}
+}
+
+// WithTransmitOptions sets the transmit options such as
+// TOEnableAPSEncryption. The default of 0 uses the module's transmit
+// options (TO).
+func WithTransmitOptions(options TransmitOption) TxOption {
+	return func(o *txOptions) {
+		o.options = options
+	}
+}
+
+// WithNetworkAddress sets the 16-bit network address of the destination if
+// known which saves the module discovering it. The default is
+// Address16Unknown.
+func WithNetworkAddress(net Addr16) TxOption {
+	return func(o *txOptions) {
+		o.net = net
+	}
+}
+
+// WithAck sets whether the module reports a transmit status for the
+// transmit. The default is true. Without it the transmit doesn't use a
+// frame ID so it doesn't count against the in-flight limit.
+func WithAck(ack bool) TxOption {
+	return func(o *txOptions) {
+		o.noAck = !ack
+	}
+}
+
+// Transmit sends data to dest. It returns once the frame has been written
+// without waiting for the transmit status unless WithRetry is used.
+func (xb *XBee) Transmit(dest Addr64, data []byte, opts ...TxOption) error {
+	o := txOptions{net: Address16Unknown, ctx: context.Background()}
+	for _, fn := range opts {
+		fn(&o)
+	}
+	request := func(frameID byte) frames.Frame {
+		return &frames.TransmitRequest{
+			FrameID:              frameID,
+			DestinationAddress:   dest,
+			DestinationAddress16: o.net,
+			BroadcastRadius:      o.radius,
+			Options:              o.options,
+			Data:                 data,
+		}
+	}
+	if o.wait {
+		return o.retry.do(o.ctx, func() error {
+			return xb.transmitStatus(request)
+		})
 	}
-	// frame := <-ch
-	// xb.unregisterListener(frameID)
-	return nil
-	// if len(frame) < 5 {
-	// 	return nil, errors.New("xbee: short frame for AT command response")
-	// }
-	// if frame[0] != frameATCommandResponse {
-	// 	return nil, fmt.Errorf("xbee: expected AT command response frame type 0x%02x got 0x%02x", frameATCommandResponse, frame[0])
-	// }
-	// if frame[2] != cmd[0] || frame[3] != cmd[1] {
-	// 	return nil, fmt.Errorf("xbee: expected AT command response cmd %02x%02x got %02x%02x", cmd[0], cmd[1], frame[2], frame[3])
-	// }
-	// switch frame[4] {
-	// case 0: // Ok
-	// case 1:
-	// 	return nil, ErrResponse
-	// case 2:
-	// 	return nil, ErrInvalidCommand
-	// case 3:
-	// 	return nil, ErrInvalidParameter
-	// default:
-	// 	return nil, fmt.Errorf("xbee: unknown error %d", frame[4])
-	// }
-	// return frame[5:], nil
-}
-
-func (xb *XBee) registerListener(frameID byte) chan Event {
+	var frameID byte
+	if !o.noAck {
+		frameID = xb.nextFrameID()
+	}
+	return xb.writeFrame(request(frameID))
+}
+
+// TransmitRaw is Transmit with positional parameters.
+//
+// Deprecated: Use Transmit with WithNetworkAddress, WithBroadcastRadius,
+// and WithTransmitOptions.
+func (xb *XBee) TransmitRaw(dest Addr64, net Addr16, broadcastRadius byte, options TransmitOption, data []byte) error {
+	return xb.Transmit(dest, data, WithNetworkAddress(net), WithBroadcastRadius(broadcastRadius), WithTransmitOptions(options))
+}
+
+// TransmitBroadcast sends data to all devices on the network.
+func (xb *XBee) TransmitBroadcast(data []byte) error {
+	return xb.Transmit(AddressBroadcast, data)
+}
+
+// ExplicitAddress holds the application layer addressing used by
+// TransmitExplicit.
+type ExplicitAddress struct {
+	SourceEndpoint      byte
+	DestinationEndpoint byte
+	ClusterID           uint16
+	ProfileID           uint16
+}
+
+// DefaultExplicitAddress is the addressing used by Transmit (Digi data
+// endpoint, serial data cluster, and Digi profile).
+var DefaultExplicitAddress = ExplicitAddress{
+	SourceEndpoint:      EndpointDigiData,
+	DestinationEndpoint: EndpointDigiData,
+	ClusterID:           ClusterSerialData,
+	ProfileID:           ProfileDigi,
+}
+
+const (
+	EndpointZDO       byte   = 0x00
+	EndpointDigiData  byte   = 0xE8
+	ProfileZDO        uint16 = 0x0000
+	ProfileDigi       uint16 = 0xC105
+	ClusterSerialData uint16 = 0x0011
+	ClusterLoopback   uint16 = 0x0012
+
+	clusterDeviceAnnounce uint16 = 0x0013 // ZDO
+)
+
+// TransmitExplicit sends data using the explicit addressing command frame
+// which allows setting the endpoints, cluster, and profile.
+func (xb *XBee) TransmitExplicit(dest Addr64, net Addr16, addr ExplicitAddress, broadcastRadius byte, options TransmitOption, data []byte) error {
+	return xb.transmitExplicit(PriorityNormal, dest, net, addr, broadcastRadius, options, data)
+}
+
+func (xb *XBee) transmitExplicit(p Priority, dest Addr64, net Addr16, addr ExplicitAddress, broadcastRadius byte, options TransmitOption, data []byte) error {
+	return xb.writeFramePriority(&frames.ExplicitTransmitRequest{
+		FrameID:              xb.nextFrameID(),
+		DestinationAddress:   dest,
+		DestinationAddress16: net,
+		SourceEndpoint:       addr.SourceEndpoint,
+		DestinationEndpoint:  addr.DestinationEndpoint,
+		ClusterID:            addr.ClusterID,
+		ProfileID:            addr.ProfileID,
+		BroadcastRadius:      broadcastRadius,
+		Options:              options,
+		Data:                 data,
+	}, p)
+}
+
+// transmitExplicitStatus sends data to dest and waits for the transmit
+// status returning a *DeliveryError if it wasn't delivered.
+func (xb *XBee) transmitExplicitStatus(dest Addr64, addr ExplicitAddress, data []byte) error {
+	_, err := xb.transmitExplicitStatusFrame(dest, addr, data)
+	return err
+}
+
+// transmitExplicitStatusFrame is like transmitExplicitStatus returning the
+// transmit status of a delivered packet.
+func (xb *XBee) transmitExplicitStatusFrame(dest Addr64, addr ExplicitAddress, data []byte) (*TransmitStatus, error) {
+	return xb.transmitStatusFrame(func(frameID byte) frames.Frame {
+		return &frames.ExplicitTransmitRequest{
+			FrameID:              frameID,
+			DestinationAddress:   dest,
+			DestinationAddress16: Address16Unknown,
+			SourceEndpoint:       addr.SourceEndpoint,
+			DestinationEndpoint:  addr.DestinationEndpoint,
+			ClusterID:            addr.ClusterID,
+			ProfileID:            addr.ProfileID,
+			Data:                 data,
+		}
+	})
+}
+
+// transmitStatus writes the transmit request returned by fn and waits for
+// the transmit status returning a *DeliveryError if it wasn't delivered.
+func (xb *XBee) transmitStatus(fn func(frameID byte) frames.Frame) error {
+	_, err := xb.transmitStatusFrame(fn)
+	return err
+}
+
+func (xb *XBee) transmitStatusFrame(fn func(frameID byte) frames.Frame) (*TransmitStatus, error) {
+	ev, err := xb.request(remoteATTimeout, fn)
+	if err != nil {
+		return nil, err
+	}
+	ts, ok := ev.(*TransmitStatus)
+	if !ok {
+		return nil, wrongFrame("transmit status", ev)
+	}
+	if ts.DeliveryStatus != DSSuccess {
+		return nil, &DeliveryError{Status: ts.DeliveryStatus}
+	}
+	return ts, nil
+}
+
+// TransmitMulticast sends data to all members of a ZigBee group.
+func (xb *XBee) TransmitMulticast(group uint16, addr ExplicitAddress, data []byte) error {
+	return xb.TransmitExplicit(AddressUnknown, Addr16(group), addr, 0, TOMulticast, data)
+}
+
+// SendRawFrame writes a frame of the given type. The payload is the frame
+// specific fields (everything following the frame type) and must include
+// the frame ID for frame types that have one. The length, checksum, and
+// escaping are handled by the library.
+func (xb *XBee) SendRawFrame(frameType byte, payload []byte) error {
+	return xb.writeFrame(frames.UnknownFrame(append([]byte{frameType}, payload...)))
+}
+
+// RequestRawFrame writes a frame of the given type with a newly allocated
+// frame ID followed by payload, and waits up to timeout for the response
+// with the same frame ID. The frame type must be one whose first field is
+// the frame ID. A response of a type the library doesn't decode is
+// returned as a frames.UnknownFrame if its first field is the frame ID.
+func (xb *XBee) RequestRawFrame(frameType byte, payload []byte, timeout time.Duration) (Event, error) {
+	return xb.request(timeout, func(frameID byte) frames.Frame {
+		b := make([]byte, 0, 2+len(payload))
+		b = append(b, frameType, frameID)
+		return frames.UnknownFrame(append(b, payload...))
+	})
+}
+
+// request writes the frame returned by fn for a newly allocated frame ID
+// and waits up to timeout for the response.
+func (xb *XBee) request(timeout time.Duration, fn func(frameID byte) frames.Frame) (Event, error) {
+	frameID, ch := xb.registerListener()
+	defer xb.unregisterListener(frameID)
+	if err := xb.writeFrame(fn(frameID)); err != nil {
+		return nil, err
+	}
+	select {
+	case ev := <-ch:
+		return ev, nil
+	case <-time.After(timeout):
+		return nil, ErrTimeout
+	}
+}
+
+// registerListener allocates a frame ID and returns a channel on which the
+// response frames with that ID will be delivered.
+func (xb *XBee) registerListener() (byte, chan Event) {
 	xb.mu.Lock()
 	defer xb.mu.Unlock()
+	frameID := xb.nextFrameIDLocked()
 	ch := make(chan Event, 1)
 	xb.idMap[frameID] = ch
-	return ch
+	return frameID, ch
 }
 
-func (xb *XBee) unregisterListener(frameID byte) {
+// listening returns frameID if a listener is registered for it and 0
+// otherwise.
+func (xb *XBee) listening(frameID byte) byte {
 	xb.mu.Lock()
 	defer xb.mu.Unlock()
+	if xb.idMap[frameID] == nil {
+		return 0
+	}
+	return frameID
+}
+
+func (xb *XBee) unregisterListener(frameID byte) {
+	xb.mu.Lock()
 	delete(xb.idMap, frameID)
+	xb.mu.Unlock()
+	xb.endRequest(frameID, nil, ErrTimeout)
 }
 
+// nextFrameID allocates a frame ID for a request whose response isn't
+// waited for, e.g. the transmit status of a Transmit.
 func (xb *XBee) nextFrameID() byte {
-	xb.frameID++
-	if xb.frameID == 0 {
-		xb.frameID = 1
-	}
-	return xb.frameID
+	xb.mu.Lock()
+	defer xb.mu.Unlock()
+	return xb.nextFrameIDLocked()
 }
 
-func (xb *XBee) writeFrame(n int) error {
-	if n < 0 || n > 65535 {
-		return fmt.Errorf("xbee: cannot write frame of size %d", n)
-	}
-	// length of frame
-	xb.wbuf[1] = byte(n >> 8)
-	xb.wbuf[2] = byte(n & 0xff)
-	// calculate checksum
-	var checksum byte
-	for _, v := range xb.wbuf[3 : 3+n] {
-		checksum += v
+// nextFrameIDLocked returns the next frame ID skipping those a listener is
+// registered for so the response to another request isn't delivered to
+// it once the IDs wrap around.
+func (xb *XBee) nextFrameIDLocked() byte {
+	for i := 0; i < 255; i++ {
+		xb.frameID++
+		if xb.frameID == 0 {
+			xb.frameID = 1
+		}
+		if xb.idMap[xb.frameID] == nil {
+			break
+		}
 	}
-	xb.wbuf[3+n] = 0xff - checksum
-	_, err := xb.port.Write(xb.wbuf[:4+n])
-	return err
+	return xb.frameID
 }
 
 func (xb *XBee) readLoop() error {
-	rd := bufio.NewReader(xb.port)
-	buf := make([]byte, 256)
+	rd := frames.NewReader(xb.port)
+	rd.Escaped = xb.escaped
 	for {
-		if buf == nil {
-			buf = make([]byte, 256)
+		var buf *[]byte
+		var data []byte
+		var err error
+		if xb.pool != nil {
+			buf = xb.pool.get()
+			data, err = rd.ReadDataInto(*buf)
+			*buf = data[:0]
 		} else {
-			buf = buf[:cap(buf)]
+			data, err = rd.ReadData()
 		}
-
-		// Read frame delimiter
-		if b, err := rd.ReadByte(); err != nil {
+		if xb.tap != nil && data != nil {
+			if err := xb.tap.WriteRecord(frames.Received, data); err != nil {
+				xb.log.Warn("xbee: failed to record frame", "err", err)
+			}
+		}
+		if err == frames.ErrChecksum {
+			xb.log.Warn("xbee: bad frame checksum", "type", frameType(data))
+			xb.stats.checksumErrors.Add(1)
+			xb.stats.resyncs.Add(1)
+			xb.traceReceive(rd.Raw(), nil, err)
+			xb.sendEvent(&MalformedFrame{Data: data, Err: err})
+			continue
+		} else if err == frames.ErrTruncated {
+			xb.log.Warn("xbee: frame interrupted by delimiter")
+			xb.stats.resyncs.Add(1)
+			xb.traceReceive(rd.Raw(), nil, err)
+			xb.sendEvent(&MalformedFrame{Err: err})
+			if buf != nil {
+				xb.pool.put(buf)
+			}
+			continue
+		} else if err == frames.ErrEmpty {
+			xb.log.Warn("xbee: empty frame received")
+			xb.stats.decodeErrors.Add(1)
+			xb.traceReceive(rd.Raw(), nil, err)
+			xb.sendEvent(&MalformedFrame{Data: data, Err: err})
+			continue
+		} else if err != nil {
 			return err
-		} else if b != frameDelimiter {
-			log.Printf("xbee.readLoop: received 0x%02x while looking for frame delimiter\n", b)
+		}
+		f, err := frames.Decode(data)
+		xb.traceReceive(rd.Raw(), f, err)
+		if err != nil {
+			xb.log.Warn("xbee: failed to decode frame", "err", err)
+			xb.stats.decodeErrors.Add(1)
+			xb.sendEvent(&MalformedFrame{Data: data, Err: err})
 			continue
 		}
-		// Read frame length
-		buf = buf[:2]
-		if _, err := io.ReadFull(rd, buf); err != nil {
-			return err
+		xb.stats.frameReceived(f)
+		if rf := xb.filter.Load(); rf != nil && !rf.pass(f) {
+			xb.stats.filteredFrames.Add(1)
+			if buf != nil {
+				xb.pool.put(buf)
+			}
+			continue
 		}
-		frameLen := (int(buf[0]) << 8) | int(buf[1])
-		// +1 for checksum
-		if cap(buf) < frameLen+1 {
-			buf = make([]byte, frameLen+1)
-		} else {
-			buf = buf[:frameLen+1]
+		if xb.dedup != nil && !xb.dedup.check(f, time.Now()) {
+			xb.stats.duplicates.Add(1)
+			if buf != nil {
+				xb.pool.put(buf)
+			}
+			continue
 		}
-		if _, err := io.ReadFull(rd, buf); err != nil {
-			return err
+		if xb.recorder != nil && recorded(f) {
+			if err := xb.recorder.record(data, f); err != nil {
+				xb.log.Warn("xbee: failed to record frame", "err", err)
+			}
 		}
-		checksum := byte(0)
-		for _, b := range buf {
-			checksum += b
+		xb.addrs.frameReceived(f)
+		if addr, ok := xb.nodes.frameReceived(f); ok && xb.rssiCh != nil {
+			xb.queueRSSI(addr)
 		}
-		if checksum != 0xff {
-			log.Printf("xbee: bad frame checksum %02x\n", checksum)
-		} else if frameLen < 2 {
-			// Normal frames have at least 2 bytes for type and ID
-			log.Println("xbee: tiny frame received")
+
+		var frameID byte
+		if idf, ok := f.(frames.Identified); ok {
+			frameID = idf.ID()
+		} else if u, ok := f.(frames.UnknownFrame); ok && len(u) > 1 {
+			// A frame of a type the library doesn't decode is
+			// assumed to carry a frame ID after the frame type if
+			// a request is waiting for one, e.g. RequestRawFrame.
+			frameID = xb.listening(u[1])
+		}
+		if frameID != 0 {
+			xb.endRequest(frameID, f, nil)
+		}
+		var ch chan Event
+		matched := false
+		xb.mu.Lock()
+		if frameID != 0 {
+			ch = xb.idMap[frameID]
+			xb.releaseInFlightLocked(frameID)
 		} else {
-			buf = buf[:len(buf)-1]
-
-			var frameID byte
-			var ev Event
-			switch buf[0] {
-			case frameModemStatus:
-				ev = ModemStatus(buf[1])
-			case frameATCommandResponse:
-				frameID = buf[1]
-				ev = &ATCommandResponse{
-					ATCommand:     ATCommand([2]byte{buf[2], buf[3]}),
-					CommandStatus: CommandStatus(buf[4]),
-					Data:          buf[5:],
-				}
-				if len(buf) > 5 {
-					buf = nil
-				}
-			case frameZigBeeTransmitStatus:
-				frameID = buf[1]
-				ev = &TransmitStatus{
-					DestinationAddress: (uint16(buf[2]) << 8) | uint16(buf[3]),
-					RetryCount:         int(buf[4]),
-					DeliveryStatus:     DeliveryStatus(buf[5]),
-					DiscoveryStatus:    DiscoveryStatus(buf[6]),
+			for _, o := range xb.observers {
+				if o.match(f) {
+					o.push(f)
 				}
-			case frameZigBeeReceivePacket:
-				ev = &ReceivePacket{
-					SourceAddress: (uint64(buf[1]) << 56) | (uint64(buf[2]) << 48) |
-						(uint64(buf[3]) << 40) | (uint64(buf[4]) << 32) |
-						(uint64(buf[5]) << 24) | (uint64(buf[6]) << 16) |
-						(uint64(buf[7]) << 8) | uint64(buf[8]),
-					SourceAddress16: (uint16(buf[9]) << 8) | uint16(buf[10]),
-					ReceiveOptions:  ReceiveOption(buf[11]),
-					Data:            buf[12:],
+			}
+			for _, m := range xb.matchers {
+				if m.match(f) {
+					m.push(f)
+					matched = true
+					break
 				}
-				buf = nil
-			default:
-				ev = UnknownFrame(buf)
-				buf = nil
 			}
-
-			var ch chan Event
-			if frameID != 0 {
-				xb.mu.Lock()
-				ch = xb.idMap[frameID]
-				xb.mu.Unlock()
+		}
+		xb.mu.Unlock()
+		if ch != nil {
+			select {
+			case ch <- f:
+			default:
+				// A listener waits for a single response so this is
+				// a duplicate or one for an earlier use of the ID.
+				xb.log.Warn("xbee: unexpected response", "frame", fmt.Sprintf("%T", f), "id", frameID)
+				xb.stats.droppedEvents.Add(1)
 			}
-			if ch != nil {
-				select {
-				case ch <- ev:
-				default:
-					// Should never happen but better to be safe
-					log.Println("xbee: internal event channel full")
-				}
-			} else {
-				select {
-				case xb.eventCh <- ev:
-				default:
-					log.Println("xbee: event channel full")
-				}
+		} else if !matched {
+			leased := buf != nil && xb.pool.lease(f, buf)
+			if !xb.sendEvent(f) && leased {
+				xb.pool.release(f)
 			}
 		}
 	}
 }
 
+// deliverEvent delivers an event to EventChan returning false if it was
+// dropped because the event channel is full or the XBee is closed.
+func (xb *XBee) deliverEvent(ev Event) bool {
+	xb.emu.RLock()
+	defer xb.emu.RUnlock()
+	if xb.eventsDone {
+		return false
+	}
+	select {
+	case <-xb.closed:
+		return false
+	default:
+	}
+	select {
+	case xb.eventCh <- ev:
+		return true
+	default:
+		xb.log.Warn("xbee: event channel full, dropping event", "frame", fmt.Sprintf("%T", ev))
+		xb.stats.droppedEvents.Add(1)
+		return false
+	}
+}
+
+// closeEvents closes EventChan once the read loop stops. Events are also
+// redelivered from other goroutines (see unregisterAndRedeliver) so it's
+// closed holding emu.
+func (xb *XBee) closeEvents() {
+	xb.emu.Lock()
+	defer xb.emu.Unlock()
+	xb.eventsDone = true
+	close(xb.eventCh)
+}
+
+func (xb *XBee) traceReceive(raw []byte, f frames.Frame, err error) {
+	if xb.tracer != nil {
+		xb.tracer.OnReceive(raw, f, err)
+	}
+}
+
+// frameType returns the frame type of frame data for logging.
+func frameType(data []byte) string {
+	if len(data) == 0 {
+		return "none"
+	}
+	return fmt.Sprintf("0x%02x", data[0])
+}
+
 func decodeUint(b []byte) uint64 {
 	var v uint64
 	for _, b := range b {
@@ -0,0 +1,81 @@
+package xbee_test
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/samuel/go-xbee/xbee"
+	"github.com/samuel/go-xbee/xbee/xbeetest"
+)
+
+const (
+	coordAddr  xbee.Addr64 = 0x0013a20040000001
+	routerAddr xbee.Addr64 = 0x0013a20040000002
+)
+
+// openPAN returns a coordinator and a router on a simulated network with
+// explicit receive (AO=1) enabled. They're closed when the test ends.
+func openPAN(t *testing.T) (coord, router *xbee.XBee) {
+	t.Helper()
+	nw := xbeetest.NewNetwork()
+	coord = openRadio(t, nw.AddRadio(coordAddr, xbee.Coordinator, "coord"))
+	router = openRadio(t, nw.AddRadio(routerAddr, xbee.Router, "router"))
+	return coord, router
+}
+
+func openRadio(t *testing.T, r *xbeetest.Radio) *xbee.XBee {
+	t.Helper()
+	r.SetRegister("AO", []byte{1})
+	xb, err := xbee.Open(r, xbee.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		xb.Close()
+		r.Close()
+		<-xb.Done()
+	})
+	return xb
+}
+
+// TestCloseWhileReceiving closes the XBee while frames are still arriving
+// and nothing reads EventChan.
+func TestCloseWhileReceiving(t *testing.T) {
+	r := xbeetest.NewRadio()
+	xb, err := xbee.Open(r, xbee.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := make(chan struct{})
+	injected := make(chan struct{})
+	go func() {
+		defer close(injected)
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if r.InjectReceive(routerAddr, 1, []byte{byte(i)}) != nil {
+				return
+			}
+		}
+	}()
+	time.Sleep(10 * time.Millisecond)
+	xb.Close()
+	time.Sleep(10 * time.Millisecond)
+	close(stop)
+	<-injected
+	r.Close()
+
+	select {
+	case <-xb.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("read loop didn't stop")
+	}
+	for range xb.EventChan() {
+		// Drain the events delivered before Close.
+	}
+}
@@ -0,0 +1,191 @@
+// Package xbeegrpc serves an xbee.XBee as the gRPC service defined in
+// xbee.proto so systems in any language can share one physical
+// coordinator:
+//
+//	s := xbeegrpc.NewServer(xb)
+//	go func() {
+//		for ev := range xb.EventChan() {
+//			s.Publish(ev)
+//		}
+//	}()
+//	gs := grpc.NewServer()
+//	s.Register(gs)
+//	gs.Serve(lis)
+//
+// The messages and service stubs are generated from xbee.proto so Go
+// clients use NewXBeeClient and clients in other languages are generated
+// from the same file.
+package xbeegrpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative xbee.proto
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samuel/go-xbee/xbee"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultDiscoverTimeout = 6 * time.Second
+	maxDiscoverTimeout     = time.Minute
+	eventQueueLen          = 32
+)
+
+// Server implements XBeeServer for an XBee.
+type Server struct {
+	UnimplementedXBeeServer
+
+	xb *xbee.XBee
+
+	mu      sync.Mutex
+	clients map[chan xbee.Event]struct{}
+}
+
+var _ XBeeServer = (*Server)(nil)
+
+// NewServer returns the service for xb. Events are only streamed once
+// passed to Publish.
+func NewServer(xb *xbee.XBee) *Server {
+	return &Server{xb: xb, clients: make(map[chan xbee.Event]struct{})}
+}
+
+// Register registers the service with a gRPC server.
+func (s *Server) Register(r grpc.ServiceRegistrar) {
+	RegisterXBeeServer(r, s)
+}
+
+// Publish sends ev to the clients streaming events. Events are dropped for
+// clients that aren't keeping up rather than blocking.
+func (s *Server) Publish(ev xbee.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.clients {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+func (s *Server) Transmit(ctx context.Context, req *TransmitRequest) (*TransmitResponse, error) {
+	if err := s.xb.Transmit(xbee.Addr64(req.Address), req.Data, xbee.WithRetry(nil), xbee.WithContext(ctx)); err != nil {
+		return nil, statusError(err)
+	}
+	return &TransmitResponse{}, nil
+}
+
+func (s *Server) ATCommand(ctx context.Context, req *ATCommandRequest) (*ATCommandResponse, error) {
+	var cmd xbee.ATCommand
+	if err := cmd.UnmarshalText([]byte(req.Command)); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	var res []byte
+	var err error
+	if req.Local {
+		res, err = s.xb.ATCommand(cmd, req.Parameter)
+	} else {
+		var opts xbee.RemoteATCommandOption
+		if req.Apply {
+			opts = xbee.RATOApplyChanges
+		}
+		res, err = s.xb.RemoteATCommand(xbee.Addr64(req.Address), xbee.Address16Unknown, cmd, req.Parameter, opts)
+	}
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &ATCommandResponse{Value: res}, nil
+}
+
+func (s *Server) Discover(ctx context.Context, req *DiscoverRequest) (*DiscoverResponse, error) {
+	timeout := defaultDiscoverTimeout
+	if req.TimeoutMs > 0 {
+		timeout = min(time.Duration(req.TimeoutMs)*time.Millisecond, maxDiscoverTimeout)
+	}
+	nodes, err := s.xb.NodeDiscover(timeout)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	res := &DiscoverResponse{}
+	for _, n := range nodes {
+		res.Nodes = append(res.Nodes, &Node{
+			Address:              uint64(n.SerialNumber),
+			NodeId:               n.NodeID,
+			ParentNetworkAddress: uint32(n.ParentNetworkAddress),
+			DeviceType:           n.DeviceType.String(),
+			ProfileId:            uint32(n.ProfileID),
+			ManufacturerId:       uint32(n.ManufacturerID),
+		})
+	}
+	return res, nil
+}
+
+func (s *Server) Events(req *EventsRequest, stream XBee_EventsServer) error {
+	ch := make(chan xbee.Event, eventQueueLen)
+	s.mu.Lock()
+	s.clients[ch] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, ch)
+		s.mu.Unlock()
+	}()
+	for {
+		select {
+		case ev := <-ch:
+			if err := stream.Send(newEvent(ev)); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+// newEvent converts an event to its message.
+func newEvent(ev xbee.Event) *Event {
+	name := fmt.Sprintf("%T", ev)
+	m := &Event{Type: name[strings.LastIndexByte(name, '.')+1:]}
+	switch ev := ev.(type) {
+	case *xbee.ReceivePacket:
+		m.Source, m.Data = uint64(ev.SourceAddress), ev.Data
+	case *xbee.ExplicitReceivePacket:
+		m.Source, m.Data = uint64(ev.SourceAddress), ev.Data
+	case *xbee.IODataSampleIndicator:
+		m.Source = uint64(ev.SourceAddress)
+	}
+	if b, err := json.Marshal(ev); err == nil {
+		m.Json = string(b)
+	}
+	return m
+}
+
+// statusError converts an error from the XBee to a gRPC status.
+func statusError(err error) error {
+	var atErr *xbee.ATError
+	var deliveryErr *xbee.DeliveryError
+	code := codes.Internal
+	switch {
+	case errors.Is(err, xbee.ErrInvalidParameter):
+		code = codes.InvalidArgument
+	case errors.As(err, &atErr) && atErr.Status == xbee.CSInvalidCommand:
+		code = codes.Unimplemented
+	case errors.As(err, &atErr), errors.As(err, &deliveryErr):
+		code = codes.Unavailable
+	case errors.Is(err, xbee.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		code = codes.DeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		code = codes.Canceled
+	case errors.Is(err, xbee.ErrClosed):
+		code = codes.Unavailable
+	}
+	return status.Error(code, err.Error())
+}
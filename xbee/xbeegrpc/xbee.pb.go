@@ -0,0 +1,629 @@
+// Service exposing an XBee module so systems in any language can share one
+// coordinator. Addresses are 64-bit IEEE addresses.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: xbee.proto
+
+package xbeegrpc
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type TransmitRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Address       uint64                 `protobuf:"fixed64,1,opt,name=address,proto3" json:"address,omitempty"`
+	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransmitRequest) Reset() {
+	*x = TransmitRequest{}
+	mi := &file_xbee_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransmitRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransmitRequest) ProtoMessage() {}
+
+func (x *TransmitRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_xbee_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransmitRequest.ProtoReflect.Descriptor instead.
+func (*TransmitRequest) Descriptor() ([]byte, []int) {
+	return file_xbee_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *TransmitRequest) GetAddress() uint64 {
+	if x != nil {
+		return x.Address
+	}
+	return 0
+}
+
+func (x *TransmitRequest) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type TransmitResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransmitResponse) Reset() {
+	*x = TransmitResponse{}
+	mi := &file_xbee_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransmitResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransmitResponse) ProtoMessage() {}
+
+func (x *TransmitResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_xbee_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransmitResponse.ProtoReflect.Descriptor instead.
+func (*TransmitResponse) Descriptor() ([]byte, []int) {
+	return file_xbee_proto_rawDescGZIP(), []int{1}
+}
+
+type ATCommandRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Address of the remote node, ignored if local is set.
+	Address uint64 `protobuf:"fixed64,1,opt,name=address,proto3" json:"address,omitempty"`
+	Local   bool   `protobuf:"varint,2,opt,name=local,proto3" json:"local,omitempty"`
+	// Two character command, e.g. "NI".
+	Command string `protobuf:"bytes,3,opt,name=command,proto3" json:"command,omitempty"`
+	// Empty to read the register.
+	Parameter []byte `protobuf:"bytes,4,opt,name=parameter,proto3" json:"parameter,omitempty"`
+	// Apply the change on a remote node.
+	Apply         bool `protobuf:"varint,5,opt,name=apply,proto3" json:"apply,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ATCommandRequest) Reset() {
+	*x = ATCommandRequest{}
+	mi := &file_xbee_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ATCommandRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ATCommandRequest) ProtoMessage() {}
+
+func (x *ATCommandRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_xbee_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ATCommandRequest.ProtoReflect.Descriptor instead.
+func (*ATCommandRequest) Descriptor() ([]byte, []int) {
+	return file_xbee_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ATCommandRequest) GetAddress() uint64 {
+	if x != nil {
+		return x.Address
+	}
+	return 0
+}
+
+func (x *ATCommandRequest) GetLocal() bool {
+	if x != nil {
+		return x.Local
+	}
+	return false
+}
+
+func (x *ATCommandRequest) GetCommand() string {
+	if x != nil {
+		return x.Command
+	}
+	return ""
+}
+
+func (x *ATCommandRequest) GetParameter() []byte {
+	if x != nil {
+		return x.Parameter
+	}
+	return nil
+}
+
+func (x *ATCommandRequest) GetApply() bool {
+	if x != nil {
+		return x.Apply
+	}
+	return false
+}
+
+type ATCommandResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Value         []byte                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ATCommandResponse) Reset() {
+	*x = ATCommandResponse{}
+	mi := &file_xbee_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ATCommandResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ATCommandResponse) ProtoMessage() {}
+
+func (x *ATCommandResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_xbee_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ATCommandResponse.ProtoReflect.Descriptor instead.
+func (*ATCommandResponse) Descriptor() ([]byte, []int) {
+	return file_xbee_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ATCommandResponse) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+type DiscoverRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// How long to wait for responses, 0 for 6 seconds.
+	TimeoutMs     uint32 `protobuf:"varint,1,opt,name=timeout_ms,json=timeoutMs,proto3" json:"timeout_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DiscoverRequest) Reset() {
+	*x = DiscoverRequest{}
+	mi := &file_xbee_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DiscoverRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DiscoverRequest) ProtoMessage() {}
+
+func (x *DiscoverRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_xbee_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DiscoverRequest.ProtoReflect.Descriptor instead.
+func (*DiscoverRequest) Descriptor() ([]byte, []int) {
+	return file_xbee_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *DiscoverRequest) GetTimeoutMs() uint32 {
+	if x != nil {
+		return x.TimeoutMs
+	}
+	return 0
+}
+
+type DiscoverResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Nodes         []*Node                `protobuf:"bytes,1,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DiscoverResponse) Reset() {
+	*x = DiscoverResponse{}
+	mi := &file_xbee_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DiscoverResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DiscoverResponse) ProtoMessage() {}
+
+func (x *DiscoverResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_xbee_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DiscoverResponse.ProtoReflect.Descriptor instead.
+func (*DiscoverResponse) Descriptor() ([]byte, []int) {
+	return file_xbee_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *DiscoverResponse) GetNodes() []*Node {
+	if x != nil {
+		return x.Nodes
+	}
+	return nil
+}
+
+type Node struct {
+	state                protoimpl.MessageState `protogen:"open.v1"`
+	Address              uint64                 `protobuf:"fixed64,1,opt,name=address,proto3" json:"address,omitempty"`
+	NodeId               string                 `protobuf:"bytes,2,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	ParentNetworkAddress uint32                 `protobuf:"varint,3,opt,name=parent_network_address,json=parentNetworkAddress,proto3" json:"parent_network_address,omitempty"`
+	// Coordinator, Router, or EndDevice.
+	DeviceType     string `protobuf:"bytes,4,opt,name=device_type,json=deviceType,proto3" json:"device_type,omitempty"`
+	ProfileId      uint32 `protobuf:"varint,5,opt,name=profile_id,json=profileId,proto3" json:"profile_id,omitempty"`
+	ManufacturerId uint32 `protobuf:"varint,6,opt,name=manufacturer_id,json=manufacturerId,proto3" json:"manufacturer_id,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Node) Reset() {
+	*x = Node{}
+	mi := &file_xbee_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Node) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Node) ProtoMessage() {}
+
+func (x *Node) ProtoReflect() protoreflect.Message {
+	mi := &file_xbee_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Node.ProtoReflect.Descriptor instead.
+func (*Node) Descriptor() ([]byte, []int) {
+	return file_xbee_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Node) GetAddress() uint64 {
+	if x != nil {
+		return x.Address
+	}
+	return 0
+}
+
+func (x *Node) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *Node) GetParentNetworkAddress() uint32 {
+	if x != nil {
+		return x.ParentNetworkAddress
+	}
+	return 0
+}
+
+func (x *Node) GetDeviceType() string {
+	if x != nil {
+		return x.DeviceType
+	}
+	return ""
+}
+
+func (x *Node) GetProfileId() uint32 {
+	if x != nil {
+		return x.ProfileId
+	}
+	return 0
+}
+
+func (x *Node) GetManufacturerId() uint32 {
+	if x != nil {
+		return x.ManufacturerId
+	}
+	return 0
+}
+
+type EventsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EventsRequest) Reset() {
+	*x = EventsRequest{}
+	mi := &file_xbee_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EventsRequest) ProtoMessage() {}
+
+func (x *EventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_xbee_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EventsRequest.ProtoReflect.Descriptor instead.
+func (*EventsRequest) Descriptor() ([]byte, []int) {
+	return file_xbee_proto_rawDescGZIP(), []int{7}
+}
+
+type Event struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Go type of the event without the package, e.g. "ReceivePacket".
+	Type string `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	// Source address of received packets and samples, 0 otherwise.
+	Source uint64 `protobuf:"fixed64,2,opt,name=source,proto3" json:"source,omitempty"`
+	// Payload of received packets.
+	Data []byte `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	// The whole event encoded as JSON.
+	Json          string `protobuf:"bytes,4,opt,name=json,proto3" json:"json,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_xbee_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_xbee_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_xbee_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Event) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Event) GetSource() uint64 {
+	if x != nil {
+		return x.Source
+	}
+	return 0
+}
+
+func (x *Event) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *Event) GetJson() string {
+	if x != nil {
+		return x.Json
+	}
+	return ""
+}
+
+var File_xbee_proto protoreflect.FileDescriptor
+
+const file_xbee_proto_rawDesc = "" +
+	"\n" +
+	"\n" +
+	"xbee.proto\x12\x04xbee\"?\n" +
+	"\x0fTransmitRequest\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\x06R\aaddress\x12\x12\n" +
+	"\x04data\x18\x02 \x01(\fR\x04data\"\x12\n" +
+	"\x10TransmitResponse\"\x90\x01\n" +
+	"\x10ATCommandRequest\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\x06R\aaddress\x12\x14\n" +
+	"\x05local\x18\x02 \x01(\bR\x05local\x12\x18\n" +
+	"\acommand\x18\x03 \x01(\tR\acommand\x12\x1c\n" +
+	"\tparameter\x18\x04 \x01(\fR\tparameter\x12\x14\n" +
+	"\x05apply\x18\x05 \x01(\bR\x05apply\")\n" +
+	"\x11ATCommandResponse\x12\x14\n" +
+	"\x05value\x18\x01 \x01(\fR\x05value\"0\n" +
+	"\x0fDiscoverRequest\x12\x1d\n" +
+	"\n" +
+	"timeout_ms\x18\x01 \x01(\rR\ttimeoutMs\"4\n" +
+	"\x10DiscoverResponse\x12 \n" +
+	"\x05nodes\x18\x01 \x03(\v2\n" +
+	".xbee.NodeR\x05nodes\"\xd8\x01\n" +
+	"\x04Node\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\x06R\aaddress\x12\x17\n" +
+	"\anode_id\x18\x02 \x01(\tR\x06nodeId\x124\n" +
+	"\x16parent_network_address\x18\x03 \x01(\rR\x14parentNetworkAddress\x12\x1f\n" +
+	"\vdevice_type\x18\x04 \x01(\tR\n" +
+	"deviceType\x12\x1d\n" +
+	"\n" +
+	"profile_id\x18\x05 \x01(\rR\tprofileId\x12'\n" +
+	"\x0fmanufacturer_id\x18\x06 \x01(\rR\x0emanufacturerId\"\x0f\n" +
+	"\rEventsRequest\"[\n" +
+	"\x05Event\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x16\n" +
+	"\x06source\x18\x02 \x01(\x06R\x06source\x12\x12\n" +
+	"\x04data\x18\x03 \x01(\fR\x04data\x12\x12\n" +
+	"\x04json\x18\x04 \x01(\tR\x04json2\xe8\x01\n" +
+	"\x04XBee\x129\n" +
+	"\bTransmit\x12\x15.xbee.TransmitRequest\x1a\x16.xbee.TransmitResponse\x12<\n" +
+	"\tATCommand\x12\x16.xbee.ATCommandRequest\x1a\x17.xbee.ATCommandResponse\x129\n" +
+	"\bDiscover\x12\x15.xbee.DiscoverRequest\x1a\x16.xbee.DiscoverResponse\x12,\n" +
+	"\x06Events\x12\x13.xbee.EventsRequest\x1a\v.xbee.Event0\x01B)Z'github.com/samuel/go-xbee/xbee/xbeegrpcb\x06proto3"
+
+var (
+	file_xbee_proto_rawDescOnce sync.Once
+	file_xbee_proto_rawDescData []byte
+)
+
+func file_xbee_proto_rawDescGZIP() []byte {
+	file_xbee_proto_rawDescOnce.Do(func() {
+		file_xbee_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_xbee_proto_rawDesc), len(file_xbee_proto_rawDesc)))
+	})
+	return file_xbee_proto_rawDescData
+}
+
+var file_xbee_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_xbee_proto_goTypes = []any{
+	(*TransmitRequest)(nil),   // 0: xbee.TransmitRequest
+	(*TransmitResponse)(nil),  // 1: xbee.TransmitResponse
+	(*ATCommandRequest)(nil),  // 2: xbee.ATCommandRequest
+	(*ATCommandResponse)(nil), // 3: xbee.ATCommandResponse
+	(*DiscoverRequest)(nil),   // 4: xbee.DiscoverRequest
+	(*DiscoverResponse)(nil),  // 5: xbee.DiscoverResponse
+	(*Node)(nil),              // 6: xbee.Node
+	(*EventsRequest)(nil),     // 7: xbee.EventsRequest
+	(*Event)(nil),             // 8: xbee.Event
+}
+var file_xbee_proto_depIdxs = []int32{
+	6, // 0: xbee.DiscoverResponse.nodes:type_name -> xbee.Node
+	0, // 1: xbee.XBee.Transmit:input_type -> xbee.TransmitRequest
+	2, // 2: xbee.XBee.ATCommand:input_type -> xbee.ATCommandRequest
+	4, // 3: xbee.XBee.Discover:input_type -> xbee.DiscoverRequest
+	7, // 4: xbee.XBee.Events:input_type -> xbee.EventsRequest
+	1, // 5: xbee.XBee.Transmit:output_type -> xbee.TransmitResponse
+	3, // 6: xbee.XBee.ATCommand:output_type -> xbee.ATCommandResponse
+	5, // 7: xbee.XBee.Discover:output_type -> xbee.DiscoverResponse
+	8, // 8: xbee.XBee.Events:output_type -> xbee.Event
+	5, // [5:9] is the sub-list for method output_type
+	1, // [1:5] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_xbee_proto_init() }
+func file_xbee_proto_init() {
+	if File_xbee_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_xbee_proto_rawDesc), len(file_xbee_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   9,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_xbee_proto_goTypes,
+		DependencyIndexes: file_xbee_proto_depIdxs,
+		MessageInfos:      file_xbee_proto_msgTypes,
+	}.Build()
+	File_xbee_proto = out.File
+	file_xbee_proto_goTypes = nil
+	file_xbee_proto_depIdxs = nil
+}
@@ -0,0 +1,252 @@
+// Service exposing an XBee module so systems in any language can share one
+// coordinator. Addresses are 64-bit IEEE addresses.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: xbee.proto
+
+package xbeegrpc
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	XBee_Transmit_FullMethodName  = "/xbee.XBee/Transmit"
+	XBee_ATCommand_FullMethodName = "/xbee.XBee/ATCommand"
+	XBee_Discover_FullMethodName  = "/xbee.XBee/Discover"
+	XBee_Events_FullMethodName    = "/xbee.XBee/Events"
+)
+
+// XBeeClient is the client API for XBee service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type XBeeClient interface {
+	// Transmit sends data to a node and waits for it to be delivered.
+	Transmit(ctx context.Context, in *TransmitRequest, opts ...grpc.CallOption) (*TransmitResponse, error)
+	// ATCommand reads or sets a register of the local module or a remote
+	// node.
+	ATCommand(ctx context.Context, in *ATCommandRequest, opts ...grpc.CallOption) (*ATCommandResponse, error)
+	// Discover runs node discovery (ND).
+	Discover(ctx context.Context, in *DiscoverRequest, opts ...grpc.CallOption) (*DiscoverResponse, error)
+	// Events streams the events received by the module.
+	Events(ctx context.Context, in *EventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error)
+}
+
+type xBeeClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewXBeeClient(cc grpc.ClientConnInterface) XBeeClient {
+	return &xBeeClient{cc}
+}
+
+func (c *xBeeClient) Transmit(ctx context.Context, in *TransmitRequest, opts ...grpc.CallOption) (*TransmitResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TransmitResponse)
+	err := c.cc.Invoke(ctx, XBee_Transmit_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *xBeeClient) ATCommand(ctx context.Context, in *ATCommandRequest, opts ...grpc.CallOption) (*ATCommandResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ATCommandResponse)
+	err := c.cc.Invoke(ctx, XBee_ATCommand_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *xBeeClient) Discover(ctx context.Context, in *DiscoverRequest, opts ...grpc.CallOption) (*DiscoverResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DiscoverResponse)
+	err := c.cc.Invoke(ctx, XBee_Discover_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *xBeeClient) Events(ctx context.Context, in *EventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &XBee_ServiceDesc.Streams[0], XBee_Events_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[EventsRequest, Event]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type XBee_EventsClient = grpc.ServerStreamingClient[Event]
+
+// XBeeServer is the server API for XBee service.
+// All implementations must embed UnimplementedXBeeServer
+// for forward compatibility.
+type XBeeServer interface {
+	// Transmit sends data to a node and waits for it to be delivered.
+	Transmit(context.Context, *TransmitRequest) (*TransmitResponse, error)
+	// ATCommand reads or sets a register of the local module or a remote
+	// node.
+	ATCommand(context.Context, *ATCommandRequest) (*ATCommandResponse, error)
+	// Discover runs node discovery (ND).
+	Discover(context.Context, *DiscoverRequest) (*DiscoverResponse, error)
+	// Events streams the events received by the module.
+	Events(*EventsRequest, grpc.ServerStreamingServer[Event]) error
+	mustEmbedUnimplementedXBeeServer()
+}
+
+// UnimplementedXBeeServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedXBeeServer struct{}
+
+func (UnimplementedXBeeServer) Transmit(context.Context, *TransmitRequest) (*TransmitResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Transmit not implemented")
+}
+func (UnimplementedXBeeServer) ATCommand(context.Context, *ATCommandRequest) (*ATCommandResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ATCommand not implemented")
+}
+func (UnimplementedXBeeServer) Discover(context.Context, *DiscoverRequest) (*DiscoverResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Discover not implemented")
+}
+func (UnimplementedXBeeServer) Events(*EventsRequest, grpc.ServerStreamingServer[Event]) error {
+	return status.Errorf(codes.Unimplemented, "method Events not implemented")
+}
+func (UnimplementedXBeeServer) mustEmbedUnimplementedXBeeServer() {}
+func (UnimplementedXBeeServer) testEmbeddedByValue()              {}
+
+// UnsafeXBeeServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to XBeeServer will
+// result in compilation errors.
+type UnsafeXBeeServer interface {
+	mustEmbedUnimplementedXBeeServer()
+}
+
+func RegisterXBeeServer(s grpc.ServiceRegistrar, srv XBeeServer) {
+	// If the following call pancis, it indicates UnimplementedXBeeServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&XBee_ServiceDesc, srv)
+}
+
+func _XBee_Transmit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransmitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(XBeeServer).Transmit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: XBee_Transmit_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(XBeeServer).Transmit(ctx, req.(*TransmitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _XBee_ATCommand_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ATCommandRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(XBeeServer).ATCommand(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: XBee_ATCommand_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(XBeeServer).ATCommand(ctx, req.(*ATCommandRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _XBee_Discover_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DiscoverRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(XBeeServer).Discover(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: XBee_Discover_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(XBeeServer).Discover(ctx, req.(*DiscoverRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _XBee_Events_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(EventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(XBeeServer).Events(m, &grpc.GenericServerStream[EventsRequest, Event]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type XBee_EventsServer = grpc.ServerStreamingServer[Event]
+
+// XBee_ServiceDesc is the grpc.ServiceDesc for XBee service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var XBee_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "xbee.XBee",
+	HandlerType: (*XBeeServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Transmit",
+			Handler:    _XBee_Transmit_Handler,
+		},
+		{
+			MethodName: "ATCommand",
+			Handler:    _XBee_ATCommand_Handler,
+		},
+		{
+			MethodName: "Discover",
+			Handler:    _XBee_Discover_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Events",
+			Handler:       _XBee_Events_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "xbee.proto",
+}
@@ -0,0 +1,269 @@
+// Package xbeehttp exposes an xbee.XBee over HTTP so other services can
+// drive it without linking Go code.
+//
+//	GET  /nodes                     the node registry (xbee.NodeStats)
+//	GET  /nodes/{addr}/at/{cmd}     read a register of a node
+//	PUT  /nodes/{addr}/at/{cmd}     set a register from {"value": "hex", "apply": true}
+//	POST /nodes/{addr}/transmit     transmit the request body to a node
+//	GET  /events                    events as server-sent events
+//
+// {addr} is a 64-bit address in hex or "local" for the local module.
+// Register values are hex strings. Errors are returned as
+// {"error": "message"}.
+//
+//	h := xbeehttp.NewHandler(xb)
+//	go func() {
+//		for ev := range xb.EventChan() {
+//			h.Publish(ev)
+//		}
+//	}()
+//	http.Handle("/", h)
+package xbeehttp
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/samuel/go-xbee/xbee"
+)
+
+const (
+	// maxTransmitSize limits the body of transmit requests. Larger payloads
+	// are rejected by the module anyway.
+	maxTransmitSize = 4096
+	eventQueueLen   = 32
+)
+
+// Handler serves the HTTP API for an XBee.
+type Handler struct {
+	xb  *xbee.XBee
+	mux *http.ServeMux
+
+	mu      sync.Mutex
+	clients map[chan xbee.Event]struct{}
+}
+
+// NewHandler returns the HTTP API for xb. Events are only streamed to
+// /events once passed to Publish.
+func NewHandler(xb *xbee.XBee) *Handler {
+	h := &Handler{
+		xb:      xb,
+		mux:     http.NewServeMux(),
+		clients: make(map[chan xbee.Event]struct{}),
+	}
+	h.mux.HandleFunc("GET /nodes", h.nodes)
+	h.mux.HandleFunc("GET /nodes/{addr}/at/{cmd}", h.getRegister)
+	h.mux.HandleFunc("PUT /nodes/{addr}/at/{cmd}", h.setRegister)
+	h.mux.HandleFunc("POST /nodes/{addr}/transmit", h.transmit)
+	h.mux.HandleFunc("GET /events", h.events)
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// Publish sends ev to the clients streaming /events. Events are dropped
+// for clients that aren't keeping up rather than blocking.
+func (h *Handler) Publish(ev xbee.Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.clients {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+func (h *Handler) nodes(w http.ResponseWriter, r *http.Request) {
+	nodes := h.xb.NodeStats()
+	if nodes == nil {
+		nodes = []xbee.NodeStats{}
+	}
+	writeJSON(w, http.StatusOK, nodes)
+}
+
+// registerValue is the body of register requests and responses.
+type registerValue struct {
+	Command string `json:"command,omitempty"`
+	Value   string `json:"value"`
+	// Apply applies the change (for a remote node with the apply changes
+	// option, for the local module with AC).
+	Apply bool `json:"apply,omitempty"`
+}
+
+func (h *Handler) getRegister(w http.ResponseWriter, r *http.Request) {
+	dest, local, cmd, ok := parseTarget(w, r)
+	if !ok {
+		return
+	}
+	var res []byte
+	var err error
+	if local {
+		res, err = h.xb.ATCommand(cmd, nil)
+	} else {
+		res, err = h.xb.RemoteATCommand(dest, xbee.Address16Unknown, cmd, nil, 0)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, registerValue{Command: cmd.String(), Value: hex.EncodeToString(res)})
+}
+
+func (h *Handler) setRegister(w http.ResponseWriter, r *http.Request) {
+	dest, local, cmd, ok := parseTarget(w, r)
+	if !ok {
+		return
+	}
+	var req registerValue
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorStatus(w, http.StatusBadRequest, fmt.Errorf("bad request body: %w", err))
+		return
+	}
+	param, err := hex.DecodeString(req.Value)
+	if err != nil {
+		writeErrorStatus(w, http.StatusBadRequest, fmt.Errorf("value isn't hex: %w", err))
+		return
+	}
+	var res []byte
+	if local {
+		res, err = h.xb.ATCommand(cmd, param)
+		if err == nil && req.Apply {
+			_, err = h.xb.ATCommand(xbee.ATCommand{'A', 'C'}, nil)
+		}
+	} else {
+		var opts xbee.RemoteATCommandOption
+		if req.Apply {
+			opts = xbee.RATOApplyChanges
+		}
+		res, err = h.xb.RemoteATCommand(dest, xbee.Address16Unknown, cmd, param, opts)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, registerValue{Command: cmd.String(), Value: hex.EncodeToString(res)})
+}
+
+func (h *Handler) transmit(w http.ResponseWriter, r *http.Request) {
+	dest, err := xbee.ParseAddr64(r.PathValue("addr"))
+	if err != nil {
+		writeErrorStatus(w, http.StatusBadRequest, err)
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxTransmitSize))
+	if err != nil {
+		writeErrorStatus(w, http.StatusRequestEntityTooLarge, err)
+		return
+	}
+	if err := h.xb.Transmit(dest, data, xbee.WithRetry(nil), xbee.WithContext(r.Context())); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeErrorStatus(w, http.StatusInternalServerError, errors.New("streaming not supported"))
+		return
+	}
+	ch := make(chan xbee.Event, eventQueueLen)
+	h.mu.Lock()
+	h.clients[ch] = struct{}{}
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		delete(h.clients, ch)
+		h.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		select {
+		case ev := <-ch:
+			b, err := json.Marshal(ev)
+			if err != nil {
+				continue
+			}
+			// The event name is the Go type without the package, e.g.
+			// ReceivePacket.
+			name := fmt.Sprintf("%T", ev)
+			for i := len(name) - 1; i >= 0; i-- {
+				if name[i] == '.' {
+					name = name[i+1:]
+					break
+				}
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, b); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// parseTarget parses the address and command of a register request
+// writing the error response if they're invalid.
+func parseTarget(w http.ResponseWriter, r *http.Request) (dest xbee.Addr64, local bool, cmd xbee.ATCommand, ok bool) {
+	if s := r.PathValue("addr"); s == "local" {
+		local = true
+	} else {
+		var err error
+		if dest, err = xbee.ParseAddr64(s); err != nil {
+			writeErrorStatus(w, http.StatusBadRequest, err)
+			return 0, false, cmd, false
+		}
+	}
+	if err := cmd.UnmarshalText([]byte(r.PathValue("cmd"))); err != nil {
+		writeErrorStatus(w, http.StatusBadRequest, err)
+		return 0, false, cmd, false
+	}
+	return dest, local, cmd, true
+}
+
+// writeError writes the response for an error from the XBee choosing the
+// status by the kind of error.
+func writeError(w http.ResponseWriter, err error) {
+	var atErr *xbee.ATError
+	var deliveryErr *xbee.DeliveryError
+	status := http.StatusInternalServerError
+	switch {
+	case errors.As(err, &atErr) && errors.Is(err, xbee.ErrInvalidParameter):
+		status = http.StatusBadRequest
+	case errors.As(err, &atErr) && atErr.Status == xbee.CSInvalidCommand:
+		status = http.StatusNotFound
+	case errors.As(err, &atErr), errors.As(err, &deliveryErr):
+		status = http.StatusBadGateway
+	case errors.Is(err, xbee.ErrTimeout):
+		status = http.StatusGatewayTimeout
+	case errors.Is(err, xbee.ErrClosed):
+		status = http.StatusServiceUnavailable
+	}
+	writeErrorStatus(w, status, err)
+}
+
+func writeErrorStatus(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, struct {
+		Error string `json:"error"`
+	}{err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
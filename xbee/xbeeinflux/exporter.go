@@ -0,0 +1,186 @@
+// Package xbeeinflux writes the IO samples and health readings of XBee
+// nodes as InfluxDB line protocol tagged by node address and identifier.
+//
+//	e := xbeeinflux.NewExporter(&xbeeinflux.HTTPWriter{
+//		URL:   "http://localhost:8086/api/v2/write?org=home&bucket=sensors",
+//		Token: token,
+//	}, xb, nil)
+//	for s := range xb.IOSamples(ctx) {
+//		e.WriteSample(s, time.Now())
+//	}
+package xbeeinflux
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samuel/go-xbee/xbee"
+)
+
+const (
+	// DefaultMeasurement is the prefix of measurement names unless
+	// configured otherwise. Samples are written to PREFIX_io and health
+	// readings to PREFIX_health.
+	DefaultMeasurement = "xbee"
+	supplyVoltageInput = 7 // analog input reporting the supply voltage
+)
+
+// Config configures an Exporter. The zero value uses the defaults.
+type Config struct {
+	// Measurement is the prefix of the measurement names. The default is
+	// DefaultMeasurement.
+	Measurement string
+	// Tags are added to every point, e.g. the site of the gateway.
+	Tags map[string]string
+}
+
+// Exporter converts samples and readings to line protocol written to an
+// io.Writer, one write per point. It's safe for concurrent use.
+type Exporter struct {
+	w           io.Writer
+	xb          *xbee.XBee
+	measurement string
+	tags        string // extra tags already escaped and sorted
+
+	mu  sync.Mutex // serializes writes
+	buf bytes.Buffer
+}
+
+// NewExporter returns an exporter writing to w. Node identifiers are
+// looked up in the node registry of xb (see xbee.XBee.NodeStats). cfg may
+// be nil to use the defaults.
+func NewExporter(w io.Writer, xb *xbee.XBee, cfg *Config) *Exporter {
+	e := &Exporter{w: w, xb: xb, measurement: DefaultMeasurement}
+	if cfg != nil {
+		if cfg.Measurement != "" {
+			e.measurement = cfg.Measurement
+		}
+		keys := make([]string, 0, len(cfg.Tags))
+		for k := range cfg.Tags {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var sb strings.Builder
+		for _, k := range keys {
+			sb.WriteString("," + escapeTag(k) + "=" + escapeTag(cfg.Tags[k]))
+		}
+		e.tags = sb.String()
+	}
+	return e
+}
+
+// WriteSample writes an IO sample as a point of PREFIX_io with an integer
+// field for each digital pin (dio0 to dio12, 0 or 1) and analog input
+// (ad0 to ad3) in the sample and the supply voltage (vcc) if sampled.
+func (e *Exporter) WriteSample(s *xbee.IODataSampleIndicator, t time.Time) error {
+	var fields []string
+	for n := 0; n < 16; n++ {
+		if high, ok := s.DigitalValue(n); ok {
+			v := 0
+			if high {
+				v = 1
+			}
+			fields = append(fields, fmt.Sprintf("dio%d=%di", n, v))
+		}
+	}
+	for n := 0; n < 8; n++ {
+		if v, ok := s.AnalogValue(n); ok {
+			name := "ad" + strconv.Itoa(n)
+			if n == supplyVoltageInput {
+				name = "vcc"
+			}
+			fields = append(fields, fmt.Sprintf("%s=%di", name, v))
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return e.writePoint(e.measurement+"_io", s.SourceAddress, fields, t)
+}
+
+// WriteEvent writes IO sample events ignoring other events so it can be
+// called with every event from EventChan.
+func (e *Exporter) WriteEvent(ev xbee.Event) error {
+	if s, ok := ev.(*xbee.IODataSampleIndicator); ok {
+		return e.WriteSample(s, time.Now())
+	}
+	return nil
+}
+
+// WriteHealth reads the signal strength of the last packet received (DB)
+// and the supply voltage (%V) of a remote node and writes them as a point
+// of PREFIX_health with the fields rssi (dBm) and supply_mv. A reading
+// that fails is left out and the error is returned if both fail.
+func (e *Exporter) WriteHealth(dest xbee.Addr64) error {
+	var fields []string
+	db, err := e.xb.RemoteATCommand(dest, xbee.Address16Unknown, xbee.ATCommand{'D', 'B'}, nil, 0)
+	if err == nil && len(db) > 0 {
+		fields = append(fields, fmt.Sprintf("rssi=%di", -int(decodeUint(db))))
+	}
+	v, verr := e.xb.RemoteATCommand(dest, xbee.Address16Unknown, xbee.ATCommand{'%', 'V'}, nil, 0)
+	if verr == nil && len(v) > 0 {
+		fields = append(fields, fmt.Sprintf("supply_mv=%di", decodeUint(v)))
+	}
+	if len(fields) == 0 {
+		if err == nil {
+			err = verr
+		}
+		return err
+	}
+	return e.writePoint(e.measurement+"_health", dest, fields, time.Now())
+}
+
+func (e *Exporter) writePoint(measurement string, addr xbee.Addr64, fields []string, t time.Time) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.buf.Reset()
+	e.buf.WriteString(escapeMeasurement(measurement))
+	e.buf.WriteString(",address=" + addr.String())
+	if ni := e.nodeID(addr); ni != "" {
+		e.buf.WriteString(",node_id=" + escapeTag(ni))
+	}
+	e.buf.WriteString(e.tags)
+	e.buf.WriteByte(' ')
+	e.buf.WriteString(strings.Join(fields, ","))
+	e.buf.WriteByte(' ')
+	e.buf.WriteString(strconv.FormatInt(t.UnixNano(), 10))
+	e.buf.WriteByte('\n')
+	_, err := e.w.Write(e.buf.Bytes())
+	return err
+}
+
+func (e *Exporter) nodeID(addr xbee.Addr64) string {
+	for _, n := range e.xb.NodeStats() {
+		if n.Address == addr {
+			return n.NodeID
+		}
+	}
+	return ""
+}
+
+func decodeUint(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+var (
+	measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	tagEscaper         = strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`)
+)
+
+func escapeMeasurement(s string) string {
+	return measurementEscaper.Replace(s)
+}
+
+func escapeTag(s string) string {
+	return tagEscaper.Replace(s)
+}
@@ -0,0 +1,42 @@
+package xbeeinflux
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// HTTPWriter writes line protocol to the InfluxDB write API with a request
+// per write. For InfluxDB 2 the URL is http://HOST:8086/api/v2/write with
+// the org and bucket query parameters.
+type HTTPWriter struct {
+	URL    string
+	Token  string       // sent as "Authorization: Token TOKEN" if set
+	Client *http.Client // the default is http.DefaultClient
+}
+
+func (w *HTTPWriter) Write(p []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(p))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if w.Token != "" {
+		req.Header.Set("Authorization", "Token "+w.Token)
+	}
+	c := w.Client
+	if c == nil {
+		c = http.DefaultClient
+	}
+	res, err := c.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return 0, fmt.Errorf("xbeeinflux: write failed: %s: %s", res.Status, bytes.TrimSpace(msg))
+	}
+	return len(p), nil
+}
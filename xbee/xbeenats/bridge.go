@@ -0,0 +1,162 @@
+// Package xbeenats bridges an xbee.XBee to NATS.
+//
+// Received packets are published to PREFIX.rx.ADDR with the payload as
+// the message data, and IO samples to PREFIX.io.ADDR as JSON. Requests
+// are served on:
+//
+//	PREFIX.tx.ADDR        transmit the message data, the reply is empty
+//	PREFIX.at.ADDR.CMD    run an AT command with the message data as the
+//	                      parameter (empty to read), the reply is the value
+//
+// ADDR is a 64-bit address in hex ("local" for AT commands to the local
+// module). Failed requests are replied to with the error in the
+// Xbee-Error header.
+//
+//	b, err := xbeenats.NewBridge(xb, nc, nil)
+//	go func() {
+//		for ev := range xb.EventChan() {
+//			b.Publish(ev)
+//		}
+//	}()
+package xbeenats
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+	"github.com/samuel/go-xbee/xbee"
+)
+
+const (
+	// DefaultPrefix is the subject prefix used unless configured otherwise.
+	DefaultPrefix = "xbee"
+	// ErrorHeader holds the error of a failed request.
+	ErrorHeader = "Xbee-Error"
+)
+
+// Config configures a Bridge. The zero value uses the defaults.
+type Config struct {
+	// Prefix is the first token of every subject. The default is
+	// DefaultPrefix.
+	Prefix string
+	// ApplyChanges applies the changes made by remote AT commands.
+	ApplyChanges bool
+}
+
+// Bridge publishes events to NATS and serves transmit and AT command
+// requests.
+type Bridge struct {
+	xb     *xbee.XBee
+	nc     *nats.Conn
+	cfg    Config
+	prefix string
+	subs   []*nats.Subscription
+}
+
+// NewBridge subscribes to the request subjects. cfg may be nil to use the
+// defaults. Events are only published once passed to Publish.
+func NewBridge(xb *xbee.XBee, nc *nats.Conn, cfg *Config) (*Bridge, error) {
+	b := &Bridge{xb: xb, nc: nc, prefix: DefaultPrefix}
+	if cfg != nil {
+		b.cfg = *cfg
+	}
+	if b.cfg.Prefix != "" {
+		b.prefix = b.cfg.Prefix
+	}
+	for subj, fn := range map[string]nats.MsgHandler{
+		b.prefix + ".tx.*":   b.transmit,
+		b.prefix + ".at.*.*": b.atCommand,
+	} {
+		// Requests are handled concurrently since they wait for the
+		// radio.
+		sub, err := nc.Subscribe(subj, func(m *nats.Msg) { go fn(m) })
+		if err != nil {
+			b.Close()
+			return nil, err
+		}
+		b.subs = append(b.subs, sub)
+	}
+	return b, nil
+}
+
+// Close unsubscribes from the request subjects.
+func (b *Bridge) Close() error {
+	var errs []error
+	for _, sub := range b.subs {
+		errs = append(errs, sub.Unsubscribe())
+	}
+	b.subs = nil
+	return errors.Join(errs...)
+}
+
+// Publish publishes received packets and IO samples. Other events are
+// ignored.
+func (b *Bridge) Publish(ev xbee.Event) error {
+	switch ev := ev.(type) {
+	case *xbee.ReceivePacket:
+		return b.nc.Publish(b.prefix+".rx."+ev.SourceAddress.String(), ev.Data)
+	case *xbee.ExplicitReceivePacket:
+		return b.nc.Publish(b.prefix+".rx."+ev.SourceAddress.String(), ev.Data)
+	case *xbee.IODataSampleIndicator:
+		data, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		return b.nc.Publish(b.prefix+".io."+ev.SourceAddress.String(), data)
+	}
+	return nil
+}
+
+func (b *Bridge) transmit(m *nats.Msg) {
+	tokens := strings.Split(m.Subject, ".")
+	dest, err := xbee.ParseAddr64(tokens[len(tokens)-1])
+	if err == nil {
+		err = b.xb.Transmit(dest, m.Data, xbee.WithRetry(nil))
+	}
+	b.reply(m, nil, err)
+}
+
+func (b *Bridge) atCommand(m *nats.Msg) {
+	tokens := strings.Split(m.Subject, ".")
+	addr, name := tokens[len(tokens)-2], tokens[len(tokens)-1]
+	var cmd xbee.ATCommand
+	if err := cmd.UnmarshalText([]byte(name)); err != nil {
+		b.reply(m, nil, err)
+		return
+	}
+	var param []byte
+	if len(m.Data) > 0 {
+		param = m.Data
+	}
+	if addr == "local" {
+		res, err := b.xb.ATCommand(cmd, param)
+		b.reply(m, res, err)
+		return
+	}
+	dest, err := xbee.ParseAddr64(addr)
+	if err != nil {
+		b.reply(m, nil, err)
+		return
+	}
+	var opts xbee.RemoteATCommandOption
+	if b.cfg.ApplyChanges {
+		opts = xbee.RATOApplyChanges
+	}
+	res, err := b.xb.RemoteATCommand(dest, xbee.Address16Unknown, cmd, param, opts)
+	b.reply(m, res, err)
+}
+
+// reply responds to a request if it has a reply subject.
+func (b *Bridge) reply(m *nats.Msg, data []byte, err error) {
+	if m.Reply == "" {
+		return
+	}
+	res := nats.NewMsg(m.Reply)
+	res.Data = data
+	if err != nil {
+		res.Header.Set(ErrorHeader, err.Error())
+	}
+	m.RespondMsg(res)
+}
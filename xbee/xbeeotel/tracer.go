@@ -0,0 +1,106 @@
+// Package xbeeotel traces the requests made by an xbee.XBee with
+// OpenTelemetry. Each request is a client span from the frame being
+// written until the response arrives so the span duration is the latency
+// of the round trip.
+//
+//	xb, err := xbee.Open(port, xbee.WithRequestTracer(xbeeotel.NewTracer(otel.GetTracerProvider())))
+package xbeeotel
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/samuel/go-xbee/xbee"
+	"github.com/samuel/go-xbee/xbee/frames"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/samuel/go-xbee/xbee/xbeeotel"
+
+// Tracer implements xbee.RequestTracer creating a span for every request.
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+func NewTracer(tp trace.TracerProvider) *Tracer {
+	return &Tracer{tracer: tp.Tracer(instrumentationName)}
+}
+
+func (t *Tracer) StartRequest(req xbee.Frame) func(res xbee.Frame, err error) {
+	name, attrs := describeRequest(req)
+	_, span := t.tracer.Start(context.Background(), name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...))
+	return func(res xbee.Frame, err error) {
+		defer span.End()
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return
+		}
+		status, ok := responseStatus(res)
+		if status != "" {
+			span.SetAttributes(attribute.String("xbee.status", status))
+		}
+		if !ok {
+			span.SetStatus(codes.Error, status)
+		}
+	}
+}
+
+func describeRequest(req xbee.Frame) (string, []attribute.KeyValue) {
+	attrs := []attribute.KeyValue{
+		attribute.Int("xbee.frame_type", int(req.FrameType())),
+	}
+	if idf, ok := req.(frames.Identified); ok {
+		attrs = append(attrs, attribute.Int("xbee.frame_id", int(idf.ID())))
+	}
+	switch f := req.(type) {
+	case *frames.ATCommandRequest:
+		return "xbee.ATCommand", append(attrs,
+			attribute.String("xbee.command", f.ATCommand.String()))
+	case *frames.RemoteATCommandRequest:
+		return "xbee.RemoteATCommand", append(attrs,
+			attribute.String("xbee.command", f.ATCommand.String()),
+			attribute.String("xbee.destination", f.DestinationAddress.String()))
+	case *frames.IPRemoteATCommandRequest:
+		return "xbee.RemoteATCommand", append(attrs,
+			attribute.String("xbee.command", f.ATCommand.String()),
+			attribute.String("xbee.destination", f.DestinationAddress.String()))
+	case *frames.TransmitRequest:
+		return "xbee.Transmit", append(attrs,
+			attribute.String("xbee.destination", f.DestinationAddress.String()),
+			attribute.Int("xbee.data_length", len(f.Data)))
+	case *frames.ExplicitTransmitRequest:
+		return "xbee.Transmit", append(attrs,
+			attribute.String("xbee.destination", f.DestinationAddress.String()),
+			attribute.Int("xbee.cluster_id", int(f.ClusterID)),
+			attribute.Int("xbee.data_length", len(f.Data)))
+	case *frames.IPv4TransmitRequest:
+		return "xbee.Transmit", append(attrs,
+			attribute.String("xbee.destination", f.DestinationAddress.String()),
+			attribute.Int("xbee.data_length", len(f.Data)))
+	}
+	return "xbee." + strings.TrimPrefix(fmt.Sprintf("%T", req), "*frames."), attrs
+}
+
+// responseStatus returns the status reported by a response and whether it
+// indicates success.
+func responseStatus(res xbee.Frame) (string, bool) {
+	switch f := res.(type) {
+	case *frames.ATCommandResponse:
+		return f.CommandStatus.String(), f.CommandStatus == frames.CSOK
+	case *frames.RemoteATCommandResponse:
+		return f.CommandStatus.String(), f.CommandStatus == frames.CSOK
+	case *frames.IPRemoteATCommandResponse:
+		return f.CommandStatus.String(), f.CommandStatus == frames.CSOK
+	case *frames.TransmitStatus:
+		return f.DeliveryStatus.String(), f.DeliveryStatus == frames.DSSuccess
+	case *frames.IPTransmitStatus:
+		return f.Status.String(), f.Status == frames.DSSuccess
+	}
+	return "", true
+}
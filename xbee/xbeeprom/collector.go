@@ -0,0 +1,99 @@
+// Package xbeeprom exports the link and node metrics of an xbee.XBee to
+// Prometheus.
+//
+//	prometheus.MustRegister(xbeeprom.NewCollector(xb))
+//	http.Handle("/metrics", promhttp.Handler())
+package xbeeprom
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/samuel/go-xbee/xbee"
+)
+
+var (
+	framesSentDesc = prometheus.NewDesc(
+		"xbee_frames_sent_total", "API frames written to the module.", []string{"type"}, nil)
+	framesReceivedDesc = prometheus.NewDesc(
+		"xbee_frames_received_total", "API frames read from the module.", []string{"type"}, nil)
+	checksumErrorsDesc = prometheus.NewDesc(
+		"xbee_checksum_errors_total", "Frames discarded because of a bad checksum.", nil, nil)
+	resyncsDesc = prometheus.NewDesc(
+		"xbee_resyncs_total", "Times the reader resynchronized after a corrupt frame.", nil, nil)
+	decodeErrorsDesc = prometheus.NewDesc(
+		"xbee_decode_errors_total", "Empty or malformed frames.", nil, nil)
+	droppedEventsDesc = prometheus.NewDesc(
+		"xbee_dropped_events_total", "Events dropped because a channel was full.", nil, nil)
+	filteredFramesDesc = prometheus.NewDesc(
+		"xbee_filtered_frames_total", "Frames dropped by the receive filter.", nil, nil)
+	duplicateBroadcastsDesc = prometheus.NewDesc(
+		"xbee_duplicate_broadcasts_total", "Repeated broadcasts detected by deduplication.", nil, nil)
+	retransmissionsDesc = prometheus.NewDesc(
+		"xbee_retransmissions_total", "Retries reported by transmit status frames.", nil, nil)
+	deliveryFailuresDesc = prometheus.NewDesc(
+		"xbee_delivery_failures_total", "Transmissions that failed to be delivered.", nil, nil)
+	nodeLastSeenDesc = prometheus.NewDesc(
+		"xbee_node_last_seen_timestamp_seconds", "Time a packet was last received from the node.", []string{"address"}, nil)
+	nodePacketsDesc = prometheus.NewDesc(
+		"xbee_node_packets_received_total", "Packets received from the node.", []string{"address"}, nil)
+	nodeRSSIDesc = prometheus.NewDesc(
+		"xbee_node_rssi_dbm", "Signal strength of the last packet received from the node.", []string{"address"}, nil)
+)
+
+// Collector is a prometheus.Collector for an XBee. Node RSSI is only
+// exported if sampling was enabled with xbee.WithRSSISampling.
+type Collector struct {
+	xb *xbee.XBee
+}
+
+func NewCollector(xb *xbee.XBee) *Collector {
+	return &Collector{xb: xb}
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- framesSentDesc
+	ch <- framesReceivedDesc
+	ch <- checksumErrorsDesc
+	ch <- resyncsDesc
+	ch <- decodeErrorsDesc
+	ch <- droppedEventsDesc
+	ch <- filteredFramesDesc
+	ch <- duplicateBroadcastsDesc
+	ch <- retransmissionsDesc
+	ch <- deliveryFailuresDesc
+	ch <- nodeLastSeenDesc
+	ch <- nodePacketsDesc
+	ch <- nodeRSSIDesc
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	st := c.xb.Stats()
+	for t, n := range st.FramesSent {
+		ch <- prometheus.MustNewConstMetric(framesSentDesc, prometheus.CounterValue, float64(n), frameTypeLabel(t))
+	}
+	for t, n := range st.FramesReceived {
+		ch <- prometheus.MustNewConstMetric(framesReceivedDesc, prometheus.CounterValue, float64(n), frameTypeLabel(t))
+	}
+	ch <- prometheus.MustNewConstMetric(checksumErrorsDesc, prometheus.CounterValue, float64(st.ChecksumErrors))
+	ch <- prometheus.MustNewConstMetric(resyncsDesc, prometheus.CounterValue, float64(st.Resyncs))
+	ch <- prometheus.MustNewConstMetric(decodeErrorsDesc, prometheus.CounterValue, float64(st.DecodeErrors))
+	ch <- prometheus.MustNewConstMetric(droppedEventsDesc, prometheus.CounterValue, float64(st.DroppedEvents))
+	ch <- prometheus.MustNewConstMetric(filteredFramesDesc, prometheus.CounterValue, float64(st.FilteredFrames))
+	ch <- prometheus.MustNewConstMetric(duplicateBroadcastsDesc, prometheus.CounterValue, float64(st.Duplicates))
+	ch <- prometheus.MustNewConstMetric(retransmissionsDesc, prometheus.CounterValue, float64(st.Retransmissions))
+	ch <- prometheus.MustNewConstMetric(deliveryFailuresDesc, prometheus.CounterValue, float64(st.DeliveryFailures))
+
+	for _, n := range c.xb.NodeStats() {
+		addr := n.Address.String()
+		ch <- prometheus.MustNewConstMetric(nodeLastSeenDesc, prometheus.GaugeValue, float64(n.LastSeen.UnixNano())/1e9, addr)
+		ch <- prometheus.MustNewConstMetric(nodePacketsDesc, prometheus.CounterValue, float64(n.PacketsReceived), addr)
+		if n.RSSI != 0 {
+			ch <- prometheus.MustNewConstMetric(nodeRSSIDesc, prometheus.GaugeValue, float64(n.RSSI), addr)
+		}
+	}
+}
+
+func frameTypeLabel(t byte) string {
+	return fmt.Sprintf("0x%02x", t)
+}
@@ -0,0 +1,272 @@
+// Package xbeerules raises alerts when conditions over the IO samples and
+// health of XBee nodes hold for a while, such as "ad1 > 900" for 5 minutes
+// or "unseen > 10m".
+//
+//	e := xbeerules.NewEngine(xb, &xbeerules.Config{
+//		OnAlert: xbeerules.SinkAction(&xbeesink.Webhook{URL: url}),
+//	})
+//	defer e.Close()
+//	e.Add(&xbeerules.Rule{Name: "tank full", Node: &tank, Expr: "ad1 > 900", For: 5 * time.Minute})
+//	for ev := range xb.EventChan() {
+//		e.Publish(ev)
+//	}
+package xbeerules
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/samuel/go-xbee/xbee"
+	"github.com/samuel/go-xbee/xbee/xbeesink"
+)
+
+const (
+	// DefaultInterval is how often rules are evaluated against the node
+	// registry unless configured otherwise.
+	DefaultInterval    = 10 * time.Second
+	supplyVoltageInput = 7 // analog input reporting the supply voltage
+)
+
+// Config configures an Engine. The zero value uses the defaults.
+type Config struct {
+	// Interval is how often rules are evaluated for nodes that haven't
+	// sent a sample, which is what detects unseen nodes. The default is
+	// DefaultInterval.
+	Interval time.Duration
+	// OnAlert is called for alerts of rules without their own action.
+	OnAlert func(*Alert)
+}
+
+// Rule raises an alert when its expression (see ParseCondition) holds for
+// a node for the duration For, and again with Resolved set once it no
+// longer holds.
+type Rule struct {
+	Name string
+	// Node is the node the rule applies to or nil for every node in the
+	// node registry.
+	Node *xbee.Addr64
+	Expr string
+	For  time.Duration
+	// Action is called for the rule's alerts instead of Config.OnAlert if
+	// set. It's called by the goroutine evaluating the rule so it must
+	// not block for long.
+	Action func(*Alert)
+
+	cond Condition
+}
+
+// Alert is raised by a rule for a node. It's an xbee.Event so it can be
+// delivered to sinks.
+type Alert struct {
+	Rule     string
+	Expr     string
+	Node     xbee.Addr64
+	NodeID   string  // only known from node discovery
+	Value    float64 // value of the variable when raised or resolved
+	Since    time.Time
+	Resolved bool
+}
+
+// SinkAction returns an action writing alerts to a sink such as a
+// webhook, ignoring errors.
+func SinkAction(s xbeesink.Sink) func(*Alert) {
+	var mu sync.Mutex
+	return func(a *Alert) {
+		mu.Lock()
+		defer mu.Unlock()
+		s.Write(xbeesink.NewRecord(a))
+	}
+}
+
+// Engine evaluates rules as samples arrive and periodically.
+type Engine struct {
+	xb       *xbee.XBee
+	onAlert  func(*Alert)
+	interval time.Duration
+	done     chan struct{}
+	once     sync.Once
+
+	mu      sync.Mutex
+	rules   []*Rule
+	samples map[xbee.Addr64]map[string]float64 // last sample values by node
+	states  map[stateKey]*state
+}
+
+type stateKey struct {
+	rule *Rule
+	node xbee.Addr64
+}
+
+type state struct {
+	since  time.Time // when the condition started holding
+	raised bool
+}
+
+// NewEngine returns an engine without rules reading node health from the
+// node registry of xb. cfg may be nil to use the defaults.
+func NewEngine(xb *xbee.XBee, cfg *Config) *Engine {
+	e := &Engine{
+		xb:       xb,
+		interval: DefaultInterval,
+		done:     make(chan struct{}),
+		samples:  make(map[xbee.Addr64]map[string]float64),
+		states:   make(map[stateKey]*state),
+	}
+	if cfg != nil {
+		if cfg.Interval > 0 {
+			e.interval = cfg.Interval
+		}
+		e.onAlert = cfg.OnAlert
+	}
+	go e.loop()
+	return e
+}
+
+// Add parses a rule's expression and adds it.
+func (e *Engine) Add(r *Rule) error {
+	c, err := ParseCondition(r.Expr)
+	if err != nil {
+		return err
+	}
+	r.cond = c
+	e.mu.Lock()
+	e.rules = append(e.rules, r)
+	e.mu.Unlock()
+	return nil
+}
+
+// Remove removes a rule without resolving its raised alerts.
+func (e *Engine) Remove(r *Rule) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for i, x := range e.rules {
+		if x == r {
+			e.rules = append(e.rules[:i], e.rules[i+1:]...)
+			break
+		}
+	}
+	for k := range e.states {
+		if k.rule == r {
+			delete(e.states, k)
+		}
+	}
+}
+
+// Publish records the values of IO sample events and evaluates the rules
+// for the sending node. Other events are ignored.
+func (e *Engine) Publish(ev xbee.Event) {
+	s, ok := ev.(*xbee.IODataSampleIndicator)
+	if !ok {
+		return
+	}
+	values := make(map[string]float64)
+	for n := 0; n <= 12; n++ {
+		if high, ok := s.DigitalValue(n); ok {
+			v := 0.0
+			if high {
+				v = 1
+			}
+			values["dio"+strconv.Itoa(n)] = v
+		}
+	}
+	for n := 0; n < 8; n++ {
+		if v, ok := s.AnalogValue(n); ok {
+			values["ad"+strconv.Itoa(n)] = float64(v)
+			if n == supplyVoltageInput {
+				values["vcc"] = float64(v)
+			}
+		}
+	}
+	e.mu.Lock()
+	e.samples[s.SourceAddress] = values
+	e.mu.Unlock()
+	e.evaluate(&s.SourceAddress)
+}
+
+// Close stops evaluating rules periodically.
+func (e *Engine) Close() {
+	e.once.Do(func() { close(e.done) })
+}
+
+func (e *Engine) loop() {
+	t := time.NewTicker(e.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			e.evaluate(nil)
+		case <-e.done:
+			return
+		}
+	}
+}
+
+// evaluate evaluates the rules for one node or all known nodes if only is
+// nil, calling the actions once the lock is released.
+func (e *Engine) evaluate(only *xbee.Addr64) {
+	now := time.Now()
+	stats := e.xb.NodeStats()
+	var alerts []*Alert
+	var actions []func(*Alert)
+
+	e.mu.Lock()
+	for _, r := range e.rules {
+		for i := range stats {
+			n := &stats[i]
+			if (r.Node != nil && *r.Node != n.Address) || (only != nil && *only != n.Address) {
+				continue
+			}
+			v, ok := e.value(n, r.cond.Var, now)
+			if !ok {
+				continue
+			}
+			key := stateKey{r, n.Address}
+			st := e.states[key]
+			if !r.cond.eval(v) {
+				if st != nil {
+					delete(e.states, key)
+					if st.raised {
+						alerts = append(alerts, &Alert{Rule: r.Name, Expr: r.Expr, Node: n.Address, NodeID: n.NodeID, Value: v, Since: st.since, Resolved: true})
+						actions = append(actions, r.Action)
+					}
+				}
+				continue
+			}
+			if st == nil {
+				st = &state{since: now}
+				e.states[key] = st
+			}
+			if !st.raised && now.Sub(st.since) >= r.For {
+				st.raised = true
+				alerts = append(alerts, &Alert{Rule: r.Name, Expr: r.Expr, Node: n.Address, NodeID: n.NodeID, Value: v, Since: st.since})
+				actions = append(actions, r.Action)
+			}
+		}
+	}
+	e.mu.Unlock()
+
+	for i, a := range alerts {
+		if fn := actions[i]; fn != nil {
+			fn(a)
+		} else if e.onAlert != nil {
+			e.onAlert(a)
+		}
+	}
+}
+
+// value returns the current value of a variable for a node, or false if
+// it's unknown.
+func (e *Engine) value(n *xbee.NodeStats, name string, now time.Time) (float64, bool) {
+	switch name {
+	case "unseen":
+		if n.LastSeen.IsZero() {
+			return 0, false
+		}
+		return now.Sub(n.LastSeen).Seconds(), true
+	case "rssi":
+		return float64(n.RSSI), n.RSSI != 0
+	}
+	v, ok := e.samples[n.Address][name]
+	return v, ok
+}
@@ -0,0 +1,95 @@
+package xbeerules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Condition is a parsed rule expression comparing a variable of a node
+// with a constant.
+type Condition struct {
+	Var   string
+	Op    string
+	Value float64
+}
+
+var ops = []string{">=", "<=", "==", "!=", ">", "<"} // longest first
+
+// ParseCondition parses an expression of the form "VAR OP VALUE" where OP
+// is one of > >= < <= == != and VAR is one of:
+//
+//	ad0 to ad7    analog input of the last IO sample (0-1023)
+//	dio0 to dio12 digital pin of the last IO sample (0 or 1)
+//	vcc           supply voltage of the last IO sample in mV
+//	rssi          signal strength of the last packet in dBm (needs WithRSSISampling)
+//	unseen        time since the node was last heard from
+//
+// The value of unseen is a duration such as 10m, the others numbers.
+func ParseCondition(s string) (Condition, error) {
+	for _, op := range ops {
+		name, value, ok := strings.Cut(s, op)
+		if !ok {
+			continue
+		}
+		c := Condition{Var: strings.ToLower(strings.TrimSpace(name)), Op: op}
+		value = strings.TrimSpace(value)
+		if !validVar(c.Var) {
+			return c, fmt.Errorf("xbeerules: unknown variable %q in %q", c.Var, s)
+		}
+		var err error
+		if c.Var == "unseen" {
+			var d time.Duration
+			d, err = time.ParseDuration(value)
+			c.Value = d.Seconds()
+		} else {
+			c.Value, err = strconv.ParseFloat(value, 64)
+		}
+		if err != nil {
+			return c, fmt.Errorf("xbeerules: bad value in %q: %w", s, err)
+		}
+		return c, nil
+	}
+	return Condition{}, fmt.Errorf("xbeerules: no comparison in %q", s)
+}
+
+func validVar(name string) bool {
+	switch name {
+	case "vcc", "rssi", "unseen":
+		return true
+	}
+	for prefix, max := range map[string]int{"ad": 7, "dio": 12} {
+		if n, ok := strings.CutPrefix(name, prefix); ok {
+			i, err := strconv.Atoi(n)
+			return err == nil && i >= 0 && i <= max && strconv.Itoa(i) == n
+		}
+	}
+	return false
+}
+
+// eval reports whether the condition holds for a value.
+func (c Condition) eval(v float64) bool {
+	switch c.Op {
+	case ">":
+		return v > c.Value
+	case ">=":
+		return v >= c.Value
+	case "<":
+		return v < c.Value
+	case "<=":
+		return v <= c.Value
+	case "==":
+		return v == c.Value
+	case "!=":
+		return v != c.Value
+	}
+	return false
+}
+
+func (c Condition) String() string {
+	if c.Var == "unseen" {
+		return fmt.Sprintf("%s %s %s", c.Var, c.Op, time.Duration(c.Value*float64(time.Second)))
+	}
+	return fmt.Sprintf("%s %s %g", c.Var, c.Op, c.Value)
+}
@@ -0,0 +1,192 @@
+// Package xbeesink delivers XBee events to generic sinks such as webhooks
+// and JSON lines files for integrations without a dedicated bridge.
+//
+//	d := xbeesink.NewDispatcher()
+//	d.Add(&xbeesink.Webhook{URL: "https://example.com/hook"}, "ReceivePacket", "IODataSampleIndicator")
+//	d.Add(xbeesink.NewWriter(os.Stdout))
+//	defer d.Close()
+//	for ev := range xb.EventChan() {
+//		d.Publish(ev)
+//	}
+package xbeesink
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/samuel/go-xbee/xbee"
+)
+
+// queueSize is the number of records buffered for each sink before
+// records are dropped.
+const queueSize = 64
+
+// Record is an event as delivered to a sink.
+type Record struct {
+	Time  time.Time  `json:"time"`
+	Type  string     `json:"type"` // Go type without the package, e.g. ReceivePacket
+	Event xbee.Event `json:"event"`
+}
+
+// NewRecord returns the record for an event received now.
+func NewRecord(ev xbee.Event) *Record {
+	return &Record{Time: time.Now(), Type: EventType(ev), Event: ev}
+}
+
+// EventType returns the name of an event's type without the package, e.g.
+// ReceivePacket, which is the name used to route events.
+func EventType(ev xbee.Event) string {
+	name := fmt.Sprintf("%T", ev)
+	for i := len(name) - 1; i >= 0; i-- {
+		if name[i] == '.' {
+			return name[i+1:]
+		}
+	}
+	return name
+}
+
+// Sink receives event records. Write is called by one goroutine at a time.
+type Sink interface {
+	Write(rec *Record) error
+	Close() error
+}
+
+// Writer is a sink writing each record as a line of JSON.
+type Writer struct {
+	w      io.Writer
+	closer io.Closer
+}
+
+// NewWriter returns a sink writing JSON lines to w, such as os.Stdout.
+// Closing the sink doesn't close w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// OpenFile returns a sink appending JSON lines to the named file, creating
+// it if it doesn't exist.
+func OpenFile(name string) (*Writer, error) {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{w: f, closer: f}, nil
+}
+
+func (s *Writer) Write(rec *Record) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	_, err = s.w.Write(append(b, '\n'))
+	return err
+}
+
+func (s *Writer) Close() error {
+	if s.closer != nil {
+		return s.closer.Close()
+	}
+	return nil
+}
+
+// Dispatcher fans events into sinks. Each sink has its own queue and
+// goroutine so a slow sink doesn't hold up the others; records are dropped
+// for a sink whose queue is full.
+type Dispatcher struct {
+	// ErrorHandler is called with the errors returned by sinks if set. It
+	// must be set before events are published.
+	ErrorHandler func(s Sink, rec *Record, err error)
+
+	mu     sync.Mutex
+	routes []*route
+	closed bool
+	wg     sync.WaitGroup
+}
+
+type route struct {
+	sink  Sink
+	types map[string]bool // nil for all events
+	ch    chan *Record
+}
+
+// NewDispatcher returns a dispatcher without sinks.
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{}
+}
+
+// Add adds a sink receiving the events of the given types (see EventType),
+// or all events if none are given.
+func (d *Dispatcher) Add(s Sink, types ...string) {
+	r := &route{sink: s, ch: make(chan *Record, queueSize)}
+	if len(types) > 0 {
+		r.types = make(map[string]bool, len(types))
+		for _, t := range types {
+			r.types[t] = true
+		}
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		s.Close()
+		return
+	}
+	d.routes = append(d.routes, r)
+	d.wg.Add(1)
+	go d.run(r)
+}
+
+func (d *Dispatcher) run(r *route) {
+	defer d.wg.Done()
+	for rec := range r.ch {
+		if err := r.sink.Write(rec); err != nil && d.ErrorHandler != nil {
+			d.ErrorHandler(r.sink, rec, err)
+		}
+	}
+}
+
+// Publish queues an event for the sinks routed its type.
+func (d *Dispatcher) Publish(ev xbee.Event) {
+	rec := NewRecord(ev)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return
+	}
+	for _, r := range d.routes {
+		if r.types != nil && !r.types[rec.Type] {
+			continue
+		}
+		select {
+		case r.ch <- rec:
+		default:
+		}
+	}
+}
+
+// Close delivers the queued records and closes the sinks returning the
+// errors closing them.
+func (d *Dispatcher) Close() error {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return nil
+	}
+	d.closed = true
+	for _, r := range d.routes {
+		close(r.ch)
+	}
+	d.mu.Unlock()
+	d.wg.Wait()
+	var errs []error
+	for _, r := range d.routes {
+		if err := r.sink.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
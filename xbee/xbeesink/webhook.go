@@ -0,0 +1,90 @@
+package xbeesink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultWebhookAttempts = 3
+	defaultWebhookBackoff  = time.Second
+)
+
+// Webhook is a sink posting each record as JSON to a URL. Requests that
+// fail or get a 5xx or 429 response are retried with exponential backoff.
+type Webhook struct {
+	URL    string
+	Header http.Header  // added to every request, e.g. Authorization
+	Client *http.Client // the default is http.DefaultClient
+	// Attempts is the number of times to try each record. The default is 3.
+	Attempts int
+	// Backoff is the delay before the first retry which doubles for each
+	// retry after it. The default is 1s.
+	Backoff time.Duration
+	// Timeout limits each request if positive.
+	Timeout time.Duration
+}
+
+func (s *Webhook) Write(rec *Record) error {
+	body, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	attempts := s.Attempts
+	if attempts <= 0 {
+		attempts = defaultWebhookAttempts
+	}
+	backoff := s.Backoff
+	if backoff <= 0 {
+		backoff = defaultWebhookBackoff
+	}
+	for i := 0; ; i++ {
+		retry, err := s.post(body)
+		if err == nil || !retry || i == attempts-1 {
+			return err
+		}
+		time.Sleep(backoff << i)
+	}
+}
+
+// post sends a record reporting whether a failure is worth retrying.
+func (s *Webhook) post(body []byte) (bool, error) {
+	ctx := context.Background()
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	for k, v := range s.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c := s.Client
+	if c == nil {
+		c = http.DefaultClient
+	}
+	res, err := c.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 4096))
+	if res.StatusCode/100 == 2 {
+		return false, nil
+	}
+	retry := res.StatusCode >= 500 || res.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("xbeesink: webhook %s: %s", s.URL, res.Status)
+}
+
+func (s *Webhook) Close() error {
+	return nil
+}
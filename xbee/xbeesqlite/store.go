@@ -0,0 +1,350 @@
+// Package xbeesqlite stores the node registry, message history, and
+// delivery outcomes of an XBee gateway in SQLite so small gateways get
+// durable state without running a database server.
+//
+//	s, err := xbeesqlite.Open("/var/lib/xbee/gateway.db")
+//	...
+//	xb, err := xbee.Open(port, xbee.WithNodeStore(s))
+//	for ev := range xb.EventChan() {
+//		s.Publish(ev)
+//	}
+//
+// Open uses the pure Go driver modernc.org/sqlite. New accepts a database
+// opened with any SQLite driver.
+package xbeesqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/samuel/go-xbee/xbee"
+	_ "modernc.org/sqlite" // registers the "sqlite" driver
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS nodes (
+	address          INTEGER PRIMARY KEY,
+	address16        INTEGER NOT NULL,
+	node_id          TEXT NOT NULL,
+	device_type      INTEGER NOT NULL,
+	last_seen        INTEGER NOT NULL,
+	packets_received INTEGER NOT NULL,
+	rssi             INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS messages (
+	id        INTEGER PRIMARY KEY AUTOINCREMENT,
+	time      INTEGER NOT NULL,
+	direction TEXT NOT NULL,
+	address   INTEGER NOT NULL,
+	data      BLOB NOT NULL,
+	delivered INTEGER,
+	status    INTEGER,
+	error     TEXT
+);
+CREATE INDEX IF NOT EXISTS messages_address_time ON messages (address, time);
+CREATE INDEX IF NOT EXISTS messages_time ON messages (time);
+`
+
+// Direction is whether a message was received or transmitted.
+type Direction string
+
+const (
+	Received    Direction = "rx"
+	Transmitted Direction = "tx"
+)
+
+// Message is a packet in the message history.
+type Message struct {
+	ID        int64
+	Time      time.Time
+	Direction Direction
+	Address   xbee.Addr64 // source of received and destination of transmitted messages
+	Data      []byte
+	// Delivered is nil for received messages and whether the transmit
+	// succeeded for transmitted messages.
+	Delivered *bool               `json:",omitempty"`
+	Status    xbee.DeliveryStatus `json:",omitempty"` // set if delivery failed with a delivery status
+	Error     string              `json:",omitempty"` // why delivery failed
+}
+
+// Store is a SQLite database of nodes and messages. It implements
+// xbee.NodeStore. It's safe for concurrent use.
+type Store struct {
+	db     *sql.DB
+	closer bool
+}
+
+// Open opens or creates the database at path with the pure Go driver.
+func Open(path string) (*Store, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows one writer at a time.
+	db.SetMaxOpenConns(1)
+	s, err := New(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	s.closer = true
+	return s, nil
+}
+
+// New returns a store using an open SQLite database creating the tables
+// if they don't exist. Close doesn't close db.
+func New(db *sql.DB) (*Store, error) {
+	if _, err := db.Exec(schema); err != nil {
+		return nil, fmt.Errorf("xbeesqlite: creating schema: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// DB returns the database for queries not covered by the store.
+func (s *Store) DB() *sql.DB {
+	return s.db
+}
+
+// Close closes the database if it was opened by Open.
+func (s *Store) Close() error {
+	if s.closer {
+		return s.db.Close()
+	}
+	return nil
+}
+
+// Load returns the saved node registry.
+func (s *Store) Load() ([]xbee.NodeStats, error) {
+	rows, err := s.db.Query(`SELECT address, address16, node_id, device_type, last_seen, packets_received, rssi FROM nodes ORDER BY address`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var nodes []xbee.NodeStats
+	for rows.Next() {
+		var n xbee.NodeStats
+		var addr, lastSeen int64
+		var addr16 uint16
+		var dt byte
+		if err := rows.Scan(&addr, &addr16, &n.NodeID, &dt, &lastSeen, &n.PacketsReceived, &n.RSSI); err != nil {
+			return nil, err
+		}
+		n.Address, n.Address16, n.DeviceType = xbee.Addr64(addr), xbee.Addr16(addr16), xbee.DeviceType(dt)
+		if lastSeen != 0 {
+			n.LastSeen = time.Unix(0, lastSeen)
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, rows.Err()
+}
+
+// Save replaces the saved node registry.
+func (s *Store) Save(nodes []xbee.NodeStats) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`DELETE FROM nodes`); err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`INSERT INTO nodes (address, address16, node_id, device_type, last_seen, packets_received, rssi) VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, n := range nodes {
+		if _, err := stmt.Exec(int64(n.Address), uint16(n.Address16), n.NodeID, byte(n.DeviceType), unixNano(n.LastSeen), n.PacketsReceived, n.RSSI); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Publish adds received packets (ReceivePacket and ExplicitReceivePacket
+// events) to the message history ignoring other events.
+func (s *Store) Publish(ev xbee.Event) error {
+	switch rx := ev.(type) {
+	case *xbee.ReceivePacket:
+		_, err := s.add(&Message{Time: time.Now(), Direction: Received, Address: rx.SourceAddress, Data: rx.Data})
+		return err
+	case *xbee.ExplicitReceivePacket:
+		_, err := s.add(&Message{Time: time.Now(), Direction: Received, Address: rx.SourceAddress, Data: rx.Data})
+		return err
+	}
+	return nil
+}
+
+// Transmit sends data with xb.Transmit retrying as configured by p (see
+// xbee.WithRetry) and adds it to the message history with the outcome.
+// The error is the transmit's.
+func (s *Store) Transmit(ctx context.Context, xb *xbee.XBee, dest xbee.Addr64, data []byte, p *xbee.RetryPolicy) error {
+	m := &Message{Time: time.Now(), Direction: Transmitted, Address: dest, Data: data}
+	err := xb.Transmit(dest, data, xbee.WithRetry(p), xbee.WithContext(ctx))
+	s.RecordDelivery(m, err)
+	return err
+}
+
+// RecordDelivery adds a transmitted message to the history with the
+// outcome of the transmit returning its ID.
+func (s *Store) RecordDelivery(m *Message, err error) (int64, error) {
+	delivered := err == nil
+	m.Direction, m.Delivered = Transmitted, &delivered
+	if err != nil {
+		m.Error = err.Error()
+		var de *xbee.DeliveryError
+		if errors.As(err, &de) {
+			m.Status = de.Status
+		}
+	}
+	return s.add(m)
+}
+
+func (s *Store) add(m *Message) (int64, error) {
+	var delivered, status any
+	if m.Delivered != nil {
+		delivered = *m.Delivered
+		if !*m.Delivered {
+			status = byte(m.Status)
+		}
+	}
+	data := m.Data
+	if data == nil {
+		data = []byte{}
+	}
+	res, err := s.db.Exec(`INSERT INTO messages (time, direction, address, data, delivered, status, error) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		unixNano(m.Time), string(m.Direction), int64(m.Address), data, delivered, status, nullString(m.Error))
+	if err != nil {
+		return 0, err
+	}
+	m.ID, err = res.LastInsertId()
+	return m.ID, err
+}
+
+// Query selects messages from the history. The zero value selects every
+// message.
+type Query struct {
+	Address   *xbee.Addr64
+	Direction Direction // empty for both
+	Since     time.Time // inclusive, zero for no lower bound
+	Until     time.Time // exclusive, zero for no upper bound
+	// Failed only selects transmitted messages that weren't delivered.
+	Failed bool
+	// Limit returns at most the Limit most recent messages if positive.
+	Limit int
+}
+
+// Messages returns the messages matching q, oldest first.
+func (s *Store) Messages(ctx context.Context, q Query) ([]Message, error) {
+	where := "1=1"
+	var args []any
+	if q.Address != nil {
+		where += " AND address = ?"
+		args = append(args, int64(*q.Address))
+	}
+	if q.Direction != "" {
+		where += " AND direction = ?"
+		args = append(args, string(q.Direction))
+	}
+	if !q.Since.IsZero() {
+		where += " AND time >= ?"
+		args = append(args, q.Since.UnixNano())
+	}
+	if !q.Until.IsZero() {
+		where += " AND time < ?"
+		args = append(args, q.Until.UnixNano())
+	}
+	if q.Failed {
+		where += " AND delivered = 0"
+	}
+	query := `SELECT id, time, direction, address, data, delivered, status, error FROM messages WHERE ` + where + ` ORDER BY time DESC, id DESC`
+	if q.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", q.Limit)
+	}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var msgs []Message
+	for rows.Next() {
+		var m Message
+		var t, addr int64
+		var dir string
+		var delivered sql.NullBool
+		var status sql.NullInt64
+		var errStr sql.NullString
+		if err := rows.Scan(&m.ID, &t, &dir, &addr, &m.Data, &delivered, &status, &errStr); err != nil {
+			return nil, err
+		}
+		m.Time, m.Direction, m.Address = time.Unix(0, t), Direction(dir), xbee.Addr64(addr)
+		if delivered.Valid {
+			m.Delivered = &delivered.Bool
+		}
+		m.Status, m.Error = xbee.DeliveryStatus(status.Int64), errStr.String
+		msgs = append(msgs, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// Reverse to return the oldest first.
+	for i, j := 0, len(msgs)-1; i < j; i, j = i+1, j-1 {
+		msgs[i], msgs[j] = msgs[j], msgs[i]
+	}
+	return msgs, nil
+}
+
+// DeliveryStats is the outcome of transmits to a node.
+type DeliveryStats struct {
+	Address   xbee.Addr64
+	Delivered int
+	Failed    int
+}
+
+// DeliveryStats returns the number of messages delivered to and failed
+// for each node since a time (zero for all time) ordered by address.
+func (s *Store) DeliveryStats(ctx context.Context, since time.Time) ([]DeliveryStats, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT address, SUM(delivered), SUM(1 - delivered) FROM messages
+		WHERE direction = ? AND time >= ? GROUP BY address ORDER BY address`, string(Transmitted), unixNano(since))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var stats []DeliveryStats
+	for rows.Next() {
+		var d DeliveryStats
+		var addr int64
+		if err := rows.Scan(&addr, &d.Delivered, &d.Failed); err != nil {
+			return nil, err
+		}
+		d.Address = xbee.Addr64(addr)
+		stats = append(stats, d)
+	}
+	return stats, rows.Err()
+}
+
+// Prune deletes messages older than a time returning the number deleted.
+func (s *Store) Prune(ctx context.Context, before time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM messages WHERE time < ?`, before.UnixNano())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func nullString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,298 @@
+package xbeetest
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/samuel/go-xbee/xbee"
+	"github.com/samuel/go-xbee/xbee/frames"
+)
+
+// Link describes the connection between two radios in a Network.
+type Link struct {
+	// Loss is the probability (0 to 1) that a transmission is lost.
+	Loss float64
+	// Latency is the one way delay of a transmission.
+	Latency time.Duration
+	// Down disconnects the radios.
+	Down bool
+}
+
+// Network is a virtual PAN connecting simulated radios. Transmissions and
+// remote AT commands are delivered to the destination radio subject to
+// the link between the radios, and node discovery reports every radio
+// reachable from the source. AddressCoordinator addresses the radio added
+// as the coordinator. Packets for the loopback cluster are echoed back by
+// the destination radio rather than delivered to its host.
+type Network struct {
+	mu          sync.Mutex
+	members     []*member
+	links       map[[2]xbee.Addr64]Link
+	defaultLink Link
+	rnd         *rand.Rand
+	next16      xbee.Addr16
+}
+
+type member struct {
+	radio      *Radio
+	addr64     xbee.Addr64
+	addr16     xbee.Addr16
+	deviceType xbee.DeviceType
+}
+
+// NewNetwork returns an empty network. Links default to no loss or
+// latency. Loss is simulated using a fixed seed so runs are repeatable.
+func NewNetwork() *Network {
+	return &Network{
+		links:  make(map[[2]xbee.Addr64]Link),
+		rnd:    rand.New(rand.NewSource(1)),
+		next16: 1,
+	}
+}
+
+// AddRadio adds a new radio to the network. The coordinator is assigned
+// the 16-bit address 0x0000.
+func (n *Network) AddRadio(addr xbee.Addr64, deviceType xbee.DeviceType, nodeID string) *Radio {
+	r := NewRadio()
+	r.SetAddress(addr)
+	r.SetRegister("NI", []byte(nodeID))
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	m := &member{radio: r, addr64: addr, deviceType: deviceType}
+	if deviceType != xbee.Coordinator {
+		m.addr16 = n.next16
+		n.next16++
+	}
+	r.SetRegister("MY", []byte{byte(m.addr16 >> 8), byte(m.addr16)})
+	r.mu.Lock()
+	r.network = n
+	r.mu.Unlock()
+	n.members = append(n.members, m)
+	return r
+}
+
+// Radio returns the radio with the 64-bit address or nil if there is none.
+func (n *Network) Radio(addr xbee.Addr64) *Radio {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if m := n.findLocked(addr, xbee.Address16Unknown); m != nil {
+		return m.radio
+	}
+	return nil
+}
+
+// SetLink sets the link between two radios in both directions.
+func (n *Network) SetLink(a, b xbee.Addr64, l Link) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.links[linkKey(a, b)] = l
+}
+
+// SetDefaultLink sets the link used between radios without a link set by
+// SetLink.
+func (n *Network) SetDefaultLink(l Link) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.defaultLink = l
+}
+
+func linkKey(a, b xbee.Addr64) [2]xbee.Addr64 {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]xbee.Addr64{a, b}
+}
+
+func (n *Network) findLocked(addr64 xbee.Addr64, addr16 xbee.Addr16) *member {
+	for _, m := range n.members {
+		if addr64 == xbee.AddressCoordinator && m.deviceType == xbee.Coordinator {
+			return m
+		}
+		if addr64 != xbee.AddressUnknown && m.addr64 == addr64 {
+			return m
+		}
+		if addr64 == xbee.AddressUnknown && m.addr16 == addr16 {
+			return m
+		}
+	}
+	return nil
+}
+
+func (n *Network) memberLocked(r *Radio) *member {
+	for _, m := range n.members {
+		if m.radio == r {
+			return m
+		}
+	}
+	return nil
+}
+
+// attemptLocked returns the latency of the link and whether a transmission
+// between the radios gets through.
+func (n *Network) attemptLocked(a, b *member) (time.Duration, bool) {
+	l, ok := n.links[linkKey(a.addr64, b.addr64)]
+	if !ok {
+		l = n.defaultLink
+	}
+	if l.Down {
+		return l.Latency, false
+	}
+	return l.Latency, n.rnd.Float64() >= l.Loss
+}
+
+// discover returns the node discovery responses for radios reachable from r.
+func (n *Network) discover(r *Radio) []ndEntry {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	src := n.memberLocked(r)
+	var nodes []ndEntry
+	for _, m := range n.members {
+		if m == src {
+			continue
+		}
+		if _, ok := n.attemptLocked(src, m); !ok {
+			continue
+		}
+		parent := xbee.Address16Unknown
+		if m.deviceType == xbee.EndDevice {
+			parent = 0
+		}
+		nodes = append(nodes, ndEntry{
+			addr16: m.addr16,
+			node: xbee.Node{
+				SerialNumber:         m.addr64,
+				NodeID:               string(m.radio.Register("NI")),
+				ParentNetworkAddress: parent,
+				DeviceType:           m.deviceType,
+				ProfileID:            xbee.ProfileDigi,
+				ManufacturerID:       0x101e,
+			},
+		})
+	}
+	return nodes
+}
+
+// transmit delivers a transmit request from r and returns the delivery
+// status once the transmission completes.
+func (n *Network) transmit(r *Radio, f frames.Frame) xbee.DeliveryStatus {
+	rx := &frames.ExplicitReceivePacket{}
+	var dest64 xbee.Addr64
+	var dest16 xbee.Addr16
+	switch f := f.(type) {
+	case *frames.TransmitRequest:
+		dest64, dest16 = f.DestinationAddress, f.DestinationAddress16
+		rx.SourceEndpoint = xbee.DefaultExplicitAddress.SourceEndpoint
+		rx.DestinationEndpoint = xbee.DefaultExplicitAddress.DestinationEndpoint
+		rx.ClusterID = xbee.DefaultExplicitAddress.ClusterID
+		rx.ProfileID = xbee.DefaultExplicitAddress.ProfileID
+		rx.Data = f.Data
+	case *frames.ExplicitTransmitRequest:
+		dest64, dest16 = f.DestinationAddress, f.DestinationAddress16
+		rx.SourceEndpoint = f.SourceEndpoint
+		rx.DestinationEndpoint = f.DestinationEndpoint
+		rx.ClusterID = f.ClusterID
+		rx.ProfileID = f.ProfileID
+		rx.Data = f.Data
+	}
+
+	n.mu.Lock()
+	src := n.memberLocked(r)
+	rx.SourceAddress, rx.SourceAddress16 = src.addr64, src.addr16
+	if dest64 == xbee.AddressBroadcast || (dest64 == xbee.AddressUnknown && isBroadcast16(dest16)) {
+		// Broadcasts aren't acknowledged so lost deliveries aren't reported.
+		rx.ReceiveOptions = xbee.ROBroadcast
+		for _, m := range n.members {
+			if m == src {
+				continue
+			}
+			if latency, ok := n.attemptLocked(src, m); ok {
+				go deliver(m.radio, rx, latency)
+			}
+		}
+		n.mu.Unlock()
+		return xbee.DSSuccess
+	}
+	dest := n.findLocked(dest64, dest16)
+	if dest == nil || dest == src {
+		n.mu.Unlock()
+		return xbee.DSAddressNotFound
+	}
+	latency, ok := n.attemptLocked(src, dest)
+	n.mu.Unlock()
+
+	rx.ReceiveOptions = xbee.ROAcknowledged
+	time.Sleep(latency)
+	if !ok {
+		return xbee.DSNetworkACKFailure
+	}
+	if isLoopback(rx) {
+		// The destination module echoes the data back to the source.
+		echo := *rx
+		echo.SourceAddress, echo.SourceAddress16 = dest.addr64, dest.addr16
+		go deliver(src.radio, &echo, latency)
+	} else {
+		deliver(dest.radio, rx, 0)
+	}
+	time.Sleep(latency)
+	return xbee.DSSuccess
+}
+
+// isLoopback returns whether a packet is for the loopback cluster which
+// Digi firmware answers itself.
+func isLoopback(rx *frames.ExplicitReceivePacket) bool {
+	return rx.DestinationEndpoint == xbee.EndpointDigiData && rx.ClusterID == xbee.ClusterLoopback &&
+		rx.ProfileID == xbee.ProfileDigi
+}
+
+func isBroadcast16(addr xbee.Addr16) bool {
+	return addr == xbee.Address16Broadcast || addr == xbee.Address16BroadcastRxOnIdle || addr == xbee.Address16BroadcastRouters
+}
+
+// deliver sends a received packet to the host of r using the explicit
+// frame if explicit receive is enabled (AO=1).
+func deliver(r *Radio, rx *frames.ExplicitReceivePacket, latency time.Duration) {
+	time.Sleep(latency)
+	if ao := r.Register("AO"); len(ao) > 0 && ao[0] != 0 {
+		r.Inject(rx)
+		return
+	}
+	r.Inject(&frames.ReceivePacket{
+		SourceAddress:   rx.SourceAddress,
+		SourceAddress16: rx.SourceAddress16,
+		ReceiveOptions:  rx.ReceiveOptions,
+		Data:            rx.Data,
+	})
+}
+
+// remoteATCommand executes a remote AT command on the destination radio
+// and sends the response to r.
+func (n *Network) remoteATCommand(r *Radio, f *frames.RemoteATCommandRequest) {
+	res := &frames.RemoteATCommandResponse{
+		FrameID:         f.FrameID,
+		SourceAddress:   f.DestinationAddress,
+		SourceAddress16: f.DestinationAddress16,
+		ATCommand:       f.ATCommand,
+		CommandStatus:   xbee.CSTxFailure,
+	}
+	n.mu.Lock()
+	src := n.memberLocked(r)
+	dest := n.findLocked(f.DestinationAddress, f.DestinationAddress16)
+	var latency time.Duration
+	ok := dest != nil && dest != src
+	if ok {
+		latency, ok = n.attemptLocked(src, dest)
+	}
+	n.mu.Unlock()
+
+	time.Sleep(latency)
+	if ok {
+		res.SourceAddress, res.SourceAddress16 = dest.addr64, dest.addr16
+		res.CommandStatus, res.Data = dest.radio.atCommand(f.ATCommand, f.Parameter)
+		time.Sleep(latency)
+	}
+	if f.FrameID != 0 {
+		r.Inject(res)
+	}
+}
@@ -0,0 +1,356 @@
+// Package xbeetest provides a simulated XBee module for testing code built
+// on the xbee package without hardware.
+package xbeetest
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/samuel/go-xbee/xbee"
+	"github.com/samuel/go-xbee/xbee/frames"
+)
+
+var ErrClosed = errors.New("xbeetest: radio closed")
+
+// Radio is a simulated module in API mode. It implements io.ReadWriter so
+// it can be passed to xbee.Open: frames written by the host are handled
+// by the simulated module and its responses are returned by Read.
+//
+// AT commands are answered from a register map. A query returns the
+// register's value or an invalid command status if it isn't set. Setting
+// a register stores the parameter.
+type Radio struct {
+	// Escaped enables API mode 2 framing. It must be set before use.
+	Escaped bool
+
+	mu          sync.Mutex
+	cond        *sync.Cond
+	out         []byte
+	closed      bool
+	registers   map[xbee.ATCommand][]byte
+	nodes       []ndEntry
+	status      xbee.DeliveryStatus
+	transmitted []frames.Frame
+	network     *Network
+
+	wmu sync.Mutex // protects wr
+	wr  *frames.Writer
+
+	startOnce sync.Once
+	pw        *io.PipeWriter
+}
+
+type ndEntry struct {
+	addr16 xbee.Addr16
+	node   xbee.Node
+}
+
+// Default register values for a ZB router in API mode 1.
+var defaultRegisters = map[string][]byte{
+	"SH": {0x00, 0x13, 0xa2, 0x00},
+	"SL": {0x40, 0x00, 0x00, 0x01},
+	"MY": {0xff, 0xfe},
+	"NI": {' '},
+	"AP": {1},
+	"AI": {0},
+	"VR": {0x40, 0x6b},
+	"HV": {0x1e, 0x42},
+	"NP": {0x00, 0x54},
+	"NT": {0x3c},
+	"NO": {0},
+	"EE": {0},
+	"EO": {0},
+	"ID": {0, 0, 0, 0, 0, 0, 0, 0},
+	"OP": {0, 0, 0, 0, 0, 0, 0, 0},
+	"BD": {7},
+	"DD": {0x00, 0x12, 0x00, 0x00},
+}
+
+// NewRadio returns a simulated module with default register values.
+func NewRadio() *Radio {
+	r := &Radio{registers: make(map[xbee.ATCommand][]byte)}
+	r.cond = sync.NewCond(&r.mu)
+	r.wr = frames.NewWriter(outWriter{r})
+	for cmd, val := range defaultRegisters {
+		r.registers[atCommand(cmd)] = val
+	}
+	return r
+}
+
+func atCommand(cmd string) xbee.ATCommand {
+	return xbee.ATCommand{cmd[0], cmd[1]}
+}
+
+// SetRegister sets the value of a register (e.g. "NI").
+func (r *Radio) SetRegister(cmd string, val []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.registers[atCommand(cmd)] = val
+}
+
+// Register returns the value of a register or nil if it isn't set.
+func (r *Radio) Register(cmd string) []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.registers[atCommand(cmd)]
+}
+
+// Address returns the 64-bit address from the SH and SL registers.
+func (r *Radio) Address() xbee.Addr64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return xbee.Addr64(decodeUint(r.registers[atCommand("SH")])<<32 | decodeUint(r.registers[atCommand("SL")]))
+}
+
+// SetAddress sets the SH and SL registers.
+func (r *Radio) SetAddress(addr xbee.Addr64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.registers[atCommand("SH")] = binary.BigEndian.AppendUint32(nil, uint32(addr>>32))
+	r.registers[atCommand("SL")] = binary.BigEndian.AppendUint32(nil, uint32(addr))
+}
+
+// AddNode adds a node reported in response to node discovery (ND).
+func (r *Radio) AddNode(addr16 xbee.Addr16, n xbee.Node) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nodes = append(r.nodes, ndEntry{addr16: addr16, node: n})
+}
+
+// SetDeliveryStatus sets the status reported for transmit requests. The
+// default is DSSuccess.
+func (r *Radio) SetDeliveryStatus(status xbee.DeliveryStatus) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status = status
+}
+
+// Transmitted returns the transmit requests written by the host.
+func (r *Radio) Transmitted() []frames.Frame {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]frames.Frame(nil), r.transmitted...)
+}
+
+// Inject sends a frame to the host as if it was generated by the module.
+func (r *Radio) Inject(f frames.Frame) error {
+	r.startOnce.Do(r.start)
+	r.wmu.Lock()
+	defer r.wmu.Unlock()
+	return r.wr.WriteFrame(f)
+}
+
+// InjectReceive sends a receive packet to the host as if data was
+// received from a remote device.
+func (r *Radio) InjectReceive(src xbee.Addr64, src16 xbee.Addr16, data []byte) error {
+	return r.Inject(&frames.ReceivePacket{
+		SourceAddress:   src,
+		SourceAddress16: src16,
+		ReceiveOptions:  xbee.ROAcknowledged,
+		Data:            data,
+	})
+}
+
+// Read returns data written by the module to the host. It blocks until
+// data is available or the radio is closed.
+func (r *Radio) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for len(r.out) == 0 {
+		if r.closed {
+			return 0, io.EOF
+		}
+		r.cond.Wait()
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+// Write handles data written by the host to the module.
+func (r *Radio) Write(p []byte) (int, error) {
+	r.startOnce.Do(r.start)
+	return r.pw.Write(p)
+}
+
+// Close closes the radio causing pending reads to return io.EOF.
+func (r *Radio) Close() error {
+	r.startOnce.Do(r.start)
+	r.mu.Lock()
+	r.closed = true
+	r.cond.Broadcast()
+	r.mu.Unlock()
+	return r.pw.Close()
+}
+
+// outWriter appends data for the host to read.
+type outWriter struct {
+	r *Radio
+}
+
+func (w outWriter) Write(p []byte) (int, error) {
+	w.r.mu.Lock()
+	defer w.r.mu.Unlock()
+	if w.r.closed {
+		return 0, ErrClosed
+	}
+	w.r.out = append(w.r.out, p...)
+	w.r.cond.Broadcast()
+	return len(p), nil
+}
+
+func (r *Radio) start() {
+	pr, pw := io.Pipe()
+	r.pw = pw
+	r.wr.Escaped = r.Escaped
+	rd := frames.NewReader(pr)
+	rd.Escaped = r.Escaped
+	go func() {
+		for {
+			data, err := rd.ReadData()
+			if err == frames.ErrChecksum || err == frames.ErrEmpty || err == frames.ErrTruncated {
+				continue
+			} else if err != nil {
+				return
+			}
+			f, err := frames.Decode(data)
+			if err != nil {
+				continue
+			}
+			r.handle(f)
+		}
+	}()
+}
+
+func (r *Radio) handle(f frames.Frame) {
+	switch f := f.(type) {
+	case *frames.ATCommandRequest:
+		r.handleATCommand(f)
+	case *frames.TransmitRequest:
+		r.handleTransmit(f, f.FrameID)
+	case *frames.ExplicitTransmitRequest:
+		r.handleTransmit(f, f.FrameID)
+	case *frames.RemoteATCommandRequest:
+		if nw := r.getNetwork(); nw != nil {
+			go nw.remoteATCommand(r, f)
+		} else if f.FrameID != 0 {
+			// There are no remote modules
+			r.Inject(&frames.RemoteATCommandResponse{
+				FrameID:         f.FrameID,
+				SourceAddress:   f.DestinationAddress,
+				SourceAddress16: f.DestinationAddress16,
+				ATCommand:       f.ATCommand,
+				CommandStatus:   xbee.CSTxFailure,
+			})
+		}
+	}
+}
+
+func (r *Radio) getNetwork() *Network {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.network
+}
+
+func (r *Radio) handleATCommand(f *frames.ATCommandRequest) {
+	if f.ATCommand == atCommand("ND") {
+		r.mu.Lock()
+		nodes := append([]ndEntry(nil), r.nodes...)
+		nw := r.network
+		r.mu.Unlock()
+		if nw != nil {
+			nodes = append(nodes, nw.discover(r)...)
+		}
+		for _, n := range nodes {
+			r.Inject(&frames.ATCommandResponse{
+				FrameID:       f.FrameID,
+				ATCommand:     f.ATCommand,
+				CommandStatus: xbee.CSOK,
+				Data:          encodeNode(n),
+			})
+		}
+		return
+	}
+
+	status, data := r.atCommand(f.ATCommand, f.Parameter)
+	if f.FrameID == 0 {
+		return
+	}
+	r.Inject(&frames.ATCommandResponse{
+		FrameID:       f.FrameID,
+		ATCommand:     f.ATCommand,
+		CommandStatus: status,
+		Data:          data,
+	})
+}
+
+// atCommand queries or sets a register.
+func (r *Radio) atCommand(cmd xbee.ATCommand, param []byte) (xbee.CommandStatus, []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch string(cmd[:]) {
+	case "WR", "AC", "CN", "FR", "RE":
+		return xbee.CSOK, nil
+	}
+	if len(param) != 0 {
+		r.registers[cmd] = append([]byte(nil), param...)
+		return xbee.CSOK, nil
+	}
+	val, ok := r.registers[cmd]
+	if !ok {
+		return xbee.CSInvalidCommand, nil
+	}
+	return xbee.CSOK, val
+}
+
+func encodeNode(n ndEntry) []byte {
+	b := binary.BigEndian.AppendUint16(nil, uint16(n.addr16))
+	b = binary.BigEndian.AppendUint64(b, uint64(n.node.SerialNumber))
+	b = append(b, n.node.NodeID...)
+	b = append(b, 0)
+	b = binary.BigEndian.AppendUint16(b, uint16(n.node.ParentNetworkAddress))
+	b = append(b, byte(n.node.DeviceType), n.node.Status)
+	b = binary.BigEndian.AppendUint16(b, n.node.ProfileID)
+	return binary.BigEndian.AppendUint16(b, n.node.ManufacturerID)
+}
+
+func (r *Radio) handleTransmit(f frames.Frame, frameID byte) {
+	r.mu.Lock()
+	r.transmitted = append(r.transmitted, f)
+	status := r.status
+	nw := r.network
+	r.mu.Unlock()
+	var dest xbee.Addr16
+	switch f := f.(type) {
+	case *frames.TransmitRequest:
+		dest = f.DestinationAddress16
+	case *frames.ExplicitTransmitRequest:
+		dest = f.DestinationAddress16
+	}
+	sendStatus := func(status xbee.DeliveryStatus) {
+		if frameID != 0 {
+			r.Inject(&frames.TransmitStatus{
+				FrameID:            frameID,
+				DestinationAddress: dest,
+				DeliveryStatus:     status,
+			})
+		}
+	}
+	if nw != nil {
+		go func() {
+			sendStatus(nw.transmit(r, f))
+		}()
+		return
+	}
+	sendStatus(status)
+}
+
+func decodeUint(b []byte) uint64 {
+	var v uint64
+	for _, b := range b {
+		v = (v << 8) | uint64(b)
+	}
+	return v
+}
@@ -0,0 +1,206 @@
+package xbee
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	xmodemSOH       = 0x01
+	xmodemEOT       = 0x04
+	xmodemACK       = 0x06
+	xmodemNAK       = 0x15
+	xmodemCAN       = 0x18
+	xmodemCRCMode   = 'C'
+	xmodemPad       = 0xff
+	xmodemBlockLen  = 128
+	xmodemRetries   = 10
+	xmodemTimeout   = 10 * time.Second
+	bootloaderReady = "BL >"
+	// Menu options of the Gecko bootloader.
+	bootloaderUpload = '1'
+	bootloaderRun    = '2'
+)
+
+// BootloaderBaudRate is the baud rate of the serial bootloader.
+const BootloaderBaudRate = 115200
+
+// ErrXMODEMCancelled is returned by UpdateFirmwareXMODEM when the
+// bootloader cancels the transfer, e.g. because the image is invalid.
+var ErrXMODEMCancelled = errors.New("xbee: XMODEM transfer cancelled by receiver")
+
+// EnterBootloader resets the local module into its serial bootloader (%P)
+// to update its firmware with UpdateFirmwareXMODEM (XBee3 only). The
+// module stops responding to API frames so the port must then be closed
+// and opened again at BootloaderBaudRate for the bootloader.
+func (xb *XBee) EnterBootloader() error {
+	if err := xb.require(FeatureBootloader); err != nil {
+		return err
+	}
+	_, err := xb.atCommand(atInvokeBootloader, nil)
+	return err
+}
+
+// UpdateFirmwareXMODEM uploads a firmware image (.gbl) to a local module
+// in its serial bootloader (see EnterBootloader) using XMODEM-CRC and runs
+// the new firmware. The port must not be used by an XBee. A transfer that
+// fails part way through must be started again from the beginning.
+// Reading from port continues in the background until the port is closed.
+func UpdateFirmwareXMODEM(ctx context.Context, port io.ReadWriter, image []byte, progress func(FirmwareProgress)) error {
+	report := func(state FirmwareUpdateState, offset int) {
+		if progress != nil {
+			progress(FirmwareProgress{State: state, Offset: offset, Total: len(image)})
+		}
+	}
+	rd := newByteReader(port)
+
+	// Bring up the menu and start the upload. The bootloader then sends
+	// 'C' to request a transfer with CRCs.
+	if _, err := port.Write([]byte{'\r'}); err != nil {
+		return err
+	}
+	if err := rd.waitFor(ctx, []byte(bootloaderReady), xmodemTimeout); err != nil {
+		return fmt.Errorf("xbee.UpdateFirmwareXMODEM: waiting for bootloader: %w", err)
+	}
+	if _, err := port.Write([]byte{bootloaderUpload}); err != nil {
+		return err
+	}
+	if err := rd.waitFor(ctx, []byte{xmodemCRCMode}, xmodemTimeout); err != nil {
+		return fmt.Errorf("xbee.UpdateFirmwareXMODEM: waiting for receiver: %w", err)
+	}
+
+	block := make([]byte, 3+xmodemBlockLen+2)
+	seq := byte(1)
+	for offset := 0; offset < len(image); offset += xmodemBlockLen {
+		report(FirmwareTransferring, offset)
+		block[0], block[1], block[2] = xmodemSOH, seq, ^seq
+		data := block[3 : 3+xmodemBlockLen]
+		n := copy(data, image[offset:])
+		for i := n; i < len(data); i++ {
+			data[i] = xmodemPad
+		}
+		crc := crc16XMODEM(data)
+		block[3+xmodemBlockLen], block[4+xmodemBlockLen] = byte(crc>>8), byte(crc)
+		if err := rd.send(ctx, port, block); err != nil {
+			return fmt.Errorf("xbee.UpdateFirmwareXMODEM: block %d: %w", seq, err)
+		}
+		seq++
+	}
+	if err := rd.send(ctx, port, []byte{xmodemEOT}); err != nil {
+		return fmt.Errorf("xbee.UpdateFirmwareXMODEM: end of transfer: %w", err)
+	}
+
+	report(FirmwareInstalling, len(image))
+	if err := rd.waitFor(ctx, []byte(bootloaderReady), xmodemTimeout); err != nil {
+		return fmt.Errorf("xbee.UpdateFirmwareXMODEM: waiting for bootloader: %w", err)
+	}
+	if _, err := port.Write([]byte{bootloaderRun}); err != nil {
+		return err
+	}
+	report(FirmwareDone, len(image))
+	return nil
+}
+
+// byteReader reads from a port in the background so reads can time out.
+type byteReader struct {
+	ch  chan byte
+	err chan error
+}
+
+func newByteReader(r io.Reader) *byteReader {
+	br := &byteReader{ch: make(chan byte, 256), err: make(chan error, 1)}
+	go func() {
+		buf := make([]byte, 64)
+		for {
+			n, err := r.Read(buf)
+			for _, c := range buf[:n] {
+				br.ch <- c
+			}
+			if err != nil {
+				br.err <- err
+				return
+			}
+		}
+	}()
+	return br
+}
+
+func (br *byteReader) readByte(ctx context.Context, timeout time.Duration) (byte, error) {
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case c := <-br.ch:
+		return c, nil
+	case err := <-br.err:
+		return 0, err
+	case <-t.C:
+		return 0, ErrTimeout
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+// waitFor reads until s has been received.
+func (br *byteReader) waitFor(ctx context.Context, s []byte, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	var seen []byte
+	for !bytes.HasSuffix(seen, s) {
+		c, err := br.readByte(ctx, time.Until(deadline))
+		if err != nil {
+			return err
+		}
+		seen = append(seen, c)
+		if len(seen) > len(s) {
+			seen = seen[1:]
+		}
+	}
+	return nil
+}
+
+// send writes b until the receiver acknowledges it.
+func (br *byteReader) send(ctx context.Context, w io.Writer, b []byte) error {
+	for range xmodemRetries {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+		for {
+			c, err := br.readByte(ctx, xmodemTimeout)
+			if errors.Is(err, ErrTimeout) {
+				break
+			} else if err != nil {
+				return err
+			}
+			switch c {
+			case xmodemACK:
+				return nil
+			case xmodemCAN:
+				return ErrXMODEMCancelled
+			}
+			if c == xmodemNAK {
+				break
+			}
+			// Ignore anything else such as a stray 'C'.
+		}
+	}
+	return ErrTimeout
+}
+
+// crc16XMODEM is the CRC-16/XMODEM (polynomial 0x1021, initial value 0).
+func crc16XMODEM(b []byte) uint16 {
+	var crc uint16
+	for _, c := range b {
+		crc ^= uint16(c) << 8
+		for range 8 {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}